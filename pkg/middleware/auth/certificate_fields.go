@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// CertificateFieldProtocol is the BRC-53 protocol of the keys the field keys of the certificate keyrings are encrypted with.
+var CertificateFieldProtocol = wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "certificate field encryption"}
+
+// ErrInvalidCertificateField is returned by DecryptFields when a revealed field or its key in the keyring can't be decrypted.
+var ErrInvalidCertificateField = errors.New("invalid certificate field")
+
+// minFieldCiphertextSize is the size of the 32 bytes IV and the 16 bytes tag of an AES-GCM encrypted field value.
+const minFieldCiphertextSize = 48
+
+// CertificateDecrypter is the part of the wallet.Interface needed to decrypt the certificate fields revealed to it.
+type CertificateDecrypter interface {
+	Decrypt(ctx context.Context, ciphertext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error)
+}
+
+// DecryptFields returns the plaintext fields the certificate reveals to the wallet, by their names.
+// Each field key of the keyring, encrypted by the subject with the "<serialNumber> <fieldName>" keyID,
+// is decrypted by the wallet and decrypts the base64 encoded field value. The fields without a key in the keyring are omitted.
+func DecryptFields(ctx context.Context, w CertificateDecrypter, certificate wallet.Certificate) (map[string]string, error) {
+	if len(certificate.Keyring) == 0 {
+		return map[string]string{}, nil
+	}
+	subject, err := wallet.ParseCounterparty(certificate.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject: %w", ErrInvalidCertificateField, err)
+	}
+
+	fields := make(map[string]string, len(certificate.Keyring))
+	for fieldName, encryptedKey := range certificate.Keyring {
+		value, ok := certificate.Fields[fieldName].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: field %q isn't an encrypted value", ErrInvalidCertificateField, fieldName)
+		}
+		plaintext, err := decryptField(ctx, w, certificate.SerialNumber+" "+fieldName, subject, encryptedKey, value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidCertificateField, fieldName, err)
+		}
+		fields[fieldName] = plaintext
+	}
+	return fields, nil
+}
+
+type certificateFieldsContextKey struct{}
+
+// WithCertificateFields returns a copy of ctx carrying the decrypted certificate fields, by the certificate type.
+func WithCertificateFields(ctx context.Context, fields map[string]map[string]string) context.Context {
+	return context.WithValue(ctx, certificateFieldsContextKey{}, fields)
+}
+
+// GetCertificateFields returns the fields revealed by the certificates of the peer, decrypted by the verifier,
+// by the certificate type and the field name. It reports false for the requests without certificates.
+func GetCertificateFields(ctx context.Context) (map[string]map[string]string, bool) {
+	fields, ok := ctx.Value(certificateFieldsContextKey{}).(map[string]map[string]string)
+	return fields, ok
+}
+
+// certificateFields decrypts the fields of the certificates by their types, the first certificate of a type wins.
+func (v *GeneralMessageVerifier) certificateFields(ctx context.Context, certificates []wallet.Certificate) (map[string]map[string]string, error) {
+	fields := make(map[string]map[string]string, len(certificates))
+	for _, certificate := range certificates {
+		if _, ok := fields[certificate.Type]; ok {
+			continue
+		}
+		decrypted, err := DecryptFields(ctx, v.wallet, certificate)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %s: %w", certificate.SerialNumber, err)
+		}
+		fields[certificate.Type] = decrypted
+	}
+	return fields, nil
+}
+
+func decryptField(ctx context.Context, w CertificateDecrypter, keyID string, subject wallet.Counterparty, encryptedKey string, value string) (string, error) {
+	keyCiphertext, err := base64.StdEncoding.DecodeString(encryptedKey)
+	if err != nil {
+		return "", fmt.Errorf("keyring entry isn't base64 encoded: %w", err)
+	}
+	fieldKey, err := w.Decrypt(ctx, keyCiphertext, CertificateFieldProtocol, keyID, subject)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt field key: %w", err)
+	}
+
+	valueCiphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("value isn't base64 encoded: %w", err)
+	}
+	if len(valueCiphertext) < minFieldCiphertextSize {
+		return "", fmt.Errorf("value has %d bytes, expected at least %d", len(valueCiphertext), minFieldCiphertextSize)
+	}
+	plaintext, err := ec.NewSymmetricKey(fieldKey).Decrypt(valueCiphertext)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
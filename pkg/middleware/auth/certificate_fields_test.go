@@ -0,0 +1,205 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+// encryptedCertificate returns a certificate of the subject with the fields encrypted with fresh field keys,
+// revealing the revealed ones to the verifier the way ProveCertificate does.
+func encryptedCertificate(t *testing.T, subject wallet.Interface, verifier string, fields map[string]string, revealed ...string) wallet.Certificate {
+	t.Helper()
+	certificate := wallet.Certificate{
+		Type:         "email verification",
+		Subject:      identityKeyOf(t, subject),
+		SerialNumber: "serial-1",
+		Certifier:    emailCertifier,
+	}
+	encryptFields(t, &certificate, subject, verifier, fields, revealed...)
+	return certificate
+}
+
+// encryptFields sets the fields of the certificate encrypted with fresh field keys,
+// and its keyring revealing the revealed ones to the verifier.
+func encryptFields(t *testing.T, certificate *wallet.Certificate, subject wallet.Interface, verifier string, fields map[string]string, revealed ...string) {
+	t.Helper()
+	verifierCounterparty, err := wallet.ParseCounterparty(verifier)
+	require.NoError(t, err)
+	certificate.Fields = map[string]any{}
+	certificate.Keyring = map[string]string{}
+	for name, value := range fields {
+		fieldKey := ec.NewSymmetricKeyFromRandom()
+		ciphertext, err := fieldKey.Encrypt([]byte(value))
+		require.NoError(t, err)
+		certificate.Fields[name] = base64.StdEncoding.EncodeToString(ciphertext)
+		if !slices.Contains(revealed, name) {
+			continue
+		}
+		encryptedKey, err := subject.Encrypt(t.Context(), fieldKey.ToBytes(), auth.CertificateFieldProtocol,
+			certificate.SerialNumber+" "+name, verifierCounterparty)
+		require.NoError(t, err)
+		certificate.Keyring[name] = base64.StdEncoding.EncodeToString(encryptedKey)
+	}
+}
+
+func TestDecryptFields(t *testing.T) {
+	subject := newKeyWallet(t)
+	verifier := newKeyWallet(t)
+	fields := map[string]string{"email": "alice@example.com", "phone": "+41 79 000 00 00"}
+
+	tests := map[string]struct {
+		certificate    func(t *testing.T) wallet.Certificate
+		expectedFields map[string]string
+		expectedError  string
+	}{
+		"Decrypt the revealed fields": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				return encryptedCertificate(t, subject, identityKeyOf(t, verifier), fields, "email", "phone")
+			},
+			expectedFields: fields,
+		},
+		"Omit the fields without a key in the keyring": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				return encryptedCertificate(t, subject, identityKeyOf(t, verifier), fields, "email")
+			},
+			expectedFields: map[string]string{"email": "alice@example.com"},
+		},
+		"Decrypt no fields of a certificate without a keyring": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				return encryptedCertificate(t, subject, identityKeyOf(t, verifier), fields)
+			},
+			expectedFields: map[string]string{},
+		},
+		"Corrupted keyring entry": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				certificate := encryptedCertificate(t, subject, identityKeyOf(t, verifier), fields, "email")
+				encryptedKey, err := base64.StdEncoding.DecodeString(certificate.Keyring["email"])
+				require.NoError(t, err)
+				encryptedKey[len(encryptedKey)-1] ^= 0xff
+				certificate.Keyring["email"] = base64.StdEncoding.EncodeToString(encryptedKey)
+				return certificate
+			},
+			expectedError: `field "email": failed to decrypt field key`,
+		},
+		"Keyring entry that isn't base64": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				certificate := encryptedCertificate(t, subject, identityKeyOf(t, verifier), fields, "email")
+				certificate.Keyring["email"] = "not base64!"
+				return certificate
+			},
+			expectedError: `field "email": keyring entry isn't base64 encoded`,
+		},
+		"Keyring entry revealed to another verifier": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				return encryptedCertificate(t, subject, identityKeyOf(t, newKeyWallet(t)), fields, "email")
+			},
+			expectedError: `field "email": failed to decrypt field key`,
+		},
+		"Field value that isn't encrypted": {
+			certificate: func(t *testing.T) wallet.Certificate {
+				certificate := encryptedCertificate(t, subject, identityKeyOf(t, verifier), fields, "email")
+				certificate.Fields["email"] = base64.StdEncoding.EncodeToString([]byte("alice@example.com"))
+				return certificate
+			},
+			expectedError: `field "email": value has 17 bytes`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			certificate := test.certificate(t)
+
+			// when
+			decrypted, err := auth.DecryptFields(t.Context(), verifier, certificate)
+
+			// then
+			if test.expectedError != "" {
+				require.ErrorIs(t, err, auth.ErrInvalidCertificateField)
+				require.ErrorContains(t, err, test.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedFields, decrypted)
+		})
+	}
+}
+
+func TestGeneralMessageVerifier_CertificateFields(t *testing.T) {
+	// presentCertificate records the certificate in the session of the client.
+	presentCertificate := func(t *testing.T, f *generalMessageFixture, certificate wallet.Certificate) {
+		t.Helper()
+		require.NoError(t, auth.RecordReceivedCertificates(f.sessions)(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{certificate}))
+	}
+
+	t.Run("Pass the decrypted fields to the handler", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		presentCertificate(t, f, encryptedCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"}, "email"))
+		var fields map[string]map[string]string
+		f.respond = func(rw http.ResponseWriter, r *http.Request) {
+			fields, _ = auth.GetCertificateFields(r.Context())
+			rw.WriteHeader(http.StatusOK)
+		}
+
+		// when
+		response, _ := send(t, validRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, map[string]map[string]string{"email verification": {"email": "alice@example.com"}}, fields)
+	})
+
+	t.Run("Reject a request with a corrupted keyring", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		certificate := encryptedCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"}, "email")
+		certificate.Keyring["email"] = base64.StdEncoding.EncodeToString([]byte("corrupted"))
+		presentCertificate(t, f, certificate)
+
+		// when
+		response, body := send(t, validRequest(t, f))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeCertificatesRejected)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("No fields without certificates", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		ok := true
+		f.respond = func(rw http.ResponseWriter, r *http.Request) {
+			_, ok = auth.GetCertificateFields(r.Context())
+			rw.WriteHeader(http.StatusOK)
+		}
+
+		// when
+		response, _ := send(t, validRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.False(t, ok)
+	})
+}
+
+func TestCertificateFields_Context(t *testing.T) {
+	// given
+	fields := map[string]map[string]string{"email verification": {"email": "alice@example.com"}}
+
+	// when
+	ctx := auth.WithCertificateFields(context.Background(), fields)
+
+	// then
+	retrieved, ok := auth.GetCertificateFields(ctx)
+	require.True(t, ok)
+	require.Equal(t, fields, retrieved)
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/wire"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// CertificateSignatureProtocol is the BRC-52 protocol of the keys the certifiers sign the certificates with.
+var CertificateSignatureProtocol = wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "certificate signature"}
+
+var (
+	// ErrInvalidCertificateSignature is returned by VerifyCertificateSignature when the certificate isn't signed by its certifier.
+	ErrInvalidCertificateSignature = errors.New("invalid certificate signature")
+	// ErrForeignCertificate is returned for a certificate presented by a peer who isn't its subject.
+	ErrForeignCertificate = errors.New("certificate of another subject")
+)
+
+// CertificateSigner is the part of the wallet.Interface a certifier needs to sign certificates.
+type CertificateSigner interface {
+	GetPublicKey(ctx context.Context, options wallet.GetPublicKeyOptions) (string, error)
+	CreateSignature(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty, privilege wallet.Privilege) ([]byte, error)
+}
+
+// SignCertificate returns a copy of the certificate issued by the certifier: with its identity key as the Certifier
+// and its signature, over the certificate without the signature, made for "anyone" with the "<type> <serialNumber>" keyID.
+func SignCertificate(ctx context.Context, certifier CertificateSigner, certificate wallet.Certificate) (wallet.Certificate, error) {
+	identityKey, err := certifier.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		return wallet.Certificate{}, fmt.Errorf("failed to get certifier identity key: %w", err)
+	}
+
+	signed := certificate.Clone()
+	signed.Certifier = identityKey
+	signed.Signature = ""
+	data, err := wire.CertificateSigningData(signed)
+	if err != nil {
+		return wallet.Certificate{}, fmt.Errorf("failed to encode certificate %s: %w", certificate.SerialNumber, err)
+	}
+	signature, err := certifier.CreateSignature(ctx, data, CertificateSignatureProtocol, certificateKeyID(signed),
+		wallet.CounterpartyAnyone(), wallet.Privilege{})
+	if err != nil {
+		return wallet.Certificate{}, fmt.Errorf("failed to sign certificate %s: %w", certificate.SerialNumber, err)
+	}
+	signed.Signature = hex.EncodeToString(signature)
+	return signed, nil
+}
+
+// VerifyCertificateSignature checks the certificate is signed by its certifier, as SignCertificate does it,
+// so anyone can verify it with the certifier's identity key.
+func VerifyCertificateSignature(ctx context.Context, certificate wallet.Certificate) error {
+	certifier, err := wallet.ParseCounterparty(certificate.Certifier)
+	if err != nil || certifier.Type != wallet.CounterpartyTypeOther {
+		return fmt.Errorf("%w: certifier %q isn't a public key", ErrInvalidCertificateSignature, certificate.Certifier)
+	}
+	signature, err := hex.DecodeString(certificate.Signature)
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%w: signature isn't hex encoded", ErrInvalidCertificateSignature)
+	}
+	data, err := wire.CertificateSigningData(certificate)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCertificateSignature, err)
+	}
+
+	valid, err := anyoneWallet().VerifySignature(ctx, data, signature, CertificateSignatureProtocol, certificateKeyID(certificate), certifier)
+	if err != nil {
+		return fmt.Errorf("failed to verify signature of certificate %s: %w", certificate.SerialNumber, err)
+	}
+	if !valid {
+		return fmt.Errorf("%w: certificate %s isn't signed by %s", ErrInvalidCertificateSignature, certificate.SerialNumber, certificate.Certifier)
+	}
+	return nil
+}
+
+// verifyPresentedCertificate checks the certificate presented by the sender is about the sender and signed by its certifier.
+func verifyPresentedCertificate(ctx context.Context, senderPublicKey string, certificate wallet.Certificate) error {
+	if certificate.Subject != senderPublicKey {
+		return fmt.Errorf("%w: certificate %s of %s presented by %s", ErrForeignCertificate, certificate.SerialNumber, certificate.Subject, senderPublicKey)
+	}
+	return VerifyCertificateSignature(ctx, certificate)
+}
+
+// verifyPresentedCertificates checks all the certificates presented by the sender, see verifyPresentedCertificate.
+func verifyPresentedCertificates(ctx context.Context, senderPublicKey string, certificates []wallet.Certificate) error {
+	for _, certificate := range certificates {
+		if err := verifyPresentedCertificate(ctx, senderPublicKey, certificate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func certificateKeyID(certificate wallet.Certificate) string {
+	return certificate.Type + " " + certificate.SerialNumber
+}
+
+// anyoneWallet is the wallet of the private key 1, deriving the keys anyone can derive.
+func anyoneWallet() *keywallet.Wallet {
+	anyone, _ := ec.PrivateKeyFromBytes([]byte{1})
+	return keywallet.NewKeyWallet(anyone)
+}
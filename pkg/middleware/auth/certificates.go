@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// OnCertificatesReceived is called with the certificates the peer presented during the handshake.
+type OnCertificatesReceived func(ctx context.Context, senderPublicKey string, certificates []wallet.Certificate) error
+
+// CertificateAcquirer is the part of the wallet.Interface needed to persist the received certificates.
+type CertificateAcquirer interface {
+	AcquireCertificate(ctx context.Context, certificate wallet.Certificate) error
+}
+
+// AcquireReceivedCertificates returns an OnCertificatesReceived callback persisting the certificates in the wallet.
+// It stops at the first certificate the wallet fails to acquire.
+func AcquireReceivedCertificates(w CertificateAcquirer) OnCertificatesReceived {
+	return func(ctx context.Context, senderPublicKey string, certificates []wallet.Certificate) error {
+		for _, certificate := range certificates {
+			if err := w.AcquireCertificate(ctx, certificate); err != nil {
+				return fmt.Errorf("failed to acquire certificate %s from %s: %w", certificate.SerialNumber, senderPublicKey, err)
+			}
+		}
+		return nil
+	}
+}
+
+// CertificateProver is the part of the wallet.Interface needed to reveal certificate fields to a peer.
+type CertificateProver interface {
+	ProveCertificate(ctx context.Context, certificate wallet.Certificate, verifier string, fieldsToReveal []string, privilege wallet.Privilege) (map[string]string, error)
+}
+
+// ProveCertificateToPeer creates the keyring revealing the fields of the certificate to the peer requesting them.
+// With privileged set, it uses the privileged keyring and tells the wallet the fields are revealed to the peer.
+func ProveCertificateToPeer(ctx context.Context, w CertificateProver, certificate wallet.Certificate, peer string, fieldsToReveal []string, privileged bool) (map[string]string, error) {
+	var privilege wallet.Privilege
+	if privileged {
+		privilege = wallet.Privilege{Privileged: true, Reason: "reveal certificate fields to the authenticated peer " + peer}
+	}
+	keyring, err := w.ProveCertificate(ctx, certificate, peer, fieldsToReveal, privilege)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prove certificate %s to %s: %w", certificate.SerialNumber, peer, err)
+	}
+	return keyring, nil
+}
@@ -0,0 +1,96 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireReceivedCertificates(t *testing.T) {
+	t.Run("Persists the received certificates in the wallet", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+		onReceived := auth.AcquireReceivedCertificates(w)
+		certificates := []wallet.Certificate{
+			{Type: "email", SerialNumber: "1", Certifier: "certifier"},
+			{Type: "phone", SerialNumber: "2", Certifier: "certifier"},
+		}
+
+		// when
+		err := onReceived(context.Background(), "peer", certificates)
+
+		// then
+		require.NoError(t, err)
+		result, err := w.ListCertificates(context.Background(), wallet.ListCertificatesOptions{})
+		require.NoError(t, err)
+		require.Equal(t, certificates, result.Certificates)
+	})
+
+	t.Run("Fails on invalid certificate", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+		onReceived := auth.AcquireReceivedCertificates(w)
+
+		// when
+		err := onReceived(context.Background(), "peer", []wallet.Certificate{{Type: "email"}})
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+// privilegeRecorder records the privilege of the proved certificates.
+type privilegeRecorder struct {
+	privileges []wallet.Privilege
+}
+
+func (r *privilegeRecorder) ProveCertificate(_ context.Context, _ wallet.Certificate, _ string, _ []string, privilege wallet.Privilege) (map[string]string, error) {
+	r.privileges = append(r.privileges, privilege)
+	return map[string]string{}, nil
+}
+
+func TestProveCertificateToPeer(t *testing.T) {
+	certificate := wallet.Certificate{SerialNumber: "1", Fields: map[string]any{"email": "encrypted-email"}}
+
+	t.Run("Proves the certificate without privileges", func(t *testing.T) {
+		// given
+		w := &privilegeRecorder{}
+
+		// when
+		_, err := auth.ProveCertificateToPeer(context.Background(), w, certificate, fixtures.PeerIdentityKey, []string{"email"}, false)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []wallet.Privilege{{}}, w.privileges)
+	})
+
+	t.Run("Passes the reason of a privileged proof", func(t *testing.T) {
+		// given
+		w := &privilegeRecorder{}
+
+		// when
+		_, err := auth.ProveCertificateToPeer(context.Background(), w, certificate, fixtures.PeerIdentityKey, []string{"email"}, true)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []wallet.Privilege{{
+			Privileged: true,
+			Reason:     "reveal certificate fields to the authenticated peer " + fixtures.PeerIdentityKey,
+		}}, w.privileges)
+	})
+
+	t.Run("Fails when the wallet doesn't allow privileged proofs", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+		// when
+		_, err := auth.ProveCertificateToPeer(context.Background(), w, certificate, fixtures.PeerIdentityKey, []string{"email"}, true)
+
+		// then
+		require.ErrorContains(t, err, fixtures.ErrorNoPrivilege)
+	})
+}
@@ -0,0 +1,216 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneralMessageVerifier_SessionChallenge(t *testing.T) {
+	tests := map[string]struct {
+		fixture      func(t *testing.T) *generalMessageFixture
+		request      func(t *testing.T, f *generalMessageFixture) *http.Request
+		expectedCode string
+		challenged   bool
+	}{
+		"Challenge a request of an unknown session": {
+			fixture: func(t *testing.T) *generalMessageFixture {
+				return newGeneralMessageFixture(t, time.Now, true)
+			},
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequestInSession(t, f.client, requestNonce2, requestNonce1, "body")
+			},
+			expectedCode: auth.ErrCodeSessionNotFound,
+			challenged:   true,
+		},
+		"Challenge a request of an expired session": {
+			fixture: func(t *testing.T) *generalMessageFixture {
+				sessions := sessionmanager.NewSessionManager(
+					sessionmanager.WithClock(func() time.Time { return time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC) }),
+					sessionmanager.WithSessionTTL(time.Hour),
+				)
+				t.Cleanup(sessions.Close)
+				return newGeneralMessageFixtureWithSessions(t, sessions, time.Now, true)
+			},
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequest(t, f.client, requestNonce1, "body")
+			},
+			expectedCode: auth.ErrCodeSessionExpired,
+			challenged:   true,
+		},
+		"Don't challenge an invalid signature in a live session": {
+			fixture: func(t *testing.T) *generalMessageFixture {
+				return newGeneralMessageFixture(t, time.Now, true)
+			},
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.Header.Set("X-Bsv-Tenant", "tenant-b")
+				return request
+			},
+			expectedCode: auth.ErrCodeInvalidSignature,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := test.fixture(t)
+
+			// when
+			response, body := send(t, test.request(t, f))
+
+			// then
+			requireRejected(t, response, body, test.expectedCode)
+			require.Equal(t, test.challenged, response.Header.Get(auth.HeaderChallenge) != "")
+			require.False(t, f.handlerCalled)
+		})
+	}
+}
+
+func TestGeneralMessageVerifier_Rehandshake(t *testing.T) {
+	// challenge returns the challenge of a request to the session lost by the server.
+	challenge := func(t *testing.T, f *generalMessageFixture) string {
+		t.Helper()
+		session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+		require.NoError(t, err)
+		require.NoError(t, f.sessions.RemoveSession(t.Context(), *session))
+
+		response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+		requireRejected(t, response, body, auth.ErrCodeSessionNotFound)
+		return response.Header.Get(auth.HeaderChallenge)
+	}
+
+	t.Run("Redo the handshake with the challenge and retry", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		challengeNonce := challenge(t, f)
+		initialRequest, err := auth.NewInitialRequest(t.Context(), f.client, auth.RequestedCertificateSet{})
+		require.NoError(t, err)
+		initialRequest.YourNonce = challengeNonce
+
+		// when
+		handshake, handshakeBody := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, initialRequest)
+		require.Equal(t, http.StatusOK, handshake.StatusCode)
+		var initialResponse auth.AuthMessage
+		require.NoError(t, json.Unmarshal(handshakeBody, &initialResponse))
+		require.NoError(t, auth.VerifyInitialResponse(t.Context(), f.client, initialRequest, initialResponse))
+		response, body := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce2, "body"))
+
+		// then
+		require.Equal(t, challengeNonce, initialResponse.InitialNonce)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "body", string(body))
+		require.Equal(t, challengeNonce, f.identity.SessionNonce)
+	})
+
+	t.Run("Reject an answered challenge", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		challengeNonce := challenge(t, f)
+		answer := func() auth.AuthMessage {
+			initialRequest, err := auth.NewInitialRequest(t.Context(), f.client, auth.RequestedCertificateSet{})
+			require.NoError(t, err)
+			initialRequest.YourNonce = challengeNonce
+			return initialRequest
+		}
+		first, _ := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, answer())
+		require.Equal(t, http.StatusOK, first.StatusCode)
+
+		// when
+		response, body := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, answer())
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeInvalidChallenge)
+	})
+
+	t.Run("Reject a challenge the server didn't create", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		initialRequest, err := auth.NewInitialRequest(t.Context(), f.client, auth.RequestedCertificateSet{})
+		require.NoError(t, err)
+		initialRequest.YourNonce = requestNonce1
+
+		// when
+		response, body := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, initialRequest)
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeInvalidChallenge)
+	})
+}
+
+func TestGeneralMessageVerifier_AuthChallenge(t *testing.T) {
+	tests := map[string]struct {
+		authenticated bool
+		opts          []auth.GeneralMessageOption
+		request       func(t *testing.T, f *generalMessageFixture) *http.Request
+		expectedCode  string
+		expectedPath  string
+	}{
+		"Describe the handshake to a request without the auth headers": {
+			authenticated: true,
+			request:       anonymousRequest,
+			expectedCode:  auth.ErrCodeMissingAuthHeaders,
+			expectedPath:  auth.DefaultAuthEndpointPath,
+		},
+		"Describe the handshake to a request of an unauthenticated session": {
+			authenticated: false,
+			request:       validRequest,
+			expectedCode:  auth.ErrCodeSessionNotAuthenticated,
+			expectedPath:  auth.DefaultAuthEndpointPath,
+		},
+		"Describe the handshake to a request of an unknown session": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequestInSession(t, f.client, requestNonce2, requestNonce1, "body")
+			},
+			expectedCode: auth.ErrCodeSessionNotFound,
+			expectedPath: auth.DefaultAuthEndpointPath,
+		},
+		"Describe the handshake on the overridden auth endpoint": {
+			authenticated: true,
+			opts:          []auth.GeneralMessageOption{auth.WithAuthEndpointPath("/auth")},
+			request:       anonymousRequest,
+			expectedCode:  auth.ErrCodeMissingAuthHeaders,
+			expectedPath:  "/auth",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, test.authenticated, test.opts...)
+
+			// when
+			response, body := send(t, test.request(t, f))
+
+			// then
+			requireRejected(t, response, body, test.expectedCode)
+			var errorResponse auth.ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errorResponse))
+			require.Equal(t, &auth.AuthChallenge{
+				Version:      "0.1",
+				IdentityKey:  f.serverKey,
+				AuthEndpoint: test.expectedPath,
+			}, errorResponse.Challenge)
+		})
+	}
+
+	t.Run("Don't describe the handshake to a request of a live session", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		request := validRequest(t, f)
+		request.Header.Set("X-Bsv-Tenant", "tenant-b")
+
+		// when
+		response, body := send(t, request)
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeInvalidSignature)
+		var errorResponse map[string]any
+		require.NoError(t, json.Unmarshal(body, &errorResponse))
+		require.NotContains(t, errorResponse, "challenge")
+	})
+}
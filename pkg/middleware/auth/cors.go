@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// corsAuthHeaders are the BRC-104 headers the browsers have to be allowed to send and read.
+var corsAuthHeaders = []string{HeaderVersion, HeaderIdentityKey, HeaderNonce, HeaderYourNonce, HeaderSignature, HeaderRequestID}
+
+// defaultCORSMethods are the methods a CORSPolicy allows unless it lists its own.
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// CORSPolicy is the CORS policy of the routes it matches, see WithCORS.
+// The x-bsv-auth headers, the other signed x-bsv-* headers, the content-type and the authorization are always allowed
+// in the requests, and the x-bsv-auth headers and the HeaderChallenge are exposed in the responses.
+type CORSPolicy struct {
+	// Routes matches the requests the policy applies to, e.g. SkipPaths("/api/"), all of them if nil
+	Routes SkipRule
+	// AllowedOrigins are the origins allowed to call the routes, "*" allows any of them
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in the requests, GET, HEAD, POST, PUT, PATCH and DELETE if empty
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed besides the always allowed ones
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers exposed besides the always exposed ones
+	ExposedHeaders []string
+	// AllowCredentials lets the browsers send the cookies, the origin is then echoed even if any is allowed
+	AllowCredentials bool
+	// MaxAge is how long the browsers may cache the answer to a preflight, not sent if zero
+	MaxAge time.Duration
+}
+
+// WithCORS answers the CORS preflights of the routes of the policy before the authentication runs,
+// and adds the CORS headers to the responses to the allowed origins, including the rejections, so the browser clients
+// can read the error codes. The policies of repeated options add up, the first one matching the request applies.
+func WithCORS(policy CORSPolicy) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.corsPolicies = append(v.corsPolicies, policy)
+	}
+}
+
+// applyCORS adds the CORS headers of the policy matching the cross-origin request and reports whether the request
+// is a preflight, answered with 204 without calling the next handler.
+func (v *GeneralMessageVerifier) applyCORS(rw http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	index := slices.IndexFunc(v.corsPolicies, func(policy CORSPolicy) bool {
+		return policy.Routes == nil || policy.Routes(r)
+	})
+	if index < 0 {
+		return false
+	}
+	policy := v.corsPolicies[index]
+
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	header := rw.Header()
+	header.Add("Vary", "Origin")
+	if policy.allowsOrigin(origin) {
+		if slices.Contains(policy.AllowedOrigins, "*") && !policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			policy.writePreflightHeaders(header, r)
+		} else {
+			header.Set("Access-Control-Expose-Headers", strings.Join(policy.exposedHeaders(), ", "))
+		}
+	}
+	if preflight {
+		rw.WriteHeader(http.StatusNoContent)
+	}
+	return preflight
+}
+
+func (p CORSPolicy) allowsOrigin(origin string) bool {
+	return slices.ContainsFunc(p.AllowedOrigins, func(allowed string) bool {
+		return allowed == "*" || strings.EqualFold(allowed, origin)
+	})
+}
+
+// writePreflightHeaders allows the methods of the policy and the requested headers it allows.
+func (p CORSPolicy) writePreflightHeaders(header http.Header, r *http.Request) {
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	var allowed []string
+	for _, name := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && p.allowsHeader(name) {
+			allowed = append(allowed, name)
+		}
+	}
+	if len(allowed) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(allowed, ", "))
+	}
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	if p.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+	}
+}
+
+func (p CORSPolicy) allowsHeader(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "x-bsv-"), name == "content-type", name == "authorization":
+		return true
+	default:
+		return slices.ContainsFunc(p.AllowedHeaders, func(allowed string) bool {
+			return strings.EqualFold(allowed, name)
+		})
+	}
+}
+
+func (p CORSPolicy) exposedHeaders() []string {
+	return append(append(slices.Clone(corsAuthHeaders), HeaderChallenge), p.ExposedHeaders...)
+}
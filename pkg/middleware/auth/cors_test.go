@@ -0,0 +1,116 @@
+package auth_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneralMessageVerifier_CORS(t *testing.T) {
+	apiPolicy := auth.CORSPolicy{
+		Routes:         auth.SkipPaths("/api/"),
+		AllowedOrigins: []string{"https://app.example.com"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	preflight := func(t *testing.T, f *generalMessageFixture, path string, origin string) *http.Request {
+		t.Helper()
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodOptions, f.server.URL+path, nil)
+		require.NoError(t, err)
+		request.Header.Set("Origin", origin)
+		request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		request.Header.Set("Access-Control-Request-Headers", "content-type, x-bsv-auth-signature, x-bsv-tenant, x-other")
+		return request
+	}
+
+	t.Run("Answer the preflight of an allowed origin before the authentication", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCORS(apiPolicy))
+
+		// when
+		response, _ := send(t, preflight(t, f, "/api/orders", "https://app.example.com"))
+
+		// then
+		require.Equal(t, http.StatusNoContent, response.StatusCode)
+		require.False(t, f.handlerCalled)
+		require.Equal(t, "https://app.example.com", response.Header.Get("Access-Control-Allow-Origin"))
+		require.Equal(t, "content-type, x-bsv-auth-signature, x-bsv-tenant", response.Header.Get("Access-Control-Allow-Headers"))
+		require.Contains(t, response.Header.Get("Access-Control-Allow-Methods"), http.MethodPost)
+		require.Equal(t, "600", response.Header.Get("Access-Control-Max-Age"))
+	})
+
+	t.Run("Answer the preflight of the auth endpoint", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCORS(auth.CORSPolicy{AllowedOrigins: []string{"*"}}))
+
+		// when
+		response, _ := send(t, preflight(t, f, auth.DefaultAuthEndpointPath, "https://app.example.com"))
+
+		// then
+		require.Equal(t, http.StatusNoContent, response.StatusCode)
+		require.Equal(t, "*", response.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Don't allow the preflight of another origin", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCORS(apiPolicy))
+
+		// when
+		response, _ := send(t, preflight(t, f, "/api/orders", "https://evil.example.com"))
+
+		// then
+		require.Equal(t, http.StatusNoContent, response.StatusCode)
+		require.Empty(t, response.Header.Get("Access-Control-Allow-Origin"))
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Authenticate the OPTIONS of a route without a policy", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCORS(apiPolicy))
+
+		// when
+		response, body := send(t, preflight(t, f, "/internal", "https://app.example.com"))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeMissingAuthHeaders)
+		require.Empty(t, response.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Expose the auth headers of the signed response", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCORS(apiPolicy))
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, f.server.URL+"/api/orders", strings.NewReader("body"))
+		require.NoError(t, err)
+		request.Header.Set("Origin", "https://app.example.com")
+		require.NoError(t, auth.SignGeneralMessage(request, f.client, f.serverKey, sessionNonce, requestNonce1, requestID))
+
+		// when
+		response, _ := send(t, request)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "https://app.example.com", response.Header.Get("Access-Control-Allow-Origin"))
+		require.Contains(t, response.Header.Get("Access-Control-Expose-Headers"), auth.HeaderSignature)
+		require.Contains(t, response.Header.Get("Access-Control-Expose-Headers"), auth.HeaderChallenge)
+		require.NotEmpty(t, response.Header.Get(auth.HeaderSignature))
+	})
+
+	t.Run("Add the CORS headers to a rejection", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCORS(apiPolicy))
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, f.server.URL+"/api/orders", nil)
+		require.NoError(t, err)
+		request.Header.Set("Origin", "https://app.example.com")
+
+		// when
+		response, body := send(t, request)
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeMissingAuthHeaders)
+		require.Equal(t, "https://app.example.com", response.Header.Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,329 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/internal/logging"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// maxAuthMessageSize is the maximum size of the body of a message sent to the auth endpoint.
+const maxAuthMessageSize = 1 << 20
+
+// signedParts are the raw JSON parts of a certificate message, signed as sent by the peer.
+type signedParts struct {
+	Certificates          json.RawMessage `json:"certificates"`
+	RequestedCertificates json.RawMessage `json:"requestedCertificates"`
+}
+
+// serveAuthEndpoint handles the non-general messages POSTed to the auth endpoint, writing the signed response
+// without calling the next handler. The general messages have to be sent to the protected routes instead.
+func (v *GeneralMessageVerifier) serveAuthEndpoint(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteError(rw, http.StatusMethodNotAllowed, ErrCodeInvalidMessage, "auth messages have to be POSTed")
+		return
+	}
+	if transport.HasAuthHeaders(r.Header) {
+		WriteError(rw, http.StatusBadRequest, ErrCodeUnsupportedMessageType, "general messages are not accepted on the auth endpoint")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, maxAuthMessageSize))
+	if err != nil {
+		WriteError(rw, http.StatusBadRequest, ErrCodeUnreadableBody, "failed to read the auth message")
+		return
+	}
+	var message AuthMessage
+	var signed signedParts
+	if err := json.Unmarshal(body, &message); err != nil {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidMessage, "auth message is not valid JSON")
+		return
+	}
+	_ = json.Unmarshal(body, &signed)
+	if message.Version != transport.AuthVersion {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidMessage, fmt.Sprintf("unsupported version %q", message.Version))
+		return
+	}
+	publicKey, err := ec.PublicKeyFromString(message.IdentityKey)
+	if err != nil {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidMessage, "identity key is not a public key")
+		return
+	}
+
+	ctx := r.Context()
+	v.logger.LogAttrs(ctx, slog.LevelDebug, "Processing auth message", messageAttrs(message)...)
+	switch message.MessageType {
+	case MessageTypeInitialRequest:
+		v.handleInitialRequest(ctx, rw, message, publicKey)
+	case MessageTypeCertificateRequest:
+		v.handleCertificateMessage(ctx, rw, message, publicKey, signed.RequestedCertificates, v.handleCertificateRequest)
+	case MessageTypeCertificateResponse:
+		v.handleCertificateMessage(ctx, rw, message, publicKey, signed.Certificates, v.handleCertificateResponse)
+	case MessageTypeGeneral:
+		WriteError(rw, http.StatusBadRequest, ErrCodeUnsupportedMessageType, "general messages have to be sent to the protected routes")
+	default:
+		WriteError(rw, http.StatusBadRequest, ErrCodeUnsupportedMessageType, fmt.Sprintf("unsupported message type %q", message.MessageType))
+	}
+}
+
+// handleInitialRequest creates the authenticated session of the peer and answers with the signed initialResponse,
+// presenting the certificates the peer requested and requesting the CertificatesToRequest.
+// An initialRequest answering the challenge of a gone session continues with the challenge as the session nonce.
+func (v *GeneralMessageVerifier) handleInitialRequest(ctx context.Context, rw http.ResponseWriter, request AuthMessage, publicKey *ec.PublicKey) {
+	if !isNonce(request.InitialNonce) {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidNonce, "initial nonce is not a base64 encoded nonce")
+		return
+	}
+	peerIdentityKey := publicKey.ToDERHex()
+
+	identityKey, err := v.wallet.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		v.internalError(rw, "Failed to get identity key", err)
+		return
+	}
+	sessionNonce, ok := v.sessionNonceFor(ctx, rw, request)
+	if !ok {
+		return
+	}
+	data, err := handshakeSignatureData(request.InitialNonce, sessionNonce)
+	if err != nil {
+		v.internalError(rw, "Failed to build initial response", err)
+		return
+	}
+	signature, err := v.wallet.CreateSignature(ctx, data, MessageSignatureProtocol,
+		request.InitialNonce+" "+sessionNonce, wallet.CounterpartyOf(publicKey), wallet.Privilege{})
+	if err != nil {
+		v.internalError(rw, "Failed to sign initial response", err)
+		return
+	}
+	certificates, err := v.certificatesFor(ctx, request.RequestedCertificates, peerIdentityKey)
+	if err != nil {
+		v.internalError(rw, "Failed to prove requested certificates", err)
+		return
+	}
+
+	now := v.now()
+	session, err := sessionmanager.NewPeerSession(sessionNonce,
+		sessionmanager.WithPeerNonce(request.InitialNonce),
+		sessionmanager.WithPeerIdentityKey(peerIdentityKey),
+		sessionmanager.WithAuthenticated(),
+		sessionmanager.WithLastUpdate(now),
+		sessionmanager.WithMeta(EstablishedAtMetaKey, now),
+	)
+	if err != nil {
+		v.internalError(rw, "Failed to create session", err)
+		return
+	}
+	if err := v.sessions.AddSession(ctx, session); err != nil {
+		v.internalError(rw, "Failed to add session", err)
+		return
+	}
+	v.logger.LogAttrs(ctx, slog.LevelInfo, "Session established", slog.String(logIdentityKey, peerIdentityKey))
+
+	response := AuthMessage{
+		Version:      transport.AuthVersion,
+		MessageType:  MessageTypeInitialResponse,
+		IdentityKey:  identityKey,
+		InitialNonce: sessionNonce,
+		YourNonce:    request.InitialNonce,
+		Certificates: certificates,
+		Signature:    signature,
+	}
+	if !v.certificatesToRequest.IsEmpty() {
+		response.RequestedCertificates = &v.certificatesToRequest
+	}
+	writeMessage(rw, response)
+}
+
+// sessionNonceFor returns the nonce of the new session, the challenge the initialRequest answers if it was sent in its yourNonce,
+// verified by the wallet, or a fresh one.
+func (v *GeneralMessageVerifier) sessionNonceFor(ctx context.Context, rw http.ResponseWriter, request AuthMessage) (string, bool) {
+	if request.YourNonce == "" {
+		sessionNonce, err := v.wallet.CreateNonce(ctx)
+		if err != nil {
+			v.internalError(rw, "Failed to create session nonce", err)
+			return "", false
+		}
+		return sessionNonce, true
+	}
+
+	valid, err := v.wallet.VerifyNonce(ctx, request.YourNonce)
+	if err != nil || !valid {
+		// the wallet reports an invalid, expired or reused nonce with an error too
+		v.reject(ctx, rw, ErrCodeInvalidChallenge, "challenge is invalid, expired or already answered", messageAttrs(request)...)
+		return "", false
+	}
+	return request.YourNonce, true
+}
+
+// handleCertificateMessage verifies the certificate message was signed by the peer of the session over its raw signed part,
+// with a fresh nonce, before handling it.
+func (v *GeneralMessageVerifier) handleCertificateMessage(
+	ctx context.Context, rw http.ResponseWriter, message AuthMessage, publicKey *ec.PublicKey, signed json.RawMessage,
+	handle func(ctx context.Context, rw http.ResponseWriter, message AuthMessage, session sessionmanager.PeerSession),
+) {
+	if !isNonce(message.Nonce) {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidNonce, message.MessageType+" nonce is not a base64 encoded nonce")
+		return
+	}
+	if len(signed) == 0 {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidMessage, message.MessageType+" is missing its signed part")
+		return
+	}
+
+	session, ok := v.getSession(ctx, rw, message.YourNonce)
+	if !ok {
+		return
+	}
+	if session.GetPeerIdentityKey() != publicKey.ToDERHex() {
+		v.reject(ctx, rw, ErrCodeIdentityMismatch, "identity key doesn't match the session", messageAttrs(message)...)
+		return
+	}
+
+	valid, err := v.wallet.VerifySignature(ctx, signed, message.Signature, MessageSignatureProtocol,
+		message.Nonce+" "+message.YourNonce, wallet.CounterpartyOf(publicKey))
+	if err != nil {
+		v.internalError(rw, "Failed to verify "+message.MessageType+" signature", err)
+		return
+	}
+	if !valid {
+		v.reject(ctx, rw, ErrCodeInvalidSignature, message.MessageType+" signature is invalid", messageAttrs(message)...)
+		return
+	}
+	fresh, err := v.nonces.Remember(ctx, message.YourNonce, message.Nonce)
+	if err != nil {
+		v.internalError(rw, "Failed to remember nonce", err)
+		return
+	}
+	if !fresh {
+		v.reject(ctx, rw, ErrCodeNonceReplayed, "nonce was already used", messageAttrs(message)...)
+		return
+	}
+
+	handle(ctx, rw, message, *session)
+}
+
+// handleCertificateRequest answers with the signed certificateResponse presenting the requested certificates.
+func (v *GeneralMessageVerifier) handleCertificateRequest(ctx context.Context, rw http.ResponseWriter, request AuthMessage, session sessionmanager.PeerSession) {
+	if request.RequestedCertificates == nil {
+		WriteError(rw, http.StatusBadRequest, ErrCodeInvalidMessage, "certificateRequest is missing the requested certificates")
+		return
+	}
+	certificates, err := v.certificatesFor(ctx, request.RequestedCertificates, session.GetPeerIdentityKey())
+	if err != nil {
+		v.internalError(rw, "Failed to prove requested certificates", err)
+		return
+	}
+	response, err := NewCertificateResponse(ctx, v.wallet, session.GetPeerIdentityKey(), session.GetPeerNonce(), certificates)
+	if err != nil {
+		v.internalError(rw, "Failed to sign certificate response", err)
+		return
+	}
+	writeMessage(rw, response)
+}
+
+// handleCertificateResponse passes the presented certificates of the trusted certifiers to the OnCertificatesReceived callback,
+// rejecting the response with the reasons the others were ignored if the CertificatesToRequest are still missing.
+func (v *GeneralMessageVerifier) handleCertificateResponse(ctx context.Context, rw http.ResponseWriter, response AuthMessage, session sessionmanager.PeerSession) {
+	certificates, ignored := v.trustedCertificates(ctx, session.GetPeerIdentityKey(), response.Certificates)
+	if len(certificates) > 0 || len(ignored) == 0 {
+		if err := v.onCertificatesReceived(ctx, session.GetPeerIdentityKey(), certificates); err != nil {
+			if !errors.Is(err, ErrCertificatesRejected) {
+				v.logger.Error("Failed to receive certificates", logging.Error(err))
+			}
+			WriteCertificatesError(rw, err)
+			return
+		}
+	}
+	if len(ignored) > 0 {
+		missing := v.certificatesToRequest.Missing(append(ReceivedCertificates(session), certificates...))
+		if !missing.IsEmpty() {
+			v.logger.LogAttrs(ctx, slog.LevelWarn, "Ignored untrusted certificates",
+				slog.String(logIdentityKey, session.GetPeerIdentityKey()), slog.Any(logDescription, ignored))
+			WriteErrorResponse(rw, http.StatusBadRequest, ErrorResponse{
+				Code:                 ErrCodeCertificatesRejected,
+				Description:          "certificates were ignored: " + strings.Join(ignored, ", "),
+				CertificatesRequired: &missing,
+			})
+			return
+		}
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	_, _ = rw.Write([]byte(`{"status":"success"}` + "\n"))
+}
+
+// certificatesFor returns the certificates of the wallet matching the requested ones,
+// each with the keyring revealing the requested fields to the peer.
+func (v *GeneralMessageVerifier) certificatesFor(ctx context.Context, requested *RequestedCertificateSet, peer string) ([]wallet.Certificate, error) {
+	if requested == nil || requested.IsEmpty() {
+		return nil, nil
+	}
+	listed, err := v.wallet.ListCertificates(ctx, wallet.ListCertificatesOptions{
+		Certifiers: requested.Certifiers,
+		Types:      slices.Sorted(maps.Keys(requested.Types)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list certificates: %w", err)
+	}
+	certificates := make([]wallet.Certificate, 0, len(listed.Certificates))
+	for _, certificate := range listed.Certificates {
+		keyring, err := ProveCertificateToPeer(ctx, v.wallet, certificate, peer, requested.Types[certificate.Type], false)
+		if err != nil {
+			return nil, err
+		}
+		certificate = certificate.Clone()
+		certificate.Keyring = keyring
+		certificates = append(certificates, certificate)
+	}
+	return certificates, nil
+}
+
+// trustedCertificates returns the certificates about the sender, signed by their certifiers, issued by the trusted certifiers,
+// of the requested types if any, and the reasons the others were ignored.
+// The certifiers aren't checked without the WithTrustedCertifiers option.
+func (v *GeneralMessageVerifier) trustedCertificates(ctx context.Context, senderPublicKey string, certificates []wallet.Certificate) ([]wallet.Certificate, []string) {
+	var trusted []wallet.Certificate
+	var ignored []string
+	for _, certificate := range certificates {
+		err := verifyPresentedCertificate(ctx, senderPublicKey, certificate)
+		_, requested := v.certificatesToRequest.Types[certificate.Type]
+		switch {
+		case errors.Is(err, ErrForeignCertificate):
+			ignored = append(ignored, fmt.Sprintf("%q certificate of another subject %s", certificate.Type, certificate.Subject))
+		case err != nil:
+			ignored = append(ignored, fmt.Sprintf("%q certificate with an invalid signature", certificate.Type))
+		case len(v.trustedCertifiers) == 0:
+			trusted = append(trusted, certificate)
+		case !slices.Contains(v.trustedCertifiers, certificate.Certifier):
+			ignored = append(ignored, fmt.Sprintf("%q certificate of the untrusted certifier %s", certificate.Type, certificate.Certifier))
+		case !v.certificatesToRequest.IsEmpty() && !requested:
+			ignored = append(ignored, fmt.Sprintf("%q certificate wasn't requested", certificate.Type))
+		default:
+			trusted = append(trusted, certificate)
+		}
+	}
+	return trusted, ignored
+}
+
+func isNonce(nonce string) bool {
+	decoded, err := base64.StdEncoding.Strict().DecodeString(nonce)
+	return err == nil && len(decoded) >= transport.MinNonceSize && len(decoded) <= transport.MaxNonceSize
+}
+
+func writeMessage(rw http.ResponseWriter, message AuthMessage) {
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(message)
+}
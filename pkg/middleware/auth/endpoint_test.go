@@ -0,0 +1,276 @@
+package auth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func messageRequest(t *testing.T, url string, message any) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(message)
+	require.NoError(t, err)
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	return request
+}
+
+func postMessage(t *testing.T, url string, message any) (*http.Response, []byte) {
+	t.Helper()
+	return send(t, messageRequest(t, url, message))
+}
+
+// handshake runs the handshake of the client through the auth endpoint, returning the verified initialResponse.
+func (f *generalMessageFixture) handshake(t *testing.T) (auth.AuthMessage, auth.AuthMessage) {
+	t.Helper()
+	request, err := auth.NewInitialRequest(t.Context(), f.client, auth.RequestedCertificateSet{})
+	require.NoError(t, err)
+
+	response, body := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, request)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var initialResponse auth.AuthMessage
+	require.NoError(t, json.Unmarshal(body, &initialResponse))
+	require.NoError(t, auth.VerifyInitialResponse(t.Context(), f.client, request, initialResponse))
+	return request, initialResponse
+}
+
+func TestAuthEndpoint_Handshake(t *testing.T) {
+	t.Run("Handshake through the auth endpoint and call a protected route", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+
+		// when
+		initialRequest, initialResponse := f.handshake(t)
+		response, body := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, f.serverKey, initialResponse.IdentityKey)
+		require.Nil(t, initialResponse.RequestedCertificates)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "body", string(body))
+		require.Equal(t, initialResponse.InitialNonce, f.identity.SessionNonce)
+		require.Equal(t, identityKeyOf(t, f.client), f.identity.IdentityKey)
+		require.Equal(t, initialRequest.InitialNonce, response.Header.Get(auth.HeaderYourNonce))
+	})
+
+	t.Run("Handshake without calling the next handler", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+
+		// when
+		_, initialResponse := f.handshake(t)
+
+		// then
+		require.False(t, f.handlerCalled)
+		session, err := f.sessions.GetSession(t.Context(), initialResponse.InitialNonce)
+		require.NoError(t, err)
+		require.True(t, session.IsAuthenticated)
+		require.Equal(t, identityKeyOf(t, f.client), session.GetPeerIdentityKey())
+	})
+
+	t.Run("Present the requested certificates through the auth endpoint", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCertificatesToRequest(emailVerification))
+		_, initialResponse := f.handshake(t)
+		require.Equal(t, &emailVerification, initialResponse.RequestedCertificates)
+		rejected, body := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce1, "body"))
+		requireRejected(t, rejected, body, auth.ErrCodeCertificatesRequired)
+		certificate := emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})
+
+		// when
+		certificateResponse, err := auth.NewCertificateResponse(t.Context(), f.client, f.serverKey, initialResponse.InitialNonce, []wallet.Certificate{certificate})
+		require.NoError(t, err)
+		received, _ := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, certificateResponse)
+		response, _ := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce2, "body"))
+
+		// then
+		require.Equal(t, http.StatusOK, received.StatusCode)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, []wallet.Certificate{certificate}, f.identity.Certificates)
+	})
+
+	t.Run("Ignore a forged certificate presented through the auth endpoint", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCertificatesToRequest(emailVerification))
+		_, initialResponse := f.handshake(t)
+		certificate := emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})
+		certificate.Fields["email"] = "mallory@example.com"
+
+		// when
+		certificateResponse, err := auth.NewCertificateResponse(t.Context(), f.client, f.serverKey, initialResponse.InitialNonce, []wallet.Certificate{certificate})
+		require.NoError(t, err)
+		received, body := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, certificateResponse)
+		response, _ := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce2, "body"))
+
+		// then
+		requireRejectedWith(t, received, body, http.StatusBadRequest, auth.ErrCodeCertificatesRejected)
+		require.Contains(t, string(body), "invalid signature")
+		require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Answer a certificate request with a signed certificate response", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		initialRequest, initialResponse := f.handshake(t)
+		certificateRequest, err := auth.NewCertificateRequest(t.Context(), f.client, f.serverKey, initialResponse.InitialNonce, emailVerification)
+		require.NoError(t, err)
+
+		// when
+		response, body := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, certificateRequest)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		var certificateResponse auth.AuthMessage
+		require.NoError(t, json.Unmarshal(body, &certificateResponse))
+		require.Equal(t, auth.MessageTypeCertificateResponse, certificateResponse.MessageType)
+		require.Equal(t, initialRequest.InitialNonce, certificateResponse.YourNonce)
+		require.Equal(t, f.serverKey, certificateResponse.IdentityKey)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Serve the auth endpoint on a configured path", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithAuthEndpointPath("/auth"))
+		request, err := auth.NewInitialRequest(t.Context(), f.client, auth.RequestedCertificateSet{})
+		require.NoError(t, err)
+
+		// when
+		response, body := postMessage(t, f.server.URL+"/auth", request)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		var initialResponse auth.AuthMessage
+		require.NoError(t, json.Unmarshal(body, &initialResponse))
+		require.NoError(t, auth.VerifyInitialResponse(t.Context(), f.client, request, initialResponse))
+	})
+}
+
+func TestAuthEndpoint_UnhappyPath(t *testing.T) {
+	tests := map[string]struct {
+		request        func(t *testing.T, f *generalMessageFixture) *http.Request
+		expectedStatus int
+		expectedCode   string
+	}{
+		"Unknown message type": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return authEndpointRequest(t, f, map[string]any{"version": "0.1", "messageType": "goodbye", "identityKey": identityKeyOf(t, f.client)})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeUnsupportedMessageType,
+		},
+		"General message type": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return authEndpointRequest(t, f, map[string]any{"version": "0.1", "messageType": "general", "identityKey": identityKeyOf(t, f.client)})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeUnsupportedMessageType,
+		},
+		"Signed general message": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.URL.Path = auth.DefaultAuthEndpointPath
+				request.URL.RawQuery = ""
+				return request
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeUnsupportedMessageType,
+		},
+		"Unsupported version": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return authEndpointRequest(t, f, map[string]any{"version": "0.2", "messageType": "initialRequest", "identityKey": identityKeyOf(t, f.client)})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeInvalidMessage,
+		},
+		"Invalid JSON": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, f.server.URL+auth.DefaultAuthEndpointPath, strings.NewReader(`{"messageType":`))
+				require.NoError(t, err)
+				return request
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeInvalidMessage,
+		},
+		"Initial request without a nonce": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return authEndpointRequest(t, f, map[string]any{"version": "0.1", "messageType": "initialRequest", "identityKey": identityKeyOf(t, f.client)})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeInvalidNonce,
+		},
+		"GET request": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, f.server.URL+auth.DefaultAuthEndpointPath, nil)
+				require.NoError(t, err)
+				return request
+			},
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedCode:   auth.ErrCodeInvalidMessage,
+		},
+		"Certificate response signed by another wallet": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				message, err := auth.NewCertificateResponse(t.Context(), newKeyWallet(t), f.serverKey, sessionNonce, nil)
+				require.NoError(t, err)
+				message.IdentityKey = identityKeyOf(t, f.client)
+				message.Certificates = []wallet.Certificate{emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})}
+				return authEndpointRequest(t, f, message)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeInvalidSignature,
+		},
+		"Certificate response for an unknown session": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				message, err := auth.NewCertificateResponse(t.Context(), f.client, f.serverKey, requestNonce2,
+					[]wallet.Certificate{emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})})
+				require.NoError(t, err)
+				return authEndpointRequest(t, f, message)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeSessionNotFound,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, true)
+
+			// when
+			response, body := send(t, test.request(t, f))
+
+			// then
+			requireRejectedWith(t, response, body, test.expectedStatus, test.expectedCode)
+			require.False(t, f.handlerCalled)
+		})
+	}
+}
+
+func TestAuthEndpoint_HandshakeOnProtectedRoute(t *testing.T) {
+	// given
+	f := newGeneralMessageFixture(t, time.Now, true)
+	request, err := auth.NewInitialRequest(t.Context(), f.client, auth.RequestedCertificateSet{})
+	require.NoError(t, err)
+
+	// when
+	response, body := postMessage(t, f.server.URL+"/orders", request)
+
+	// then
+	requireRejected(t, response, body, auth.ErrCodeMissingAuthHeaders)
+	require.False(t, f.handlerCalled)
+	sessions, err := f.sessions.GetSessionsByIdentityKey(t.Context(), identityKeyOf(t, f.client))
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+}
+
+func authEndpointRequest(t *testing.T, f *generalMessageFixture, message any) *http.Request {
+	t.Helper()
+	return messageRequest(t, f.server.URL+auth.DefaultAuthEndpointPath, message)
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Machine-readable codes of the responses rejecting a general message or an auth message.
+// They are part of the API, the clients branch on them, so they never change once released.
+const (
+	ErrCodeMissingAuthHeaders      = "ERR_MISSING_AUTH_HEADERS"
+	ErrCodeMalformedAuthHeaders    = "ERR_MALFORMED_AUTH_HEADERS"
+	ErrCodeInvalidNonce            = "ERR_INVALID_NONCE"
+	ErrCodeUnreadableBody          = "ERR_UNREADABLE_BODY"
+	ErrCodeSessionNotFound         = "ERR_SESSION_NOT_FOUND"
+	ErrCodeSessionExpired          = "ERR_SESSION_EXPIRED"
+	ErrCodeInvalidChallenge        = "ERR_INVALID_CHALLENGE"
+	ErrCodeSessionNotAuthenticated = "ERR_SESSION_NOT_AUTHENTICATED"
+	ErrCodeIdentityMismatch        = "ERR_IDENTITY_MISMATCH"
+	ErrCodeNonceReplayed           = "ERR_NONCE_REPLAYED"
+	ErrCodeInvalidSignature        = "ERR_INVALID_SIGNATURE"
+	ErrCodeCertificatesRequired    = "ERR_CERTIFICATES_REQUIRED"
+	ErrCodeCertificatesRejected    = "ERR_CERTIFICATES_REJECTED"
+	ErrCodeInvalidMessage          = "ERR_INVALID_MESSAGE"
+	ErrCodeUnsupportedMessageType  = "ERR_UNSUPPORTED_MESSAGE_TYPE"
+	ErrCodeInternal                = "ERR_INTERNAL"
+)
+
+// ErrorResponse is the JSON body of the responses rejecting a general message or an auth message.
+type ErrorResponse struct {
+	Status      string `json:"status"`
+	Code        string `json:"code"`
+	Description string `json:"description"`
+	// CertificatesRequired are the requested certificates the peer hasn't presented yet, with ErrCodeCertificatesRequired
+	CertificatesRequired *RequestedCertificateSet `json:"certificatesRequired,omitempty"`
+	// Challenge tells the peer how to authenticate, with the codes rejecting a request without an authenticated session
+	Challenge *AuthChallenge `json:"challenge,omitempty"`
+}
+
+// AuthChallenge describes the handshake a peer has to make before sending the general messages.
+type AuthChallenge struct {
+	// Version is the version of the BRC-104 auth protocol the server supports
+	Version string `json:"version"`
+	// IdentityKey is the identity key of the server, the counterparty of the handshake
+	IdentityKey string `json:"identityKey"`
+	// AuthEndpoint is the path of the auth endpoint the handshake is POSTed to
+	AuthEndpoint string `json:"authEndpoint"`
+}
+
+// WriteError writes the JSON ErrorResponse with the code and the description, e.g. from a handler
+// rejecting a request the verifier let through.
+func WriteError(rw http.ResponseWriter, status int, code string, description string) {
+	WriteErrorResponse(rw, status, ErrorResponse{Code: code, Description: description})
+}
+
+// WriteErrorResponse writes the JSON ErrorResponse with the status "error".
+func WriteErrorResponse(rw http.ResponseWriter, status int, response ErrorResponse) {
+	response.Status = "error"
+	if localizing, ok := rw.(*localizingResponseWriter); ok {
+		response.Description = localizing.renderer(response.Code, localizing.locale, response.Description)
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(response)
+}
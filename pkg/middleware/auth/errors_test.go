@@ -0,0 +1,107 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteError(t *testing.T) {
+	// given
+	recorder := httptest.NewRecorder()
+	description := `unsupported version "0.2" of the "initialRequest"` + "\n"
+
+	// when
+	auth.WriteError(recorder, http.StatusBadRequest, auth.ErrCodeInvalidMessage, description)
+
+	// then
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	require.Equal(t, map[string]any{"status": "error", "code": auth.ErrCodeInvalidMessage, "description": description}, body)
+}
+
+func TestGeneralMessageVerifier_ErrorResponses(t *testing.T) {
+	tests := map[string]struct {
+		opts           []auth.GeneralMessageOption
+		request        func(t *testing.T, f *generalMessageFixture) *http.Request
+		expectedStatus int
+		expectedCode   string
+	}{
+		"Missing auth headers": {
+			request:        anonymousRequest,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeMissingAuthHeaders,
+		},
+		"Malformed auth headers": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.Header.Set(auth.HeaderNonce, `"not base64"`)
+				return request
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeMalformedAuthHeaders,
+		},
+		"Session not found": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequestInSession(t, f.client, requestNonce2, requestNonce1, "body")
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeSessionNotFound,
+		},
+		"Invalid signature": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.Header.Set("X-Bsv-Tenant", "tenant-b")
+				return request
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeInvalidSignature,
+		},
+		"Certificates required": {
+			opts:           []auth.GeneralMessageOption{auth.WithCertificatesToRequest(emailVerification)},
+			request:        validRequest,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeCertificatesRequired,
+		},
+		"Invalid nonce of a handshake": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return authEndpointRequest(t, f, map[string]any{
+					"version": "0.1", "messageType": "initialRequest", "identityKey": identityKeyOf(t, f.client), "initialNonce": `"nonce"`,
+				})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeInvalidNonce,
+		},
+		"Invalid nonce of a certificate response": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				message, err := auth.NewCertificateResponse(t.Context(), f.client, f.serverKey, sessionNonce, nil)
+				require.NoError(t, err)
+				message.Nonce = "nonce"
+				return authEndpointRequest(t, f, message)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeInvalidNonce,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, true, test.opts...)
+
+			// when
+			response, body := send(t, test.request(t, f))
+
+			// then
+			requireRejectedWith(t, response, body, test.expectedStatus, test.expectedCode)
+			require.Equal(t, "application/json", response.Header.Get("Content-Type"))
+			require.False(t, f.handlerCalled)
+		})
+	}
+}
@@ -0,0 +1,488 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/internal/logging"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport/payload"
+)
+
+// BRC-104 headers of a general message, see the transport package.
+const (
+	HeaderVersion     = transport.HeaderVersion
+	HeaderIdentityKey = transport.HeaderIdentityKey
+	HeaderNonce       = transport.HeaderNonce
+	HeaderYourNonce   = transport.HeaderYourNonce
+	HeaderSignature   = transport.HeaderSignature
+	HeaderRequestID   = transport.HeaderRequestID
+	HeaderChallenge   = transport.HeaderChallenge
+)
+
+// MessageSignatureProtocol is the protocol of the key signing the general messages, the same one the TypeScript SDK uses.
+var MessageSignatureProtocol = wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "auth message signature"}
+
+// GeneralMessageVerifier verifies the BRC-104 general messages, the requests made in an authenticated session.
+// Every request has to be signed by the peer over its request ID, method, path, query, signed headers and body,
+// with the keyID made of its fresh request nonce and the session nonce, chaining the request to the session.
+type GeneralMessageVerifier struct {
+	wallet                 wallet.Interface
+	sessions               sessionmanager.Interface
+	allowUnauthenticated   bool
+	observeOnly            bool
+	errorRenderer          ErrorRenderer
+	skipRules              []SkipRule
+	corsPolicies           []CORSPolicy
+	certificatesToRequest  RequestedCertificateSet
+	trustedCertifiers      []string
+	onCertificatesReceived OnCertificatesReceived
+	authEndpointPath       string
+	expectedNetwork        string
+	maxBodySize            int64
+	signedResponseHeaders  []string
+	nonces                 NonceStore
+	now                    func() time.Time
+	logger                 *slog.Logger
+}
+
+// GeneralMessageOption configures the GeneralMessageVerifier.
+type GeneralMessageOption func(*GeneralMessageVerifier)
+
+// WithAllowUnauthenticated lets the requests without any x-bsv-auth headers through, with the UnknownIdentity in the context.
+// The requests presenting any of the headers are still verified and rejected if they fail, never downgraded to anonymous ones.
+func WithAllowUnauthenticated(allow bool) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.allowUnauthenticated = allow
+	}
+}
+
+// WithSkipAuth lets the requests matching the rule, e.g. the health checks and the metrics scrapes, bypass the authentication:
+// they are passed to the next handler without any identity in the context, without touching the sessions or the nonces.
+// A matching request presenting auth headers is passed through too, unverified and unsigned.
+// The rules of repeated options add up, a request matching any of them is skipped. The auth endpoint is never skipped.
+func WithSkipAuth(rule SkipRule) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.skipRules = append(v.skipRules, rule)
+	}
+}
+
+// WithCertificatesToRequest requires the peers to present the certificates before calling the protected routes,
+// the handshake asks for them in its initialResponse.
+func WithCertificatesToRequest(certificates RequestedCertificateSet) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.certificatesToRequest = certificates
+	}
+}
+
+// WithTrustedCertifiers accepts only the certificates issued by the certifiers, given by their identity keys,
+// and of the types of the CertificatesToRequest if any. The other certificates presented on the auth endpoint are ignored,
+// the OnCertificatesReceived callback never sees them, and the certificateResponse is rejected naming them
+// if the CertificatesToRequest are still missing. The handshake requests the certificates of the trusted certifiers
+// unless the CertificatesToRequest name their own certifiers.
+func WithTrustedCertifiers(certifiers ...string) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.trustedCertifiers = certifiers
+	}
+}
+
+// WithOnCertificatesReceived sets the callback validating the certificates the peers present on the auth endpoint.
+// It's called like the validate callback of ValidateReceivedCertificates, with the fields decrypted,
+// and the certificates it accepts are recorded in the sessions like the RecordReceivedCertificates, which is the default, does.
+func WithOnCertificatesReceived(onCertificatesReceived OnCertificatesReceived) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.onCertificatesReceived = onCertificatesReceived
+	}
+}
+
+// WithAuthEndpointPath overrides the path of the auth endpoint, the DefaultAuthEndpointPath by default.
+func WithAuthEndpointPath(path string) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.authEndpointPath = path
+	}
+}
+
+// WithExpectedNetwork makes NewGeneralMessageVerifier refuse a wallet on another network, NetworkMainnet or NetworkTestnet,
+// see VerifyWalletNetwork. The network isn't checked by default.
+func WithExpectedNetwork(network string) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.expectedNetwork = network
+	}
+}
+
+// WithMaxBodySize overrides the size of the largest body of a general message the verifier reads to check its signature,
+// payload.DefaultMaxBodySize by default. A larger request is rejected with 413 and ErrCodeUnreadableBody.
+func WithMaxBodySize(size int64) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.maxBodySize = size
+	}
+}
+
+// WithSignedResponseHeaders signs only the response headers of the allowlist, given by their case-insensitive names,
+// instead of the x-bsv-* ones other than x-bsv-auth-* and the authorization BRC-104 signs, see payload.WithSignedHeaders.
+// The headers a proxy adds or rewrites on the way, e.g. Via, must stay out of it, the clients verify the response they get.
+func WithSignedResponseHeaders(names ...string) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.signedResponseHeaders = names
+	}
+}
+
+// WithNonceStore overrides the store of the accepted request nonces, a MemoryNonceStore with the DefaultNonceReplayWindow by default.
+func WithNonceStore(nonces NonceStore) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.nonces = nonces
+	}
+}
+
+// WithVerifierClock overrides the clock setting the LastUpdate of the sessions.
+func WithVerifierClock(now func() time.Time) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.now = now
+	}
+}
+
+// WithVerifierLogger sets the logger of the verifier, the default one if nil.
+// The messages are logged at Debug, the established and removed sessions at Info, the rejected signatures
+// and nonces at Warn, without the secrets, and the failures of the wallet and the SessionManager at Error.
+func WithVerifierLogger(logger *slog.Logger) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.logger = logger
+	}
+}
+
+// WithoutVerifierLogging disables the logging of the verifier.
+func WithoutVerifierLogging() GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.logger = slog.New(slog.DiscardHandler)
+	}
+}
+
+// NewGeneralMessageVerifier creates a verifier checking the signatures with the wallet and the sessions with the SessionManager.
+// It fails with ErrNetworkMismatch if the wallet isn't on the network given WithExpectedNetwork.
+func NewGeneralMessageVerifier(w wallet.Interface, sessions sessionmanager.Interface, opts ...GeneralMessageOption) (*GeneralMessageVerifier, error) {
+	v := &GeneralMessageVerifier{
+		wallet:           w,
+		sessions:         sessions,
+		authEndpointPath: DefaultAuthEndpointPath,
+		maxBodySize:      payload.DefaultMaxBodySize,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.expectedNetwork != "" {
+		if err := VerifyWalletNetwork(context.Background(), w, v.expectedNetwork); err != nil {
+			return nil, err
+		}
+	}
+	if len(v.trustedCertifiers) > 0 && len(v.certificatesToRequest.Certifiers) == 0 {
+		v.certificatesToRequest.Certifiers = v.trustedCertifiers
+	}
+	if v.onCertificatesReceived == nil {
+		v.onCertificatesReceived = RecordReceivedCertificates(sessions)
+	} else {
+		v.onCertificatesReceived = ValidateReceivedCertificates(sessions, w, v.onCertificatesReceived)
+	}
+	if v.nonces == nil {
+		v.nonces = NewMemoryNonceStore(DefaultNonceReplayWindow)
+	}
+	v.logger = logging.Child(v.logger, "general-message-verifier")
+	v.logSessionLifecycle()
+	return v, nil
+}
+
+// Handler verifies the general message before calling the next handler with the identity of the peer and its session
+// in the request context. The session has to be authenticated, its LastUpdate is refreshed and the request nonce remembered
+// in the NonceStore, rejecting its replays.
+// The response of the handler is buffered and sent signed for the peer, with the x-bsv-auth headers.
+// A request failing the verification, or from a session that hasn't presented the CertificatesToRequest yet
+// or presented certificates rejected by the application,
+// is rejected with 401 and an ErrorResponse, unless it has no auth headers at all and the verifier is created WithAllowUnauthenticated.
+// A request presenting only some of the auth headers, or malformed ones, is rejected with 400 naming the offending header.
+// A request of a session that is gone, expired or lost by a restart, is rejected with ErrCodeSessionNotFound
+// or ErrCodeSessionExpired and a fresh nonce in the HeaderChallenge, telling the client to redo the handshake.
+// The rejections of the requests without an authenticated session carry the AuthChallenge describing the handshake.
+//
+// The handshake and certificate messages POSTed to the auth endpoint are answered directly, without calling the next handler:
+// an initialRequest creates the authenticated session of the peer, a certificateRequest is answered with the certificates
+// of the wallet and a certificateResponse is passed to the OnCertificatesReceived callback.
+//
+// The fields the certificates of the peer reveal to the wallet are decrypted for the handlers, see GetCertificateFields,
+// rejecting the request with ErrCodeCertificatesRejected if they can't be.
+//
+// The descriptions of the ErrorResponses are rendered in the locale of the request by the renderer WithErrorRenderer, if any.
+//
+// A verifier created WithObserveOnly logs the rejections instead, passing the rejected requests through.
+//
+// The requests matching a rule of WithSkipAuth are passed to the next handler as they are, before any of the checks.
+// The CORS preflights of the routes of WithCORS are answered before anything else.
+func (v *GeneralMessageVerifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if v.applyCORS(rw, r) {
+			return
+		}
+		if r.URL.Path == v.authEndpointPath {
+			v.serveAuthEndpoint(v.localized(rw, r), r)
+			return
+		}
+		if v.skipped(r) {
+			next.ServeHTTP(rw, r)
+			return
+		}
+
+		ctx := r.Context()
+		if !transport.HasAuthHeaders(r.Header) && v.allowUnauthenticated {
+			next.ServeHTTP(rw, r.WithContext(WithIdentity(ctx, UnknownIdentity())))
+			return
+		}
+		errorWriter := v.localized(rw, r)
+		verifying := errorWriter
+		var observed *rejectionRecorder
+		if v.observeOnly {
+			observed = newRejectionRecorder()
+			verifying = observed
+		}
+		message, ok := v.verify(verifying, r)
+		if !ok {
+			if observed != nil {
+				v.logObservedRejection(ctx, observed)
+				next.ServeHTTP(rw, r.WithContext(WithIdentity(ctx, UnknownIdentity())))
+			}
+			return
+		}
+		signing := newSigningResponseWriter(rw)
+		next.ServeHTTP(signing, r.WithContext(message.ctx))
+		if err := v.signResponse(message.ctx, signing, message.session, message.requestID); err != nil {
+			v.internalError(errorWriter, "Failed to sign response", err)
+		}
+	})
+}
+
+// verifiedMessage is a general message that passed the verification, with the context of the next handler.
+type verifiedMessage struct {
+	ctx       context.Context
+	session   sessionmanager.PeerSession
+	requestID []byte
+}
+
+// verify verifies the general message, or rejects it writing the ErrorResponse to the ResponseWriter.
+func (v *GeneralMessageVerifier) verify(rw http.ResponseWriter, r *http.Request) (verifiedMessage, bool) {
+	ctx := r.Context()
+	if !transport.HasAuthHeaders(r.Header) {
+		v.writeAuthChallenge(ctx, rw, ErrCodeMissingAuthHeaders, "general message auth headers are missing")
+		return verifiedMessage{}, false
+	}
+	headers, err := transport.ParseAuthHeaders(r.Header)
+	if err != nil {
+		WriteError(rw, http.StatusBadRequest, ErrCodeMalformedAuthHeaders, err.Error())
+		return verifiedMessage{}, false
+	}
+	identityKey, requestNonce, sessionNonce, requestID := headers.IdentityKey, headers.Nonce, headers.YourNonce, headers.RequestID
+	requestAttrs := []slog.Attr{
+		slog.String(logIdentityKey, identityKey),
+		slog.String(logRequestID, base64.StdEncoding.EncodeToString(requestID)),
+	}
+	v.logger.LogAttrs(ctx, slog.LevelDebug, "Processing general message",
+		append(requestAttrs, slog.String(logNonce, requestNonce), slog.String(logSessionNonce, sessionNonce))...)
+
+	session, ok := v.getSession(ctx, rw, sessionNonce)
+	if !ok {
+		return verifiedMessage{}, false
+	}
+	if !session.IsAuthenticated {
+		v.writeAuthChallenge(ctx, rw, ErrCodeSessionNotAuthenticated, "session is not authenticated")
+		return verifiedMessage{}, false
+	}
+	if session.GetPeerIdentityKey() != identityKey {
+		v.reject(ctx, rw, ErrCodeIdentityMismatch, "identity key doesn't match the session", requestAttrs...)
+		return verifiedMessage{}, false
+	}
+	if requestNonce == session.GetPeerNonce() {
+		v.reject(ctx, rw, ErrCodeNonceReplayed, "request nonce is the handshake nonce", requestAttrs...)
+		return verifiedMessage{}, false
+	}
+
+	requestPayload, err := payload.BuildRequestPayload(r, requestID, payload.WithMaxBodySize(v.maxBodySize))
+	if errors.Is(err, payload.ErrBodyTooLarge) {
+		WriteError(rw, http.StatusRequestEntityTooLarge, ErrCodeUnreadableBody, fmt.Sprintf("request body is larger than %d bytes", v.maxBodySize))
+		return verifiedMessage{}, false
+	}
+	if err != nil {
+		WriteError(rw, http.StatusBadRequest, ErrCodeUnreadableBody, "failed to read the request body")
+		return verifiedMessage{}, false
+	}
+
+	valid, err := v.wallet.VerifySignature(ctx, requestPayload, headers.Signature, MessageSignatureProtocol,
+		requestNonce+" "+sessionNonce, wallet.CounterpartyOf(headers.PublicKey))
+	if err != nil {
+		v.internalError(rw, "Failed to verify general message signature", err)
+		return verifiedMessage{}, false
+	}
+	if !valid {
+		v.reject(ctx, rw, ErrCodeInvalidSignature, "general message signature is invalid", requestAttrs...)
+		return verifiedMessage{}, false
+	}
+
+	fresh, err := v.nonces.Remember(ctx, sessionNonce, requestNonce)
+	if err != nil {
+		v.internalError(rw, "Failed to remember request nonce", err)
+		return verifiedMessage{}, false
+	}
+	if !fresh {
+		v.reject(ctx, rw, ErrCodeNonceReplayed, "request nonce was already used", requestAttrs...)
+		return verifiedMessage{}, false
+	}
+
+	if reason, rejected := CertificatesRejection(*session); rejected {
+		WriteError(rw, http.StatusUnauthorized, ErrCodeCertificatesRejected, "certificates were rejected: "+reason)
+		return verifiedMessage{}, false
+	}
+	certificates := ReceivedCertificates(*session)
+	if missing := v.certificatesToRequest.Missing(certificates); !missing.IsEmpty() {
+		WriteErrorResponse(rw, http.StatusUnauthorized, ErrorResponse{
+			Code:                 ErrCodeCertificatesRequired,
+			Description:          "certificates are required: " + missing.String(),
+			CertificatesRequired: &missing,
+		})
+		return verifiedMessage{}, false
+	}
+
+	fields, err := v.certificateFields(ctx, certificates)
+	if err != nil {
+		v.reject(ctx, rw, ErrCodeCertificatesRejected, "certificate fields can't be decrypted", append(requestAttrs, logging.Error(err))...)
+		return verifiedMessage{}, false
+	}
+
+	// only the LastUpdate is written, the metadata may have changed since the session was read
+	now := v.now()
+	session.LastUpdate = now
+	if err := v.sessions.TouchSession(ctx, session.GetSessionNonce(), now); err != nil {
+		// a stale LastUpdate only brings the expiry of the session closer, the verified request goes on
+		v.logger.LogAttrs(ctx, slog.LevelError, "Failed to update session",
+			slog.String(logIdentityKey, identityKey), logging.Error(err))
+	}
+
+	ctx = WithIdentity(ctx, Identity{
+		IdentityKey:     identityKey,
+		SessionNonce:    sessionNonce,
+		Authenticated:   true,
+		Version:         headers.Version,
+		EstablishedAt:   sessionEstablishedAt(*session),
+		AuthenticatedAt: now,
+		Certificates:    certificates,
+		AuthMethod:      AuthMethodMutual,
+	})
+	ctx = WithSession(ctx, *session)
+	if len(certificates) > 0 {
+		ctx = WithCertificateFields(ctx, fields)
+	}
+	return verifiedMessage{ctx: ctx, session: *session, requestID: requestID}, true
+}
+
+// CertificatesToRequest returns the certificates the peers have to present, for the initialResponse of the handshake.
+func (v *GeneralMessageVerifier) CertificatesToRequest() RequestedCertificateSet {
+	return v.certificatesToRequest
+}
+
+// SignGeneralMessage signs the request as a general message of the session with the wallet of the peer,
+// setting the BRC-104 headers, the counterpart of the GeneralMessageVerifier for Go clients and tests.
+// The requestNonce has to be fresh for every request, the serverIdentityKey is the identity key of the verifying wallet.
+func SignGeneralMessage(r *http.Request, w wallet.Interface, serverIdentityKey string, sessionNonce string, requestNonce string, requestID []byte) error {
+	ctx := r.Context()
+	identityKey, err := w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		return fmt.Errorf("failed to get identity key: %w", err)
+	}
+	counterparty, err := wallet.ParseCounterparty(serverIdentityKey)
+	if err != nil {
+		return fmt.Errorf("invalid server identity key: %w", err)
+	}
+
+	requestPayload, err := payload.BuildRequestPayload(r, requestID)
+	if err != nil {
+		return fmt.Errorf("failed to build request payload: %w", err)
+	}
+
+	signature, err := w.CreateSignature(ctx, requestPayload, MessageSignatureProtocol,
+		requestNonce+" "+sessionNonce, counterparty, wallet.Privilege{})
+	if err != nil {
+		return fmt.Errorf("failed to sign general message: %w", err)
+	}
+
+	r.Header.Set(HeaderVersion, transport.AuthVersion)
+	r.Header.Set(HeaderIdentityKey, identityKey)
+	r.Header.Set(HeaderNonce, requestNonce)
+	r.Header.Set(HeaderYourNonce, sessionNonce)
+	r.Header.Set(HeaderSignature, hex.EncodeToString(signature))
+	r.Header.Set(HeaderRequestID, base64.StdEncoding.EncodeToString(requestID))
+	return nil
+}
+
+// getSession returns the session of the session nonce, or answers with the challenge to redo the handshake if it's gone.
+// A session found through the nonce it was rotated from is accepted, one found by the identity key it falls back to isn't.
+func (v *GeneralMessageVerifier) getSession(ctx context.Context, rw http.ResponseWriter, sessionNonce string) (*sessionmanager.PeerSession, bool) {
+	session, err := v.sessions.GetSession(ctx, sessionNonce)
+	switch {
+	case errors.Is(err, sessionmanager.ErrSessionExpired):
+		v.writeChallenge(ctx, rw, ErrCodeSessionExpired, "session expired")
+		return nil, false
+	case errors.Is(err, sessionmanager.ErrSessionNotFound) || (err == nil && session.GetPeerIdentityKey() == sessionNonce):
+		v.writeChallenge(ctx, rw, ErrCodeSessionNotFound, "session not found")
+		return nil, false
+	case err != nil:
+		v.internalError(rw, "Failed to get session", err)
+		return nil, false
+	}
+	return session, true
+}
+
+// writeChallenge rejects the message of a gone session with 401 and a fresh nonce in the HeaderChallenge,
+// telling the client to redo the handshake instead of retrying.
+func (v *GeneralMessageVerifier) writeChallenge(ctx context.Context, rw http.ResponseWriter, code string, description string) {
+	challenge, err := v.wallet.CreateNonce(ctx)
+	if err != nil {
+		v.internalError(rw, "Failed to create challenge nonce", err)
+		return
+	}
+	rw.Header().Set(HeaderChallenge, challenge)
+	v.writeAuthChallenge(ctx, rw, code, description)
+}
+
+// writeAuthChallenge rejects the message without an authenticated session with 401 and the AuthChallenge
+// describing the handshake the peer has to make.
+func (v *GeneralMessageVerifier) writeAuthChallenge(ctx context.Context, rw http.ResponseWriter, code string, description string) {
+	identityKey, err := v.wallet.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		v.internalError(rw, "Failed to get identity key", err)
+		return
+	}
+	WriteErrorResponse(rw, http.StatusUnauthorized, ErrorResponse{
+		Code:        code,
+		Description: description,
+		Challenge: &AuthChallenge{
+			Version:      transport.AuthVersion,
+			IdentityKey:  identityKey,
+			AuthEndpoint: v.authEndpointPath,
+		},
+	})
+}
+
+// reject logs the failed verification of the message at Warn, without its secrets, before rejecting it with 401.
+func (v *GeneralMessageVerifier) reject(ctx context.Context, rw http.ResponseWriter, code string, description string, attrs ...slog.Attr) {
+	v.logger.LogAttrs(ctx, slog.LevelWarn, "Rejected auth message",
+		append([]slog.Attr{slog.String(logCode, code), slog.String(logDescription, description)}, attrs...)...)
+	WriteError(rw, http.StatusUnauthorized, code, description)
+}
+
+func (v *GeneralMessageVerifier) internalError(rw http.ResponseWriter, msg string, err error) {
+	v.logger.Error(msg, logging.Error(err))
+	WriteError(rw, http.StatusInternalServerError, ErrCodeInternal, "failed to verify the general message")
+}
@@ -0,0 +1,574 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+// The nonces of the fixtures, base64 encoded 32 bytes like the nonces of the wallets.
+const (
+	sessionNonce  = "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+	peerNonce     = "AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="
+	requestNonce1 = "ERERERERERERERERERERERERERERERERERERERERERE="
+	requestNonce2 = "EhISEhISEhISEhISEhISEhISEhISEhISEhISEhISEhI="
+)
+
+var requestID = bytes.Repeat([]byte{7}, 32)
+
+type generalMessageFixture struct {
+	server        *httptest.Server
+	sessions      *sessionmanager.SessionManager
+	client        wallet.Interface
+	serverWallet  wallet.Interface
+	serverKey     string
+	handlerCalled bool
+	identity      auth.Identity
+	// respond writes the response of the handler, echoing the request body if nil
+	respond http.HandlerFunc
+}
+
+func newKeyWallet(t *testing.T) wallet.Interface {
+	t.Helper()
+	key, err := ec.NewPrivateKey()
+	require.NoError(t, err)
+	return keywallet.NewKeyWallet(key)
+}
+
+func identityKeyOf(t *testing.T, w wallet.Interface) string {
+	t.Helper()
+	identityKey, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+	require.NoError(t, err)
+	return identityKey
+}
+
+// newGeneralMessageFixture serves a handler behind the verifier, with a session of the client, authenticated if asked for.
+func newGeneralMessageFixture(t *testing.T, now func() time.Time, authenticated bool, opts ...auth.GeneralMessageOption) *generalMessageFixture {
+	t.Helper()
+	return newGeneralMessageFixtureWithSessions(t, sessionmanager.NewSessionManager(), now, authenticated, opts...)
+}
+
+// newGeneralMessageFixtureWithSessions is newGeneralMessageFixture keeping the sessions in the SessionManager.
+func newGeneralMessageFixtureWithSessions(t *testing.T, sessions *sessionmanager.SessionManager, now func() time.Time, authenticated bool, opts ...auth.GeneralMessageOption) *generalMessageFixture {
+	t.Helper()
+	serverWallet := newKeyWallet(t)
+	f := &generalMessageFixture{
+		sessions:     sessions,
+		client:       newKeyWallet(t),
+		serverWallet: serverWallet,
+		serverKey:    identityKeyOf(t, serverWallet),
+	}
+
+	sessionOpts := []sessionmanager.PeerSessionOption{
+		sessionmanager.WithPeerNonce(peerNonce),
+		sessionmanager.WithPeerIdentityKey(identityKeyOf(t, f.client)),
+		sessionmanager.WithLastUpdate(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)),
+	}
+	if authenticated {
+		sessionOpts = append(sessionOpts, sessionmanager.WithAuthenticated())
+	}
+	session, err := sessionmanager.NewPeerSession(sessionNonce, sessionOpts...)
+	require.NoError(t, err)
+	require.NoError(t, f.sessions.AddSession(t.Context(), session))
+
+	verifier, err := auth.NewGeneralMessageVerifier(serverWallet, f.sessions, append([]auth.GeneralMessageOption{auth.WithVerifierClock(now)}, opts...)...)
+	require.NoError(t, err)
+	f.server = httptest.NewServer(verifier.Handler(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		f.handlerCalled = true
+		f.identity, _ = auth.GetIdentity(r.Context())
+		if f.respond != nil {
+			f.respond(rw, r)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		_, _ = rw.Write(body)
+	})))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *generalMessageFixture) signedRequest(t *testing.T, signer wallet.Interface, requestNonce string, body string) *http.Request {
+	t.Helper()
+	return f.signedRequestInSession(t, signer, sessionNonce, requestNonce, body)
+}
+
+func (f *generalMessageFixture) signedRequestInSession(t *testing.T, signer wallet.Interface, session string, requestNonce string, body string) *http.Request {
+	t.Helper()
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, f.server.URL+"/orders?page=2", strings.NewReader(body))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+	request.Header.Set("X-Bsv-Tenant", "tenant-a")
+	require.NoError(t, auth.SignGeneralMessage(request, signer, f.serverKey, session, requestNonce, requestID))
+	return request
+}
+
+func send(t *testing.T, request *http.Request) (*http.Response, []byte) {
+	t.Helper()
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response, body
+}
+
+func requireRejected(t *testing.T, response *http.Response, body []byte, code string) {
+	t.Helper()
+	requireRejectedWith(t, response, body, http.StatusUnauthorized, code)
+}
+
+func requireRejectedWith(t *testing.T, response *http.Response, body []byte, status int, code string) {
+	t.Helper()
+	require.Equal(t, status, response.StatusCode)
+	var errorResponse auth.ErrorResponse
+	require.NoError(t, json.Unmarshal(body, &errorResponse))
+	require.Equal(t, "error", errorResponse.Status)
+	require.Equal(t, code, errorResponse.Code)
+}
+
+func TestGeneralMessageVerifier_HappyPath(t *testing.T) {
+	// given
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := newGeneralMessageFixture(t, func() time.Time { return now }, true)
+	request := f.signedRequest(t, f.client, requestNonce1, `{"item":"coffee"}`)
+
+	// when
+	response, body := send(t, request)
+
+	// then
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.JSONEq(t, `{"item":"coffee"}`, string(body))
+	require.True(t, f.handlerCalled)
+	require.Equal(t, identityKeyOf(t, f.client), f.identity.IdentityKey)
+	require.Equal(t, sessionNonce, f.identity.SessionNonce)
+	require.Equal(t, auth.AuthMethodMutual, f.identity.AuthMethod)
+
+	session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+	require.NoError(t, err)
+	require.Equal(t, now, session.LastUpdate)
+}
+
+func TestGeneralMessageVerifier_ConcurrentSessionWrite(t *testing.T) {
+	// given
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	store := &racingStore{SessionStore: sessionmanager.NewMemoryStore(), key: auth.CertificatesRejectionMetaKey, value: "revoked"}
+	sessions := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store), sessionmanager.WithClock(func() time.Time { return now }))
+	f := newGeneralMessageFixtureWithSessions(t, sessions, func() time.Time { return now }, true)
+
+	// when
+	response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "first"))
+
+	// then
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+	require.NoError(t, err)
+	require.Equal(t, now, session.LastUpdate)
+	rejection, ok := session.GetMeta(auth.CertificatesRejectionMetaKey)
+	require.True(t, ok)
+	require.Equal(t, "revoked", rejection)
+
+	// when
+	response, body := send(t, f.signedRequest(t, f.client, requestNonce2, "second"))
+
+	// then
+	requireRejected(t, response, body, auth.ErrCodeCertificatesRejected)
+}
+
+func TestGeneralMessageVerifier_TouchFailure(t *testing.T) {
+	// given
+	logger, writer := newCapturingLogger(slog.LevelWarn)
+	store := &failingPutStore{SessionStore: sessionmanager.NewMemoryStore()}
+	sessions := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+	f := newGeneralMessageFixtureWithSessions(t, sessions, time.Now, true, auth.WithVerifierLogger(logger))
+	store.failing.Store(true)
+
+	// when
+	response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+	// then
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.True(t, f.handlerCalled)
+	records := logRecords(t, writer)
+	require.Len(t, records, 1)
+	require.Equal(t, "ERROR", records[0]["level"])
+	require.Equal(t, "Failed to update session", records[0]["msg"])
+	require.Equal(t, identityKeyOf(t, f.client), records[0]["identity_key"])
+	require.Contains(t, records[0]["error"], "store unavailable")
+}
+
+// failingPutStore fails to store the sessions once failing is set.
+type failingPutStore struct {
+	sessionmanager.SessionStore
+	failing atomic.Bool
+}
+
+func (s *failingPutStore) Put(ctx context.Context, session sessionmanager.PeerSession) error {
+	if s.failing.Load() {
+		return errors.New("store unavailable")
+	}
+	return s.SessionStore.Put(ctx, session)
+}
+
+// racingStore writes the metadata into the stored session right after it's read the first time,
+// like a concurrent writer between the read of the session and its update.
+type racingStore struct {
+	sessionmanager.SessionStore
+	once  sync.Once
+	key   string
+	value any
+}
+
+func (s *racingStore) GetByNonce(ctx context.Context, sessionNonce string) (sessionmanager.PeerSession, bool, error) {
+	session, exists, err := s.SessionStore.GetByNonce(ctx, sessionNonce)
+	if err != nil || !exists {
+		return session, exists, err
+	}
+	s.once.Do(func() {
+		written := session.Clone()
+		written.SetMeta(s.key, s.value)
+		err = s.SessionStore.Put(ctx, written)
+	})
+	return session, exists, err
+}
+
+func TestGeneralMessageVerifier_Chaining(t *testing.T) {
+	t.Run("Accept the next request with a fresh nonce", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		first, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "first"))
+		require.Equal(t, http.StatusOK, first.StatusCode)
+
+		// when
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce2, "second"))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+
+	t.Run("Reject a reused request nonce", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		first, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "first"))
+		require.Equal(t, http.StatusOK, first.StatusCode)
+		f.handlerCalled = false
+
+		// when
+		response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "first"))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeNonceReplayed)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Reject the handshake nonce of the peer", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+
+		// when
+		response, body := send(t, f.signedRequest(t, f.client, peerNonce, "body"))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeNonceReplayed)
+	})
+}
+
+func TestGeneralMessageVerifier_RotatedSession(t *testing.T) {
+	t.Run("Accept the rotated session nonce within the grace period", func(t *testing.T) {
+		// given
+		now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		sessions := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithNonceCreator(newKeyWallet(t)),
+		)
+		f := newGeneralMessageFixtureWithSessions(t, sessions, func() time.Time { return now }, true)
+		rotatedNonce, err := sessions.RotateSessionNonce(t.Context(), sessionNonce)
+		require.NoError(t, err)
+
+		// when
+		response, _ := send(t, validRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.True(t, f.handlerCalled)
+		require.Equal(t, identityKeyOf(t, f.client), f.identity.IdentityKey)
+		session, err := sessions.GetSession(t.Context(), rotatedNonce)
+		require.NoError(t, err)
+		require.Equal(t, rotatedNonce, session.GetSessionNonce())
+	})
+
+	t.Run("Challenge the rotated session nonce after the grace period", func(t *testing.T) {
+		// given
+		now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		sessions := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithNonceCreator(newKeyWallet(t)),
+			sessionmanager.WithRotationGracePeriod(time.Minute),
+		)
+		f := newGeneralMessageFixtureWithSessions(t, sessions, func() time.Time { return now }, true)
+		_, err := sessions.RotateSessionNonce(t.Context(), sessionNonce)
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(2 * time.Minute)
+		response, body := send(t, validRequest(t, f))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeSessionNotFound)
+		require.False(t, f.handlerCalled)
+	})
+}
+
+func TestGeneralMessageVerifier_MaxBodySize(t *testing.T) {
+	// given
+	f := newGeneralMessageFixture(t, time.Now, true, auth.WithMaxBodySize(4))
+
+	// when
+	response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "12345"))
+
+	// then
+	requireRejectedWith(t, response, body, http.StatusRequestEntityTooLarge, auth.ErrCodeUnreadableBody)
+	require.False(t, f.handlerCalled)
+}
+
+func TestGeneralMessageVerifier_UnhappyPath(t *testing.T) {
+	tests := map[string]struct {
+		authenticated bool
+		request       func(t *testing.T, f *generalMessageFixture) *http.Request
+		expectedCode  string
+	}{
+		"Tampered body": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, `{"amount":1}`)
+				request.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`))
+				request.ContentLength = int64(len(`{"amount":1000}`))
+				return request
+			},
+			expectedCode: auth.ErrCodeInvalidSignature,
+		},
+		"Tampered signed header": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.Header.Set("X-Bsv-Tenant", "tenant-b")
+				return request
+			},
+			expectedCode: auth.ErrCodeInvalidSignature,
+		},
+		"Signature from a different identity key": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, newKeyWallet(t), requestNonce1, "body")
+				request.Header.Set(auth.HeaderIdentityKey, identityKeyOf(t, f.client))
+				return request
+			},
+			expectedCode: auth.ErrCodeInvalidSignature,
+		},
+		"Identity key of another peer": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequest(t, newKeyWallet(t), requestNonce1, "body")
+			},
+			expectedCode: auth.ErrCodeIdentityMismatch,
+		},
+		"Unknown session": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.Header.Set(auth.HeaderYourNonce, "ExMTExMTExMTExMTExMTExMTExMTExMTExMTExMTExM=")
+				return request
+			},
+			expectedCode: auth.ErrCodeSessionNotFound,
+		},
+		"Unauthenticated session": {
+			authenticated: false,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequest(t, f.client, requestNonce1, "body")
+			},
+			expectedCode: auth.ErrCodeSessionNotAuthenticated,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, test.authenticated)
+			request := test.request(t, f)
+
+			// when
+			response, body := send(t, request)
+
+			// then
+			requireRejected(t, response, body, test.expectedCode)
+			require.False(t, f.handlerCalled)
+			session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+			require.NoError(t, err)
+			require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), session.LastUpdate)
+		})
+	}
+}
+
+func TestGeneralMessageVerifier_MalformedHeaders(t *testing.T) {
+	tests := map[string]struct {
+		modify              func(t *testing.T, f *generalMessageFixture, request *http.Request)
+		expectedDescription string
+	}{
+		"Missing signature": {
+			modify: func(_ *testing.T, _ *generalMessageFixture, request *http.Request) {
+				request.Header.Del(auth.HeaderSignature)
+			},
+			expectedDescription: "x-bsv-auth-signature: header is missing",
+		},
+		"Malformed signature": {
+			modify: func(_ *testing.T, _ *generalMessageFixture, request *http.Request) {
+				request.Header.Set(auth.HeaderSignature, "not hex")
+			},
+			expectedDescription: "x-bsv-auth-signature: header is malformed: not hex encoded",
+		},
+		"Unsupported version": {
+			modify: func(_ *testing.T, _ *generalMessageFixture, request *http.Request) {
+				request.Header.Set(auth.HeaderVersion, "0.2")
+			},
+			expectedDescription: `x-bsv-auth-version: unsupported version "0.2", expected "0.1"`,
+		},
+		"Identity key in place of the session nonce": {
+			modify: func(t *testing.T, f *generalMessageFixture, request *http.Request) {
+				request.Header.Set(auth.HeaderYourNonce, identityKeyOf(t, f.client))
+			},
+			expectedDescription: "x-bsv-auth-your-nonce: header is malformed: not base64 encoded",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, true)
+			request := f.signedRequest(t, f.client, requestNonce1, "body")
+			test.modify(t, f, request)
+
+			// when
+			response, body := send(t, request)
+
+			// then
+			requireRejectedWith(t, response, body, http.StatusBadRequest, auth.ErrCodeMalformedAuthHeaders)
+			var errorResponse auth.ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errorResponse))
+			require.Equal(t, test.expectedDescription, errorResponse.Description)
+			require.False(t, f.handlerCalled)
+		})
+	}
+}
+
+func TestGeneralMessageVerifier_AllowUnauthenticated(t *testing.T) {
+	tests := map[string]struct {
+		allowUnauthenticated bool
+		request              func(t *testing.T, f *generalMessageFixture) *http.Request
+		expectedStatus       int
+		expectedCode         string
+		expectedIdentity     func(t *testing.T, f *generalMessageFixture) auth.Identity
+	}{
+		"Reject a request without auth headers by default": {
+			request:        anonymousRequest,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeMissingAuthHeaders,
+		},
+		"Let a request without auth headers through as unknown": {
+			allowUnauthenticated: true,
+			request:              anonymousRequest,
+			expectedStatus:       http.StatusOK,
+			expectedIdentity: func(_ *testing.T, _ *generalMessageFixture) auth.Identity {
+				return auth.UnknownIdentity()
+			},
+		},
+		"Reject a request with broken auth headers by default": {
+			request:        brokenRequest,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   auth.ErrCodeMalformedAuthHeaders,
+		},
+		"Reject a request with broken auth headers when unauthenticated ones are allowed": {
+			allowUnauthenticated: true,
+			request:              brokenRequest,
+			expectedStatus:       http.StatusBadRequest,
+			expectedCode:         auth.ErrCodeMalformedAuthHeaders,
+		},
+		"Reject a request with an invalid signature when unauthenticated ones are allowed": {
+			allowUnauthenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := f.signedRequest(t, f.client, requestNonce1, "body")
+				request.Header.Set(auth.HeaderSignature, "3006020101020101")
+				return request
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   auth.ErrCodeInvalidSignature,
+		},
+		"Authenticate a signed request by default": {
+			request:          validRequest,
+			expectedStatus:   http.StatusOK,
+			expectedIdentity: clientIdentity,
+		},
+		"Authenticate a signed request when unauthenticated ones are allowed": {
+			allowUnauthenticated: true,
+			request:              validRequest,
+			expectedStatus:       http.StatusOK,
+			expectedIdentity:     clientIdentity,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, true, auth.WithAllowUnauthenticated(test.allowUnauthenticated))
+			request := test.request(t, f)
+
+			// when
+			response, body := send(t, request)
+
+			// then
+			if test.expectedStatus != http.StatusOK {
+				requireRejectedWith(t, response, body, test.expectedStatus, test.expectedCode)
+				require.False(t, f.handlerCalled)
+				return
+			}
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			require.True(t, f.handlerCalled)
+			expected := test.expectedIdentity(t, f)
+			require.Equal(t, expected.IdentityKey, f.identity.IdentityKey)
+			require.Equal(t, expected.AuthMethod, f.identity.AuthMethod)
+		})
+	}
+}
+
+func anonymousRequest(t *testing.T, f *generalMessageFixture) *http.Request {
+	request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, f.server.URL+"/catalog", nil)
+	require.NoError(t, err)
+	return request
+}
+
+// brokenRequest presents only some of the auth headers.
+func brokenRequest(t *testing.T, f *generalMessageFixture) *http.Request {
+	request := anonymousRequest(t, f)
+	request.Header.Set(auth.HeaderIdentityKey, identityKeyOf(t, f.client))
+	return request
+}
+
+func validRequest(t *testing.T, f *generalMessageFixture) *http.Request {
+	return f.signedRequest(t, f.client, requestNonce1, "body")
+}
+
+func clientIdentity(t *testing.T, f *generalMessageFixture) auth.Identity {
+	return auth.Identity{IdentityKey: identityKeyOf(t, f.client), AuthMethod: auth.AuthMethodMutual}
+}
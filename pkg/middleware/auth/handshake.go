@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport"
+)
+
+// DefaultAuthEndpointPath is the path the reference clients send the non-general messages to.
+const DefaultAuthEndpointPath = "/.well-known/auth"
+
+// BRC-103 message types.
+const (
+	MessageTypeInitialRequest      = "initialRequest"
+	MessageTypeInitialResponse     = "initialResponse"
+	MessageTypeCertificateRequest  = "certificateRequest"
+	MessageTypeCertificateResponse = "certificateResponse"
+	MessageTypeGeneral             = "general"
+)
+
+// AuthMessage is the JSON body of the BRC-103 messages sent to the auth endpoint and of their responses,
+// with the same fields as the AuthMessage of the TypeScript SDK.
+type AuthMessage struct {
+	Version     string `json:"version"`
+	MessageType string `json:"messageType"`
+	IdentityKey string `json:"identityKey"`
+	// Nonce is the fresh nonce of a certificate message
+	Nonce string `json:"nonce,omitempty"`
+	// InitialNonce is the session nonce of the sender, chosen in the initialRequest or the initialResponse
+	InitialNonce string `json:"initialNonce,omitempty"`
+	// YourNonce is the session nonce of the receiver
+	YourNonce             string                   `json:"yourNonce,omitempty"`
+	Certificates          []wallet.Certificate     `json:"certificates,omitempty"`
+	RequestedCertificates *RequestedCertificateSet `json:"requestedCertificates,omitempty"`
+	Signature             ByteArray                `json:"signature,omitempty"`
+}
+
+// ByteArray is marshaled to JSON as an array of numbers, the way the TypeScript SDK sends the signatures.
+type ByteArray []byte
+
+// MarshalJSON writes the bytes as an array of numbers.
+func (b ByteArray) MarshalJSON() ([]byte, error) {
+	numbers := make([]int, len(b))
+	for i, value := range b {
+		numbers[i] = int(value)
+	}
+	return json.Marshal(numbers) //nolint: wrapcheck // ints always marshal
+}
+
+// UnmarshalJSON reads the bytes from an array of numbers.
+func (b *ByteArray) UnmarshalJSON(data []byte) error {
+	var numbers []uint8
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		return fmt.Errorf("invalid byte array: %w", err)
+	}
+	*b = numbers
+	return nil
+}
+
+// NewInitialRequest starts a handshake with the wallet of the peer, asking for the requested certificates if not empty,
+// the counterpart of the auth endpoint of the GeneralMessageVerifier for Go clients and tests.
+// The InitialNonce of the request becomes the nonce the server chains its responses to.
+func NewInitialRequest(ctx context.Context, w wallet.Interface, requested RequestedCertificateSet) (AuthMessage, error) {
+	identityKey, err := w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to get identity key: %w", err)
+	}
+	initialNonce, err := w.CreateNonce(ctx)
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to create initial nonce: %w", err)
+	}
+	request := AuthMessage{
+		Version:      transport.AuthVersion,
+		MessageType:  MessageTypeInitialRequest,
+		IdentityKey:  identityKey,
+		InitialNonce: initialNonce,
+	}
+	if !requested.IsEmpty() {
+		request.RequestedCertificates = &requested
+	}
+	return request, nil
+}
+
+// VerifyInitialResponse checks the initialResponse of the server answers the initialRequest and is signed by the server,
+// the InitialNonce of the response is the session nonce for SignGeneralMessage.
+func VerifyInitialResponse(ctx context.Context, w wallet.Interface, request AuthMessage, response AuthMessage) error {
+	if response.MessageType != MessageTypeInitialResponse {
+		return fmt.Errorf("unexpected message type %q", response.MessageType)
+	}
+	if response.YourNonce != request.InitialNonce {
+		return fmt.Errorf("initial response answers a different initial request")
+	}
+	counterparty, err := wallet.ParseCounterparty(response.IdentityKey)
+	if err != nil {
+		return fmt.Errorf("invalid server identity key: %w", err)
+	}
+	data, err := handshakeSignatureData(request.InitialNonce, response.InitialNonce)
+	if err != nil {
+		return err
+	}
+	valid, err := w.VerifySignature(ctx, data, response.Signature, MessageSignatureProtocol,
+		request.InitialNonce+" "+response.InitialNonce, counterparty)
+	if err != nil {
+		return fmt.Errorf("failed to verify initial response signature: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("initial response signature is invalid")
+	}
+	return nil
+}
+
+// NewCertificateResponse presents the certificates to the server of the session, signed with the wallet of the peer.
+func NewCertificateResponse(ctx context.Context, w wallet.Interface, serverIdentityKey string, sessionNonce string, certificates []wallet.Certificate) (AuthMessage, error) {
+	signed, err := json.Marshal(certificates)
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to marshal certificates: %w", err)
+	}
+	message, err := newSignedMessage(ctx, w, MessageTypeCertificateResponse, serverIdentityKey, sessionNonce, signed)
+	if err != nil {
+		return AuthMessage{}, err
+	}
+	message.Certificates = certificates
+	return message, nil
+}
+
+// NewCertificateRequest asks the server of the session for the certificates, signed with the wallet of the peer.
+func NewCertificateRequest(ctx context.Context, w wallet.Interface, serverIdentityKey string, sessionNonce string, requested RequestedCertificateSet) (AuthMessage, error) {
+	signed, err := json.Marshal(requested)
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to marshal requested certificates: %w", err)
+	}
+	message, err := newSignedMessage(ctx, w, MessageTypeCertificateRequest, serverIdentityKey, sessionNonce, signed)
+	if err != nil {
+		return AuthMessage{}, err
+	}
+	message.RequestedCertificates = &requested
+	return message, nil
+}
+
+// newSignedMessage creates a certificate message with a fresh nonce, signing the JSON of its certificates part
+// with the keyID chained to the session nonce of the receiver, like the TypeScript SDK does.
+func newSignedMessage(ctx context.Context, w wallet.Interface, messageType string, receiverIdentityKey string, yourNonce string, signed []byte) (AuthMessage, error) {
+	identityKey, err := w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to get identity key: %w", err)
+	}
+	counterparty, err := wallet.ParseCounterparty(receiverIdentityKey)
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("invalid receiver identity key: %w", err)
+	}
+	nonce, err := w.CreateNonce(ctx)
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to create nonce: %w", err)
+	}
+	signature, err := w.CreateSignature(ctx, signed, MessageSignatureProtocol, nonce+" "+yourNonce, counterparty, wallet.Privilege{})
+	if err != nil {
+		return AuthMessage{}, fmt.Errorf("failed to sign %s: %w", messageType, err)
+	}
+	return AuthMessage{
+		Version:     transport.AuthVersion,
+		MessageType: messageType,
+		IdentityKey: identityKey,
+		Nonce:       nonce,
+		YourNonce:   yourNonce,
+		Signature:   signature,
+	}, nil
+}
+
+// handshakeSignatureData returns the data signed in the initialResponse, the decoded nonces of the peer and of the server.
+func handshakeSignatureData(initialNonce string, sessionNonce string) ([]byte, error) {
+	peer, err := base64.StdEncoding.DecodeString(initialNonce)
+	if err != nil {
+		return nil, fmt.Errorf("initial nonce is not base64 encoded: %w", err)
+	}
+	session, err := base64.StdEncoding.DecodeString(sessionNonce)
+	if err != nil {
+		return nil, fmt.Errorf("session nonce is not base64 encoded: %w", err)
+	}
+	return append(peer, session...), nil
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// AuthMethod describes how the identity of the peer was established.
+type AuthMethod string
+
+// Supported authentication methods.
+const (
+	// AuthMethodMutual means the peer completed BRC-103 mutual authentication.
+	AuthMethodMutual AuthMethod = "mutual"
+	// AuthMethodUnauthenticated means the request was let through without authenticating the peer.
+	AuthMethodUnauthenticated AuthMethod = "unauthenticated"
+)
+
+// Identity holds everything the middleware knows about the peer that sent the request.
+type Identity struct {
+	// IdentityKey is the identity public key of the peer
+	IdentityKey string
+	// SessionNonce is the nonce of the session the request was made in, e.g. to correlate the logs of the session
+	SessionNonce string
+	// Authenticated tells whether the peer authenticated the request, false for the anonymous peers let through
+	Authenticated bool
+	// Version is the version of the auth protocol the request was sent with
+	Version string
+	// EstablishedAt is the time the session was established by the handshake, zero if the session wasn't created by the verifier
+	EstablishedAt time.Time
+	// AuthenticatedAt is the time the request was authenticated
+	AuthenticatedAt time.Time
+	// Certificates are the certificates presented by the peer
+	Certificates []wallet.Certificate
+	// AuthMethod describes how the identity was established
+	AuthMethod AuthMethod
+}
+
+// UnknownIdentityKey is the identity key of the anonymous peers let through without authentication.
+const UnknownIdentityKey = "unknown"
+
+// UnknownIdentity returns the identity of an anonymous peer let through without authentication.
+func UnknownIdentity() Identity {
+	return Identity{IdentityKey: UnknownIdentityKey, AuthMethod: AuthMethodUnauthenticated}
+}
+
+// EstablishedAtMetaKey is the session metadata key of the time the session was established by the handshake.
+const EstablishedAtMetaKey = "auth.establishedAt"
+
+type identityContextKey struct{}
+
+// WithIdentity returns a copy of ctx carrying the given identity,
+// with its identity key as the peer the wallet is called for.
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	ctx = wallet.WithPeer(ctx, identity.IdentityKey)
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// GetIdentity returns the identity stored in the context, if any.
+func GetIdentity(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityContextKey{}).(Identity)
+	return identity, ok
+}
+
+// GetIdentityFromContext returns the identity key of the peer stored in the context, if any.
+// It's kept for compatibility, GetIdentity returns the whole identity.
+func GetIdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := GetIdentity(ctx)
+	return identity.IdentityKey, ok
+}
+
+// MustGetIdentity returns the identity stored in the context and panics if there is none.
+// It is meant for handlers that are only reachable through the auth middleware.
+func MustGetIdentity(ctx context.Context) Identity {
+	identity, ok := GetIdentity(ctx)
+	if !ok {
+		panic("auth: no identity in request context, is the handler wrapped with the auth middleware?")
+	}
+	return identity
+}
+
+// IsAuthenticated reports whether the context carries the identity of a mutually authenticated peer.
+func IsAuthenticated(ctx context.Context) bool {
+	identity, ok := GetIdentity(ctx)
+	return ok && identity.AuthMethod == AuthMethodMutual && identity.IdentityKey != ""
+}
+
+// sessionEstablishedAt returns the time the session was established,
+// which external stores decode from JSON as an RFC 3339 string.
+func sessionEstablishedAt(session sessionmanager.PeerSession) time.Time {
+	value, _ := session.GetMeta(EstablishedAtMetaKey)
+	switch establishedAt := value.(type) {
+	case time.Time:
+		return establishedAt
+	case string:
+		parsed, _ := time.Parse(time.RFC3339Nano, establishedAt)
+		return parsed
+	}
+	return time.Time{}
+}
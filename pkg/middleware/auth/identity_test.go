@@ -0,0 +1,143 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentity_Context(t *testing.T) {
+	t.Run("Round trip identity through context", func(t *testing.T) {
+		// given
+		identity := auth.Identity{
+			IdentityKey:     "02identity",
+			SessionNonce:    "session-nonce",
+			AuthenticatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Certificates:    []wallet.Certificate{{Type: "email"}},
+			AuthMethod:      auth.AuthMethodMutual,
+		}
+
+		// when
+		ctx := auth.WithIdentity(context.Background(), identity)
+
+		// then
+		retrieved, ok := auth.GetIdentity(ctx)
+		require.True(t, ok)
+		require.Equal(t, identity, retrieved)
+		require.Equal(t, identity, auth.MustGetIdentity(ctx))
+		require.True(t, auth.IsAuthenticated(ctx))
+		peer, ok := wallet.PeerFromContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, identity.IdentityKey, peer)
+	})
+
+	t.Run("Unauthenticated identity", func(t *testing.T) {
+		// when
+		ctx := auth.WithIdentity(context.Background(), auth.Identity{AuthMethod: auth.AuthMethodUnauthenticated})
+
+		// then
+		_, ok := auth.GetIdentity(ctx)
+		require.True(t, ok)
+		require.False(t, auth.IsAuthenticated(ctx))
+		_, ok = wallet.PeerFromContext(ctx)
+		require.False(t, ok)
+	})
+
+	t.Run("Unknown identity of an anonymous peer", func(t *testing.T) {
+		// when
+		ctx := auth.WithIdentity(context.Background(), auth.UnknownIdentity())
+
+		// then
+		identity, ok := auth.GetIdentity(ctx)
+		require.True(t, ok)
+		require.Equal(t, auth.UnknownIdentityKey, identity.IdentityKey)
+		require.False(t, auth.IsAuthenticated(ctx))
+	})
+
+	t.Run("Identity key through the legacy helper", func(t *testing.T) {
+		// given
+		ctx := auth.WithIdentity(context.Background(), auth.Identity{IdentityKey: "02identity", AuthMethod: auth.AuthMethodMutual})
+
+		// when
+		identityKey, ok := auth.GetIdentityFromContext(ctx)
+
+		// then
+		require.True(t, ok)
+		require.Equal(t, "02identity", identityKey)
+	})
+
+	t.Run("No identity in context", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+
+		// when
+		_, ok := auth.GetIdentity(ctx)
+		_, legacyOK := auth.GetIdentityFromContext(ctx)
+
+		// then
+		require.False(t, ok)
+		require.False(t, legacyOK)
+		require.False(t, auth.IsAuthenticated(ctx))
+		require.Panics(t, func() { auth.MustGetIdentity(ctx) })
+	})
+}
+
+func TestGeneralMessageVerifier_Identity(t *testing.T) {
+	t.Run("Pass the identity of a peer authenticated by the handshake", func(t *testing.T) {
+		// given
+		establishedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		now := establishedAt
+		f := newGeneralMessageFixture(t, func() time.Time { return now }, true)
+		_, initialResponse := f.handshake(t)
+		now = establishedAt.Add(time.Minute)
+
+		// when
+		response, _ := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, auth.Identity{
+			IdentityKey:     identityKeyOf(t, f.client),
+			SessionNonce:    initialResponse.InitialNonce,
+			Authenticated:   true,
+			Version:         "0.1",
+			EstablishedAt:   establishedAt,
+			AuthenticatedAt: now,
+			AuthMethod:      auth.AuthMethodMutual,
+		}, f.identity)
+	})
+
+	t.Run("Read the establishment time decoded from JSON by an external store", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+		require.NoError(t, err)
+		session.SetMeta(auth.EstablishedAtMetaKey, "2025-06-01T12:00:00Z")
+		require.NoError(t, f.sessions.UpdateSession(t.Context(), *session))
+
+		// when
+		response, _ := send(t, validRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), f.identity.EstablishedAt)
+	})
+
+	t.Run("Pass the identity of an anonymous peer", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithAllowUnauthenticated(true))
+
+		// when
+		response, _ := send(t, anonymousRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, auth.UnknownIdentity(), f.identity)
+		require.False(t, f.identity.Authenticated)
+	})
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrorRenderer renders the description of the ErrorResponse with the code in the locale of the request,
+// the preferred language tag of its Accept-Language, empty if it has none. It gets the English description
+// the verifier would send and returns the one to send instead, e.g. the same one for the locales it doesn't translate.
+// The code is never rendered, the clients keep branching on it.
+type ErrorRenderer func(code string, locale string, description string) string
+
+// WithErrorRenderer renders the descriptions of the ErrorResponses the verifier writes, on the protected routes
+// and on the auth endpoint, with the renderer. The descriptions are sent as they are by default.
+func WithErrorRenderer(renderer ErrorRenderer) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.errorRenderer = renderer
+	}
+}
+
+// localizingResponseWriter renders the descriptions of the ErrorResponses written with WriteErrorResponse.
+type localizingResponseWriter struct {
+	http.ResponseWriter
+	locale   string
+	renderer ErrorRenderer
+}
+
+// localized returns the ResponseWriter the verifier writes its ErrorResponses to, rendering them in the locale of the request.
+func (v *GeneralMessageVerifier) localized(rw http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if v.errorRenderer == nil {
+		return rw
+	}
+	return &localizingResponseWriter{ResponseWriter: rw, locale: RequestLocale(r), renderer: v.errorRenderer}
+}
+
+// RequestLocale returns the language tag the request prefers in its Accept-Language, the one with the highest quality
+// and the first one of those, empty if it doesn't accept any specific language.
+func RequestLocale(r *http.Request) string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	var languages []language
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality > 0 {
+			languages = append(languages, language{tag: tag, quality: quality})
+		}
+	}
+	if len(languages) == 0 {
+		return ""
+	}
+	slices.SortStableFunc(languages, func(a, b language) int {
+		return cmp.Compare(b.quality, a.quality)
+	})
+	return languages[0].tag
+}
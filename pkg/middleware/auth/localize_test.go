@@ -0,0 +1,120 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+// germanRenderer translates the missing auth headers to German, keeping the other descriptions.
+func germanRenderer(code string, locale string, description string) string {
+	if locale == "de-DE" && code == auth.ErrCodeMissingAuthHeaders {
+		return "Die Authentifizierungs-Header fehlen"
+	}
+	return description
+}
+
+func TestGeneralMessageVerifier_ErrorRenderer(t *testing.T) {
+	tests := map[string]struct {
+		request             func(t *testing.T, f *generalMessageFixture) *http.Request
+		acceptLanguage      string
+		expectedCode        string
+		expectedDescription string
+	}{
+		"Render the description in the preferred locale": {
+			request:             anonymousRequest,
+			acceptLanguage:      "en;q=0.5, de-DE",
+			expectedCode:        auth.ErrCodeMissingAuthHeaders,
+			expectedDescription: "Die Authentifizierungs-Header fehlen",
+		},
+		"Keep the description the renderer doesn't translate": {
+			request:             anonymousRequest,
+			acceptLanguage:      "fr-FR",
+			expectedCode:        auth.ErrCodeMissingAuthHeaders,
+			expectedDescription: "general message auth headers are missing",
+		},
+		"Render the description on the auth endpoint": {
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, f.server.URL+auth.DefaultAuthEndpointPath, nil)
+				require.NoError(t, err)
+				return request
+			},
+			acceptLanguage:      "de-DE",
+			expectedCode:        auth.ErrCodeInvalidMessage,
+			expectedDescription: "auth messages have to be POSTed",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			var rendered []string
+			f := newGeneralMessageFixture(t, time.Now, true, auth.WithErrorRenderer(func(code string, locale string, description string) string {
+				rendered = append(rendered, code+" "+locale)
+				return germanRenderer(code, locale, description)
+			}))
+			request := test.request(t, f)
+			request.Header.Set("Accept-Language", test.acceptLanguage)
+
+			// when
+			response, body := send(t, request)
+
+			// then
+			var errorResponse auth.ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errorResponse))
+			require.NotEqual(t, http.StatusOK, response.StatusCode)
+			require.Equal(t, test.expectedCode, errorResponse.Code)
+			require.Equal(t, test.expectedDescription, errorResponse.Description)
+			require.Equal(t, []string{test.expectedCode + " " + auth.RequestLocale(request)}, rendered)
+		})
+	}
+
+	t.Run("Don't render the responses of the handler", func(t *testing.T) {
+		// given
+		rendered := false
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithErrorRenderer(func(_ string, _ string, description string) string {
+			rendered = true
+			return description
+		}))
+
+		// when
+		response, _ := send(t, validRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.False(t, rendered)
+	})
+}
+
+func TestRequestLocale(t *testing.T) {
+	tests := map[string]struct {
+		acceptLanguage string
+		expected       string
+	}{
+		"No Accept-Language":            {acceptLanguage: "", expected: ""},
+		"Single language":               {acceptLanguage: "de-DE", expected: "de-DE"},
+		"First of the equal qualities":  {acceptLanguage: "pl, en", expected: "pl"},
+		"Highest quality":               {acceptLanguage: "en;q=0.8, de;q=0.9, fr;q=0.1", expected: "de"},
+		"Wildcard only":                 {acceptLanguage: "*", expected: ""},
+		"Language not accepted":         {acceptLanguage: "en;q=0, de;q=0.2", expected: "de"},
+		"Malformed quality is skipped":  {acceptLanguage: "en;q=high, de;q=0.2", expected: "de"},
+		"Whitespace around the entries": {acceptLanguage: "  fr-CA ; q=0.9 ,en;q=0.5", expected: "fr-CA"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "https://example.com/", nil)
+			require.NoError(t, err)
+			request.Header.Set("Accept-Language", test.acceptLanguage)
+
+			// when
+			locale := auth.RequestLocale(request)
+
+			// then
+			require.Equal(t, test.expected, locale)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"log/slog"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+)
+
+// Attribute keys of the records logged by the GeneralMessageVerifier.
+const (
+	logIdentityKey  = "identity_key"
+	logRequestID    = "request_id"
+	logMessageType  = "message_type"
+	logCode         = "code"
+	logDescription  = "description"
+	logNonce        = "nonce"
+	logSessionNonce = "session_nonce"
+	logStatus       = "status"
+)
+
+// sessionLifecycle is implemented by the SessionManager, reporting the sessions it removes.
+type sessionLifecycle interface {
+	OnRemoved(hook func(sessionmanager.PeerSession))
+	OnExpired(hook func(sessionmanager.PeerSession))
+}
+
+// logSessionLifecycle logs the removed and expired sessions at Info, if the SessionManager reports them.
+func (v *GeneralMessageVerifier) logSessionLifecycle() {
+	lifecycle, ok := v.sessions.(sessionLifecycle)
+	if !ok {
+		return
+	}
+	lifecycle.OnRemoved(func(session sessionmanager.PeerSession) {
+		v.logger.Info("Session removed", slog.String(logIdentityKey, session.GetPeerIdentityKey()))
+	})
+	lifecycle.OnExpired(func(session sessionmanager.PeerSession) {
+		v.logger.Info("Session expired", slog.String(logIdentityKey, session.GetPeerIdentityKey()))
+	})
+}
+
+// messageAttrs are the attributes of the auth message safe to log above Debug.
+func messageAttrs(message AuthMessage) []slog.Attr {
+	return []slog.Attr{slog.String(logMessageType, message.MessageType), slog.String(logIdentityKey, message.IdentityKey)}
+}
@@ -0,0 +1,118 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/internal/logging"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func newCapturingLogger(level slog.Level) (*slog.Logger, *logging.TestWriter) {
+	writer := &logging.TestWriter{}
+	return slog.New(slog.NewJSONHandler(writer, &slog.HandlerOptions{Level: level})), writer
+}
+
+func logRecords(t *testing.T, writer *logging.TestWriter) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(writer.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestGeneralMessageVerifier_Logging(t *testing.T) {
+	t.Run("Log a failed signature at Warn without the secrets", func(t *testing.T) {
+		// given
+		logger, writer := newCapturingLogger(slog.LevelInfo)
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithVerifierLogger(logger))
+		request := f.signedRequest(t, f.client, requestNonce1, "body")
+		request.Header.Set("X-Bsv-Tenant", "tenant-b")
+		signature := request.Header.Get(auth.HeaderSignature)
+
+		// when
+		response, _ := send(t, request)
+
+		// then
+		require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+		records := logRecords(t, writer)
+		require.Len(t, records, 1)
+		require.Equal(t, "WARN", records[0]["level"])
+		require.Equal(t, "Rejected auth message", records[0]["msg"])
+		require.Equal(t, "general-message-verifier", records[0]["service"])
+		require.Equal(t, auth.ErrCodeInvalidSignature, records[0]["code"])
+		require.Equal(t, identityKeyOf(t, f.client), records[0]["identity_key"])
+		require.Equal(t, base64.StdEncoding.EncodeToString(requestID), records[0]["request_id"])
+		require.NotContains(t, writer.String(), signature)
+		require.NotContains(t, writer.String(), requestNonce1)
+		require.NotContains(t, writer.String(), sessionNonce)
+	})
+
+	t.Run("Log the processed messages at Debug", func(t *testing.T) {
+		// given
+		logger, writer := newCapturingLogger(slog.LevelDebug)
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithVerifierLogger(logger))
+
+		// when
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		records := logRecords(t, writer)
+		require.Len(t, records, 1)
+		require.Equal(t, "DEBUG", records[0]["level"])
+		require.Equal(t, "Processing general message", records[0]["msg"])
+		require.Equal(t, requestNonce1, records[0]["nonce"])
+	})
+
+	t.Run("Log the established and removed sessions at Info", func(t *testing.T) {
+		// given
+		logger, writer := newCapturingLogger(slog.LevelInfo)
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithVerifierLogger(logger))
+		_, initialResponse := f.handshake(t)
+		session, err := f.sessions.GetSession(t.Context(), initialResponse.InitialNonce)
+		require.NoError(t, err)
+
+		// when
+		require.NoError(t, f.sessions.RemoveSession(t.Context(), *session))
+
+		// then
+		records := logRecords(t, writer)
+		require.Len(t, records, 2)
+		require.Equal(t, "Session established", records[0]["msg"])
+		require.Equal(t, "Session removed", records[1]["msg"])
+		for _, record := range records {
+			require.Equal(t, "INFO", record["level"])
+			require.Equal(t, identityKeyOf(t, f.client), record["identity_key"])
+		}
+		require.NotContains(t, writer.String(), initialResponse.InitialNonce)
+	})
+
+	t.Run("Log the failures of the backends at Error", func(t *testing.T) {
+		// given
+		logger, writer := newCapturingLogger(slog.LevelInfo)
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithVerifierLogger(logger), auth.WithNonceStore(failingNonceStore{}))
+
+		// when
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		records := logRecords(t, writer)
+		require.Len(t, records, 1)
+		require.Equal(t, "ERROR", records[0]["level"])
+		require.Equal(t, "Failed to remember request nonce", records[0]["msg"])
+	})
+}
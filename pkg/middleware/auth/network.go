@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNetworkMismatch is returned when the wallet is on a different network than the one the middleware is configured for.
+var ErrNetworkMismatch = errors.New("wallet network mismatch")
+
+// NetworkReporter is the part of the wallet.Interface needed to check the network of the wallet.
+type NetworkReporter interface {
+	GetNetwork(ctx context.Context) (string, error)
+}
+
+// VerifyWalletNetwork checks the wallet is on the given network, so the middleware can refuse to start with a wallet
+// of the wrong network instead of failing on the first request.
+func VerifyWalletNetwork(ctx context.Context, w NetworkReporter, network string) error {
+	actual, err := w.GetNetwork(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get wallet network: %w", err)
+	}
+	if actual != network {
+		return fmt.Errorf("%w: wallet is on %s, middleware is configured for %s", ErrNetworkMismatch, actual, network)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyWalletNetwork(t *testing.T) {
+	t.Run("Accepts the wallet on the configured network", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockNetwork(wallet.NetworkTestnet))
+
+		// when
+		err := auth.VerifyWalletNetwork(context.Background(), w, wallet.NetworkTestnet)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("Refuses the wallet on another network", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockNetwork(wallet.NetworkTestnet))
+
+		// when
+		err := auth.VerifyWalletNetwork(context.Background(), w, wallet.NetworkMainnet)
+
+		// then
+		require.ErrorIs(t, err, auth.ErrNetworkMismatch)
+	})
+}
+
+func TestNewGeneralMessageVerifier_ExpectedNetwork(t *testing.T) {
+	t.Run("Refuses to start with a wallet on another network", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockNetwork(wallet.NetworkTestnet))
+
+		// when
+		verifier, err := auth.NewGeneralMessageVerifier(w, sessionmanager.NewSessionManager(), auth.WithExpectedNetwork(wallet.NetworkMainnet))
+
+		// then
+		require.ErrorIs(t, err, auth.ErrNetworkMismatch)
+		require.Nil(t, verifier)
+	})
+
+	t.Run("Starts with a wallet on the expected network", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockNetwork(wallet.NetworkTestnet))
+
+		// when
+		verifier, err := auth.NewGeneralMessageVerifier(w, sessionmanager.NewSessionManager(), auth.WithExpectedNetwork(wallet.NetworkTestnet))
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, verifier)
+	})
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultNonceReplayWindow is the default time the MemoryNonceStore remembers the accepted request nonces for.
+const DefaultNonceReplayWindow = 10 * time.Minute
+
+// NonceStore remembers the request nonces accepted in the sessions, so the GeneralMessageVerifier can reject their replays.
+// Implementations must be safe for concurrent use; a shared one, e.g. backed by Redis, protects all the replicas.
+type NonceStore interface {
+	// Remember stores the nonce as used in the session, it returns false if it already was.
+	Remember(ctx context.Context, sessionNonce string, nonce string) (bool, error)
+}
+
+// MemoryNonceStore remembers the request nonces in memory for a time window, forgetting the older ones,
+// so the memory is bounded by the number of requests within the window.
+type MemoryNonceStore struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu sync.Mutex
+	// order holds the rememberedNonce entries from the oldest to the newest
+	order   *list.List
+	entries map[nonceKey]*list.Element
+}
+
+// MemoryNonceStoreOption configures the MemoryNonceStore.
+type MemoryNonceStoreOption func(*MemoryNonceStore)
+
+// WithNonceStoreClock overrides the clock used to expire the remembered nonces.
+func WithNonceStoreClock(now func() time.Time) MemoryNonceStoreOption {
+	return func(s *MemoryNonceStore) {
+		s.now = now
+	}
+}
+
+type nonceKey struct {
+	sessionNonce string
+	nonce        string
+}
+
+type rememberedNonce struct {
+	key       nonceKey
+	expiresAt time.Time
+}
+
+// NewMemoryNonceStore creates a store remembering the nonces for the window.
+func NewMemoryNonceStore(window time.Duration, opts ...MemoryNonceStoreOption) *MemoryNonceStore {
+	s := &MemoryNonceStore{
+		window:  window,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[nonceKey]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Remember stores the nonce as used in the session, it returns false if it already was within the window.
+func (s *MemoryNonceStore) Remember(ctx context.Context, sessionNonce string, nonce string) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.forgetExpired(now)
+
+	key := nonceKey{sessionNonce: sessionNonce, nonce: nonce}
+	if _, exists := s.entries[key]; exists {
+		return false, nil
+	}
+	s.entries[key] = s.order.PushBack(&rememberedNonce{key: key, expiresAt: now.Add(s.window)})
+	return true, nil
+}
+
+// Len returns the number of remembered nonces, including the expired ones not forgotten yet.
+func (s *MemoryNonceStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryNonceStore) forgetExpired(now time.Time) {
+	for oldest := s.order.Front(); oldest != nil; oldest = s.order.Front() {
+		entry := oldest.Value.(*rememberedNonce)
+		if now.Before(entry.expiresAt) {
+			return
+		}
+		s.order.Remove(oldest)
+		delete(s.entries, entry.key)
+	}
+}
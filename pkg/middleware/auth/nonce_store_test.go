@@ -0,0 +1,136 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryNonceStore_Remember(t *testing.T) {
+	t.Run("Reject the nonce remembered in the session", func(t *testing.T) {
+		// given
+		store := auth.NewMemoryNonceStore(time.Minute)
+		first, err := store.Remember(t.Context(), "session-a", "nonce-1")
+		require.NoError(t, err)
+
+		// when
+		second, err := store.Remember(t.Context(), "session-a", "nonce-1")
+
+		// then
+		require.NoError(t, err)
+		require.True(t, first)
+		require.False(t, second)
+	})
+
+	t.Run("Remember the same nonce in different sessions independently", func(t *testing.T) {
+		// given
+		store := auth.NewMemoryNonceStore(time.Minute)
+		first, err := store.Remember(t.Context(), "session-a", "nonce-1")
+		require.NoError(t, err)
+
+		// when
+		other, err := store.Remember(t.Context(), "session-b", "nonce-1")
+
+		// then
+		require.NoError(t, err)
+		require.True(t, first)
+		require.True(t, other)
+	})
+
+	t.Run("Forget the nonces after the window", func(t *testing.T) {
+		// given
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := auth.NewMemoryNonceStore(time.Minute, auth.WithNonceStoreClock(func() time.Time { return now }))
+		_, err := store.Remember(t.Context(), "session-a", "nonce-1")
+		require.NoError(t, err)
+		now = now.Add(30 * time.Second)
+		_, err = store.Remember(t.Context(), "session-a", "nonce-2")
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(30 * time.Second)
+		expired, err := store.Remember(t.Context(), "session-a", "nonce-1")
+		require.NoError(t, err)
+		remembered, err := store.Remember(t.Context(), "session-a", "nonce-2")
+		require.NoError(t, err)
+
+		// then
+		require.True(t, expired)
+		require.False(t, remembered)
+		require.Equal(t, 2, store.Len())
+	})
+
+	t.Run("Return the error of a done context", func(t *testing.T) {
+		// given
+		store := auth.NewMemoryNonceStore(time.Minute)
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		// when
+		_, err := store.Remember(ctx, "session-a", "nonce-1")
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+type failingNonceStore struct{}
+
+func (failingNonceStore) Remember(_ context.Context, _ string, _ string) (bool, error) {
+	return false, errors.New("redis is down")
+}
+
+func TestGeneralMessageVerifier_NonceReplay(t *testing.T) {
+	t.Run("Reject the same request sent twice", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		request := f.signedRequest(t, f.client, requestNonce1, "body")
+		replay, err := http.NewRequestWithContext(t.Context(), request.Method, request.URL.String(), strings.NewReader("body"))
+		require.NoError(t, err)
+		replay.Header = request.Header.Clone()
+		first, _ := send(t, request)
+		require.Equal(t, http.StatusOK, first.StatusCode)
+		f.handlerCalled = false
+
+		// when
+		response, body := send(t, replay)
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeNonceReplayed)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Accept the replay after the window", func(t *testing.T) {
+		// given
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := auth.NewMemoryNonceStore(time.Minute, auth.WithNonceStoreClock(func() time.Time { return now }))
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithNonceStore(store))
+		first, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+		require.Equal(t, http.StatusOK, first.StatusCode)
+
+		// when
+		now = now.Add(time.Minute)
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+
+	t.Run("Fail when the nonce store fails", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithNonceStore(failingNonceStore{}))
+
+		// when
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, http.StatusInternalServerError, response.StatusCode)
+		require.False(t, f.handlerCalled)
+	})
+}
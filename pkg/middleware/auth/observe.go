@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// WithObserveOnly runs the verification without enforcing it, e.g. while rolling the authentication out:
+// the requests failing it are logged at Warn, with the code and the description of the ErrorResponse they would get,
+// and passed to the next handler with the UnknownIdentity in the context instead of being rejected.
+// The verified requests get their identity and their signed responses as usual. The auth endpoint is still enforced.
+func WithObserveOnly(observe bool) GeneralMessageOption {
+	return func(v *GeneralMessageVerifier) {
+		v.observeOnly = observe
+	}
+}
+
+// rejectionRecorder records the rejection written by the verification, never sent when only observing.
+type rejectionRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newRejectionRecorder() *rejectionRecorder {
+	return &rejectionRecorder{header: http.Header{}}
+}
+
+// Header returns the headers of the rejection.
+func (r *rejectionRecorder) Header() http.Header {
+	return r.header
+}
+
+// WriteHeader records the status of the rejection, only the first one counts.
+func (r *rejectionRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Write records the body of the rejection.
+func (r *rejectionRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data) //nolint: wrapcheck // bytes.Buffer never returns an error
+}
+
+// logObservedRejection logs the rejection the request would have got at Warn.
+func (v *GeneralMessageVerifier) logObservedRejection(ctx context.Context, rejection *rejectionRecorder) {
+	var response ErrorResponse
+	_ = json.Unmarshal(rejection.body.Bytes(), &response)
+	v.logger.LogAttrs(ctx, slog.LevelWarn, "Observed rejection of general message",
+		slog.Int(logStatus, rejection.status), slog.String(logCode, response.Code), slog.String(logDescription, response.Description))
+}
@@ -0,0 +1,103 @@
+package auth_test
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneralMessageVerifier_ObserveOnly(t *testing.T) {
+	tests := map[string]struct {
+		authenticated bool
+		request       func(t *testing.T, f *generalMessageFixture) *http.Request
+		code          string
+	}{
+		"Pass the request without the auth headers through": {
+			authenticated: true,
+			request:       anonymousRequest,
+			code:          auth.ErrCodeMissingAuthHeaders,
+		},
+		"Pass the request with some of the auth headers through": {
+			authenticated: true,
+			request:       brokenRequest,
+			code:          auth.ErrCodeMalformedAuthHeaders,
+		},
+		"Pass the request with an invalid signature through": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				request := validRequest(t, f)
+				request.Header.Set("X-Bsv-Tenant", "tenant-b")
+				return request
+			},
+			code: auth.ErrCodeInvalidSignature,
+		},
+		"Pass the request of an unauthenticated session through": {
+			authenticated: false,
+			request:       validRequest,
+			code:          auth.ErrCodeSessionNotAuthenticated,
+		},
+		"Pass the request of a session that is gone through": {
+			authenticated: true,
+			request: func(t *testing.T, f *generalMessageFixture) *http.Request {
+				return f.signedRequestInSession(t, f.client, requestNonce2, requestNonce1, "body")
+			},
+			code: auth.ErrCodeSessionNotFound,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			logger, writer := newCapturingLogger(slog.LevelWarn)
+			f := newGeneralMessageFixture(t, time.Now, test.authenticated, auth.WithObserveOnly(true), auth.WithVerifierLogger(logger))
+
+			// when
+			response, _ := send(t, test.request(t, f))
+
+			// then
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			require.True(t, f.handlerCalled)
+			require.Equal(t, auth.UnknownIdentity(), f.identity)
+			require.Empty(t, response.Header.Get(auth.HeaderSignature))
+			require.Empty(t, response.Header.Get(auth.HeaderChallenge))
+			records := logRecords(t, writer)
+			observed := records[len(records)-1]
+			require.Equal(t, "WARN", observed["level"])
+			require.Equal(t, "Observed rejection of general message", observed["msg"])
+			require.Equal(t, test.code, observed["code"])
+		})
+	}
+
+	t.Run("Pass the request with a body too large for the verification through unread", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithObserveOnly(true), auth.WithMaxBodySize(4), auth.WithoutVerifierLogging())
+		request := f.signedRequest(t, f.client, requestNonce1, strings.Repeat("a", 8))
+
+		// when
+		response, body := send(t, request)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, strings.Repeat("a", 8), string(body))
+		require.Equal(t, auth.UnknownIdentity(), f.identity)
+	})
+
+	t.Run("Sign the response of a verified request", func(t *testing.T) {
+		// given
+		logger, writer := newCapturingLogger(slog.LevelWarn)
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithObserveOnly(true), auth.WithVerifierLogger(logger))
+
+		// when
+		response, _ := send(t, validRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, identityKeyOf(t, f.client), f.identity.IdentityKey)
+		require.NotEmpty(t, response.Header.Get(auth.HeaderSignature))
+		require.Empty(t, writer.String())
+	})
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// Session metadata keys of the certificates presented by the peer.
+const (
+	// CertificatesMetaKey is the key of the accepted certificates presented by the peer
+	CertificatesMetaKey = "auth.certificates"
+	// CertificatesRejectionMetaKey is the key of the reason the application rejected the certificates presented by the peer
+	CertificatesRejectionMetaKey = "auth.certificatesRejection"
+)
+
+// ErrCertificatesRejected is returned by the callback of ValidateReceivedCertificates when the application rejects the certificates.
+var ErrCertificatesRejected = errors.New("certificates rejected")
+
+// RequestedCertificateSet is the certificatesToRequest of the handshake, the certificates the peer has to present:
+// for each of the types, one issued by any of the certifiers, revealing the listed fields.
+type RequestedCertificateSet struct {
+	// Certifiers are the identity keys of the accepted certifiers, any certifier is accepted if empty
+	Certifiers []string `json:"certifiers"`
+	// Types maps the required certificate types to the names of the fields they have to reveal
+	Types map[string][]string `json:"types"`
+}
+
+// IsEmpty reports whether the set requests no certificates.
+func (s RequestedCertificateSet) IsEmpty() bool {
+	return len(s.Types) == 0
+}
+
+// Missing returns the part of the set the certificates don't satisfy, empty if they satisfy all of it.
+func (s RequestedCertificateSet) Missing(certificates []wallet.Certificate) RequestedCertificateSet {
+	missing := RequestedCertificateSet{Certifiers: s.Certifiers}
+	for certType, fields := range s.Types {
+		if slices.ContainsFunc(certificates, func(c wallet.Certificate) bool { return s.satisfies(c, certType, fields) }) {
+			continue
+		}
+		if missing.Types == nil {
+			missing.Types = make(map[string][]string)
+		}
+		missing.Types[certType] = fields
+	}
+	return missing
+}
+
+// String lists the requested types with their fields, sorted by the type.
+func (s RequestedCertificateSet) String() string {
+	types := make([]string, 0, len(s.Types))
+	for _, certType := range slices.Sorted(maps.Keys(s.Types)) {
+		types = append(types, fmt.Sprintf("%q revealing %v", certType, s.Types[certType]))
+	}
+	description := strings.Join(types, ", ")
+	if len(s.Certifiers) > 0 {
+		description += fmt.Sprintf(" from any of the certifiers %v", s.Certifiers)
+	}
+	return description
+}
+
+// RecordReceivedCertificates returns an OnCertificatesReceived callback storing the certificates
+// in the metadata of all the sessions of the peer, so the GeneralMessageVerifier can check the requested ones arrived.
+// It clears the rejection of the certificates the peer presented before, see CertificatesRejection.
+func RecordReceivedCertificates(sessions sessionmanager.Interface) OnCertificatesReceived {
+	return func(ctx context.Context, senderPublicKey string, certificates []wallet.Certificate) error {
+		peerSessions, err := sessions.GetSessionsByIdentityKey(ctx, senderPublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to get sessions of %s: %w", senderPublicKey, err)
+		}
+		for _, session := range peerSessions {
+			session.SetMeta(CertificatesMetaKey, append(ReceivedCertificates(session), certificates...))
+			session.DeleteMeta(CertificatesRejectionMetaKey)
+			if err := sessions.UpdateSession(ctx, session); err != nil {
+				return fmt.Errorf("failed to record certificates of %s: %w", senderPublicKey, err)
+			}
+		}
+		return nil
+	}
+}
+
+// ValidateReceivedCertificates returns an OnCertificatesReceived callback letting the application validate the certificates,
+// e.g. check the email domain, before recording them like RecordReceivedCertificates.
+// The certificates not about the peer or not signed by their certifiers are rejected before the validate callback sees them.
+// The validate callback gets copies of the certificates with the fields revealed to the wallet decrypted, the others omitted,
+// and a panic inside it rejects them like an error does.
+// The rejection is recorded in the sessions of the peer instead, so the GeneralMessageVerifier rejects them,
+// and returned wrapped in ErrCertificatesRejected, see WriteCertificatesError.
+func ValidateReceivedCertificates(sessions sessionmanager.Interface, w CertificateDecrypter, validate OnCertificatesReceived) OnCertificatesReceived {
+	record := RecordReceivedCertificates(sessions)
+	return func(ctx context.Context, senderPublicKey string, certificates []wallet.Certificate) error {
+		err := verifyPresentedCertificates(ctx, senderPublicKey, certificates)
+		var decrypted []wallet.Certificate
+		if err == nil {
+			decrypted, err = decryptedCertificates(ctx, w, certificates)
+		}
+		if err == nil {
+			err = callValidate(ctx, validate, senderPublicKey, decrypted)
+		}
+		if err != nil {
+			if recordErr := recordRejection(ctx, sessions, senderPublicKey, err); recordErr != nil {
+				return recordErr
+			}
+			return fmt.Errorf("%w: %w", ErrCertificatesRejected, err)
+		}
+		return record(ctx, senderPublicKey, certificates)
+	}
+}
+
+// WriteCertificatesError responds to the peer whose certificates failed to be received,
+// with 400 and ErrCodeCertificatesRejected if they were rejected, with 500 otherwise.
+func WriteCertificatesError(rw http.ResponseWriter, err error) {
+	if errors.Is(err, ErrCertificatesRejected) {
+		WriteError(rw, http.StatusBadRequest, ErrCodeCertificatesRejected, err.Error())
+		return
+	}
+	WriteError(rw, http.StatusInternalServerError, ErrCodeInternal, "failed to receive the certificates")
+}
+
+// ReceivedCertificates returns the certificates the peer presented in the session,
+// which external stores decode from JSON as generic values.
+func ReceivedCertificates(session sessionmanager.PeerSession) []wallet.Certificate {
+	value, ok := session.GetMeta(CertificatesMetaKey)
+	if !ok {
+		return nil
+	}
+	if certificates, ok := value.([]wallet.Certificate); ok {
+		return slices.Clone(certificates)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var certificates []wallet.Certificate
+	if err := json.Unmarshal(raw, &certificates); err != nil {
+		return nil
+	}
+	return certificates
+}
+
+// CertificatesRejection returns the reason the application rejected the certificates presented in the session, if it did.
+func CertificatesRejection(session sessionmanager.PeerSession) (string, bool) {
+	value, _ := session.GetMeta(CertificatesRejectionMetaKey)
+	reason, ok := value.(string)
+	return reason, ok
+}
+
+func callValidate(ctx context.Context, validate OnCertificatesReceived, senderPublicKey string, certificates []wallet.Certificate) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("certificates callback panicked: %v", r)
+		}
+	}()
+
+	return validate(ctx, senderPublicKey, certificates)
+}
+
+// decryptedCertificates returns copies of the certificates with the plaintext fields revealed to the wallet, see DecryptFields.
+func decryptedCertificates(ctx context.Context, w CertificateDecrypter, certificates []wallet.Certificate) ([]wallet.Certificate, error) {
+	decrypted := make([]wallet.Certificate, len(certificates))
+	for i, certificate := range certificates {
+		fields, err := DecryptFields(ctx, w, certificate)
+		if err != nil {
+			return nil, fmt.Errorf("certificate %s: %w", certificate.SerialNumber, err)
+		}
+		decrypted[i] = certificate.Clone()
+		decrypted[i].Fields = make(map[string]any, len(fields))
+		for name, value := range fields {
+			decrypted[i].Fields[name] = value
+		}
+		decrypted[i].Keyring = nil
+	}
+	return decrypted, nil
+}
+
+func recordRejection(ctx context.Context, sessions sessionmanager.Interface, senderPublicKey string, rejection error) error {
+	peerSessions, err := sessions.GetSessionsByIdentityKey(ctx, senderPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions of %s: %w", senderPublicKey, err)
+	}
+	for _, session := range peerSessions {
+		session.SetMeta(CertificatesRejectionMetaKey, rejection.Error())
+		if err := sessions.UpdateSession(ctx, session); err != nil {
+			return fmt.Errorf("failed to record certificates rejection of %s: %w", senderPublicKey, err)
+		}
+	}
+	return nil
+}
+
+func (s RequestedCertificateSet) satisfies(certificate wallet.Certificate, certType string, fields []string) bool {
+	if certificate.Type != certType {
+		return false
+	}
+	if len(s.Certifiers) > 0 && !slices.Contains(s.Certifiers, certificate.Certifier) {
+		return false
+	}
+	// every certificate carries all its fields encrypted, only the keyring reveals them to the verifier
+	for _, field := range fields {
+		if _, ok := certificate.Keyring[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
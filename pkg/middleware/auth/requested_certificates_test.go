@@ -0,0 +1,484 @@
+package auth_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	emailCertifierKey = certifierKey(0x0e)
+	otherCertifierKey = certifierKey(0x0f)
+	// emailCertifier is the identity key of the certifier of the email verification certificates
+	emailCertifier = hex.EncodeToString(emailCertifierKey.PubKey().Compressed())
+	// otherCertifier is the identity key of a certifier the tests don't trust
+	otherCertifier = hex.EncodeToString(otherCertifierKey.PubKey().Compressed())
+	// emailVerificationType is the type of the email verification certificates
+	emailVerificationType = certificateID("email verification")
+)
+
+var emailVerification = auth.RequestedCertificateSet{
+	Certifiers: []string{emailCertifier},
+	Types:      map[string][]string{emailVerificationType: {"email"}},
+}
+
+// certifierKey returns the fixed private key of a test certifier.
+func certifierKey(b byte) *ec.PrivateKey {
+	key, _ := ec.PrivateKeyFromBytes(bytes.Repeat([]byte{b}, 32))
+	return key
+}
+
+// certificateID returns the base64 encoded 32 bytes identifier of the name, the form of the certificate types and serial numbers.
+func certificateID(name string) string {
+	id := sha256.Sum256([]byte(name))
+	return base64.StdEncoding.EncodeToString(id[:])
+}
+
+// signedCertificate returns a certificate of the type about the subject, issued and signed by the certifier,
+// with the fields encrypted and all of them revealed to the verifier.
+func signedCertificate(t *testing.T, certifier *ec.PrivateKey, certType string, subject wallet.Interface, verifier string, fields map[string]string) wallet.Certificate {
+	t.Helper()
+	certificate := wallet.Certificate{
+		Type:               certType,
+		SerialNumber:       certificateID("serial-1"),
+		Subject:            identityKeyOf(t, subject),
+		RevocationOutpoint: strings.Repeat("00", 32) + ".0",
+	}
+	encryptFields(t, &certificate, subject, verifier, fields, slices.Collect(maps.Keys(fields))...)
+	certificate, err := auth.SignCertificate(t.Context(), keywallet.NewKeyWallet(certifier), certificate)
+	require.NoError(t, err)
+	return certificate
+}
+
+// emailCertificate returns an email verification certificate about the subject signed by the email certifier,
+// revealing the fields to the verifier.
+func emailCertificate(t *testing.T, subject wallet.Interface, verifier string, fields map[string]string) wallet.Certificate {
+	t.Helper()
+	return signedCertificate(t, emailCertifierKey, emailVerificationType, subject, verifier, fields)
+}
+
+func TestRequestedCertificateSet_Missing(t *testing.T) {
+	subject := newKeyWallet(t)
+	verifier := identityKeyOf(t, newKeyWallet(t))
+	tests := map[string]struct {
+		certificates    []wallet.Certificate
+		expectedMissing bool
+	}{
+		"Requested certificate": {
+			certificates: []wallet.Certificate{emailCertificate(t, subject, verifier, map[string]string{"email": "alice@example.com"})},
+		},
+		"No certificates": {
+			expectedMissing: true,
+		},
+		"Certificate of another certifier": {
+			certificates:    []wallet.Certificate{signedCertificate(t, otherCertifierKey, emailVerificationType, subject, verifier, map[string]string{"email": "alice@example.com"})},
+			expectedMissing: true,
+		},
+		"Certificate without the requested field": {
+			certificates:    []wallet.Certificate{emailCertificate(t, subject, verifier, map[string]string{"name": "Alice"})},
+			expectedMissing: true,
+		},
+		"Certificate not revealing the requested field": {
+			certificates: func() []wallet.Certificate {
+				certificate := emailCertificate(t, subject, verifier, map[string]string{"email": "alice@example.com"})
+				delete(certificate.Keyring, "email")
+				return []wallet.Certificate{certificate}
+			}(),
+			expectedMissing: true,
+		},
+		"Certificate of another type": {
+			certificates:    []wallet.Certificate{signedCertificate(t, emailCertifierKey, certificateID("age verification"), subject, verifier, map[string]string{"email": "alice@example.com"})},
+			expectedMissing: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			missing := emailVerification.Missing(test.certificates)
+
+			// then
+			require.Equal(t, test.expectedMissing, !missing.IsEmpty())
+		})
+	}
+}
+
+func TestRecordReceivedCertificates(t *testing.T) {
+	t.Run("Record the certificates in all the sessions of the peer", func(t *testing.T) {
+		// given
+		sessions := sessionmanager.NewSessionManager()
+		peerSessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 2)
+		for _, session := range peerSessions {
+			require.NoError(t, sessions.AddSession(t.Context(), session))
+		}
+		certificate := emailCertificate(t, newKeyWallet(t), identityKeyOf(t, newKeyWallet(t)), map[string]string{"email": "alice@example.com"})
+
+		// when
+		err := auth.RecordReceivedCertificates(sessions)(t.Context(), peerSessions[0].GetPeerIdentityKey(), []wallet.Certificate{certificate})
+
+		// then
+		require.NoError(t, err)
+		for _, session := range peerSessions {
+			stored, err := sessions.GetSession(t.Context(), session.GetSessionNonce())
+			require.NoError(t, err)
+			require.Equal(t, []wallet.Certificate{certificate}, auth.ReceivedCertificates(*stored))
+		}
+	})
+
+	t.Run("Read the certificates decoded from JSON by an external store", func(t *testing.T) {
+		// given
+		certificate := emailCertificate(t, newKeyWallet(t), identityKeyOf(t, newKeyWallet(t)), map[string]string{"email": "alice@example.com"})
+		raw, err := json.Marshal([]wallet.Certificate{certificate})
+		require.NoError(t, err)
+		var decoded any
+		require.NoError(t, json.Unmarshal(raw, &decoded))
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta(auth.CertificatesMetaKey, decoded)
+
+		// when
+		certificates := auth.ReceivedCertificates(session)
+
+		// then
+		require.Equal(t, []wallet.Certificate{certificate}, certificates)
+	})
+}
+
+func TestGeneralMessageVerifier_CertificatesToRequest(t *testing.T) {
+	t.Run("Accept the session after the peer presented the certificates", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCertificatesToRequest(emailVerification))
+		certificate := emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})
+		err := auth.RecordReceivedCertificates(f.sessions)(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{certificate})
+		require.NoError(t, err)
+
+		// when
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.True(t, f.handlerCalled)
+		require.Equal(t, []wallet.Certificate{certificate}, f.identity.Certificates)
+	})
+
+	t.Run("Reject the session of a peer that didn't reveal the requested fields", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCertificatesToRequest(emailVerification))
+		certificate := emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})
+		delete(certificate.Keyring, "email")
+		err := auth.RecordReceivedCertificates(f.sessions)(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{certificate})
+		require.NoError(t, err)
+
+		// when
+		response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeCertificatesRequired)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Reject the session of a peer that ignored the request", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithCertificatesToRequest(emailVerification))
+
+		// when
+		response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeCertificatesRequired)
+		require.False(t, f.handlerCalled)
+		var errorResponse auth.ErrorResponse
+		require.NoError(t, json.Unmarshal(body, &errorResponse))
+		require.Equal(t, &emailVerification, errorResponse.CertificatesRequired)
+		require.Contains(t, errorResponse.Description, fmt.Sprintf("%q revealing [email]", emailVerificationType))
+	})
+}
+
+func TestValidateReceivedCertificates(t *testing.T) {
+	acceptExampleDomain := func(_ context.Context, _ string, certificates []wallet.Certificate) error {
+		for _, certificate := range certificates {
+			if !strings.HasSuffix(certificate.Fields["email"].(string), "@example.com") {
+				return errors.New("email domain not allowed")
+			}
+		}
+		return nil
+	}
+
+	failOnCall := func(_ context.Context, _ string, _ []wallet.Certificate) error {
+		panic("callback got unverified certificates")
+	}
+
+	tests := map[string]struct {
+		validate         auth.OnCertificatesReceived
+		email            string
+		tamper           func(t *testing.T, f *generalMessageFixture, certificate *wallet.Certificate)
+		expectedErr      string
+		expectedCause    error
+		expectedStatus   int
+		expectedRecorded bool
+	}{
+		"Callback accepts the certificates": {
+			validate:         acceptExampleDomain,
+			email:            "alice@example.com",
+			expectedStatus:   http.StatusOK,
+			expectedRecorded: true,
+		},
+		"Callback rejects the certificates": {
+			validate:       acceptExampleDomain,
+			email:          "alice@other.com",
+			expectedErr:    "certificates rejected: email domain not allowed",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"Callback panics": {
+			validate: func(_ context.Context, _ string, _ []wallet.Certificate) error {
+				panic("unexpected certificate")
+			},
+			email:          "alice@example.com",
+			expectedErr:    "certificates rejected: certificates callback panicked: unexpected certificate",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"Forged certificate is rejected before the callback": {
+			validate: failOnCall,
+			email:    "alice@example.com",
+			tamper: func(_ *testing.T, _ *generalMessageFixture, certificate *wallet.Certificate) {
+				certificate.Fields["email"] = "mallory@example.com"
+			},
+			expectedCause:  auth.ErrInvalidCertificateSignature,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"Certificate with a corrupted keyring is rejected before the callback": {
+			validate: failOnCall,
+			email:    "alice@example.com",
+			tamper: func(_ *testing.T, _ *generalMessageFixture, certificate *wallet.Certificate) {
+				certificate.Keyring["email"] = base64.StdEncoding.EncodeToString([]byte("corrupted"))
+			},
+			expectedCause:  auth.ErrInvalidCertificateField,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"Certificate of another subject is rejected before the callback": {
+			validate: failOnCall,
+			email:    "alice@example.com",
+			tamper: func(t *testing.T, f *generalMessageFixture, certificate *wallet.Certificate) {
+				*certificate = emailCertificate(t, newKeyWallet(t), f.serverKey, map[string]string{"email": "alice@example.com"})
+			},
+			expectedCause:  auth.ErrForeignCertificate,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, true, auth.WithCertificatesToRequest(emailVerification))
+			certificate := emailCertificate(t, f.client, f.serverKey, map[string]string{"email": test.email})
+			if test.tamper != nil {
+				test.tamper(t, f, &certificate)
+			}
+			receive := auth.ValidateReceivedCertificates(f.sessions, f.serverWallet, test.validate)
+
+			// when
+			err := receive(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{certificate})
+
+			// then
+			recorder := httptest.NewRecorder()
+			if err != nil {
+				if test.expectedCause != nil {
+					require.ErrorIs(t, err, test.expectedCause)
+				} else {
+					require.EqualError(t, err, test.expectedErr)
+				}
+				require.ErrorIs(t, err, auth.ErrCertificatesRejected)
+				auth.WriteCertificatesError(recorder, err)
+			}
+			require.Equal(t, test.expectedStatus, recorder.Code)
+
+			session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+			require.NoError(t, err)
+			require.Equal(t, test.expectedRecorded, len(auth.ReceivedCertificates(*session)) == 1)
+
+			response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+			require.Equal(t, test.expectedRecorded, response.StatusCode == http.StatusOK)
+		})
+	}
+
+	t.Run("Callback gets copies of the certificates", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		certificate := emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})
+		encryptedEmail := certificate.Fields["email"]
+		var validated wallet.Certificate
+		receive := auth.ValidateReceivedCertificates(f.sessions, f.serverWallet, func(_ context.Context, _ string, certificates []wallet.Certificate) error {
+			validated = certificates[0].Clone()
+			certificates[0].Fields["email"] = "mallory@example.com"
+			certificates[0].SerialNumber = "changed"
+			return nil
+		})
+
+		// when
+		err := receive(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{certificate})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{"email": "alice@example.com"}, validated.Fields)
+		require.Equal(t, encryptedEmail, certificate.Fields["email"])
+		session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, []wallet.Certificate{certificate}, auth.ReceivedCertificates(*session))
+	})
+
+	t.Run("Accept the certificates presented after a rejection", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		receive := auth.ValidateReceivedCertificates(f.sessions, f.serverWallet, acceptExampleDomain)
+		err := receive(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@other.com"})})
+		require.ErrorIs(t, err, auth.ErrCertificatesRejected)
+
+		// when
+		err = receive(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@example.com"})})
+
+		// then
+		require.NoError(t, err)
+		session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+		require.NoError(t, err)
+		_, rejected := auth.CertificatesRejection(*session)
+		require.False(t, rejected)
+		response, _ := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+		require.Equal(t, http.StatusOK, response.StatusCode)
+	})
+
+	t.Run("Reject the general messages of a rejected session", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true)
+		receive := auth.ValidateReceivedCertificates(f.sessions, f.serverWallet, acceptExampleDomain)
+		err := receive(t.Context(), identityKeyOf(t, f.client), []wallet.Certificate{emailCertificate(t, f.client, f.serverKey, map[string]string{"email": "alice@other.com"})})
+		require.ErrorIs(t, err, auth.ErrCertificatesRejected)
+
+		// when
+		response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeCertificatesRejected)
+		require.False(t, f.handlerCalled)
+	})
+}
+
+func TestGeneralMessageVerifier_TrustedCertifiers(t *testing.T) {
+	ageVerificationType := certificateID("age verification")
+	tests := map[string]struct {
+		certificate           func(t *testing.T, subject wallet.Interface, verifier string) wallet.Certificate
+		expectedStatus        int
+		expectedReason        string
+		expectedReceived      bool
+		expectedGeneralStatus int
+	}{
+		"Accept a requested certificate of a trusted certifier": {
+			certificate: func(t *testing.T, subject wallet.Interface, verifier string) wallet.Certificate {
+				return emailCertificate(t, subject, verifier, map[string]string{"email": "alice@example.com"})
+			},
+			expectedStatus:        http.StatusOK,
+			expectedReceived:      true,
+			expectedGeneralStatus: http.StatusOK,
+		},
+		"Ignore a certificate of a trusted certifier of another type": {
+			certificate: func(t *testing.T, subject wallet.Interface, verifier string) wallet.Certificate {
+				return signedCertificate(t, emailCertifierKey, ageVerificationType, subject, verifier, map[string]string{"email": "alice@example.com"})
+			},
+			expectedStatus:        http.StatusBadRequest,
+			expectedReason:        fmt.Sprintf("%q certificate wasn't requested", ageVerificationType),
+			expectedGeneralStatus: http.StatusUnauthorized,
+		},
+		"Ignore a certificate of an untrusted certifier": {
+			certificate: func(t *testing.T, subject wallet.Interface, verifier string) wallet.Certificate {
+				return signedCertificate(t, otherCertifierKey, emailVerificationType, subject, verifier, map[string]string{"email": "alice@example.com"})
+			},
+			expectedStatus:        http.StatusBadRequest,
+			expectedReason:        fmt.Sprintf("%q certificate of the untrusted certifier %s", emailVerificationType, otherCertifier),
+			expectedGeneralStatus: http.StatusUnauthorized,
+		},
+		"Ignore a certificate with a forged signature": {
+			certificate: func(t *testing.T, subject wallet.Interface, verifier string) wallet.Certificate {
+				certificate := emailCertificate(t, subject, verifier, map[string]string{"email": "alice@example.com"})
+				certificate.Fields["email"] = "mallory@example.com"
+				return certificate
+			},
+			expectedStatus:        http.StatusBadRequest,
+			expectedReason:        fmt.Sprintf("%q certificate with an invalid signature", emailVerificationType),
+			expectedGeneralStatus: http.StatusUnauthorized,
+		},
+		"Ignore a certificate claiming a trusted certifier it isn't signed by": {
+			certificate: func(t *testing.T, subject wallet.Interface, verifier string) wallet.Certificate {
+				certificate := signedCertificate(t, otherCertifierKey, emailVerificationType, subject, verifier, map[string]string{"email": "alice@example.com"})
+				certificate.Certifier = emailCertifier
+				return certificate
+			},
+			expectedStatus:        http.StatusBadRequest,
+			expectedReason:        fmt.Sprintf("%q certificate with an invalid signature", emailVerificationType),
+			expectedGeneralStatus: http.StatusUnauthorized,
+		},
+		"Ignore a certificate of another subject": {
+			certificate: func(t *testing.T, _ wallet.Interface, verifier string) wallet.Certificate {
+				return emailCertificate(t, newKeyWallet(t), verifier, map[string]string{"email": "alice@example.com"})
+			},
+			expectedStatus:        http.StatusBadRequest,
+			expectedReason:        fmt.Sprintf("%q certificate of another subject", emailVerificationType),
+			expectedGeneralStatus: http.StatusUnauthorized,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			var received []wallet.Certificate
+			f := newGeneralMessageFixture(t, time.Now, true,
+				auth.WithCertificatesToRequest(auth.RequestedCertificateSet{Types: emailVerification.Types}),
+				auth.WithTrustedCertifiers(emailCertifier),
+				auth.WithOnCertificatesReceived(func(_ context.Context, _ string, certificates []wallet.Certificate) error {
+					received = append(received, certificates...)
+					return nil
+				}),
+			)
+			_, initialResponse := f.handshake(t)
+			certificate := test.certificate(t, f.client, f.serverKey)
+			certificateResponse, err := auth.NewCertificateResponse(t.Context(), f.client, f.serverKey, initialResponse.InitialNonce, []wallet.Certificate{certificate})
+			require.NoError(t, err)
+
+			// when
+			response, body := postMessage(t, f.server.URL+auth.DefaultAuthEndpointPath, certificateResponse)
+			general, _ := send(t, f.signedRequestInSession(t, f.client, initialResponse.InitialNonce, requestNonce1, "body"))
+
+			// then
+			require.Equal(t, []string{emailCertifier}, initialResponse.RequestedCertificates.Certifiers)
+			if test.expectedReceived {
+				require.Len(t, received, 1)
+				require.Equal(t, map[string]any{"email": "alice@example.com"}, received[0].Fields)
+			} else {
+				require.Empty(t, received)
+			}
+			require.Equal(t, test.expectedGeneralStatus, general.StatusCode)
+			if test.expectedReason == "" {
+				require.Equal(t, test.expectedStatus, response.StatusCode)
+				return
+			}
+			requireRejectedWith(t, response, body, test.expectedStatus, auth.ErrCodeCertificatesRejected)
+			var errorResponse auth.ErrorResponse
+			require.NoError(t, json.Unmarshal(body, &errorResponse))
+			require.Contains(t, errorResponse.Description, test.expectedReason)
+			require.Equal(t, &auth.RequestedCertificateSet{Certifiers: []string{emailCertifier}, Types: emailVerification.Types}, errorResponse.CertificatesRequired)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport/payload"
+)
+
+// signingResponseWriter buffers the response of the handler, so it can be signed before anything is sent to the peer.
+// Flush is deferred until the response is signed, because the signature covers the whole body.
+type signingResponseWriter struct {
+	rw      http.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	flushed bool
+}
+
+func newSigningResponseWriter(rw http.ResponseWriter) *signingResponseWriter {
+	return &signingResponseWriter{rw: rw}
+}
+
+// Header returns the headers of the underlying ResponseWriter, sent with the signed response.
+func (w *signingResponseWriter) Header() http.Header {
+	return w.rw.Header()
+}
+
+// WriteHeader records the status of the response, only the first one counts.
+func (w *signingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write buffers the body of the response.
+func (w *signingResponseWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data) //nolint: wrapcheck // bytes.Buffer never returns an error
+}
+
+// Flush records the handler asked for a flush, done once the response is signed.
+func (w *signingResponseWriter) Flush() {
+	w.flushed = true
+}
+
+// signResponse signs the buffered response to the peer of the session and sends it with the x-bsv-auth headers.
+func (v *GeneralMessageVerifier) signResponse(ctx context.Context, w *signingResponseWriter, session sessionmanager.PeerSession, requestID []byte) error {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	counterparty, err := wallet.ParseCounterparty(session.GetPeerIdentityKey())
+	if err != nil {
+		return fmt.Errorf("invalid peer identity key: %w", err)
+	}
+	identityKey, err := v.wallet.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+	if err != nil {
+		return fmt.Errorf("failed to get identity key: %w", err)
+	}
+	responseNonce, err := v.wallet.CreateNonce(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create response nonce: %w", err)
+	}
+	var payloadOpts []payload.Option
+	if v.signedResponseHeaders != nil {
+		payloadOpts = append(payloadOpts, payload.WithSignedHeaders(v.signedResponseHeaders...))
+	}
+	responsePayload, err := payload.BuildResponsePayload(requestID, status, w.Header(), w.body.Bytes(), payloadOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to build response payload: %w", err)
+	}
+	signature, err := v.wallet.CreateSignature(ctx, responsePayload, MessageSignatureProtocol,
+		responseNonce+" "+session.GetPeerNonce(), counterparty, wallet.Privilege{})
+	if err != nil {
+		return fmt.Errorf("failed to sign response: %w", err)
+	}
+
+	header := w.Header()
+	header.Set(HeaderVersion, transport.AuthVersion)
+	header.Set(HeaderIdentityKey, identityKey)
+	header.Set(HeaderNonce, responseNonce)
+	header.Set(HeaderYourNonce, session.GetPeerNonce())
+	header.Set(HeaderSignature, hex.EncodeToString(signature))
+	header.Set(HeaderRequestID, base64.StdEncoding.EncodeToString(requestID))
+	w.rw.WriteHeader(status)
+	_, _ = w.rw.Write(w.body.Bytes())
+	if flusher, ok := w.rw.(http.Flusher); ok && w.flushed {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package auth_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+// requestIDHex is the hex of the requestID signed by the fixture requests.
+var requestIDHex = strings.Repeat("07", 32)
+
+// requireSignedResponse verifies the x-bsv-auth headers of the response and its signature over the payload,
+// the way the TypeScript client verifies it.
+func requireSignedResponse(t *testing.T, f *generalMessageFixture, response *http.Response, payloadHex string) {
+	t.Helper()
+	require.Equal(t, f.serverKey, response.Header.Get(auth.HeaderIdentityKey))
+	require.Equal(t, peerNonce, response.Header.Get(auth.HeaderYourNonce))
+	require.Equal(t, base64.StdEncoding.EncodeToString(requestID), response.Header.Get(auth.HeaderRequestID))
+	responseNonce := response.Header.Get(auth.HeaderNonce)
+	require.NotEmpty(t, responseNonce)
+
+	payload, err := hex.DecodeString(payloadHex)
+	require.NoError(t, err)
+	signature, err := hex.DecodeString(response.Header.Get(auth.HeaderSignature))
+	require.NoError(t, err)
+	server, err := wallet.ParseCounterparty(f.serverKey)
+	require.NoError(t, err)
+
+	valid, err := f.client.VerifySignature(t.Context(), payload, signature, auth.MessageSignatureProtocol, responseNonce+" "+peerNonce, server)
+	require.NoError(t, err)
+	require.True(t, valid)
+}
+
+func TestGeneralMessageVerifier_SignResponse(t *testing.T) {
+	tests := map[string]struct {
+		opts           []auth.GeneralMessageOption
+		respond        http.HandlerFunc
+		expectedStatus int
+		expectedBody   string
+		// expectedPayload is the hex of the payload the TypeScript client verifies the signature over:
+		// the request ID, the varint status, the varint count of the signed headers, each of them as varint length
+		// prefixed lowercase name and value, and the varint length prefixed body, ff ff..ff (-1) if empty
+		expectedPayload string
+	}{
+		"Sign the status, the signed headers and the body": {
+			respond: func(rw http.ResponseWriter, _ *http.Request) {
+				rw.Header().Set("Content-Type", "text/plain")
+				rw.Header().Set("X-Bsv-Price", "100")
+				rw.WriteHeader(http.StatusCreated)
+				_, _ = io.WriteString(rw, "hello")
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   "hello",
+			expectedPayload: requestIDHex +
+				"c9" + // 201
+				"01" + "0b" + hex.EncodeToString([]byte("x-bsv-price")) + "03" + hex.EncodeToString([]byte("100")) +
+				"05" + hex.EncodeToString([]byte("hello")),
+		},
+		"Sign the allowlisted headers in their canonical order": {
+			opts: []auth.GeneralMessageOption{auth.WithSignedResponseHeaders("X-Request-Id", "Content-Type")},
+			respond: func(rw http.ResponseWriter, _ *http.Request) {
+				rw.Header().Set("X-Request-Id", "abc")
+				rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				rw.Header().Set("X-Bsv-Price", "100")
+				rw.Header().Set("Via", "1.1 proxy")
+				_, _ = io.WriteString(rw, "hello")
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "hello",
+			expectedPayload: requestIDHex +
+				"c8" + // 200
+				"02" +
+				"0c" + hex.EncodeToString([]byte("content-type")) + "0a" + hex.EncodeToString([]byte("text/plain")) +
+				"0c" + hex.EncodeToString([]byte("x-request-id")) + "03" + hex.EncodeToString([]byte("abc")) +
+				"05" + hex.EncodeToString([]byte("hello")),
+		},
+		"Sign an empty response": {
+			respond:         func(_ http.ResponseWriter, _ *http.Request) {},
+			expectedStatus:  http.StatusOK,
+			expectedPayload: requestIDHex + "c8" + "00" + "ffffffffffffffffff",
+		},
+		"Sign the whole body of a flushing handler": {
+			respond: func(rw http.ResponseWriter, _ *http.Request) {
+				_, _ = io.WriteString(rw, "first ")
+				rw.(http.Flusher).Flush()
+				_, _ = io.WriteString(rw, "second")
+			},
+			expectedStatus:  http.StatusOK,
+			expectedBody:    "first second",
+			expectedPayload: requestIDHex + "c8" + "00" + "0c" + hex.EncodeToString([]byte("first second")),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			f := newGeneralMessageFixture(t, time.Now, true, test.opts...)
+			f.respond = test.respond
+
+			// when
+			response, body := send(t, f.signedRequest(t, f.client, requestNonce1, "body"))
+
+			// then
+			require.Equal(t, test.expectedStatus, response.StatusCode)
+			require.Equal(t, test.expectedBody, string(body))
+			requireSignedResponse(t, f, response, test.expectedPayload)
+		})
+	}
+
+	t.Run("Don't sign the response to an anonymous peer", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithAllowUnauthenticated(true))
+
+		// when
+		response, _ := send(t, anonymousRequest(t, f))
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Empty(t, response.Header.Get(auth.HeaderSignature))
+	})
+}
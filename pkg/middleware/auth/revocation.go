@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+)
+
+// ErrNotAuthenticated is returned when the request context doesn't carry the identity of an authenticated peer.
+var ErrNotAuthenticated = errors.New("request is not authenticated")
+
+// RevokeIdentity removes all sessions of the peer that sent the request, forcing it to authenticate again.
+// It's meant for application handlers reacting to their own abuse detection and returns how many sessions were removed.
+func RevokeIdentity(ctx context.Context, sessions sessionmanager.Interface) (int, error) {
+	if !IsAuthenticated(ctx) {
+		return 0, ErrNotAuthenticated
+	}
+
+	removed, err := sessions.RemoveAllSessionsForPeer(ctx, MustGetIdentity(ctx).IdentityKey)
+	if err != nil {
+		return removed, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return removed, nil
+}
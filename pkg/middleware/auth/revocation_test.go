@@ -0,0 +1,49 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevokeIdentity(t *testing.T) {
+	t.Run("Removes all sessions of the authenticated peer", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(context.Background(), session))
+		}
+		other := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(context.Background(), other))
+
+		ctx := auth.WithIdentity(context.Background(), auth.Identity{
+			IdentityKey: *sessions[0].PeerIdentityKey,
+			AuthMethod:  auth.AuthMethodMutual,
+		})
+
+		// when
+		removed, err := auth.RevokeIdentity(ctx, sessionManager)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 3, removed)
+		require.False(t, sessionManager.HasSession(ctx, *sessions[0].PeerIdentityKey))
+		require.True(t, sessionManager.HasSession(ctx, *other.SessionNonce))
+	})
+
+	t.Run("Fails for unauthenticated request", func(t *testing.T) {
+		// given
+		ctx := auth.WithIdentity(context.Background(), auth.Identity{AuthMethod: auth.AuthMethodUnauthenticated})
+
+		// when
+		removed, err := auth.RevokeIdentity(ctx, sessionmanager.NewSessionManager())
+
+		// then
+		require.ErrorIs(t, err, auth.ErrNotAuthenticated)
+		require.Zero(t, removed)
+	})
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+)
+
+type sessionContextKey struct{}
+
+// WithSession returns a copy of ctx carrying a copy of the session the request was made in,
+// so the handlers can read it, including its metadata, without another SessionManager lookup.
+func WithSession(ctx context.Context, session sessionmanager.PeerSession) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session.Clone())
+}
+
+// GetSessionFromContext returns the session stored in the context, if any.
+// Every call returns a separate copy, so changing it doesn't affect other handlers;
+// use UpdateSession of the SessionManager to persist the changes.
+func GetSessionFromContext(ctx context.Context) (sessionmanager.PeerSession, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(sessionmanager.PeerSession)
+	if !ok {
+		return sessionmanager.PeerSession{}, false
+	}
+	return session.Clone(), true
+}
@@ -0,0 +1,87 @@
+package auth_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSessionFromContext(t *testing.T) {
+	t.Run("Read session metadata in a handler", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// the session is attached the way the auth middleware does it after verifying the request
+		withSession := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				stored, err := sessionManager.GetSession(r.Context(), r.Header.Get("X-Session-Nonce"))
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(auth.WithSession(r.Context(), *stored)))
+			})
+		}
+		handler := withSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, ok := auth.GetSessionFromContext(r.Context())
+			if !ok {
+				http.Error(w, "no session", http.StatusInternalServerError)
+				return
+			}
+			tenant, _ := session.GetMeta("tenant")
+			_, _ = io.WriteString(w, tenant.(string))
+		}))
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		request.Header.Set("X-Session-Nonce", *session.SessionNonce)
+
+		// when
+		response, err := http.DefaultClient.Do(request)
+
+		// then
+		require.NoError(t, err)
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "tenant-a", string(body))
+	})
+
+	t.Run("Modifying the returned session doesn't affect the context", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		ctx := auth.WithSession(context.Background(), session)
+
+		// when
+		retrieved, ok := auth.GetSessionFromContext(ctx)
+		require.True(t, ok)
+		retrieved.Metadata["tenant"] = "tenant-b"
+		*retrieved.SessionNonce = "modified"
+
+		// then
+		again, ok := auth.GetSessionFromContext(ctx)
+		require.True(t, ok)
+		require.Equal(t, session, again)
+	})
+
+	t.Run("No session in context", func(t *testing.T) {
+		// when
+		_, ok := auth.GetSessionFromContext(context.Background())
+
+		// then
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SkipRule tells whether the request bypasses the authentication, see WithSkipAuth.
+type SkipRule func(r *http.Request) bool
+
+// SkipPaths matches the requests by their path, exactly, or by prefix for the paths ending with a slash,
+// like the patterns of the http.ServeMux: "/metrics" matches only "/metrics" while "/debug/" matches "/debug/pprof" too.
+func SkipPaths(paths ...string) SkipRule {
+	return func(r *http.Request) bool {
+		return slices.ContainsFunc(paths, func(path string) bool {
+			if strings.HasSuffix(path, "/") {
+				return strings.HasPrefix(r.URL.Path, path)
+			}
+			return r.URL.Path == path
+		})
+	}
+}
+
+// SkipMethods matches the requests by their method, in any case, e.g. the OPTIONS preflight requests.
+func SkipMethods(methods ...string) SkipRule {
+	return func(r *http.Request) bool {
+		return slices.ContainsFunc(methods, func(method string) bool {
+			return strings.EqualFold(r.Method, method)
+		})
+	}
+}
+
+func (v *GeneralMessageVerifier) skipped(r *http.Request) bool {
+	return slices.ContainsFunc(v.skipRules, func(rule SkipRule) bool {
+		return rule(r)
+	})
+}
@@ -0,0 +1,155 @@
+package auth_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/middleware/auth"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipRules(t *testing.T) {
+	tests := map[string]struct {
+		rule     auth.SkipRule
+		method   string
+		path     string
+		expected bool
+	}{
+		"Match an exact path": {
+			rule:     auth.SkipPaths("/healthz", "/metrics"),
+			path:     "/metrics",
+			expected: true,
+		},
+		"Don't match a subpath of an exact path": {
+			rule: auth.SkipPaths("/metrics"),
+			path: "/metrics/internal",
+		},
+		"Don't match a path sharing the prefix of an exact path": {
+			rule: auth.SkipPaths("/healthz"),
+			path: "/healthzz",
+		},
+		"Match a subpath of a prefix": {
+			rule:     auth.SkipPaths("/debug/"),
+			path:     "/debug/pprof/heap",
+			expected: true,
+		},
+		"Match the path of a prefix itself": {
+			rule:     auth.SkipPaths("/debug/"),
+			path:     "/debug/",
+			expected: true,
+		},
+		"Don't match the path of a prefix without its slash": {
+			rule: auth.SkipPaths("/debug/"),
+			path: "/debug",
+		},
+		"Match a method": {
+			rule:     auth.SkipMethods(http.MethodOptions),
+			method:   http.MethodOptions,
+			path:     "/orders",
+			expected: true,
+		},
+		"Match a method in any case": {
+			rule:     auth.SkipMethods("options"),
+			method:   http.MethodOptions,
+			path:     "/orders",
+			expected: true,
+		},
+		"Don't match another method": {
+			rule:   auth.SkipMethods(http.MethodOptions),
+			method: http.MethodPost,
+			path:   "/orders",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			request, err := http.NewRequestWithContext(t.Context(), test.method, "http://example.com"+test.path, nil)
+			require.NoError(t, err)
+
+			// when
+			matched := test.rule(request)
+
+			// then
+			require.Equal(t, test.expected, matched)
+		})
+	}
+}
+
+func TestGeneralMessageVerifier_SkipAuth(t *testing.T) {
+	skipHealthChecks := auth.WithSkipAuth(auth.SkipPaths("/healthz", "/metrics"))
+
+	t.Run("Pass a skipped request without auth headers or identity", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, skipHealthChecks)
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, f.server.URL+"/healthz", nil)
+		require.NoError(t, err)
+
+		// when
+		response, _ := send(t, request)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.True(t, f.handlerCalled)
+		require.Zero(t, f.identity)
+		require.Empty(t, response.Header.Get(auth.HeaderSignature))
+	})
+
+	t.Run("Pass a skipped request with auth headers unverified", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithSkipAuth(auth.SkipMethods(http.MethodPost)), auth.WithNonceStore(failingNonceStore{}))
+		request := f.signedRequest(t, f.client, requestNonce1, "body")
+		request.Header.Set("X-Bsv-Tenant", "tenant-b")
+
+		// when
+		response, body := send(t, request)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "body", string(body))
+		require.Zero(t, f.identity)
+		require.Empty(t, response.Header.Get(auth.HeaderSignature))
+		session, err := f.sessions.GetSession(t.Context(), sessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), session.LastUpdate)
+	})
+
+	t.Run("Verify the requests not matching any rule", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, skipHealthChecks, auth.WithSkipAuth(auth.SkipMethods(http.MethodOptions)))
+
+		// when
+		response, body := send(t, anonymousRequest(t, f))
+
+		// then
+		requireRejected(t, response, body, auth.ErrCodeMissingAuthHeaders)
+		require.False(t, f.handlerCalled)
+	})
+
+	t.Run("Pass a skipped request without the unknown identity when unauthenticated ones are allowed", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, skipHealthChecks, auth.WithAllowUnauthenticated(true))
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, f.server.URL+"/metrics", nil)
+		require.NoError(t, err)
+
+		// when
+		response, _ := send(t, request)
+
+		// then
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.True(t, f.handlerCalled)
+		require.Zero(t, f.identity)
+	})
+
+	t.Run("Never skip the auth endpoint", func(t *testing.T) {
+		// given
+		f := newGeneralMessageFixture(t, time.Now, true, auth.WithSkipAuth(auth.SkipPaths("/")), auth.WithSkipAuth(auth.SkipPaths("/.well-known/")))
+
+		// when
+		_, initialResponse := f.handshake(t)
+
+		// then
+		require.False(t, f.handlerCalled)
+		require.NotEmpty(t, initialResponse.InitialNonce)
+	})
+}
@@ -0,0 +1,53 @@
+package money
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrInvalidRate is returned when the exchange rate provider returns a non-positive, NaN or infinite rate.
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// RateFunc returns the price of one BSV expressed in the given fiat currency (e.g. "USD").
+type RateFunc func(ctx context.Context, currency string) (float64, error)
+
+// FiatConverter converts amounts between satoshis and fiat currencies using a pluggable rate source.
+type FiatConverter struct {
+	rate RateFunc
+}
+
+// NewFiatConverter creates a new FiatConverter using the given exchange rate source.
+func NewFiatConverter(rate RateFunc) *FiatConverter {
+	return &FiatConverter{rate: rate}
+}
+
+// ToFiat converts the satoshis amount to the given fiat currency.
+func (c *FiatConverter) ToFiat(ctx context.Context, amount Satoshis, currency string) (float64, error) {
+	rate, err := c.bsvRate(ctx, currency)
+	if err != nil {
+		return 0, err
+	}
+	return float64(amount) / float64(SatoshisPerBSV) * rate, nil
+}
+
+// FromFiat converts the fiat amount in the given currency to satoshis, rounding to the nearest satoshi.
+func (c *FiatConverter) FromFiat(ctx context.Context, amount float64, currency string) (Satoshis, error) {
+	rate, err := c.bsvRate(ctx, currency)
+	if err != nil {
+		return 0, err
+	}
+	return FromFloat(amount / rate * float64(SatoshisPerBSV))
+}
+
+func (c *FiatConverter) bsvRate(ctx context.Context, currency string) (float64, error) {
+	rate, err := c.rate(ctx, currency)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s exchange rate: %w", currency, err)
+	}
+	if !(rate > 0) || math.IsInf(rate, 0) {
+		return 0, fmt.Errorf("%w: %v %s per BSV", ErrInvalidRate, rate, currency)
+	}
+	return rate, nil
+}
@@ -0,0 +1,99 @@
+package money_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/money"
+	"github.com/stretchr/testify/require"
+)
+
+func fixedRate(rate float64) money.RateFunc {
+	return func(_ context.Context, _ string) (float64, error) {
+		return rate, nil
+	}
+}
+
+func TestFiatConverter_HappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	converter := money.NewFiatConverter(fixedRate(50))
+
+	// when
+	fiat, err := converter.ToFiat(ctx, money.SatoshisPerBSV/2, "USD")
+
+	// then
+	require.NoError(t, err)
+	require.InDelta(t, 25.0, fiat, 1e-9)
+
+	// when
+	amount, err := converter.FromFiat(ctx, 0.01, "USD")
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, money.Satoshis(20_000), amount)
+}
+
+func TestFiatConverter_ErrorPath(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("rate source failure", func(t *testing.T) {
+		// given
+		errRate := errors.New("rate unavailable")
+		converter := money.NewFiatConverter(func(_ context.Context, _ string) (float64, error) {
+			return 0, errRate
+		})
+
+		// when
+		_, err := converter.ToFiat(ctx, 1, "USD")
+
+		// then
+		require.ErrorIs(t, err, errRate)
+	})
+
+	t.Run("non-positive rate", func(t *testing.T) {
+		// given
+		converter := money.NewFiatConverter(fixedRate(0))
+
+		// when
+		_, err := converter.FromFiat(ctx, 1, "USD")
+
+		// then
+		require.ErrorIs(t, err, money.ErrInvalidRate)
+	})
+
+	t.Run("NaN rate", func(t *testing.T) {
+		// given
+		converter := money.NewFiatConverter(fixedRate(math.NaN()))
+
+		// when
+		_, err := converter.ToFiat(ctx, 1, "USD")
+
+		// then
+		require.ErrorIs(t, err, money.ErrInvalidRate)
+	})
+
+	t.Run("infinite rate", func(t *testing.T) {
+		// given
+		converter := money.NewFiatConverter(fixedRate(math.Inf(1)))
+
+		// when
+		_, err := converter.FromFiat(ctx, 1, "USD")
+
+		// then
+		require.ErrorIs(t, err, money.ErrInvalidRate)
+	})
+
+	t.Run("negative fiat amount", func(t *testing.T) {
+		// given
+		converter := money.NewFiatConverter(fixedRate(50))
+
+		// when
+		_, err := converter.FromFiat(ctx, -1, "USD")
+
+		// then
+		require.ErrorIs(t, err, money.ErrInvalidAmount)
+	})
+}
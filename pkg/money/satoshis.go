@@ -0,0 +1,138 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// SatoshisPerBSV is the number of satoshis in one BSV.
+const SatoshisPerBSV Satoshis = 100_000_000
+
+const bsvDecimals = 8
+
+var (
+	// ErrOverflow is returned when an arithmetic operation exceeds the satoshis range.
+	ErrOverflow = errors.New("satoshis amount overflow")
+	// ErrUnderflow is returned when a subtraction would result in a negative amount.
+	ErrUnderflow = errors.New("satoshis amount underflow")
+	// ErrInvalidAmount is returned when an amount cannot be parsed.
+	ErrInvalidAmount = errors.New("invalid amount")
+)
+
+// Satoshis represents an amount of BSV expressed in satoshis.
+type Satoshis uint64
+
+// Add returns the sum of s and other or ErrOverflow if the result doesn't fit into Satoshis.
+func (s Satoshis) Add(other Satoshis) (Satoshis, error) {
+	sum, carry := bits.Add64(uint64(s), uint64(other), 0)
+	if carry != 0 {
+		return 0, ErrOverflow
+	}
+	return Satoshis(sum), nil
+}
+
+// Sub returns the difference of s and other or ErrUnderflow if other is greater than s.
+func (s Satoshis) Sub(other Satoshis) (Satoshis, error) {
+	if other > s {
+		return 0, ErrUnderflow
+	}
+	return s - other, nil
+}
+
+// Mul returns s multiplied by factor or ErrOverflow if the result doesn't fit into Satoshis.
+func (s Satoshis) Mul(factor uint64) (Satoshis, error) {
+	hi, lo := bits.Mul64(uint64(s), factor)
+	if hi != 0 {
+		return 0, ErrOverflow
+	}
+	return Satoshis(lo), nil
+}
+
+// Sum adds up all the given amounts, returning ErrOverflow if the total doesn't fit into Satoshis.
+func Sum(amounts ...Satoshis) (Satoshis, error) {
+	var total Satoshis
+	for _, amount := range amounts {
+		var err error
+		total, err = total.Add(amount)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// String returns the amount as a plain number of satoshis.
+func (s Satoshis) String() string {
+	return strconv.FormatUint(uint64(s), 10)
+}
+
+// BSVString returns the amount formatted in BSV with all 8 decimal places, e.g. "0.00001000".
+func (s Satoshis) BSVString() string {
+	whole := s / SatoshisPerBSV
+	fraction := s % SatoshisPerBSV
+	return fmt.Sprintf("%d.%08d", uint64(whole), uint64(fraction))
+}
+
+// ParseSatoshis parses a plain, non-negative integer number of satoshis.
+func ParseSatoshis(value string) (Satoshis, error) {
+	amount, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a satoshis amount", ErrInvalidAmount, value)
+	}
+	return Satoshis(amount), nil
+}
+
+// ParseBSV parses a decimal BSV amount (e.g. "0.5" or "12.00000001") into Satoshis.
+// More than 8 decimal places are rejected instead of being rounded.
+func ParseBSV(value string) (Satoshis, error) {
+	value = strings.TrimSpace(value)
+	wholePart, fractionPart, _ := strings.Cut(value, ".")
+	if wholePart == "" && fractionPart == "" {
+		return 0, fmt.Errorf("%w: %q is not a BSV amount", ErrInvalidAmount, value)
+	}
+	if len(fractionPart) > bsvDecimals {
+		return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrInvalidAmount, value, bsvDecimals)
+	}
+
+	whole, err := parseDigits(wholePart)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a BSV amount", ErrInvalidAmount, value)
+	}
+	fraction, err := parseDigits(fractionPart + strings.Repeat("0", bsvDecimals-len(fractionPart)))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a BSV amount", ErrInvalidAmount, value)
+	}
+
+	amount, err := Satoshis(whole).Mul(uint64(SatoshisPerBSV))
+	if err != nil {
+		return 0, err
+	}
+	return amount.Add(Satoshis(fraction))
+}
+
+func parseDigits(digits string) (uint64, error) {
+	if digits == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse digits: %w", err)
+	}
+	return n, nil
+}
+
+// FromFloat converts a non-negative floating point amount of satoshis into Satoshis, rounding to the nearest satoshi.
+func FromFloat(amount float64) (Satoshis, error) {
+	if math.IsNaN(amount) || amount < 0 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidAmount, amount)
+	}
+	rounded := math.Round(amount)
+	if rounded >= math.MaxUint64 {
+		return 0, ErrOverflow
+	}
+	return Satoshis(rounded), nil
+}
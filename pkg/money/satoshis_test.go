@@ -0,0 +1,158 @@
+package money_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/money"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSatoshis_Arithmetic(t *testing.T) {
+	t.Run("Add", func(t *testing.T) {
+		// when
+		sum, err := money.Satoshis(1000).Add(234)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, money.Satoshis(1234), sum)
+	})
+
+	t.Run("Add overflow", func(t *testing.T) {
+		// when
+		_, err := money.Satoshis(math.MaxUint64).Add(1)
+
+		// then
+		require.ErrorIs(t, err, money.ErrOverflow)
+	})
+
+	t.Run("Sub", func(t *testing.T) {
+		// when
+		diff, err := money.Satoshis(1000).Sub(1)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, money.Satoshis(999), diff)
+	})
+
+	t.Run("Sub underflow", func(t *testing.T) {
+		// when
+		_, err := money.Satoshis(1).Sub(2)
+
+		// then
+		require.ErrorIs(t, err, money.ErrUnderflow)
+	})
+
+	t.Run("Mul", func(t *testing.T) {
+		// when
+		product, err := money.Satoshis(250).Mul(4)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, money.Satoshis(1000), product)
+	})
+
+	t.Run("Mul overflow", func(t *testing.T) {
+		// when
+		_, err := money.Satoshis(math.MaxUint64 / 2).Mul(3)
+
+		// then
+		require.ErrorIs(t, err, money.ErrOverflow)
+	})
+
+	t.Run("Sum", func(t *testing.T) {
+		// when
+		total, err := money.Sum(1, 2, 3)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, money.Satoshis(6), total)
+	})
+
+	t.Run("Sum overflow", func(t *testing.T) {
+		// when
+		_, err := money.Sum(1, math.MaxUint64)
+
+		// then
+		require.ErrorIs(t, err, money.ErrOverflow)
+	})
+}
+
+func TestSatoshis_Format(t *testing.T) {
+	tests := map[string]struct {
+		amount   money.Satoshis
+		plain    string
+		bsvValue string
+	}{
+		"zero":        {amount: 0, plain: "0", bsvValue: "0.00000000"},
+		"one satoshi": {amount: 1, plain: "1", bsvValue: "0.00000001"},
+		"one BSV":     {amount: money.SatoshisPerBSV, plain: "100000000", bsvValue: "1.00000000"},
+		"mixed":       {amount: 1_234_567_890, plain: "1234567890", bsvValue: "12.34567890"},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// then
+			require.Equal(t, test.plain, test.amount.String())
+			require.Equal(t, test.bsvValue, test.amount.BSVString())
+		})
+	}
+}
+
+func TestParseSatoshis(t *testing.T) {
+	t.Run("valid amount", func(t *testing.T) {
+		// when
+		amount, err := money.ParseSatoshis(" 1000 ")
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, money.Satoshis(1000), amount)
+	})
+
+	for _, value := range []string{"", "-1", "1.5", "abc", "18446744073709551616"} {
+		t.Run("invalid amount "+value, func(t *testing.T) {
+			// when
+			_, err := money.ParseSatoshis(value)
+
+			// then
+			require.ErrorIs(t, err, money.ErrInvalidAmount)
+		})
+	}
+}
+
+func TestParseBSV(t *testing.T) {
+	valid := map[string]money.Satoshis{
+		"1":           money.SatoshisPerBSV,
+		"0.5":         50_000_000,
+		".00000001":   1,
+		"12.34567890": 1_234_567_890,
+		"3.":          3 * money.SatoshisPerBSV,
+	}
+	for value, expected := range valid {
+		t.Run("valid amount "+value, func(t *testing.T) {
+			// when
+			amount, err := money.ParseBSV(value)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, expected, amount)
+		})
+	}
+
+	for _, value := range []string{"", ".", "-1", "+1", "1.000000001", "1,5", "abc"} {
+		t.Run("invalid amount "+value, func(t *testing.T) {
+			// when
+			_, err := money.ParseBSV(value)
+
+			// then
+			require.ErrorIs(t, err, money.ErrInvalidAmount)
+		})
+	}
+
+	t.Run("overflow", func(t *testing.T) {
+		// when
+		_, err := money.ParseBSV("184467440737.09551616")
+
+		// then
+		require.ErrorIs(t, err, money.ErrOverflow)
+	})
+}
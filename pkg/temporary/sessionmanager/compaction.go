@@ -0,0 +1,117 @@
+package sessionmanager
+
+import "context"
+
+// CompactionResult describes the outcome of a single compaction run.
+type CompactionResult struct {
+	// Sessions is the number of sessions held after the compaction
+	Sessions int
+	// Identities is the number of peerIdentityKeys held after the compaction
+	Identities int
+	// ReclaimedNonceSlots is the number of unused slots released from the identity index slices
+	ReclaimedNonceSlots int
+	// PrunedNonces is the number of dangling nonces, without a stored session, removed from the identity index
+	PrunedNonces int
+}
+
+// Compact rebuilds the internal maps and identity index slices so that the memory they hold
+// is proportional to the live sessions. Go maps never shrink after deletes,
+// so without compaction a long-running manager keeps the memory of its peak session count.
+// The index of a store implementing IndexPruner is swept of the nonces without a stored session.
+// Stores which don't support compaction only get the sweep, and the live sessions and identities are counted from their List.
+func (m *SessionManager) Compact() CompactionResult {
+	ctx := context.Background()
+	prunedNonces := 0
+	if pruner, ok := m.store.(IndexPruner); ok {
+		prunedNonces = m.sweepIndex(ctx, pruner)
+	}
+
+	var result CompactionResult
+	if store, ok := m.store.(compactableStore); ok {
+		result = store.Compact()
+	} else if sessions, err := m.store.List(ctx); err == nil {
+		identities := make(map[string]struct{})
+		for _, session := range sessions {
+			if identityKey := session.GetPeerIdentityKey(); identityKey != "" {
+				identities[identityKey] = struct{}{}
+			}
+		}
+		result.Sessions = len(sessions)
+		result.Identities = len(identities)
+	}
+	result.PrunedNonces = prunedNonces
+	return result
+}
+
+// sweepIndex prunes the nonces without a stored session from the index of every peerIdentityKey, under its stripe,
+// and returns how many it pruned. The sweep is best effort, a peerIdentityKey failing to be swept is skipped.
+func (m *SessionManager) sweepIndex(ctx context.Context, pruner IndexPruner) int {
+	identityKeys, err := pruner.IdentityKeys(ctx)
+	if err != nil {
+		return 0
+	}
+
+	pruned := 0
+	for _, identityKey := range identityKeys {
+		pruned += m.sweepIdentity(ctx, pruner, identityKey)
+	}
+	return pruned
+}
+
+func (m *SessionManager) sweepIdentity(ctx context.Context, pruner IndexPruner, identityKey string) int {
+	unlock := m.identityLocks.lock(identityKey)
+	defer unlock()
+
+	sessionNonces, err := m.store.GetNoncesByIdentity(ctx, identityKey)
+	if err != nil {
+		return 0
+	}
+	var dangling []string
+	for _, sessionNonce := range sessionNonces {
+		if _, exists, err := m.store.GetByNonce(ctx, sessionNonce); err == nil && !exists {
+			dangling = append(dangling, sessionNonce)
+		}
+	}
+	if len(dangling) == 0 || pruner.PruneIndex(ctx, identityKey, dangling) != nil {
+		return 0
+	}
+	return len(dangling)
+}
+
+// Compact rebuilds the maps and identity index slices of the store to release the memory of removed sessions.
+// The shards are compacted one at a time, so the store stays available during the compaction.
+func (s *MemoryStore) Compact() CompactionResult {
+	var result CompactionResult
+	for i := range s.sessionShards {
+		shard := &s.sessionShards[i]
+		shard.mu.Lock()
+		sessions := make(map[string]PeerSession, len(shard.sessions))
+		for nonce, session := range shard.sessions {
+			sessions[nonce] = session
+		}
+		shard.sessions = sessions
+		result.Sessions += len(sessions)
+		shard.mu.Unlock()
+	}
+
+	for i := range s.identityShards {
+		index := &s.identityShards[i]
+		index.mu.Lock()
+		identityKeyToSessions := make(map[string][]string, len(index.identityKeyToSessions))
+		for identityKey, nonces := range index.identityKeyToSessions {
+			result.ReclaimedNonceSlots += cap(nonces) - len(nonces)
+			identityKeyToSessions[identityKey] = append(make([]string, 0, len(nonces)), nonces...)
+		}
+		index.identityKeyToSessions = identityKeyToSessions
+		result.Identities += len(identityKeyToSessions)
+		index.mu.Unlock()
+	}
+	return result
+}
+
+func (m *SessionManager) runCompaction() {
+	result := m.Compact()
+	if m.onCompacted != nil {
+		m.onCompacted(result)
+	}
+}
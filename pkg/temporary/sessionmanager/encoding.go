@@ -0,0 +1,90 @@
+package sessionmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SessionFormatVersion is the version of the serialization format produced by PeerSession.MarshalBinary.
+const SessionFormatVersion byte = 1
+
+var (
+	// ErrUnsupportedSessionVersion is returned by PeerSession.UnmarshalBinary when the session was serialized
+	// by an unknown format version.
+	ErrUnsupportedSessionVersion = errors.New("unsupported session format version")
+	// ErrInvalidSessionEncoding is returned by PeerSession.UnmarshalBinary when the data can't be decoded.
+	ErrInvalidSessionEncoding = errors.New("invalid session encoding")
+)
+
+// encodedSession is the version 1 representation of a PeerSession.
+// Its field order is fixed, so the same session always serializes to the same bytes.
+type encodedSession struct {
+	IsAuthenticated bool   `json:"isAuthenticated"`
+	SessionNonce    string `json:"sessionNonce,omitempty"`
+	PeerNonce       string `json:"peerNonce,omitempty"`
+	PeerIdentityKey string `json:"peerIdentityKey,omitempty"`
+	LastUpdate      string `json:"lastUpdate"`
+	// Metadata was added without bumping the version, older decoders ignore it and older data has none
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// MarshalBinary serializes the session into the stable format shared by the session stores,
+// so the sessions can be migrated between them.
+//
+// The format is a single SessionFormatVersion byte followed by a JSON object.
+// Nil and empty nonces and identity keys are both omitted, and LastUpdate is written in UTC as RFC3339Nano.
+// Empty metadata is omitted, map keys are sorted, so the output is stable.
+func (s PeerSession) MarshalBinary() ([]byte, error) {
+	payload, err := json.Marshal(encodedSession{
+		IsAuthenticated: s.IsAuthenticated,
+		SessionNonce:    s.GetSessionNonce(),
+		PeerNonce:       s.GetPeerNonce(),
+		PeerIdentityKey: s.GetPeerIdentityKey(),
+		LastUpdate:      s.LastUpdate.UTC().Format(time.RFC3339Nano),
+		Metadata:        s.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session: %w", err)
+	}
+	return append([]byte{SessionFormatVersion}, payload...), nil
+}
+
+// UnmarshalBinary restores the session serialized by MarshalBinary.
+// Omitted nonces and identity keys are decoded as nil and LastUpdate is decoded in UTC.
+// The metadata values are decoded as generic JSON values, e.g. numbers as float64.
+func (s *PeerSession) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("%w: empty data", ErrInvalidSessionEncoding)
+	}
+	if data[0] != SessionFormatVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSessionVersion, data[0])
+	}
+
+	var decoded encodedSession
+	if err := json.Unmarshal(data[1:], &decoded); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSessionEncoding, err)
+	}
+	lastUpdate, err := time.Parse(time.RFC3339Nano, decoded.LastUpdate)
+	if err != nil {
+		return fmt.Errorf("%w: last update: %w", ErrInvalidSessionEncoding, err)
+	}
+
+	*s = PeerSession{
+		IsAuthenticated: decoded.IsAuthenticated,
+		SessionNonce:    pointerTo(decoded.SessionNonce),
+		PeerNonce:       pointerTo(decoded.PeerNonce),
+		PeerIdentityKey: pointerTo(decoded.PeerIdentityKey),
+		LastUpdate:      lastUpdate.UTC(),
+		Metadata:        decoded.Metadata,
+	}
+	return nil
+}
+
+func pointerTo(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
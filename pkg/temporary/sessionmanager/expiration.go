@@ -0,0 +1,52 @@
+package sessionmanager
+
+import (
+	"context"
+	"fmt"
+)
+
+// RemoveExpiredSessions removes all sessions whose LastUpdate is older than the session TTL
+// and returns how many were removed. It does nothing when no session TTL is configured.
+// It's called periodically in the background, but can also be called manually.
+func (m *SessionManager) RemoveExpiredSessions(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	if m.sessionTTL <= 0 {
+		return 0, nil
+	}
+
+	var events sessionEvents
+	defer m.notifyAll(&events)
+
+	return m.removeSessionsWhere(ctx, m.isExpired, eventExpired, &events)
+}
+
+// removeSessionsWhere removes all sessions matching the predicate, each checked again under the stripe of its sessionNonce,
+// so sessions refreshed meanwhile are kept. Every removal is recorded as an event of the given kind.
+func (m *SessionManager) removeSessionsWhere(ctx context.Context, predicate func(PeerSession) bool, kind eventKind, events *sessionEvents) (int, error) {
+	sessions, err := m.store.List(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	removed := 0
+	for _, session := range sessions {
+		if !predicate(session) {
+			continue
+		}
+		ok, err := m.removeSession(ctx, *session.SessionNonce, kind, events, predicate)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// isExpired checks if the session outlived the configured session TTL.
+func (m *SessionManager) isExpired(session PeerSession) bool {
+	return m.sessionTTL > 0 && m.now().Sub(session.LastUpdate) > m.sessionTTL
+}
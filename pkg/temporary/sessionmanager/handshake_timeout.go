@@ -0,0 +1,31 @@
+package sessionmanager
+
+import (
+	"context"
+	"fmt"
+)
+
+// RemoveAbandonedHandshakes removes all unauthenticated sessions whose LastUpdate is older than the handshake timeout
+// and returns how many were removed. It does nothing when no handshake timeout is configured.
+// It's called periodically in the background, but can also be called manually.
+func (m *SessionManager) RemoveAbandonedHandshakes(ctx context.Context) (int, error) {
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	if m.handshakeTimeout <= 0 {
+		return 0, nil
+	}
+
+	var events sessionEvents
+	defer m.notifyAll(&events)
+
+	deadline := m.now().Add(-m.handshakeTimeout)
+	removed, err := m.removeSessionsWhere(ctx, func(session PeerSession) bool {
+		return !session.IsAuthenticated && session.LastUpdate.Before(deadline)
+	}, eventExpired, &events)
+
+	if removed > 0 {
+		m.stats.handshakesAbandoned(removed)
+	}
+	return removed, err
+}
@@ -0,0 +1,105 @@
+package sessionmanager
+
+import "sync"
+
+// eventKind identifies a session lifecycle transition.
+type eventKind int
+
+const (
+	// eventAdded is recorded when a session with a new sessionNonce is stored.
+	eventAdded eventKind = iota
+	// eventAuthenticated is recorded when a session becomes authenticated.
+	eventAuthenticated
+	// eventRemoved is recorded when a session is removed explicitly or to respect the per identity cap.
+	eventRemoved
+	// eventEvicted is recorded when a session is removed to respect the global capacity.
+	eventEvicted
+	// eventExpired is recorded when a session outlives the session TTL or the handshake timeout.
+	eventExpired
+)
+
+type sessionEvent struct {
+	kind    eventKind
+	session PeerSession
+}
+
+// sessionHooks holds the registered lifecycle callbacks, it has its own lock,
+// so the hooks can be registered and invoked concurrently with the SessionManager operations.
+type sessionHooks struct {
+	mu            sync.RWMutex
+	added         []func(PeerSession)
+	authenticated []func(PeerSession)
+	removed       []func(PeerSession)
+	expired       []func(PeerSession)
+}
+
+// OnAdded registers a hook called whenever a session with a new sessionNonce is added.
+func (m *SessionManager) OnAdded(hook func(PeerSession)) {
+	m.hooks.register(&m.hooks.added, hook)
+}
+
+// OnAuthenticated registers a hook called once a session becomes authenticated,
+// either by adding an authenticated session or by an update switching IsAuthenticated from false to true.
+func (m *SessionManager) OnAuthenticated(hook func(PeerSession)) {
+	m.hooks.register(&m.hooks.authenticated, hook)
+}
+
+// OnRemoved registers a hook called whenever a session is removed by RemoveSession or evicted to respect capacity limits.
+func (m *SessionManager) OnRemoved(hook func(PeerSession)) {
+	m.hooks.register(&m.hooks.removed, hook)
+}
+
+// OnExpired registers a hook called whenever a session is removed
+// because it outlived the session TTL or didn't complete the handshake in time.
+func (m *SessionManager) OnExpired(hook func(PeerSession)) {
+	m.hooks.register(&m.hooks.expired, hook)
+}
+
+func (h *sessionHooks) register(hooks *[]func(PeerSession), hook func(PeerSession)) {
+	if hook == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	*hooks = append(*hooks, hook)
+}
+
+// sessionEvents collects the events of an operation, they're passed to the hooks once the operation released its locks.
+type sessionEvents []sessionEvent
+
+func (e *sessionEvents) record(kind eventKind, session PeerSession) {
+	*e = append(*e, sessionEvent{kind: kind, session: session.Clone()})
+}
+
+// notifyAll passes the recorded events to the hooks, the caller must not hold any lock,
+// so the hooks can safely call back into the SessionManager.
+func (m *SessionManager) notifyAll(events *sessionEvents) {
+	for _, event := range *events {
+		m.notify(event)
+	}
+}
+
+func (m *SessionManager) notify(event sessionEvent) {
+	m.hooks.mu.RLock()
+	var hooks []func(PeerSession)
+	switch event.kind {
+	case eventAdded:
+		hooks = m.hooks.added
+	case eventAuthenticated:
+		hooks = m.hooks.authenticated
+	case eventRemoved, eventEvicted:
+		hooks = m.hooks.removed
+	case eventExpired:
+		hooks = m.hooks.expired
+	}
+	m.hooks.mu.RUnlock()
+
+	if event.kind == eventEvicted && m.onEvicted != nil {
+		m.onEvicted(event.session)
+	}
+	for _, hook := range hooks {
+		hook(event.session)
+	}
+}
@@ -0,0 +1,59 @@
+package sessionmanager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// DefaultMaxSessionsPerIdentity is the default number of concurrent sessions kept for a single peerIdentityKey.
+const DefaultMaxSessionsPerIdentity = 10
+
+// evictExcessSessions removes sessions of the peerIdentityKey exceeding the per identity cap under the stripe of the peerIdentityKey,
+// the caller must not hold any stripe. The oldest unauthenticated sessions are evicted first, then the oldest authenticated ones.
+// The session with the keep nonce is never evicted.
+func (m *SessionManager) evictExcessSessions(ctx context.Context, identityKey string, keep string, events *sessionEvents) error {
+	if m.maxSessionsPerIdentity <= 0 {
+		return nil
+	}
+
+	unlock := m.identityLocks.lock(identityKey)
+	defer unlock()
+
+	nonces, err := m.store.GetNoncesByIdentity(ctx, identityKey)
+	if err != nil {
+		return fmt.Errorf("failed to get sessions by identity key: %w", err)
+	}
+	if len(nonces) <= m.maxSessionsPerIdentity {
+		return nil
+	}
+
+	candidates := make([]PeerSession, 0, len(nonces))
+	for _, nonce := range nonces {
+		if nonce == keep {
+			continue
+		}
+		session, exists, err := m.store.GetByNonce(ctx, nonce)
+		if err != nil {
+			return fmt.Errorf("failed to get session: %w", err)
+		}
+		if exists {
+			candidates = append(candidates, session)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].IsAuthenticated != candidates[j].IsAuthenticated {
+			return !candidates[i].IsAuthenticated
+		}
+		return candidates[i].LastUpdate.Before(candidates[j].LastUpdate)
+	})
+
+	excess := len(nonces) - m.maxSessionsPerIdentity
+	for _, session := range candidates[:min(excess, len(candidates))] {
+		if _, err := m.removeSession(ctx, *session.SessionNonce, eventRemoved, events, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package sessionmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "bsv_auth"
+
+var _ Interface = (*Instrumented)(nil)
+
+// statsReporter is implemented by session managers able to report their aggregates, e.g. SessionManager.
+type statsReporter interface {
+	Stats() Stats
+}
+
+// Instrumented is a decorator exposing Prometheus metrics of any session manager implementation.
+type Instrumented struct {
+	inner Interface
+
+	operations            *prometheus.CounterVec
+	misses                prometheus.Counter
+	getLatency            prometheus.Histogram
+	identitySessions      prometheus.Histogram
+	activeSessions        prometheus.GaugeFunc
+	authenticatedSessions prometheus.GaugeFunc
+}
+
+// NewInstrumented wraps the session manager and registers its metrics with the registerer,
+// prometheus.DefaultRegisterer is used when the registerer is nil.
+//
+// The metrics are:
+//   - bsv_auth_session_operations_total counter of adds, updates, touches, removes and peer purges, labeled by operation,
+//   - bsv_auth_session_misses_total counter of GetSession calls which didn't find a session,
+//   - bsv_auth_session_get_duration_seconds histogram of GetSession latency,
+//   - bsv_auth_session_sessions_per_identity histogram of sessions kept for the peer, observed on every add,
+//   - bsv_auth_session_active_sessions and bsv_auth_session_authenticated_sessions gauges,
+//     registered only when the wrapped manager reports its Stats, like SessionManager does.
+func NewInstrumented(inner Interface, reg prometheus.Registerer) (*Instrumented, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	i := &Instrumented{
+		inner: inner,
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "session",
+			Name:      "operations_total",
+			Help:      "Number of session operations by operation.",
+		}, []string{"operation"}),
+		misses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "session",
+			Name:      "misses_total",
+			Help:      "Number of session lookups which didn't find a session.",
+		}),
+		getLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "session",
+			Name:      "get_duration_seconds",
+			Help:      "Latency of session lookups.",
+			Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}),
+		identitySessions: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "session",
+			Name:      "sessions_per_identity",
+			Help:      "Number of sessions kept for a peer identity key, observed whenever a session is added.",
+			Buckets:   []float64{1, 2, 5, 10, 20, 50, 100},
+		}),
+	}
+
+	collectors := []prometheus.Collector{i.operations, i.misses, i.getLatency, i.identitySessions}
+
+	if stats, ok := inner.(statsReporter); ok {
+		i.activeSessions = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "session",
+			Name:      "active_sessions",
+			Help:      "Number of active sessions.",
+		}, func() float64 { return float64(stats.Stats().ActiveSessions) })
+		i.authenticatedSessions = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "session",
+			Name:      "authenticated_sessions",
+			Help:      "Number of active authenticated sessions.",
+		}, func() float64 { return float64(stats.Stats().AuthenticatedSessions) })
+		collectors = append(collectors, i.activeSessions, i.authenticatedSessions)
+	}
+
+	for _, collector := range collectors {
+		if err := reg.Register(collector); err != nil {
+			return nil, fmt.Errorf("failed to register session manager metrics: %w", err)
+		}
+	}
+	return i, nil
+}
+
+// AddSession adds the session to the wrapped manager and observes the number of sessions of its peer.
+func (i *Instrumented) AddSession(ctx context.Context, session PeerSession) error {
+	i.operations.WithLabelValues("add").Inc()
+	if err := i.inner.AddSession(ctx, session); err != nil {
+		return err
+	}
+	i.observeIdentitySessions(ctx, session)
+	return nil
+}
+
+// UpdateSession updates the session in the wrapped manager.
+func (i *Instrumented) UpdateSession(ctx context.Context, session PeerSession) error {
+	i.operations.WithLabelValues("update").Inc()
+	return i.inner.UpdateSession(ctx, session)
+}
+
+// TouchSession sets the LastUpdate of the session in the wrapped manager.
+func (i *Instrumented) TouchSession(ctx context.Context, sessionNonce string, lastUpdate time.Time) error {
+	i.operations.WithLabelValues("touch").Inc()
+	return i.inner.TouchSession(ctx, sessionNonce, lastUpdate)
+}
+
+// GetSession retrieves the session from the wrapped manager, measuring the latency and counting misses.
+func (i *Instrumented) GetSession(ctx context.Context, identifier string) (*PeerSession, error) {
+	start := time.Now()
+	session, err := i.inner.GetSession(ctx, identifier)
+	i.getLatency.Observe(time.Since(start).Seconds())
+
+	if errors.Is(err, ErrSessionNotFound) {
+		i.misses.Inc()
+	}
+	return session, err
+}
+
+// GetSessionsByIdentityKey retrieves all sessions of the peer from the wrapped manager.
+func (i *Instrumented) GetSessionsByIdentityKey(ctx context.Context, identityKey string) ([]PeerSession, error) {
+	return i.inner.GetSessionsByIdentityKey(ctx, identityKey)
+}
+
+// RemoveSession removes the session from the wrapped manager.
+func (i *Instrumented) RemoveSession(ctx context.Context, session PeerSession) error {
+	i.operations.WithLabelValues("remove").Inc()
+	return i.inner.RemoveSession(ctx, session)
+}
+
+// RemoveAllSessionsForPeer removes all sessions of the peer from the wrapped manager.
+func (i *Instrumented) RemoveAllSessionsForPeer(ctx context.Context, identityKey string) (int, error) {
+	i.operations.WithLabelValues("remove_all").Inc()
+	return i.inner.RemoveAllSessionsForPeer(ctx, identityKey)
+}
+
+// HasSession checks if the wrapped manager has a session for the identifier.
+func (i *Instrumented) HasSession(ctx context.Context, identifier string) bool {
+	return i.inner.HasSession(ctx, identifier)
+}
+
+func (i *Instrumented) observeIdentitySessions(ctx context.Context, session PeerSession) {
+	if session.PeerIdentityKey == nil {
+		return
+	}
+
+	sessions, err := i.inner.GetSessionsByIdentityKey(ctx, *session.PeerIdentityKey)
+	if err != nil {
+		return
+	}
+	i.identitySessions.Observe(float64(len(sessions)))
+}
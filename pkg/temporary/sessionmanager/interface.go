@@ -1,23 +1,55 @@
 package sessionmanager
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound is returned by GetSession when there is no session for the given identifier
+	// and by UpdateSession when there is no session to update.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionExpired is returned by GetSession for the sessionNonce of a session that outlived the session TTL
+	// and isn't removed yet, it matches ErrSessionNotFound too.
+	ErrSessionExpired = fmt.Errorf("%w: session expired", ErrSessionNotFound)
+	// ErrSessionAlreadyExists is returned by AddSession when a session with the same sessionNonce already exists.
+	ErrSessionAlreadyExists = errors.New("session already exists")
+)
+
 // Interface is an interface for managing peer sessions.
+// Errors other than ErrSessionNotFound mean that the underlying storage failed or the context was done.
 type Interface interface {
 	// AddSession adds a session to the manager, associating it with its sessionNonce,
 	// and also with its peerIdentityKey (if any). This does NOT overwrite existing
 	// sessions for the same peerIdentityKey, allowing multiple concurrent sessions.
-	AddSession(session PeerSession)
-	// UpdateSession updates a session in the manager.
-	UpdateSession(session PeerSession)
+	// If a session with the same sessionNonce already exists, ErrSessionAlreadyExists is returned.
+	AddSession(ctx context.Context, session PeerSession) error
+	// UpdateSession updates an existing session in the manager.
+	// If there is no session with the same sessionNonce, ErrSessionNotFound is returned.
+	UpdateSession(ctx context.Context, session PeerSession) error
+	// TouchSession sets the LastUpdate of the stored session with the sessionNonce and leaves the rest of it as stored,
+	// so it doesn't overwrite the changes made since the session was read, like UpdateSession of a stale copy would.
+	// If there is no session with the sessionNonce, ErrSessionNotFound is returned.
+	TouchSession(ctx context.Context, sessionNonce string, lastUpdate time.Time) error
 	// GetSession retrieves a session based on a given identifier, which can be:
 	// - A sessionNonce, or
 	// - A peerIdentityKey.
 	// If it is a `sessionNonce`, returns that exact session.
 	// If it is a `peerIdentityKey`, returns the "best" (e.g. most recently updated,
 	// authenticated) session associated with that peer, if any.
-	GetSession(identifier string) *PeerSession
+	// If there is no such session, ErrSessionNotFound is returned.
+	GetSession(ctx context.Context, identifier string) (*PeerSession, error)
+	// GetSessionsByIdentityKey retrieves all sessions associated with the peerIdentityKey,
+	// the most recently updated first. It returns an empty slice if there are none.
+	GetSessionsByIdentityKey(ctx context.Context, identityKey string) ([]PeerSession, error)
 	// RemoveSession removes a session from the manager by clearing all associated identifiers.
-	RemoveSession(session PeerSession)
+	RemoveSession(ctx context.Context, session PeerSession) error
+	// RemoveAllSessionsForPeer removes every session associated with the peerIdentityKey
+	// and returns how many sessions were removed.
+	RemoveAllSessionsForPeer(ctx context.Context, identityKey string) (int, error)
 	// HasSession checks if a session exists for a given identifier (either sessionNonce or identityKey).
 	// Returns true if the session exists, false otherwise.
-	HasSession(identifier string) bool
+	HasSession(ctx context.Context, identifier string) bool
 }
@@ -0,0 +1,34 @@
+package sessionmanager
+
+import (
+	"context"
+	"time"
+)
+
+// runEvery calls task every interval in a background goroutine until the SessionManager is closed.
+// The context passed to the task is cancelled by Close.
+func (m *SessionManager) runEvery(interval time.Duration, task func(ctx context.Context)) {
+	m.background.Add(1)
+	go func() {
+		defer m.background.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.backgroundCtx.Done():
+				return
+			case <-ticker.C:
+				task(m.backgroundCtx)
+			}
+		}
+	}()
+}
+
+// Close stops all background tasks of the SessionManager and waits for them to finish.
+// It is safe to call Close multiple times.
+func (m *SessionManager) Close() {
+	m.stop()
+	m.background.Wait()
+}
@@ -0,0 +1,56 @@
+package sessionmanager
+
+import "context"
+
+// LegacyInterface is the previous, context-free version of Interface.
+//
+// Deprecated: use Interface, LegacyInterface will be removed in the next release.
+type LegacyInterface interface {
+	AddSession(session PeerSession) error
+	UpdateSession(session PeerSession) error
+	GetSession(identifier string) (*PeerSession, error)
+	RemoveSession(session PeerSession) error
+	HasSession(identifier string) bool
+}
+
+var _ LegacyInterface = (*LegacyAdapter)(nil)
+
+// LegacyAdapter exposes an Interface with the previous, context-free method signatures.
+// Every call is made with context.Background(), so it can't be cancelled.
+//
+// Deprecated: pass a context to Interface directly, LegacyAdapter will be removed in the next release.
+type LegacyAdapter struct {
+	manager Interface
+}
+
+// NewLegacyAdapter wraps the manager with the previous, context-free method signatures.
+//
+// Deprecated: pass a context to Interface directly, NewLegacyAdapter will be removed in the next release.
+func NewLegacyAdapter(manager Interface) *LegacyAdapter {
+	return &LegacyAdapter{manager: manager}
+}
+
+// AddSession calls AddSession of the wrapped manager with context.Background().
+func (a *LegacyAdapter) AddSession(session PeerSession) error {
+	return a.manager.AddSession(context.Background(), session)
+}
+
+// UpdateSession calls UpdateSession of the wrapped manager with context.Background().
+func (a *LegacyAdapter) UpdateSession(session PeerSession) error {
+	return a.manager.UpdateSession(context.Background(), session)
+}
+
+// GetSession calls GetSession of the wrapped manager with context.Background().
+func (a *LegacyAdapter) GetSession(identifier string) (*PeerSession, error) {
+	return a.manager.GetSession(context.Background(), identifier)
+}
+
+// RemoveSession calls RemoveSession of the wrapped manager with context.Background().
+func (a *LegacyAdapter) RemoveSession(session PeerSession) error {
+	return a.manager.RemoveSession(context.Background(), session)
+}
+
+// HasSession calls HasSession of the wrapped manager with context.Background().
+func (a *LegacyAdapter) HasSession(identifier string) bool {
+	return a.manager.HasSession(context.Background(), identifier)
+}
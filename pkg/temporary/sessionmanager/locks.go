@@ -0,0 +1,37 @@
+package sessionmanager
+
+import "sync"
+
+// lockStripes is the number of mutexes the sessionNonces and the peerIdentityKeys are spread over.
+const lockStripes = 64
+
+// stripedLocks serializes the operations on the same key without a global lock.
+// The keys are hashed onto a fixed set of mutexes, so operations on unrelated keys rarely wait for each other.
+// The mutexes aren't reentrant: a caller holding a stripe must not lock another key of the same stripedLocks,
+// except both at once through lockPair.
+type stripedLocks [lockStripes]sync.Mutex
+
+// lock locks the stripe of the key and returns the function unlocking it.
+func (l *stripedLocks) lock(key string) func() {
+	mu := &l[hashKey(key)%lockStripes]
+	mu.Lock()
+	return mu.Unlock
+}
+
+// lockPair locks the stripes of both keys in the order of the stripes, so two callers locking the same pair can't deadlock,
+// and returns the function unlocking them.
+func (l *stripedLocks) lockPair(a, b string) func() {
+	i, j := hashKey(a)%lockStripes, hashKey(b)%lockStripes
+	if i == j {
+		return l.lock(a)
+	}
+	if i > j {
+		i, j = j, i
+	}
+	l[i].Lock()
+	l[j].Lock()
+	return func() {
+		l[j].Unlock()
+		l[i].Unlock()
+	}
+}
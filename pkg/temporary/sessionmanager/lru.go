@@ -0,0 +1,100 @@
+package sessionmanager
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// lruIndex orders sessionNonces from the most to the least recently used.
+// It has its own lock, because lookups don't lock the sessions they use.
+type lruIndex struct {
+	mu       sync.Mutex
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newLRUIndex() *lruIndex {
+	return &lruIndex{
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// popOverflow removes and returns the least recently used sessionNonce if there are more than capacity of them.
+// Popping it under the lock of the index lets concurrent callers evict distinct sessions.
+func (l *lruIndex) popOverflow(capacity int) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.order.Len() <= capacity {
+		return "", false
+	}
+	sessionNonce := l.order.Remove(l.order.Back()).(string)
+	delete(l.elements, sessionNonce)
+	return sessionNonce, true
+}
+
+// touch marks the sessionNonce as the most recently used, the caller must hold the stripe of the sessionNonce.
+func (m *SessionManager) touch(sessionNonce string) {
+	if m.recentlyUsed == nil {
+		return
+	}
+
+	m.recentlyUsed.mu.Lock()
+	defer m.recentlyUsed.mu.Unlock()
+
+	if element, exists := m.recentlyUsed.elements[sessionNonce]; exists {
+		m.recentlyUsed.order.MoveToFront(element)
+		return
+	}
+	m.recentlyUsed.elements[sessionNonce] = m.recentlyUsed.order.PushFront(sessionNonce)
+}
+
+// touchIfTracked marks the sessionNonce as the most recently used unless it was forgotten meanwhile,
+// so lookups, which don't lock the session, can't bring back the nonce of a concurrently removed session.
+func (m *SessionManager) touchIfTracked(sessionNonce string) {
+	if m.recentlyUsed == nil {
+		return
+	}
+
+	m.recentlyUsed.mu.Lock()
+	defer m.recentlyUsed.mu.Unlock()
+
+	if element, exists := m.recentlyUsed.elements[sessionNonce]; exists {
+		m.recentlyUsed.order.MoveToFront(element)
+	}
+}
+
+// forget drops the sessionNonce from the usage order, the caller must hold the stripe of the sessionNonce.
+func (m *SessionManager) forget(sessionNonce string) {
+	if m.recentlyUsed == nil {
+		return
+	}
+
+	m.recentlyUsed.mu.Lock()
+	defer m.recentlyUsed.mu.Unlock()
+
+	if element, exists := m.recentlyUsed.elements[sessionNonce]; exists {
+		m.recentlyUsed.order.Remove(element)
+		delete(m.recentlyUsed.elements, sessionNonce)
+	}
+}
+
+// evictLeastRecentlyUsed removes the least recently used sessions until the global capacity is respected.
+// The caller must not hold any stripe.
+func (m *SessionManager) evictLeastRecentlyUsed(ctx context.Context, events *sessionEvents) error {
+	if m.recentlyUsed == nil {
+		return nil
+	}
+
+	for {
+		sessionNonce, ok := m.recentlyUsed.popOverflow(m.maxSessions)
+		if !ok {
+			return nil
+		}
+		if _, err := m.removeSession(ctx, sessionNonce, eventEvicted, events, nil); err != nil {
+			return err
+		}
+	}
+}
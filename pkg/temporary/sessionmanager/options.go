@@ -0,0 +1,90 @@
+package sessionmanager
+
+import "time"
+
+// Option configures the SessionManager.
+type Option func(*SessionManager)
+
+// WithClock overrides the clock used by the SessionManager, it's mostly useful for testing.
+func WithClock(now func() time.Time) Option {
+	return func(m *SessionManager) {
+		m.now = now
+	}
+}
+
+// WithSessionSelector overrides the strategy used by GetSession to choose the "best" session for a peerIdentityKey.
+func WithSessionSelector(selector SessionSelector) Option {
+	return func(m *SessionManager) {
+		m.selectSession = selector
+	}
+}
+
+// WithCompactionInterval enables periodic compaction of the SessionManager internals.
+// The optional onCompacted callback receives the result of every run, e.g. to export it as metrics.
+func WithCompactionInterval(interval time.Duration, onCompacted func(CompactionResult)) Option {
+	return func(m *SessionManager) {
+		m.compactionInterval = interval
+		m.onCompacted = onCompacted
+	}
+}
+
+// WithHandshakeTimeout sets the deadline for peers to complete the handshake.
+// Unauthenticated sessions not updated within the timeout are periodically removed and the peer has to start over.
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(m *SessionManager) {
+		m.handshakeTimeout = timeout
+	}
+}
+
+// WithSessionTTL sets the time after which a session that hasn't been updated expires.
+// Expired sessions are treated as absent by GetSession and HasSession and are periodically removed in the background,
+// so the SessionManager must be closed with Close when it's no longer needed.
+func WithSessionTTL(ttl time.Duration) Option {
+	return func(m *SessionManager) {
+		m.sessionTTL = ttl
+	}
+}
+
+// WithSessionStore replaces the default in-memory store with the given SessionStore.
+func WithSessionStore(store SessionStore) Option {
+	return func(m *SessionManager) {
+		m.store = store
+	}
+}
+
+// WithMaxSessionsPerIdentity limits the number of concurrent sessions kept for a single peerIdentityKey,
+// DefaultMaxSessionsPerIdentity is used when not set. Adding a session beyond the limit evicts
+// the oldest unauthenticated session of the peer, or the oldest authenticated one if there are no unauthenticated.
+// A limit of zero or less disables the cap.
+func WithMaxSessionsPerIdentity(limit int) Option {
+	return func(m *SessionManager) {
+		m.maxSessionsPerIdentity = limit
+	}
+}
+
+// WithMaxSessions sets a hard limit on the number of sessions kept by the SessionManager.
+// Adding a session beyond the limit evicts the least recently used session, where both AddSession and GetSession
+// count as a use. The optional onEvicted callback receives every evicted session, e.g. to log which peer was evicted,
+// it's called after the internal lock is released. Only sessions added through the SessionManager are counted.
+// A limit of zero or less disables the bound.
+func WithMaxSessions(limit int, onEvicted func(PeerSession)) Option {
+	return func(m *SessionManager) {
+		m.maxSessions = limit
+		m.onEvicted = onEvicted
+	}
+}
+
+// WithNonceCreator sets the NonceCreator used by RotateSessionNonce, typically the wallet of the server.
+func WithNonceCreator(creator NonceCreator) Option {
+	return func(m *SessionManager) {
+		m.nonceCreator = creator
+	}
+}
+
+// WithRotationGracePeriod sets how long the old sessionNonce stays resolvable after RotateSessionNonce,
+// DefaultRotationGracePeriod is used when not set.
+func WithRotationGracePeriod(period time.Duration) Option {
+	return func(m *SessionManager) {
+		m.rotationGracePeriod = period
+	}
+}
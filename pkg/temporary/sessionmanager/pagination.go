@@ -0,0 +1,77 @@
+package sessionmanager
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+)
+
+const (
+	// DefaultPageSize is the number of sessions returned by ListSessions when no limit is given.
+	DefaultPageSize = 100
+	// MaxPageSize is the maximal number of sessions returned by a single ListSessions call.
+	MaxPageSize = 1000
+)
+
+// ListSessions returns a page of sessions ordered by their sessionNonce, starting after the given cursor.
+// Pass an empty cursor to get the first page and the returned nextCursor to get the following one,
+// an empty nextCursor means there are no more sessions. Sessions added or removed between the pages
+// don't break the iteration, they're just included or skipped. A cursor which can't be decoded yields an empty page.
+// A limit of zero or less uses DefaultPageSize, limits above MaxPageSize are capped.
+func (m *SessionManager) ListSessions(ctx context.Context, cursor string, limit int) ([]PeerSession, string, error) {
+	if ctx.Err() != nil {
+		return nil, "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	after, ok := decodeCursor(cursor)
+	if !ok {
+		return []PeerSession{}, "", nil
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	sessions, err := m.store.List(ctx)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list sessions: %w", err)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return *sessions[i].SessionNonce < *sessions[j].SessionNonce
+	})
+
+	start := sort.Search(len(sessions), func(i int) bool {
+		return *sessions[i].SessionNonce > after
+	})
+
+	page := make([]PeerSession, 0, limit)
+	nextCursor := ""
+	for _, session := range sessions[start:] {
+		if m.isExpired(session) {
+			continue
+		}
+		if len(page) == limit {
+			nextCursor = encodeCursor(*page[len(page)-1].SessionNonce)
+			break
+		}
+		page = append(page, session)
+	}
+	return page, nextCursor, nil
+}
+
+// encodeCursor makes the cursor opaque, so the callers don't rely on it being a sessionNonce.
+func encodeCursor(sessionNonce string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sessionNonce))
+}
+
+func decodeCursor(cursor string) (string, bool) {
+	if cursor == "" {
+		return "", true
+	}
+	sessionNonce, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(sessionNonce) == 0 {
+		return "", false
+	}
+	return string(sessionNonce), true
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+)
+
+// Option configures the Redis SessionManager.
+type Option func(*SessionManager)
+
+// WithKeyPrefix sets the prefix of all keys written by the SessionManager, so several instances can share one Redis.
+func WithKeyPrefix(prefix string) Option {
+	return func(m *SessionManager) {
+		m.keyPrefix = prefix
+	}
+}
+
+// WithSessionTTL makes the stored sessions expire in Redis once they haven't been updated for the given duration.
+func WithSessionTTL(ttl time.Duration) Option {
+	return func(m *SessionManager) {
+		m.sessionTTL = ttl
+	}
+}
+
+// WithSessionSelector overrides the strategy used by GetSession to choose the "best" session for a peerIdentityKey.
+func WithSessionSelector(selector sessionmanager.SessionSelector) Option {
+	return func(m *SessionManager) {
+		m.selectSession = selector
+	}
+}
+
+// WithLogger sets the logger used to report Redis failures which can't be returned to the caller.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *SessionManager) {
+		m.logger = logger
+	}
+}
+
+// WithClock overrides the clock used by the SessionManager, it's mostly useful for testing.
+func WithClock(now func() time.Time) Option {
+	return func(m *SessionManager) {
+		m.now = now
+	}
+}
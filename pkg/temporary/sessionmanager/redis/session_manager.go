@@ -0,0 +1,432 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/internal/logging"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const defaultKeyPrefix = "bsv-auth:"
+
+// maxPutAttempts bounds the retries of a session change losing the race with a concurrent change of the same session.
+const maxPutAttempts = 32
+
+// putRetryBackoff is the unit of the random delay before the retry of a session change, growing with the attempts,
+// so the concurrent changes of a session spread out instead of failing each other again.
+const putRetryBackoff = time.Millisecond
+
+var _ sessionmanager.Interface = (*SessionManager)(nil)
+
+// SessionManager is a SessionManager implementation storing the sessions in Redis,
+// so they can be shared between multiple replicas of a service.
+//
+// Every PeerSession is stored in the format of PeerSession.MarshalBinary under its sessionNonce,
+// and every peerIdentityKey has a Redis set holding the nonces of its sessions.
+type SessionManager struct {
+	client        goredis.UniversalClient
+	keyPrefix     string
+	sessionTTL    time.Duration
+	selectSession sessionmanager.SessionSelector
+	logger        *slog.Logger
+	now           func() time.Time
+}
+
+// NewSessionManager creates a new Redis backed SessionManager using the given client.
+func NewSessionManager(client goredis.UniversalClient, opts ...Option) *SessionManager {
+	m := &SessionManager{
+		client:        client,
+		keyPrefix:     defaultKeyPrefix,
+		selectSession: sessionmanager.DefaultSessionSelector,
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.logger = logging.Child(m.logger, "redis-session-manager")
+	return m
+}
+
+// AddSession stores the session under its sessionNonce and adds the nonce to the set of its peerIdentityKey.
+// This does NOT overwrite existing sessions for the same peerIdentityKey, allowing multiple concurrent sessions.
+// Adding a session with an already stored sessionNonce fails with sessionmanager.ErrSessionAlreadyExists,
+// a session which outlived the session TTL with sessionmanager.ErrSessionExpired,
+// and a session breaking the rules of PeerSession.Validate is rejected with sessionmanager.ErrInvalidSession.
+func (m *SessionManager) AddSession(ctx context.Context, session sessionmanager.PeerSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	return m.putSession(ctx, *session.SessionNonce, func(previous *sessionmanager.PeerSession) (sessionmanager.PeerSession, error) {
+		if previous != nil {
+			return sessionmanager.PeerSession{}, sessionmanager.ErrSessionAlreadyExists
+		}
+		return session, nil
+	})
+}
+
+// UpdateSession replaces the stored session with the same sessionNonce, moving it to the set of its new peerIdentityKey
+// if it changed. Updating a session which isn't stored fails with sessionmanager.ErrSessionNotFound.
+// The session is validated the same way as in AddSession.
+func (m *SessionManager) UpdateSession(ctx context.Context, session sessionmanager.PeerSession) error {
+	if err := session.Validate(); err != nil {
+		return err
+	}
+	return m.putSession(ctx, *session.SessionNonce, func(previous *sessionmanager.PeerSession) (sessionmanager.PeerSession, error) {
+		if previous == nil {
+			return sessionmanager.PeerSession{}, sessionmanager.ErrSessionNotFound
+		}
+		return session, nil
+	})
+}
+
+// TouchSession sets the LastUpdate of the stored session with the sessionNonce, keeping its other fields as they're stored.
+// Touching a session which isn't stored fails with sessionmanager.ErrSessionNotFound.
+func (m *SessionManager) TouchSession(ctx context.Context, sessionNonce string, lastUpdate time.Time) error {
+	return m.putSession(ctx, sessionNonce, func(previous *sessionmanager.PeerSession) (sessionmanager.PeerSession, error) {
+		if previous == nil {
+			return sessionmanager.PeerSession{}, sessionmanager.ErrSessionNotFound
+		}
+		touched := *previous
+		touched.LastUpdate = lastUpdate
+		return touched, nil
+	})
+}
+
+// putSession stores the session the change returns for the one stored under the sessionNonce, nil if there is none.
+// The session key is watched, so a concurrent change of the same session makes the transaction fail instead of
+// leaving the identity sets inconsistent or losing the change. The failed transaction is retried with the change
+// applied to the newly stored session, after a random delay, up to maxPutAttempts times.
+func (m *SessionManager) putSession(ctx context.Context, sessionNonce string, change func(previous *sessionmanager.PeerSession) (sessionmanager.PeerSession, error)) error {
+	sessionKey := m.sessionKey(sessionNonce)
+
+	put := func(tx *goredis.Tx) error {
+		previous, err := m.getSessionByKey(ctx, tx, sessionKey)
+		if err != nil {
+			return err
+		}
+		session, err := change(previous)
+		if err != nil {
+			return err
+		}
+
+		expireAt, ok := m.expiration(session)
+		if !ok {
+			return sessionmanager.ErrSessionExpired
+		}
+
+		data, err := encodeSession(session)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.SetArgs(ctx, sessionKey, data, goredis.SetArgs{ExpireAt: expireAt})
+
+			if previous != nil && previous.PeerIdentityKey != nil &&
+				(session.PeerIdentityKey == nil || *previous.PeerIdentityKey != *session.PeerIdentityKey) {
+				pipe.SRem(ctx, m.identityKey(*previous.PeerIdentityKey), *session.SessionNonce)
+			}
+			if session.PeerIdentityKey != nil {
+				identityKey := m.identityKey(*session.PeerIdentityKey)
+				pipe.SAdd(ctx, identityKey, *session.SessionNonce)
+				if !expireAt.IsZero() {
+					// the set lives as long as its longest session, so an older session never shortens it
+					pipe.Do(ctx, "pexpireat", identityKey, expireAt.UnixMilli(), "NX")
+					pipe.Do(ctx, "pexpireat", identityKey, expireAt.UnixMilli(), "GT")
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to store session: %w", err)
+		}
+		return nil
+	}
+
+	for attempt := range maxPutAttempts {
+		err := m.client.Watch(ctx, put, sessionKey)
+		if !errors.Is(err, goredis.TxFailedErr) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(rand.N(time.Duration(attempt+1) * putRetryBackoff)):
+		}
+	}
+	return fmt.Errorf("session was modified concurrently %d times: %w", maxPutAttempts, goredis.TxFailedErr)
+}
+
+// GetSession retrieves a session by its sessionNonce, or the "best" session of a peerIdentityKey.
+func (m *SessionManager) GetSession(ctx context.Context, identifier string) (*sessionmanager.PeerSession, error) {
+	session, err := m.getSessionByNonce(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		return session, nil
+	}
+
+	sessions, err := m.getSessionsByIdentityKey(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, sessionmanager.ErrSessionNotFound
+	}
+
+	bestSession := m.selectSession(sessions)
+	if bestSession == nil {
+		return nil, sessionmanager.ErrSessionNotFound
+	}
+	return bestSession, nil
+}
+
+// GetSessionsByIdentityKey retrieves all sessions of the peerIdentityKey, the most recently updated first.
+func (m *SessionManager) GetSessionsByIdentityKey(ctx context.Context, identityKey string) ([]sessionmanager.PeerSession, error) {
+	sessions, err := m.getSessionsByIdentityKey(ctx, identityKey)
+	if err != nil {
+		return nil, err
+	}
+	sessionmanager.SortByMostRecent(sessions)
+	return sessions, nil
+}
+
+// RemoveSession removes the session and its nonce from the set of its peerIdentityKey.
+func (m *SessionManager) RemoveSession(ctx context.Context, session sessionmanager.PeerSession) error {
+	if session.SessionNonce == nil {
+		return nil
+	}
+	_, err := m.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Del(ctx, m.sessionKey(*session.SessionNonce))
+		if session.PeerIdentityKey != nil {
+			pipe.SRem(ctx, m.identityKey(*session.PeerIdentityKey), *session.SessionNonce)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove session: %w", err)
+	}
+	return nil
+}
+
+// RemoveAllSessionsForPeer removes every session of the peerIdentityKey and returns how many sessions were removed.
+// Only the nonces read at the start are removed from the identity set, so sessions added concurrently stay indexed.
+func (m *SessionManager) RemoveAllSessionsForPeer(ctx context.Context, identityKey string) (int, error) {
+	nonces, err := m.client.SMembers(ctx, m.identityKey(identityKey)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session nonces: %w", err)
+	}
+	if len(nonces) == 0 {
+		return 0, nil
+	}
+
+	members := make([]any, len(nonces))
+	for i, nonce := range nonces {
+		members[i] = nonce
+	}
+
+	var deleted *goredis.IntCmd
+	_, err = m.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		deleted = pipe.Del(ctx, m.sessionKeys(nonces)...)
+		pipe.SRem(ctx, m.identityKey(identityKey), members...)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove sessions: %w", err)
+	}
+	return int(deleted.Val()), nil
+}
+
+// Compact sweeps the sets of all peerIdentityKeys and removes the nonces whose sessions expired,
+// which are otherwise pruned only when the peer is looked up. It returns how many nonces were removed.
+// The sets are scanned incrementally, so Compact doesn't block Redis, but it should run infrequently.
+func (m *SessionManager) Compact(ctx context.Context) (int, error) {
+	pruned := 0
+	iter := m.client.Scan(ctx, 0, m.identityKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		removed, err := m.pruneIdentitySet(ctx, iter.Val())
+		if err != nil {
+			return pruned, err
+		}
+		pruned += removed
+	}
+	if err := iter.Err(); err != nil {
+		return pruned, fmt.Errorf("failed to scan identity keys: %w", err)
+	}
+	return pruned, nil
+}
+
+// pruneIdentitySet removes the nonces without a stored session from the identity set stored under the key.
+func (m *SessionManager) pruneIdentitySet(ctx context.Context, key string) (int, error) {
+	nonces, err := m.client.SMembers(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get session nonces: %w", err)
+	}
+	if len(nonces) == 0 {
+		return 0, nil
+	}
+
+	exists := make([]*goredis.IntCmd, len(nonces))
+	_, err = m.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, nonce := range nonces {
+			exists[i] = pipe.Exists(ctx, m.sessionKey(nonce))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to check sessions: %w", err)
+	}
+
+	var dangling []any
+	for i, nonce := range nonces {
+		if exists[i].Val() == 0 {
+			dangling = append(dangling, nonce)
+		}
+	}
+	if len(dangling) == 0 {
+		return 0, nil
+	}
+
+	removed, err := m.client.SRem(ctx, key, dangling...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune session nonces: %w", err)
+	}
+	return int(removed), nil
+}
+
+// HasSession checks if a session exists for a given identifier (either sessionNonce or identityKey).
+// Redis failures are logged and reported as no session.
+func (m *SessionManager) HasSession(ctx context.Context, identifier string) bool {
+	exists, err := m.client.Exists(ctx, m.sessionKey(identifier)).Result()
+	if err != nil {
+		m.logger.Error("Failed to check session", logging.Error(err))
+		return false
+	}
+	if exists > 0 {
+		return true
+	}
+
+	nonces, err := m.client.SMembers(ctx, m.identityKey(identifier)).Result()
+	if err != nil {
+		m.logger.Error("Failed to check sessions by identity key", logging.Error(err))
+		return false
+	}
+	if len(nonces) == 0 {
+		return false
+	}
+
+	exists, err = m.client.Exists(ctx, m.sessionKeys(nonces)...).Result()
+	if err != nil {
+		m.logger.Error("Failed to check sessions by identity key", logging.Error(err))
+		return false
+	}
+	return exists > 0
+}
+
+func (m *SessionManager) getSessionByNonce(ctx context.Context, sessionNonce string) (*sessionmanager.PeerSession, error) {
+	return m.getSessionByKey(ctx, m.client, m.sessionKey(sessionNonce))
+}
+
+func (m *SessionManager) getSessionByKey(ctx context.Context, client goredis.Cmdable, sessionKey string) (*sessionmanager.PeerSession, error) {
+	data, err := client.Get(ctx, sessionKey).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	session, err := decodeSession(data)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// getSessionsByIdentityKey fetches all sessions of the peerIdentityKey and prunes the nonces of sessions that expired.
+func (m *SessionManager) getSessionsByIdentityKey(ctx context.Context, identityKey string) ([]sessionmanager.PeerSession, error) {
+	nonces, err := m.client.SMembers(ctx, m.identityKey(identityKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session nonces: %w", err)
+	}
+	if len(nonces) == 0 {
+		return nil, nil
+	}
+
+	values, err := m.client.MGet(ctx, m.sessionKeys(nonces)...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions: %w", err)
+	}
+
+	sessions := make([]sessionmanager.PeerSession, 0, len(values))
+	var dangling []any
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			dangling = append(dangling, nonces[i])
+			continue
+		}
+
+		session, err := decodeSession([]byte(data))
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if len(dangling) > 0 {
+		if err := m.client.SRem(ctx, m.identityKey(identityKey), dangling...).Err(); err != nil {
+			m.logger.Warn("Failed to prune expired session nonces", logging.Error(err))
+		}
+	}
+	return sessions, nil
+}
+
+// expiration computes when the session should expire in Redis.
+// It returns false if the session is already expired and should not be stored at all.
+func (m *SessionManager) expiration(session sessionmanager.PeerSession) (time.Time, bool) {
+	if m.sessionTTL <= 0 {
+		return time.Time{}, true
+	}
+	expireAt := session.LastUpdate.Add(m.sessionTTL)
+	return expireAt, expireAt.After(m.now())
+}
+
+func (m *SessionManager) sessionKey(sessionNonce string) string {
+	return m.keyPrefix + "session:" + sessionNonce
+}
+
+func (m *SessionManager) sessionKeys(sessionNonces []string) []string {
+	keys := make([]string, len(sessionNonces))
+	for i, nonce := range sessionNonces {
+		keys[i] = m.sessionKey(nonce)
+	}
+	return keys
+}
+
+func (m *SessionManager) identityKey(peerIdentityKey string) string {
+	return m.keyPrefix + "identity:" + peerIdentityKey
+}
+
+func encodeSession(session sessionmanager.PeerSession) ([]byte, error) {
+	data, err := session.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode session: %w", err)
+	}
+	return data, nil
+}
+
+func decodeSession(data []byte) (sessionmanager.PeerSession, error) {
+	var session sessionmanager.PeerSession
+	if err := session.UnmarshalBinary(data); err != nil {
+		return sessionmanager.PeerSession{}, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return session, nil
+}
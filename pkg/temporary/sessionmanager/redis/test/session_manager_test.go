@@ -0,0 +1,531 @@
+package redis_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager/redis"
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSessionManager(t *testing.T, opts ...redis.Option) (*redis.SessionManager, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return redis.NewSessionManager(client, opts...), server
+}
+
+// newSessions creates sessions of a single peer with distinct, UTC based LastUpdate values,
+// so they survive the round trip through Redis unchanged.
+func newSessions(t *testing.T, count int) []sessionmanager.PeerSession {
+	sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, count)
+	base := time.Now().UTC().Truncate(time.Second)
+	for i := range sessions {
+		sessions[i].LastUpdate = base.Add(time.Duration(i) * time.Second)
+	}
+	return sessions
+}
+
+func TestRedisSessionManager_HappyPath(t *testing.T) {
+	t.Run("Add and get session by both keys", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, session, *retrievedSession)
+
+		retrievedSession, err = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, session, *retrievedSession)
+
+		require.True(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+
+	t.Run("Correctly get best session by identity key", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		sessions := newSessions(t, 3)
+		sessions[0].IsAuthenticated = true
+
+		// when
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+
+		// then - the "best" session should be the authenticated one
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[0], *retrievedSession)
+	})
+
+	t.Run("Get all sessions by identity key", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		sessions := newSessions(t, 3)
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+
+		// when
+		retrievedSessions, err := sessionManager.GetSessionsByIdentityKey(t.Context(), *sessions[0].PeerIdentityKey)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []sessionmanager.PeerSession{sessions[2], sessions[1], sessions[0]}, retrievedSessions)
+	})
+
+	t.Run("Remove all sessions for peer", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		sessions := newSessions(t, 3)
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+		identityKey := *sessions[0].PeerIdentityKey
+		// the first session leaves a dangling nonce in the identity set
+		require.True(t, server.Del("bsv-auth:session:"+*sessions[0].SessionNonce))
+
+		// when
+		removed, err := sessionManager.RemoveAllSessionsForPeer(t.Context(), identityKey)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+		require.False(t, sessionManager.HasSession(t.Context(), identityKey))
+		require.False(t, server.Exists("bsv-auth:identity:"+identityKey))
+	})
+
+	t.Run("Update session", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		session.IsAuthenticated = true
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), session))
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, session, *retrievedSession)
+	})
+
+	t.Run("Update session with changed identity key", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		oldIdentityKey := *session.PeerIdentityKey
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		newIdentityKey := "new-identity-key"
+		session.PeerIdentityKey = &newIdentityKey
+		err := sessionManager.UpdateSession(t.Context(), session)
+
+		// then
+		require.NoError(t, err)
+		require.False(t, server.Exists("bsv-auth:identity:"+oldIdentityKey))
+		require.False(t, sessionManager.HasSession(t.Context(), oldIdentityKey))
+
+		retrievedSession, err := sessionManager.GetSession(t.Context(), newIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, session, *retrievedSession)
+	})
+
+	t.Run("Remove session", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+
+		// then
+		_, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		_, err = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+
+	t.Run("Touch session keeps the metadata written since it was read", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t, redis.WithSessionTTL(time.Hour))
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		concurrent := session.Clone()
+		concurrent.SetMeta("tenant", "tenant-a")
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), concurrent))
+
+		// when
+		lastUpdate := session.LastUpdate.Add(time.Minute)
+		err := sessionManager.TouchSession(t.Context(), *session.SessionNonce, lastUpdate)
+
+		// then
+		require.NoError(t, err)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, lastUpdate, retrievedSession.LastUpdate)
+		tenant, ok := retrievedSession.GetMeta("tenant")
+		require.True(t, ok)
+		require.Equal(t, "tenant-a", tenant)
+	})
+
+	t.Run("Concurrent touches of the same session all succeed", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t, redis.WithSessionTTL(time.Hour))
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		tenant := session.Clone()
+		tenant.SetMeta("tenant", "tenant-a")
+
+		// when
+		var wg sync.WaitGroup
+		errs := make(chan error, 8*10+1)
+		for i := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := range 10 {
+					errs <- sessionManager.TouchSession(t.Context(), *session.SessionNonce, session.LastUpdate.Add(time.Duration(i*10+j)*time.Second))
+				}
+			}()
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- sessionManager.UpdateSession(t.Context(), tenant)
+		}()
+		wg.Wait()
+		close(errs)
+
+		// then
+		for err := range errs {
+			require.NoError(t, err)
+		}
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		_, ok := retrievedSession.GetMeta("tenant")
+		require.True(t, ok)
+	})
+
+	t.Run("Store sessions in the versioned format", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// then
+		stored, err := server.Get("bsv-auth:session:" + *session.SessionNonce)
+		require.NoError(t, err)
+		expected, err := session.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, string(expected), stored)
+	})
+
+	t.Run("Use key prefix", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t, redis.WithKeyPrefix("tenant-a:"))
+		session := newSessions(t, 1)[0]
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// then
+		require.True(t, server.Exists("tenant-a:session:"+*session.SessionNonce))
+		require.True(t, server.Exists("tenant-a:identity:"+*session.PeerIdentityKey))
+	})
+}
+
+func TestRedisSessionManager_Expiration(t *testing.T) {
+	t.Run("Session keys expire with the session", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t, redis.WithSessionTTL(time.Hour))
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		server.FastForward(2 * time.Hour)
+
+		// then
+		_, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		_, err = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+
+	t.Run("Expired nonces are pruned from the identity set", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		sessions := newSessions(t, 2)
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+		server.Del("bsv-auth:session:" + *sessions[0].SessionNonce)
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[1], *retrievedSession)
+
+		members, err := server.SMembers("bsv-auth:identity:" + *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, []string{*sessions[1].SessionNonce}, members)
+	})
+
+	t.Run("Compact sweeps the nonces of expired sessions", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		sessions := newSessions(t, 3)
+		other := newSessions(t, 1)[0]
+		for _, session := range append(sessions, other) {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+		server.Del("bsv-auth:session:" + *sessions[0].SessionNonce)
+		server.Del("bsv-auth:session:" + *sessions[1].SessionNonce)
+
+		// when
+		pruned, err := sessionManager.Compact(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 2, pruned)
+		members, err := server.SMembers("bsv-auth:identity:" + *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, []string{*sessions[2].SessionNonce}, members)
+		members, err = server.SMembers("bsv-auth:identity:" + *other.PeerIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, []string{*other.SessionNonce}, members)
+
+		// when
+		server.Del("bsv-auth:session:" + *sessions[2].SessionNonce)
+		require.False(t, sessionManager.HasSession(t.Context(), *sessions[0].PeerIdentityKey))
+		pruned, err = sessionManager.Compact(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 1, pruned)
+		require.False(t, server.Exists("bsv-auth:identity:"+*sessions[0].PeerIdentityKey))
+	})
+
+	t.Run("Already expired session is not stored", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t, redis.WithSessionTTL(time.Hour))
+		session := newSessions(t, 1)[0]
+		session.LastUpdate = session.LastUpdate.Add(-2 * time.Hour)
+
+		// when
+		err := sessionManager.AddSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionExpired)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("Already expired session is not updated", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t, redis.WithSessionTTL(time.Hour))
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		stale := session
+		stale.IsAuthenticated = !session.IsAuthenticated
+		stale.LastUpdate = session.LastUpdate.Add(-2 * time.Hour)
+
+		// when
+		err := sessionManager.UpdateSession(t.Context(), stale)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionExpired)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, session, *retrievedSession)
+	})
+
+	t.Run("Identity set outlives its longest session", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t, redis.WithSessionTTL(time.Hour))
+		sessions := newSessions(t, 2)
+		newer, older := sessions[1], sessions[0]
+		older.LastUpdate = newer.LastUpdate.Add(-30 * time.Minute)
+		require.NoError(t, sessionManager.AddSession(t.Context(), newer))
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), older))
+		server.FastForward(45 * time.Minute)
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *newer.PeerIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, newer, *retrievedSession)
+	})
+}
+
+func TestRedisSessionManager_ErrorPath(t *testing.T) {
+	t.Run("Get non-existent session", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), "non-existent-key")
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.Nil(t, retrievedSession)
+		require.False(t, sessionManager.HasSession(t.Context(), "non-existent-key"))
+	})
+
+	t.Run("Touch non-existent session", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+
+		// when
+		err := sessionManager.TouchSession(t.Context(), "non-existent-nonce", time.Now())
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), "non-existent-nonce"))
+	})
+
+	t.Run("Add session twice", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		duplicate := session
+		duplicate.IsAuthenticated = true
+		err := sessionManager.AddSession(t.Context(), duplicate)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionAlreadyExists)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, session, *retrievedSession)
+	})
+
+	t.Run("Add invalid session", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		session.IsAuthenticated = true
+		session.PeerIdentityKey = nil
+
+		// when
+		err := sessionManager.AddSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrInvalidSession)
+		require.False(t, server.Exists("bsv-auth:session:"+*session.SessionNonce))
+	})
+
+	t.Run("Update non-existent session", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+
+		// when
+		err := sessionManager.UpdateSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, server.Exists("bsv-auth:session:"+*session.SessionNonce))
+		require.False(t, server.Exists("bsv-auth:identity:"+*session.PeerIdentityKey))
+	})
+
+	t.Run("Update after remove", func(t *testing.T) {
+		// given
+		sessionManager, _ := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+
+		// when
+		err := sessionManager.UpdateSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("Redis unavailable", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		server.Close()
+
+		// when
+		err := sessionManager.AddSession(t.Context(), session)
+
+		// then
+		require.Error(t, err)
+
+		// when
+		_, err = sessionManager.GetSession(t.Context(), *session.SessionNonce)
+
+		// then
+		require.Error(t, err)
+		require.NotErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		session := newSessions(t, 1)[0]
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		// when
+		err := sessionManager.AddSession(ctx, session)
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		require.False(t, server.Exists("bsv-auth:session:"+*session.SessionNonce))
+
+		// when
+		_, err = sessionManager.GetSession(ctx, *session.SessionNonce)
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("Corrupted session data", func(t *testing.T) {
+		// given
+		sessionManager, server := newTestSessionManager(t)
+		require.NoError(t, server.Set("bsv-auth:session:corrupted", "{not-json"))
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), "corrupted")
+
+		// then
+		require.Error(t, err)
+		require.NotErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.Nil(t, retrievedSession)
+	})
+}
@@ -0,0 +1,111 @@
+package sessionmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRotationGracePeriod is the default time the old sessionNonce stays resolvable after a rotation.
+const DefaultRotationGracePeriod = 30 * time.Second
+
+// ErrNoNonceCreator is returned by RotateSessionNonce when the SessionManager has no NonceCreator configured.
+var ErrNoNonceCreator = errors.New("no nonce creator configured")
+
+// NonceCreator creates nonces for rotated sessions, it's satisfied by the wallet of the server.
+type NonceCreator interface {
+	CreateNonce(ctx context.Context) (string, error)
+}
+
+// rotatedNonce points from a rotated sessionNonce to its replacement until the grace period ends.
+type rotatedNonce struct {
+	replacement string
+	until       time.Time
+}
+
+// RotateSessionNonce re-keys the session with the oldNonce under a new nonce created by the configured NonceCreator
+// and refreshes its LastUpdate. The old nonce stays resolvable for the rotation grace period,
+// so in-flight requests using it still find the session, after that it's forgotten.
+func (m *SessionManager) RotateSessionNonce(ctx context.Context, oldNonce string) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	if m.nonceCreator == nil {
+		return "", ErrNoNonceCreator
+	}
+
+	// the nonce is created before locking, so a slow wallet doesn't block the other operations
+	newNonce, err := m.nonceCreator.CreateNonce(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create nonce: %w", err)
+	}
+
+	unlock := m.sessionLocks.lockPair(oldNonce, newNonce)
+	defer unlock()
+
+	session, exists, err := m.store.GetByNonce(ctx, oldNonce)
+	if err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if !exists || m.isExpired(session) {
+		return "", ErrSessionNotFound
+	}
+	if _, taken, err := m.store.GetByNonce(ctx, newNonce); err != nil {
+		return "", fmt.Errorf("failed to get session: %w", err)
+	} else if taken {
+		return "", errors.New("created nonce is already used by another session")
+	}
+
+	rotated := session.Clone()
+	rotated.SessionNonce = &newNonce
+	rotated.LastUpdate = m.now()
+
+	if err := m.store.Put(ctx, rotated); err != nil {
+		return "", fmt.Errorf("failed to store session: %w", err)
+	}
+	// the old nonce resolves to the new one before it's deleted, so the lookups, which don't lock, always find the session
+	m.recordRotation(oldNonce, newNonce)
+	if err := m.store.Delete(ctx, oldNonce); err != nil {
+		return "", fmt.Errorf("failed to delete session: %w", err)
+	}
+	m.forget(oldNonce)
+	m.touch(newNonce)
+
+	return newNonce, nil
+}
+
+// recordRotation makes the oldNonce resolve to the newNonce for the rotation grace period.
+// The rotations are recorded one at a time, while the lookups resolve the nonces without locking.
+func (m *SessionManager) recordRotation(oldNonce, newNonce string) {
+	m.rotationMu.Lock()
+	defer m.rotationMu.Unlock()
+
+	now := m.now()
+	m.rotatedNonces.Range(func(nonce, value any) bool {
+		alias := value.(rotatedNonce)
+		switch {
+		case now.After(alias.until):
+			m.rotatedNonces.Delete(nonce)
+		case alias.replacement == oldNonce:
+			// nonces rotated earlier now point to the latest replacement
+			m.rotatedNonces.Store(nonce, rotatedNonce{replacement: newNonce, until: alias.until})
+		}
+		return true
+	})
+	m.rotatedNonces.Store(oldNonce, rotatedNonce{replacement: newNonce, until: now.Add(m.rotationGracePeriod)})
+}
+
+// resolveRotatedNonce returns the replacement of the identifier if it's a sessionNonce rotated within the grace period,
+// otherwise the identifier itself.
+func (m *SessionManager) resolveRotatedNonce(identifier string) string {
+	value, exists := m.rotatedNonces.Load(identifier)
+	if !exists {
+		return identifier
+	}
+	alias := value.(rotatedNonce)
+	if m.now().After(alias.until) {
+		return identifier
+	}
+	return alias.replacement
+}
@@ -0,0 +1,44 @@
+package sessionmanager
+
+import "sort"
+
+// SessionSelector picks the "best" session out of all sessions associated with a single peerIdentityKey.
+// It returns nil if none of the sessions should be used.
+type SessionSelector func(sessions []PeerSession) *PeerSession
+
+// DefaultSessionSelector selects the most recent session, or the most recent authenticated one if there are any.
+func DefaultSessionSelector(sessions []PeerSession) *PeerSession {
+	var bestSession *PeerSession
+	for i := range sessions {
+		session := &sessions[i]
+
+		// If no session is selected yet, set the current session
+		if bestSession == nil {
+			bestSession = session
+			continue
+		}
+
+		// If the current session is authenticated and the bestSession is not, update bestSession
+		if session.IsAuthenticated && !bestSession.IsAuthenticated {
+			bestSession = session
+			continue
+		}
+
+		// If both are authenticated or both are not, select the most recent one
+		if session.IsAuthenticated == bestSession.IsAuthenticated && session.LastUpdate.After(bestSession.LastUpdate) {
+			bestSession = session
+		}
+	}
+	return bestSession
+}
+
+// SortByMostRecent sorts the sessions from the most to the least recently updated.
+// Sessions updated at the same time are ordered by their sessionNonce, so the order is deterministic.
+func SortByMostRecent(sessions []PeerSession) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUpdate.Equal(sessions[j].LastUpdate) {
+			return sessions[i].LastUpdate.After(sessions[j].LastUpdate)
+		}
+		return sessions[i].GetSessionNonce() < sessions[j].GetSessionNonce()
+	})
+}
@@ -1,166 +1,442 @@
 package sessionmanager
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"time"
 )
 
+var _ Interface = (*SessionManager)(nil)
+
 // SessionManager is a mock implementation of the SessionManager interface.
+// It's a policy layer (best session selection, expiration, statistics) over a pluggable SessionStore.
+//
+// There is no global lock: lookups only rely on the store, while the operations changing a session lock the stripe
+// of its sessionNonce and the operations spanning the sessions of a peer, e.g. the per identity cap, the stripe of its peerIdentityKey.
+// A peerIdentityKey stripe is always locked before a sessionNonce stripe, and at most one stripe of each kind is held,
+// except the two sessionNonces of a rotation locked together through lockPair.
 type SessionManager struct {
-	mu sync.Mutex
-	// sessions is a map of sessionNonce to a Session
-	sessions map[string]PeerSession
-	// identityKeyToSessions is a map of peerIdentityKey to a list of sessionNonce's
-	identityKeyToSessions map[string][]string
+	// sessionLocks serialize the changes of the sessions, striped by the sessionNonce
+	sessionLocks stripedLocks
+	// identityLocks serialize the changes spanning the sessions of a peer, striped by the peerIdentityKey
+	identityLocks stripedLocks
+	// store keeps the sessions and the peerIdentityKey index
+	store SessionStore
+	// stats holds the incrementally maintained aggregates
+	stats *sessionStats
+	// selectSession picks the "best" session for a peerIdentityKey
+	selectSession SessionSelector
+	now           func() time.Time
+
+	compactionInterval time.Duration
+	onCompacted        func(CompactionResult)
+	handshakeTimeout   time.Duration
+	sessionTTL         time.Duration
+
+	maxSessionsPerIdentity int
+	maxSessions            int
+	onEvicted              func(PeerSession)
+	nonceCreator           NonceCreator
+	rotationGracePeriod    time.Duration
+	// rotationMu serializes the changes of rotatedNonces
+	rotationMu sync.Mutex
+	// rotatedNonces maps the rotated sessionNonces to their rotatedNonce replacements during the grace period
+	rotatedNonces sync.Map
+	// recentlyUsed orders the sessionNonces by their last use, it's maintained only when maxSessions is set
+	recentlyUsed *lruIndex
+
+	hooks sessionHooks
+
+	// backgroundCtx is cancelled by Close to terminate the background tasks
+	backgroundCtx context.Context
+	stop          context.CancelFunc
+	background    sync.WaitGroup
 }
 
-// NewSessionManager creates a new SessionManager.
-func NewSessionManager() *SessionManager {
-	return &SessionManager{
-		sessions:              make(map[string]PeerSession),
-		identityKeyToSessions: make(map[string][]string),
+// NewSessionManager creates a new SessionManager, by default backed by a MemoryStore.
+func NewSessionManager(opts ...Option) *SessionManager {
+	m := &SessionManager{
+		store:         NewMemoryStore(),
+		stats:         newSessionStats(),
+		selectSession: DefaultSessionSelector,
+		now:           time.Now,
+
+		maxSessionsPerIdentity: DefaultMaxSessionsPerIdentity,
+		rotationGracePeriod:    DefaultRotationGracePeriod,
+	}
+	m.backgroundCtx, m.stop = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.maxSessions > 0 {
+		m.recentlyUsed = newLRUIndex()
+	}
+
+	if m.compactionInterval > 0 {
+		m.runEvery(m.compactionInterval, func(context.Context) { m.runCompaction() })
 	}
+	// errors of the background tasks are ignored, the next run will retry
+	if m.sessionTTL > 0 {
+		m.runEvery(m.sessionTTL, func(ctx context.Context) { _, _ = m.RemoveExpiredSessions(ctx) })
+	}
+	if m.handshakeTimeout > 0 {
+		m.runEvery(m.handshakeTimeout, func(ctx context.Context) { _, _ = m.RemoveAbandonedHandshakes(ctx) })
+	}
+	return m
 }
 
 // AddSession adds a session to the manager, associating it with its sessionNonce and also with its peerIdentityKey.
-func (m *SessionManager) AddSession(session PeerSession) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// This does NOT overwrite existing sessions for the same peerIdentityKey, allowing multiple concurrent sessions
+// up to the per identity cap (see WithMaxSessionsPerIdentity).
+// Adding a session with an already known sessionNonce fails with ErrSessionAlreadyExists,
+// and a session breaking the rules of PeerSession.Validate is rejected with ErrInvalidSession.
+func (m *SessionManager) AddSession(ctx context.Context, session PeerSession) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
 
-	if session.SessionNonce != nil {
-		m.sessions[*session.SessionNonce] = session
+	if err := session.Validate(); err != nil {
+		return err
+	}
+
+	return m.putSession(ctx, session, false)
+}
+
+// UpdateSession replaces an existing session with the same sessionNonce, re-indexing it if its peerIdentityKey changed.
+// Updating an unknown or expired session fails with ErrSessionNotFound, so removed sessions are never resurrected.
+// The session is validated the same way as in AddSession.
+func (m *SessionManager) UpdateSession(ctx context.Context, session PeerSession) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := session.Validate(); err != nil {
+		return err
+	}
+
+	return m.putSession(ctx, session, true)
+}
+
+// TouchSession sets the LastUpdate of the stored session with the sessionNonce, keeping its other fields as they're stored.
+// Touching an unknown or expired session fails with ErrSessionNotFound.
+func (m *SessionManager) TouchSession(ctx context.Context, sessionNonce string, lastUpdate time.Time) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	sessionNonce, unlock := m.lockSession(sessionNonce)
+	defer unlock()
+
+	session, exists, err := m.store.GetByNonce(ctx, sessionNonce)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if !exists || m.isExpired(session) {
+		return ErrSessionNotFound
 	}
 
+	session.LastUpdate = lastUpdate
+	if err := m.store.Put(ctx, session); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	m.touch(sessionNonce)
+	return nil
+}
+
+// putSession stores the session and enforces the capacity limits.
+// It requires the session to exist when update is true and to not exist otherwise.
+func (m *SessionManager) putSession(ctx context.Context, session PeerSession, update bool) error {
+	var events sessionEvents
+	defer m.notifyAll(&events)
+
+	// in-flight updates using a recently rotated nonce update the replacement instead of resurrecting the old nonce
+	sessionNonce, unlock := m.lockSession(*session.SessionNonce)
+	if sessionNonce != *session.SessionNonce {
+		session = session.Clone()
+		session.SessionNonce = &sessionNonce
+	}
+	err := m.storeSession(ctx, session, update, &events)
+	unlock()
+	if err != nil {
+		return err
+	}
+
+	// the capacity limits are enforced without the stripe of the session, the evicted sessions lock their own stripes
 	if session.PeerIdentityKey != nil {
-		m.addSessionByIdentityKey(session)
+		if err := m.evictExcessSessions(ctx, *session.PeerIdentityKey, sessionNonce, &events); err != nil {
+			return err
+		}
 	}
+	return m.evictLeastRecentlyUsed(ctx, &events)
 }
 
-// addSessionByIdentityKey adds a session nonce to the manager by associating it with its peerIdentityKey.
-// This does NOT overwrite existing sessions for the same peerIdentityKey, allowing multiple concurrent sessions.
-func (m *SessionManager) addSessionByIdentityKey(session PeerSession) {
-	sessionNonces, exists := m.identityKeyToSessions[*session.PeerIdentityKey]
+// storeSession puts the session into the store, the caller must hold the stripe of its sessionNonce.
+// It requires the session to exist when update is true and to not exist otherwise.
+func (m *SessionManager) storeSession(ctx context.Context, session PeerSession, update bool, events *sessionEvents) error {
+	previous, exists, err := m.store.GetByNonce(ctx, *session.SessionNonce)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	// an expired session which wasn't removed yet can be replaced by a new one, but not updated
+	live := exists && !m.isExpired(previous)
+	if update && !live {
+		return ErrSessionNotFound
+	}
+	if !update && live {
+		return ErrSessionAlreadyExists
+	}
+
+	if err := m.store.Put(ctx, session); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+
 	if exists {
-		// append sessionNonce to existing list
-		// at this point we have at least two concurrent sessions for the same peerIdentityKey
-		m.identityKeyToSessions[*session.PeerIdentityKey] = append(sessionNonces, *session.SessionNonce)
-		return
+		m.stats.sessionReplaced(previous, session)
+	} else {
+		m.stats.sessionAdded(session, m.now())
+	}
+	if !live {
+		events.record(eventAdded, session)
 	}
+	if session.IsAuthenticated && (!live || !previous.IsAuthenticated) {
+		events.record(eventAuthenticated, session)
+	}
+	m.touch(*session.SessionNonce)
+	return nil
+}
 
-	// create new list with sessionNonce and assign to peerIdentityKey
-	m.identityKeyToSessions[*session.PeerIdentityKey] = []string{*session.SessionNonce}
+// lockSession resolves a recently rotated sessionNonce to its replacement and locks the stripe of the resolved nonce.
+// A rotation finished while waiting for the stripe is noticed, so the returned sessionNonce is always the current one.
+func (m *SessionManager) lockSession(sessionNonce string) (string, func()) {
+	for {
+		resolved := m.resolveRotatedNonce(sessionNonce)
+		unlock := m.sessionLocks.lock(resolved)
+		if m.resolveRotatedNonce(resolved) == resolved {
+			return resolved, unlock
+		}
+		unlock()
+	}
 }
 
 // GetSession retrieves a "best" session based on a given identifier, which can be a sessionNonce or a peerIdentityKey.
-func (m *SessionManager) GetSession(identifier string) *PeerSession {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// The returned session is always a fresh deep copy, so modifying it doesn't affect the state of the manager.
+func (m *SessionManager) GetSession(ctx context.Context, identifier string) (*PeerSession, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
 
-	// try to get session by sessionNonce
-	if session, exists := m.sessions[identifier]; exists {
-		return &session
+	// try to get session by sessionNonce, a recently rotated one resolves to its replacement
+	session, exists, err := m.store.GetByNonce(ctx, m.resolveRotatedNonce(identifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if exists {
+		if m.isExpired(session) {
+			return nil, ErrSessionExpired
+		}
+		m.touchIfTracked(*session.SessionNonce)
+		return freshCopy(session), nil
 	}
 
 	// check if sessions exists by peerIdentityKey
-	sessionNonces, exists := m.identityKeyToSessions[identifier]
-	if !exists {
-		return nil
+	sessionNonces, err := m.store.GetNoncesByIdentity(ctx, identifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions by identity key: %w", err)
+	}
+	if len(sessionNonces) == 0 {
+		return nil, ErrSessionNotFound
 	}
 
 	// get the "best" session
-	bestSession := m.getBestSession(sessionNonces)
+	return m.getBestSession(ctx, identifier, sessionNonces)
+}
+
+// getBestSession retrieves the "best" session from a list of sessionNonces using the configured SessionSelector.
+func (m *SessionManager) getBestSession(ctx context.Context, identityKey string, sessionNonces []string) (*PeerSession, error) {
+	candidates, err := m.getLiveSessions(ctx, identityKey, sessionNonces)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrSessionNotFound
+	}
+
+	bestSession := m.selectSession(candidates)
+	if bestSession == nil {
+		return nil, ErrSessionNotFound
+	}
+	m.touchIfTracked(*bestSession.SessionNonce)
+	return freshCopy(*bestSession), nil
+}
 
-	return bestSession
+// freshCopy returns a pointer to a deep copy of the session.
+func freshCopy(session PeerSession) *PeerSession {
+	clone := session.Clone()
+	return &clone
 }
 
-// getBestSession retrieves the "best" session from a list of sessionNonces.
-// The "best" session is the most recent one, or the most recent authenticated one if there are multiple.
-func (m *SessionManager) getBestSession(sessionNonces []string) *PeerSession {
-	var bestSession *PeerSession
+// getLiveSessions retrieves the sessions of the peerIdentityKey with the given sessionNonces, skipping expired sessions.
+// Dangling nonces, which don't resolve to any session, are skipped and pruned from the index if the store supports it.
+func (m *SessionManager) getLiveSessions(ctx context.Context, identityKey string, sessionNonces []string) ([]PeerSession, error) {
+	sessions := make([]PeerSession, 0, len(sessionNonces))
+	var dangling []string
 	for _, sessionNonce := range sessionNonces {
-		session, exists := m.sessions[sessionNonce]
+		session, exists, err := m.store.GetByNonce(ctx, sessionNonce)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get session: %w", err)
+		}
 		if !exists {
+			dangling = append(dangling, sessionNonce)
 			continue
 		}
-
-		// If no session is selected yet, set the current session
-		if bestSession == nil {
-			bestSession = &session
+		if m.isExpired(session) {
 			continue
 		}
+		sessions = append(sessions, session)
+	}
 
-		// If the current session is authenticated and the bestSession is not, update bestSession
-		if session.IsAuthenticated && !bestSession.IsAuthenticated {
-			bestSession = &session
-			continue
-		}
+	m.pruneDanglingNonces(ctx, identityKey, dangling)
+	return sessions, nil
+}
 
-		// If both are authenticated or both are not, select the most recent one
-		if session.IsAuthenticated == bestSession.IsAuthenticated && session.LastUpdate.After(bestSession.LastUpdate) {
-			bestSession = &session
-		}
+// pruneDanglingNonces writes the dangling nonces found by a lookup back to the store, under the stripe of the peerIdentityKey.
+// The store keeps the nonces a session was stored under meanwhile. A failed pruning doesn't fail the lookup.
+func (m *SessionManager) pruneDanglingNonces(ctx context.Context, identityKey string, dangling []string) {
+	if len(dangling) == 0 {
+		return
+	}
+	if pruner, ok := m.store.(IndexPruner); ok {
+		unlock := m.identityLocks.lock(identityKey)
+		defer unlock()
+		_ = pruner.PruneIndex(ctx, identityKey, dangling)
+	}
+}
+
+// GetSessionsByIdentityKey retrieves all sessions of the peerIdentityKey, the most recently updated first.
+// Unlike GetSession it doesn't count as a use of the sessions.
+func (m *SessionManager) GetSessionsByIdentityKey(ctx context.Context, identityKey string) ([]PeerSession, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	sessionNonces, err := m.store.GetNoncesByIdentity(ctx, identityKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sessions by identity key: %w", err)
 	}
-	return bestSession
+
+	sessions, err := m.getLiveSessions(ctx, identityKey, sessionNonces)
+	if err != nil {
+		return nil, err
+	}
+	SortByMostRecent(sessions)
+	return sessions, nil
 }
 
 // RemoveSession removes a session from the manager by clearing all associated identifiers.
-func (m *SessionManager) RemoveSession(session PeerSession) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+func (m *SessionManager) RemoveSession(ctx context.Context, session PeerSession) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
 
-	if session.SessionNonce != nil {
-		delete(m.sessions, *session.SessionNonce)
+	if session.SessionNonce == nil {
+		return nil
 	}
 
-	if session.PeerIdentityKey != nil {
-		sessionNonces, exists := m.identityKeyToSessions[*session.PeerIdentityKey]
-		if !exists {
-			return
-		}
+	var events sessionEvents
+	defer m.notifyAll(&events)
 
-		updatedNonces := removeSessionNonce(sessionNonces, *session.SessionNonce)
+	_, err := m.removeSession(ctx, *session.SessionNonce, eventRemoved, &events, nil)
+	return err
+}
 
-		// if there are no more sessions for the peerIdentityKey, remove the key
-		if len(updatedNonces) == 0 {
-			delete(m.identityKeyToSessions, *session.PeerIdentityKey)
-			return
-		}
+// RemoveAllSessionsForPeer removes every session associated with the peerIdentityKey, e.g. to revoke
+// a compromised identity, and returns how many sessions were removed.
+func (m *SessionManager) RemoveAllSessionsForPeer(ctx context.Context, identityKey string) (int, error) {
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	var events sessionEvents
+	defer m.notifyAll(&events)
+
+	unlock := m.identityLocks.lock(identityKey)
+	defer unlock()
+
+	sessionNonces, err := m.store.GetNoncesByIdentity(ctx, identityKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sessions by identity key: %w", err)
+	}
 
-		// update the list of sessionNonces for the peerIdentityKey
-		m.identityKeyToSessions[*session.PeerIdentityKey] = updatedNonces
+	removed := 0
+	for _, sessionNonce := range sessionNonces {
+		// dangling nonces without a session are skipped
+		ok, err := m.removeSession(ctx, sessionNonce, eventRemoved, &events, nil)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
 	}
+	return removed, nil
 }
 
-// HasSession checks if a session exists for a given identifier (either sessionNonce or identityKey).
-func (m *SessionManager) HasSession(identifier string) bool {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// removeSession clears all identifiers of the session with the given nonce under the stripe of the nonce
+// and reports whether it was removed. With a predicate, the session is removed only if the predicate holds for it,
+// so sessions changed since the caller inspected them are checked again. The removal is recorded as an event of the given kind.
+func (m *SessionManager) removeSession(ctx context.Context, sessionNonce string, kind eventKind, events *sessionEvents, predicate func(PeerSession) bool) (bool, error) {
+	unlock := m.sessionLocks.lock(sessionNonce)
+	defer unlock()
 
-	// check if session exists by sessionNonce
-	_, exists := m.sessions[identifier]
-	if exists {
-		return true
+	removed, exists, err := m.store.GetByNonce(ctx, sessionNonce)
+	if err != nil {
+		return false, fmt.Errorf("failed to get session: %w", err)
 	}
-
-	// check if sessions are assigned to peerIdentityKey
-	nonces, exists := m.identityKeyToSessions[identifier]
 	if !exists {
-		return false
+		m.forget(sessionNonce)
+		return false, nil
+	}
+	if predicate != nil && !predicate(removed) {
+		return false, nil
 	}
 
-	return len(nonces) > 0
+	if err := m.store.Delete(ctx, sessionNonce); err != nil {
+		return false, fmt.Errorf("failed to delete session: %w", err)
+	}
+	m.forget(sessionNonce)
+	m.stats.sessionRemoved(removed)
+	events.record(kind, removed)
+	return true, nil
 }
 
-// UpdateSession updates a session in the manager.
-func (m *SessionManager) UpdateSession(session PeerSession) {
-	m.AddSession(session)
-}
+// HasSession checks if a session exists for a given identifier (either sessionNonce or identityKey).
+// Only live sessions count, so a peerIdentityKey whose index holds just dangling or expired nonces has no session.
+// A failing store or a done context is reported as no session.
+func (m *SessionManager) HasSession(ctx context.Context, identifier string) bool {
+	if ctx.Err() != nil {
+		return false
+	}
 
-func removeSessionNonce(slice []string, target string) []string {
-	newSlice := slice[:0] // Reuse the same slice memory
-	for _, str := range slice {
-		if str != target {
-			newSlice = append(newSlice, str)
+	// check if session exists by sessionNonce, a recently rotated one resolves to its replacement
+	session, exists, err := m.store.GetByNonce(ctx, m.resolveRotatedNonce(identifier))
+	if err != nil {
+		return false
+	}
+	if exists {
+		return !m.isExpired(session)
+	}
+
+	// check if non-expired sessions are assigned to peerIdentityKey
+	nonces, err := m.store.GetNoncesByIdentity(ctx, identifier)
+	if err != nil {
+		return false
+	}
+	for _, nonce := range nonces {
+		if session, exists, err := m.store.GetByNonce(ctx, nonce); err == nil && exists && !m.isExpired(session) {
+			return true
 		}
 	}
-	return newSlice
+	return false
 }
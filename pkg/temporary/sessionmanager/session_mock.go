@@ -10,8 +10,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// NewPeerSession creates a new PeerSession with random values.
-func NewPeerSession(t *testing.T) PeerSession {
+// NewRandomPeerSession creates a new PeerSession with random values.
+func NewRandomPeerSession(t *testing.T) PeerSession {
 	sNonce, err := randomHex(32)
 	require.NoError(t, err)
 	pNonce, err := randomHex(32)
@@ -24,7 +24,7 @@ func NewPeerSession(t *testing.T) PeerSession {
 		SessionNonce:    &sNonce,
 		PeerNonce:       &pNonce,
 		PeerIdentityKey: &pIdentityKey,
-		LastUpdate:      time.Now(),
+		LastUpdate:      time.Now().UTC().Round(0),
 	}
 }
 
@@ -45,7 +45,7 @@ func NewPeerSessionsForThisSameIdentityKey(t *testing.T, count int) []PeerSessio
 			SessionNonce:    &sNonce,
 			PeerNonce:       &pNonce,
 			PeerIdentityKey: &pIdentityKey,
-			LastUpdate:      time.Now(),
+			LastUpdate:      time.Now().UTC().Round(0),
 		}
 	}
 
@@ -0,0 +1,103 @@
+package sessionmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SnapshotVersion is the version of the format produced by Export.
+const SnapshotVersion = 1
+
+var (
+	// ErrUnsupportedSnapshotVersion is returned by Import when the snapshot was produced by an unknown format version.
+	ErrUnsupportedSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrInvalidSnapshot is returned by Import when the snapshot can't be decoded or contains invalid sessions.
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+)
+
+type snapshot struct {
+	Version  int                 `json:"version"`
+	Sessions []snapshotSession   `json:"sessions"`
+	Index    map[string][]string `json:"identityKeyToSessions"`
+}
+
+type snapshotSession struct {
+	IsAuthenticated bool           `json:"isAuthenticated"`
+	SessionNonce    *string        `json:"sessionNonce"`
+	PeerNonce       *string        `json:"peerNonce"`
+	PeerIdentityKey *string        `json:"peerIdentityKey"`
+	LastUpdate      time.Time      `json:"lastUpdate"`
+	Metadata        map[string]any `json:"metadata,omitempty"`
+}
+
+// Export serializes all sessions together with the peerIdentityKey index, so they can be restored by Import,
+// e.g. after the process restarts.
+func (m *SessionManager) Export(ctx context.Context) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	sessions, err := m.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	SortByMostRecent(sessions)
+	exported := snapshot{
+		Version:  SnapshotVersion,
+		Sessions: make([]snapshotSession, 0, len(sessions)),
+		Index:    make(map[string][]string),
+	}
+	for _, session := range sessions {
+		exported.Sessions = append(exported.Sessions, snapshotSession(session))
+		if session.PeerIdentityKey != nil {
+			exported.Index[*session.PeerIdentityKey] = append(exported.Index[*session.PeerIdentityKey], *session.SessionNonce)
+		}
+	}
+
+	data, err := json.Marshal(exported)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return data, nil
+}
+
+// Import restores the sessions serialized by Export. Sessions already expired according to the session TTL are skipped.
+// The serialized peerIdentityKey index isn't trusted, it's rebuilt from the sessions instead.
+// The sessions are added with AddSession, so the capacity limits and hooks apply to them.
+// Nothing is imported if the snapshot is invalid.
+func (m *SessionManager) Import(ctx context.Context, data []byte) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	var imported snapshot
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+	if imported.Version != SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedSnapshotVersion, imported.Version)
+	}
+	sessions := make([]PeerSession, 0, len(imported.Sessions))
+	for i, session := range imported.Sessions {
+		if err := PeerSession(session).Validate(); err != nil {
+			return fmt.Errorf("%w: session %d: %w", ErrInvalidSnapshot, i, err)
+		}
+		sessions = append(sessions, PeerSession(session))
+	}
+
+	// add the oldest sessions first, so the most recent ones are kept when the capacity limits are hit
+	SortByMostRecent(sessions)
+	for i := len(sessions) - 1; i >= 0; i-- {
+		if m.isExpired(sessions[i]) {
+			continue
+		}
+		if err := m.AddSession(ctx, sessions[i]); err != nil {
+			return fmt.Errorf("failed to import session: %w", err)
+		}
+	}
+	return nil
+}
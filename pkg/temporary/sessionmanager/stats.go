@@ -0,0 +1,150 @@
+package sessionmanager
+
+import (
+	"sync"
+	"time"
+)
+
+// statsBucketCount is the number of one-minute buckets kept for the new sessions window.
+const statsBucketCount = 60
+
+// Stats is a point-in-time snapshot of the SessionManager activity.
+type Stats struct {
+	// ActiveSessions is the number of sessions currently held by the manager
+	ActiveSessions int
+	// AuthenticatedSessions is the number of held sessions that are authenticated
+	AuthenticatedSessions int
+	// DistinctIdentitiesToday is the number of distinct peer identity keys that added a session since midnight (UTC)
+	DistinctIdentitiesToday int
+	// AbandonedHandshakes is the total number of unauthenticated sessions removed because the handshake was not completed in time
+	AbandonedHandshakes int
+}
+
+// sessionStats keeps aggregates updated incrementally on every mutation,
+// so reading them never requires scanning the sessions under the manager lock.
+type sessionStats struct {
+	mu            sync.Mutex
+	active        int
+	authenticated int
+	// newSessions holds the count of added sessions per minute, indexed by minute % statsBucketCount
+	newSessions [statsBucketCount]minuteBucket
+	// today is the current UTC day number since the unix epoch
+	today           int64
+	identitiesToday map[string]struct{}
+	abandoned       int
+}
+
+type minuteBucket struct {
+	minute int64
+	count  int
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{
+		identitiesToday: make(map[string]struct{}),
+	}
+}
+
+func (s *sessionStats) sessionAdded(session PeerSession, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active++
+	if session.IsAuthenticated {
+		s.authenticated++
+	}
+
+	minute := now.Unix() / 60
+	bucket := &s.newSessions[minute%statsBucketCount]
+	if bucket.minute != minute {
+		bucket.minute = minute
+		bucket.count = 0
+	}
+	bucket.count++
+
+	if session.PeerIdentityKey != nil {
+		s.rollDay(now)
+		s.identitiesToday[*session.PeerIdentityKey] = struct{}{}
+	}
+}
+
+func (s *sessionStats) sessionReplaced(previous, current PeerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if previous.IsAuthenticated && !current.IsAuthenticated {
+		s.authenticated--
+	}
+	if !previous.IsAuthenticated && current.IsAuthenticated {
+		s.authenticated++
+	}
+}
+
+func (s *sessionStats) sessionRemoved(session PeerSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	if session.IsAuthenticated {
+		s.authenticated--
+	}
+}
+
+func (s *sessionStats) handshakesAbandoned(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.abandoned += count
+}
+
+func (s *sessionStats) snapshot(now time.Time) Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rollDay(now)
+	return Stats{
+		ActiveSessions:          s.active,
+		AuthenticatedSessions:   s.authenticated,
+		DistinctIdentitiesToday: len(s.identitiesToday),
+		AbandonedHandshakes:     s.abandoned,
+	}
+}
+
+func (s *sessionStats) newSessionsWithin(window time.Duration, now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minutes := int64(window / time.Minute)
+	if minutes > statsBucketCount {
+		minutes = statsBucketCount
+	}
+
+	current := now.Unix() / 60
+	total := 0
+	for _, bucket := range s.newSessions {
+		if bucket.minute > current-minutes && bucket.minute <= current {
+			total += bucket.count
+		}
+	}
+	return total
+}
+
+func (s *sessionStats) rollDay(now time.Time) {
+	day := now.Unix() / int64(24*time.Hour/time.Second)
+	if day != s.today {
+		s.today = day
+		s.identitiesToday = make(map[string]struct{})
+	}
+}
+
+// Stats returns the current aggregate statistics of the SessionManager.
+// It doesn't take the sessions lock, so it's cheap to call from dashboards and health endpoints.
+func (m *SessionManager) Stats() Stats {
+	return m.stats.snapshot(m.now())
+}
+
+// NewSessionsWithin returns the number of sessions added within the given window.
+// The window has a one-minute resolution and is capped at one hour.
+func (m *SessionManager) NewSessionsWithin(window time.Duration) int {
+	return m.stats.newSessionsWithin(window, m.now())
+}
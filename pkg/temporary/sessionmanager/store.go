@@ -0,0 +1,246 @@
+package sessionmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// SessionStore persists sessions on behalf of the SessionManager.
+// The SessionManager owns the policy (best session selection, expiration, eviction),
+// while the store is only responsible for keeping the sessions and the peerIdentityKey index consistent.
+// Implementations must be safe for concurrent use by multiple goroutines and should give up once the context is done.
+type SessionStore interface {
+	// Put stores the session under its sessionNonce, replacing any previous session with the same nonce.
+	// The nonce must be indexed exactly once under the peerIdentityKey of the stored session (if any).
+	Put(ctx context.Context, session PeerSession) error
+	// GetByNonce returns the session stored under the given sessionNonce.
+	// The returned bool is false if there is no such session.
+	GetByNonce(ctx context.Context, sessionNonce string) (PeerSession, bool, error)
+	// GetNoncesByIdentity returns the nonces of all sessions indexed under the given peerIdentityKey.
+	GetNoncesByIdentity(ctx context.Context, identityKey string) ([]string, error)
+	// Delete removes the session stored under the given sessionNonce together with its index entry.
+	Delete(ctx context.Context, sessionNonce string) error
+	// List returns all stored sessions.
+	List(ctx context.Context) ([]PeerSession, error)
+}
+
+// compactableStore is implemented by stores which can release memory held by removed sessions.
+type compactableStore interface {
+	Compact() CompactionResult
+}
+
+// IndexPruner is implemented by stores whose peerIdentityKey index can reference nonces without a stored session,
+// e.g. because the backend expires the sessions on its own. The SessionManager prunes the dangling nonces its lookups
+// run into, and Compact sweeps the whole index.
+type IndexPruner interface {
+	// IdentityKeys returns the peerIdentityKeys with any nonces in the index.
+	IdentityKeys(ctx context.Context) ([]string, error)
+	// PruneIndex removes the nonces from the index of the peerIdentityKey, unless a session is stored under them.
+	PruneIndex(ctx context.Context, identityKey string, sessionNonces []string) error
+}
+
+// storeShards is the number of independently locked shards of the MemoryStore.
+const storeShards = 64
+
+// MemoryStore is the default in-memory SessionStore, its methods never return errors and ignore the context.
+// It stores and returns deep copies of the sessions, so the callers can't modify its state.
+//
+// Both the sessions and the peerIdentityKey index are split into shards with their own locks,
+// so operations on different sessions don't contend. A session shard is always locked before an identity shard.
+type MemoryStore struct {
+	// sessionShards hold maps of sessionNonce to a Session, sharded by the sessionNonce
+	sessionShards [storeShards]sessionShard
+	// identityShards hold maps of peerIdentityKey to a list of sessionNonce's, sharded by the peerIdentityKey
+	identityShards [storeShards]identityShard
+}
+
+type sessionShard struct {
+	mu       sync.RWMutex
+	sessions map[string]PeerSession
+}
+
+type identityShard struct {
+	mu                    sync.RWMutex
+	identityKeyToSessions map[string][]string
+}
+
+// NewMemoryStore creates a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{}
+	for i := range s.sessionShards {
+		s.sessionShards[i].sessions = make(map[string]PeerSession)
+		s.identityShards[i].identityKeyToSessions = make(map[string][]string)
+	}
+	return s
+}
+
+// Put stores the session under its sessionNonce and indexes it under its peerIdentityKey.
+func (s *MemoryStore) Put(_ context.Context, session PeerSession) error {
+	if session.SessionNonce == nil {
+		return nil
+	}
+	nonce := *session.SessionNonce
+
+	shard := s.sessionShard(nonce)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if previous, exists := shard.sessions[nonce]; exists {
+		s.unindex(previous)
+	}
+	shard.sessions[nonce] = session.Clone()
+
+	if session.PeerIdentityKey != nil {
+		// at this point we may have several concurrent sessions for the same peerIdentityKey
+		index := s.identityShard(*session.PeerIdentityKey)
+		index.mu.Lock()
+		index.identityKeyToSessions[*session.PeerIdentityKey] = append(index.identityKeyToSessions[*session.PeerIdentityKey], nonce)
+		index.mu.Unlock()
+	}
+	return nil
+}
+
+// GetByNonce returns the session stored under the given sessionNonce.
+func (s *MemoryStore) GetByNonce(_ context.Context, sessionNonce string) (PeerSession, bool, error) {
+	shard := s.sessionShard(sessionNonce)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+
+	session, exists := shard.sessions[sessionNonce]
+	return session.Clone(), exists, nil
+}
+
+// GetNoncesByIdentity returns a copy of the nonces indexed under the given peerIdentityKey.
+func (s *MemoryStore) GetNoncesByIdentity(_ context.Context, identityKey string) ([]string, error) {
+	index := s.identityShard(identityKey)
+	index.mu.RLock()
+	defer index.mu.RUnlock()
+
+	nonces := index.identityKeyToSessions[identityKey]
+	if len(nonces) == 0 {
+		return nil, nil
+	}
+	return append([]string(nil), nonces...), nil
+}
+
+// Delete removes the session stored under the given sessionNonce together with its index entry.
+func (s *MemoryStore) Delete(_ context.Context, sessionNonce string) error {
+	shard := s.sessionShard(sessionNonce)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	session, exists := shard.sessions[sessionNonce]
+	if !exists {
+		return nil
+	}
+	delete(shard.sessions, sessionNonce)
+	s.unindex(session)
+	return nil
+}
+
+// List returns all stored sessions.
+func (s *MemoryStore) List(_ context.Context) ([]PeerSession, error) {
+	var sessions []PeerSession
+	for i := range s.sessionShards {
+		shard := &s.sessionShards[i]
+		shard.mu.RLock()
+		for _, session := range shard.sessions {
+			sessions = append(sessions, session.Clone())
+		}
+		shard.mu.RUnlock()
+	}
+	return sessions, nil
+}
+
+// IdentityKeys returns the peerIdentityKeys with any nonces in the index.
+func (s *MemoryStore) IdentityKeys(_ context.Context) ([]string, error) {
+	var identityKeys []string
+	for i := range s.identityShards {
+		index := &s.identityShards[i]
+		index.mu.RLock()
+		for identityKey := range index.identityKeyToSessions {
+			identityKeys = append(identityKeys, identityKey)
+		}
+		index.mu.RUnlock()
+	}
+	return identityKeys, nil
+}
+
+// PruneIndex removes the nonces from the index of the peerIdentityKey, unless a session is stored under them.
+// The index of the MemoryStore is kept consistent by its own methods, so it only guards against a nonce indexed in error.
+func (s *MemoryStore) PruneIndex(_ context.Context, identityKey string, sessionNonces []string) error {
+	for _, nonce := range sessionNonces {
+		shard := s.sessionShard(nonce)
+		shard.mu.RLock()
+		if _, exists := shard.sessions[nonce]; !exists {
+			index := s.identityShard(identityKey)
+			index.mu.Lock()
+			if nonces := removeSessionNonce(index.identityKeyToSessions[identityKey], nonce); len(nonces) > 0 {
+				index.identityKeyToSessions[identityKey] = nonces
+			} else {
+				delete(index.identityKeyToSessions, identityKey)
+			}
+			index.mu.Unlock()
+		}
+		shard.mu.RUnlock()
+	}
+	return nil
+}
+
+// unindex removes the nonce of the session from its peerIdentityKey index,
+// the caller must hold the lock of the session shard.
+func (s *MemoryStore) unindex(session PeerSession) {
+	if session.PeerIdentityKey == nil {
+		return
+	}
+
+	index := s.identityShard(*session.PeerIdentityKey)
+	index.mu.Lock()
+	defer index.mu.Unlock()
+
+	sessionNonces, exists := index.identityKeyToSessions[*session.PeerIdentityKey]
+	if !exists {
+		return
+	}
+
+	updatedNonces := removeSessionNonce(sessionNonces, *session.SessionNonce)
+
+	// if there are no more sessions for the peerIdentityKey, remove the key
+	if len(updatedNonces) == 0 {
+		delete(index.identityKeyToSessions, *session.PeerIdentityKey)
+		return
+	}
+
+	// update the list of sessionNonces for the peerIdentityKey
+	index.identityKeyToSessions[*session.PeerIdentityKey] = updatedNonces
+}
+
+func (s *MemoryStore) sessionShard(sessionNonce string) *sessionShard {
+	return &s.sessionShards[hashKey(sessionNonce)%storeShards]
+}
+
+func (s *MemoryStore) identityShard(identityKey string) *identityShard {
+	return &s.identityShards[hashKey(identityKey)%storeShards]
+}
+
+// hashKey hashes the key with 32-bit FNV-1a, inlined to avoid allocating a hash.Hash on every lookup.
+func hashKey(key string) uint32 {
+	const offset32, prime32 = 2166136261, 16777619
+
+	hash := uint32(offset32)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+func removeSessionNonce(slice []string, target string) []string {
+	newSlice := slice[:0] // Reuse the same slice memory
+	for _, str := range slice {
+		if str != target {
+			newSlice = append(newSlice, str)
+		}
+	}
+	return newSlice
+}
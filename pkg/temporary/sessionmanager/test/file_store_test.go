@@ -0,0 +1,137 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+)
+
+// fileStore is a toy SessionStore persisting all sessions into a single gob file on every change.
+// It exists to prove that the SessionManager works on top of any SessionStore.
+type fileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+type fileStoreData struct {
+	Sessions map[string]sessionmanager.PeerSession
+	// Order keeps the insertion order of the nonces, so the identity index order is stable
+	Order []string
+}
+
+func newFileStore(t *testing.T) sessionmanager.SessionStore {
+	return &fileStore{path: filepath.Join(t.TempDir(), "sessions.gob")}
+}
+
+func (s *fileStore) Put(_ context.Context, session sessionmanager.PeerSession) error {
+	return s.update(func(data *fileStoreData) {
+		nonce := *session.SessionNonce
+		if _, exists := data.Sessions[nonce]; !exists {
+			data.Order = append(data.Order, nonce)
+		}
+		data.Sessions[nonce] = session
+	})
+}
+
+func (s *fileStore) GetByNonce(_ context.Context, sessionNonce string) (sessionmanager.PeerSession, bool, error) {
+	data, err := s.read()
+	if err != nil {
+		return sessionmanager.PeerSession{}, false, err
+	}
+	session, exists := data.Sessions[sessionNonce]
+	return session, exists, nil
+}
+
+func (s *fileStore) GetNoncesByIdentity(_ context.Context, identityKey string) ([]string, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	var nonces []string
+	for _, nonce := range data.Order {
+		session := data.Sessions[nonce]
+		if session.PeerIdentityKey != nil && *session.PeerIdentityKey == identityKey {
+			nonces = append(nonces, nonce)
+		}
+	}
+	return nonces, nil
+}
+
+func (s *fileStore) Delete(_ context.Context, sessionNonce string) error {
+	return s.update(func(data *fileStoreData) {
+		delete(data.Sessions, sessionNonce)
+		order := data.Order[:0]
+		for _, nonce := range data.Order {
+			if nonce != sessionNonce {
+				order = append(order, nonce)
+			}
+		}
+		data.Order = order
+	})
+}
+
+func (s *fileStore) List(_ context.Context) ([]sessionmanager.PeerSession, error) {
+	data, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]sessionmanager.PeerSession, 0, len(data.Order))
+	for _, nonce := range data.Order {
+		sessions = append(sessions, data.Sessions[nonce])
+	}
+	return sessions, nil
+}
+
+func (s *fileStore) read() (fileStoreData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+func (s *fileStore) update(change func(data *fileStoreData)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.load()
+	if err != nil {
+		return err
+	}
+	change(&data)
+
+	file, err := os.Create(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return gob.NewEncoder(file).Encode(data)
+}
+
+func (s *fileStore) load() (fileStoreData, error) {
+	data := fileStoreData{Sessions: make(map[string]sessionmanager.PeerSession)}
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return data, err
+	}
+	defer file.Close()
+
+	err = gob.NewDecoder(file).Decode(&data)
+	return data, err
+}
+
+// sessionStores lists the SessionStore implementations the SessionManager test suite runs against.
+func sessionStores() map[string]func(t *testing.T) sessionmanager.SessionStore {
+	return map[string]func(t *testing.T) sessionmanager.SessionStore{
+		"memory store": func(*testing.T) sessionmanager.SessionStore { return sessionmanager.NewMemoryStore() },
+		"file store":   newFileStore,
+	}
+}
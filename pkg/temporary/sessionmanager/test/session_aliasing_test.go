@@ -0,0 +1,123 @@
+package auth_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_Aliasing(t *testing.T) {
+	t.Run("Modifying added session doesn't affect the manager", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		original := session.Clone()
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		*session.PeerNonce = "modified"
+		*session.PeerIdentityKey = "modified"
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *original.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, original, *retrievedSession)
+	})
+
+	t.Run("Modifying retrieved session doesn't affect the manager", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.NoError(t, err)
+		*retrievedSession.SessionNonce = "modified"
+		*retrievedSession.PeerIdentityKey = "modified"
+		retrievedSession.IsAuthenticated = true
+
+		// then
+		retrievedAgain, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, session, *retrievedAgain)
+		require.NotSame(t, retrievedSession, retrievedAgain)
+	})
+
+	t.Run("Clone copies the pointed values", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+
+		// when
+		clone := session.Clone()
+
+		// then
+		require.Equal(t, session, clone)
+		require.NotSame(t, session.SessionNonce, clone.SessionNonce)
+		require.NotSame(t, session.PeerNonce, clone.PeerNonce)
+		require.NotSame(t, session.PeerIdentityKey, clone.PeerIdentityKey)
+		require.Nil(t, sessionmanager.PeerSession{}.Clone().SessionNonce)
+	})
+}
+
+func TestSessionManager_ConcurrentGetAndUpdate(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager()
+	session := sessionmanager.NewRandomPeerSession(t)
+	base := time.Now().Round(0)
+
+	// versioned derives all fields which may change from the version, so a mix of two versions is detectable
+	versioned := func(version int) sessionmanager.PeerSession {
+		updated := session.Clone()
+		peerNonce := fmt.Sprintf("peer-nonce-%d", version)
+		updated.PeerNonce = &peerNonce
+		updated.IsAuthenticated = version%2 == 1
+		updated.LastUpdate = base.Add(time.Duration(version) * time.Millisecond)
+		return updated
+	}
+	require.NoError(t, sessionManager.AddSession(t.Context(), versioned(0)))
+
+	const writers, readers, iterations = 4, 4, 500
+	var wg sync.WaitGroup
+	retrieved := make(chan sessionmanager.PeerSession, readers*iterations*2)
+
+	// when
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				_ = sessionManager.UpdateSession(t.Context(), versioned(w*iterations+i+1))
+			}
+		}()
+	}
+	for r := 0; r < readers; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				for _, identifier := range []string{*session.SessionNonce, *session.PeerIdentityKey} {
+					if current, err := sessionManager.GetSession(t.Context(), identifier); err == nil {
+						*current.PeerNonce += "-mutated-by-reader"
+						retrieved <- *current
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(retrieved)
+
+	// then
+	require.NotEmpty(t, retrieved)
+	for current := range retrieved {
+		version := int(current.LastUpdate.Sub(base) / time.Millisecond)
+		expected := versioned(version)
+		*expected.PeerNonce += "-mutated-by-reader"
+		require.Equal(t, expected, current)
+	}
+}
@@ -0,0 +1,73 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_Compact(t *testing.T) {
+	t.Run("Compact keeps live sessions and reclaims index slots", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 8)
+		for _, session := range sessions {
+			sessionManager.AddSession(t.Context(), session)
+		}
+		for _, session := range sessions[:6] {
+			sessionManager.RemoveSession(t.Context(), session)
+		}
+		standalone := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), standalone)
+
+		// when
+		result := sessionManager.Compact()
+
+		// then
+		require.Equal(t, 3, result.Sessions)
+		require.Equal(t, 2, result.Identities)
+		require.Positive(t, result.ReclaimedNonceSlots)
+
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[7].PeerIdentityKey)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[7], *retrievedSession)
+		require.True(t, sessionManager.HasSession(t.Context(), *standalone.SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *sessions[0].SessionNonce))
+	})
+
+	t.Run("Compact empty manager", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+
+		// when
+		result := sessionManager.Compact()
+
+		// then
+		require.Equal(t, sessionmanager.CompactionResult{}, result)
+	})
+
+	t.Run("Periodic compaction reports results until closed", func(t *testing.T) {
+		// given
+		results := make(chan sessionmanager.CompactionResult, 16)
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithCompactionInterval(time.Millisecond, func(result sessionmanager.CompactionResult) {
+				select {
+				case results <- result:
+				default:
+				}
+			}),
+		)
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+
+		// when
+		result := <-results
+		sessionManager.Close()
+
+		// then
+		require.Equal(t, 1, result.Sessions)
+		sessionManager.Close()
+	})
+}
@@ -0,0 +1,106 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_CancelledContext(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager()
+	session := sessionmanager.NewRandomPeerSession(t)
+	require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	t.Run("AddSession returns the context error", func(t *testing.T) {
+		// when
+		err := sessionManager.AddSession(ctx, sessionmanager.NewRandomPeerSession(t))
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("UpdateSession returns the context error", func(t *testing.T) {
+		// when
+		err := sessionManager.UpdateSession(ctx, session)
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("GetSession returns the context error", func(t *testing.T) {
+		// when
+		retrievedSession, err := sessionManager.GetSession(ctx, *session.SessionNonce)
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.Nil(t, retrievedSession)
+	})
+
+	t.Run("RemoveSession returns the context error and keeps the session", func(t *testing.T) {
+		// when
+		err := sessionManager.RemoveSession(ctx, session)
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		require.True(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("HasSession reports no session", func(t *testing.T) {
+		// when
+		exists := sessionManager.HasSession(ctx, *session.SessionNonce)
+
+		// then
+		require.False(t, exists)
+	})
+
+	t.Run("RemoveExpiredSessions returns the context error", func(t *testing.T) {
+		// when
+		removed, err := sessionManager.RemoveExpiredSessions(ctx)
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		require.Zero(t, removed)
+	})
+}
+
+func TestLegacyAdapter(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager()
+	adapter := sessionmanager.NewLegacyAdapter(sessionManager)
+	session := sessionmanager.NewRandomPeerSession(t)
+
+	// when
+	err := adapter.AddSession(session)
+
+	// then
+	require.NoError(t, err)
+	require.True(t, adapter.HasSession(*session.PeerIdentityKey))
+
+	retrievedSession, err := adapter.GetSession(*session.SessionNonce)
+	require.NoError(t, err)
+	require.Equal(t, session, *retrievedSession)
+
+	// when
+	session.IsAuthenticated = true
+	err = adapter.UpdateSession(session)
+
+	// then
+	require.NoError(t, err)
+	retrievedSession, err = sessionManager.GetSession(t.Context(), *session.SessionNonce)
+	require.NoError(t, err)
+	require.True(t, retrievedSession.IsAuthenticated)
+
+	// when
+	err = adapter.RemoveSession(session)
+
+	// then
+	require.NoError(t, err)
+	require.False(t, adapter.HasSession(*session.SessionNonce))
+}
@@ -0,0 +1,191 @@
+package auth_test
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_DanglingNonces(t *testing.T) {
+	t.Run("HasSession ignores dangling nonces", func(t *testing.T) {
+		// given
+		store := newLeakyStore()
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		require.True(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+
+		// when
+		store.lose(*session.SessionNonce)
+
+		// then
+		require.False(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+		require.Equal(t, 1, store.indexSize(*session.PeerIdentityKey))
+	})
+
+	t.Run("GetSession prunes dangling nonces", func(t *testing.T) {
+		// given
+		store := newLeakyStore()
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+		identityKey := *sessions[0].PeerIdentityKey
+		store.lose(*sessions[0].SessionNonce)
+		store.lose(*sessions[1].SessionNonce)
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), identityKey)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, sessions[2], *retrievedSession)
+		require.Equal(t, 1, store.indexSize(identityKey))
+	})
+
+	t.Run("GetSession of a peer with only dangling nonces", func(t *testing.T) {
+		// given
+		store := newLeakyStore()
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		store.lose(*session.SessionNonce)
+
+		// when
+		_, err := sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.Zero(t, store.indexSize(*session.PeerIdentityKey))
+	})
+
+	t.Run("Compact sweeps the whole index", func(t *testing.T) {
+		// given
+		store := newLeakyStore()
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+		lost := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 2)
+		kept := sessionmanager.NewRandomPeerSession(t)
+		for _, session := range append(lost, kept) {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+		for _, session := range lost {
+			store.lose(*session.SessionNonce)
+		}
+
+		// when
+		result := sessionManager.Compact()
+
+		// then
+		require.Equal(t, 2, result.PrunedNonces)
+		require.Equal(t, 1, result.Sessions)
+		require.Equal(t, 1, result.Identities)
+		require.Zero(t, store.indexSize(*lost[0].PeerIdentityKey))
+		require.Equal(t, 1, store.indexSize(*kept.PeerIdentityKey))
+		require.True(t, sessionManager.HasSession(t.Context(), *kept.PeerIdentityKey))
+	})
+}
+
+// leakyStore is a SessionStore which can lose sessions without updating its identity index, like a buggy backend.
+type leakyStore struct {
+	mu       sync.Mutex
+	sessions map[string]sessionmanager.PeerSession
+	index    map[string][]string
+}
+
+func newLeakyStore() *leakyStore {
+	return &leakyStore{
+		sessions: make(map[string]sessionmanager.PeerSession),
+		index:    make(map[string][]string),
+	}
+}
+
+// lose drops the session but keeps its nonce in the identity index.
+func (s *leakyStore) lose(sessionNonce string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionNonce)
+}
+
+func (s *leakyStore) indexSize(identityKey string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index[identityKey])
+}
+
+func (s *leakyStore) Put(_ context.Context, session sessionmanager.PeerSession) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unindex(*session.SessionNonce)
+	s.sessions[*session.SessionNonce] = session.Clone()
+	if identityKey := session.GetPeerIdentityKey(); identityKey != "" {
+		s.index[identityKey] = append(s.index[identityKey], *session.SessionNonce)
+	}
+	return nil
+}
+
+func (s *leakyStore) GetByNonce(_ context.Context, sessionNonce string) (sessionmanager.PeerSession, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, exists := s.sessions[sessionNonce]
+	return session.Clone(), exists, nil
+}
+
+func (s *leakyStore) GetNoncesByIdentity(_ context.Context, identityKey string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.index[identityKey]), nil
+}
+
+func (s *leakyStore) Delete(_ context.Context, sessionNonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unindex(sessionNonce)
+	delete(s.sessions, sessionNonce)
+	return nil
+}
+
+func (s *leakyStore) List(_ context.Context) ([]sessionmanager.PeerSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := make([]sessionmanager.PeerSession, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, session.Clone())
+	}
+	return sessions, nil
+}
+
+func (s *leakyStore) PruneIndex(_ context.Context, identityKey string, sessionNonces []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.index[identityKey] = slices.DeleteFunc(s.index[identityKey], func(nonce string) bool {
+		_, exists := s.sessions[nonce]
+		return !exists && slices.Contains(sessionNonces, nonce)
+	})
+	if len(s.index[identityKey]) == 0 {
+		delete(s.index, identityKey)
+	}
+	return nil
+}
+
+func (s *leakyStore) IdentityKeys(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Collect(maps.Keys(s.index)), nil
+}
+
+// unindex removes the nonce from the index of the stored session, the caller must hold the lock.
+func (s *leakyStore) unindex(sessionNonce string) {
+	session, exists := s.sessions[sessionNonce]
+	if !exists || session.PeerIdentityKey == nil {
+		return
+	}
+	s.index[*session.PeerIdentityKey] = slices.DeleteFunc(s.index[*session.PeerIdentityKey], func(nonce string) bool {
+		return nonce == sessionNonce
+	})
+}
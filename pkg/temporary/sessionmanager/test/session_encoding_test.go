@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files")
+
+func TestPeerSession_EncodingGolden(t *testing.T) {
+	sessionNonce := "c2Vzc2lvbi1ub25jZQ=="
+	peerNonce := "cGVlci1ub25jZQ=="
+	identityKey := "02a9c6b1b4b5e1a0bdfa8b3e9b5e8e2c4f4bba0f9f7a1c7e7d5b1b4c3e2d1a0f9e"
+	empty := ""
+	lastUpdate := time.Date(2025, time.March, 14, 15, 9, 26, 535897932, time.UTC)
+
+	tests := map[string]sessionmanager.PeerSession{
+		"session_v1_authenticated": {
+			IsAuthenticated: true,
+			SessionNonce:    &sessionNonce,
+			PeerNonce:       &peerNonce,
+			PeerIdentityKey: &identityKey,
+			LastUpdate:      lastUpdate,
+		},
+		"session_v1_pending_handshake": {
+			SessionNonce: &sessionNonce,
+			LastUpdate:   lastUpdate.In(time.FixedZone("CET", 60*60)),
+		},
+		"session_v1_empty_pointers": {
+			SessionNonce:    &sessionNonce,
+			PeerNonce:       &empty,
+			PeerIdentityKey: &empty,
+			LastUpdate:      lastUpdate,
+		},
+	}
+	for name, session := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			golden := filepath.Join("testdata", name+".golden")
+
+			// when
+			data, err := session.MarshalBinary()
+
+			// then
+			require.NoError(t, err)
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(golden, data, 0o600))
+			}
+			expected, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			require.Equal(t, string(expected), string(data))
+		})
+	}
+}
+
+func TestPeerSession_EncodingRoundTrip(t *testing.T) {
+	t.Run("Decode the encoded session", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.IsAuthenticated = true
+		data, err := session.MarshalBinary()
+		require.NoError(t, err)
+
+		// when
+		var decoded sessionmanager.PeerSession
+		err = decoded.UnmarshalBinary(data)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, session, decoded)
+	})
+
+	t.Run("Decode nil and empty pointers the same way", func(t *testing.T) {
+		// given
+		empty := ""
+		withNil := sessionmanager.PeerSession{LastUpdate: time.Now().UTC()}
+		withEmpty := sessionmanager.PeerSession{SessionNonce: &empty, PeerNonce: &empty, PeerIdentityKey: &empty, LastUpdate: withNil.LastUpdate}
+
+		// when
+		nilData, err := withNil.MarshalBinary()
+		require.NoError(t, err)
+		emptyData, err := withEmpty.MarshalBinary()
+		require.NoError(t, err)
+
+		var decoded sessionmanager.PeerSession
+		require.NoError(t, decoded.UnmarshalBinary(emptyData))
+
+		// then
+		require.Equal(t, nilData, emptyData)
+		require.Nil(t, decoded.SessionNonce)
+		require.Nil(t, decoded.PeerNonce)
+		require.Nil(t, decoded.PeerIdentityKey)
+	})
+
+	t.Run("Decode the golden file", func(t *testing.T) {
+		// given
+		data, err := os.ReadFile(filepath.Join("testdata", "session_v1_pending_handshake.golden"))
+		require.NoError(t, err)
+
+		// when
+		var decoded sessionmanager.PeerSession
+		err = decoded.UnmarshalBinary(data)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, "c2Vzc2lvbi1ub25jZQ==", decoded.GetSessionNonce())
+		require.Nil(t, decoded.PeerIdentityKey)
+		require.Equal(t, time.Date(2025, time.March, 14, 15, 9, 26, 535897932, time.UTC), decoded.LastUpdate)
+	})
+}
+
+func TestPeerSession_DecodingErrors(t *testing.T) {
+	tests := map[string]struct {
+		data        []byte
+		expectedErr error
+	}{
+		"empty data": {
+			data:        nil,
+			expectedErr: sessionmanager.ErrInvalidSessionEncoding,
+		},
+		"future version": {
+			data:        append([]byte{sessionmanager.SessionFormatVersion + 1}, `{"sessionNonce":"nonce"}`...),
+			expectedErr: sessionmanager.ErrUnsupportedSessionVersion,
+		},
+		"unversioned JSON": {
+			data:        []byte(`{"sessionNonce":"nonce"}`),
+			expectedErr: sessionmanager.ErrUnsupportedSessionVersion,
+		},
+		"corrupted payload": {
+			data:        append([]byte{sessionmanager.SessionFormatVersion}, `{"sessionNonce":`...),
+			expectedErr: sessionmanager.ErrInvalidSessionEncoding,
+		},
+		"invalid last update": {
+			data:        append([]byte{sessionmanager.SessionFormatVersion}, `{"sessionNonce":"nonce","lastUpdate":"yesterday"}`...),
+			expectedErr: sessionmanager.ErrInvalidSessionEncoding,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			var decoded sessionmanager.PeerSession
+			err := decoded.UnmarshalBinary(test.data)
+
+			// then
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_Expiration(t *testing.T) {
+	t.Run("Session expires between AddSession and GetSession", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithSessionTTL(time.Hour),
+		)
+		defer sessionManager.Close()
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), session)
+
+		// when
+		now = now.Add(2 * time.Hour)
+
+		// then
+		_, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionExpired)
+		_, err = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+
+	t.Run("Updated session doesn't expire", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithSessionTTL(time.Hour),
+		)
+		defer sessionManager.Close()
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), session)
+
+		// when
+		now = now.Add(50 * time.Minute)
+		session.LastUpdate = now
+		sessionManager.UpdateSession(t.Context(), session)
+		now = now.Add(50 * time.Minute)
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, session, *retrievedSession)
+	})
+
+	t.Run("Live session is preferred over an expired authenticated one", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithSessionTTL(time.Hour),
+		)
+		defer sessionManager.Close()
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 2)
+		sessions[0].IsAuthenticated = true
+		sessions[0].LastUpdate = now.Add(-2 * time.Hour)
+		sessions[1].LastUpdate = now
+
+		// when
+		sessionManager.AddSession(t.Context(), sessions[0])
+		sessionManager.AddSession(t.Context(), sessions[1])
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[1], *retrievedSession)
+	})
+
+	t.Run("Authenticated session is preferred over a newer one once the newer expires", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithSessionTTL(time.Hour),
+		)
+		defer sessionManager.Close()
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 2)
+		sessions[0].IsAuthenticated = true
+		sessions[0].LastUpdate = now.Add(-30 * time.Minute)
+		sessions[1].IsAuthenticated = true
+		sessions[1].LastUpdate = now.Add(-90 * time.Minute)
+		sessionManager.AddSession(t.Context(), sessions[0])
+		sessionManager.AddSession(t.Context(), sessions[1])
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[0], *retrievedSession)
+	})
+
+	t.Run("Expired sessions are removed from both indexes", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithSessionTTL(time.Hour),
+		)
+		defer sessionManager.Close()
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		for _, session := range sessions {
+			sessionManager.AddSession(t.Context(), session)
+		}
+
+		// when
+		now = now.Add(2 * time.Hour)
+		removed, err := sessionManager.RemoveExpiredSessions(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 3, removed)
+		result := sessionManager.Compact()
+		require.Zero(t, result.Sessions)
+		require.Zero(t, result.Identities)
+	})
+
+	t.Run("Background janitor removes expired sessions", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionTTL(10 * time.Millisecond))
+		defer sessionManager.Close()
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+
+		// then
+		require.Eventually(t, func() bool {
+			return sessionManager.Stats().ActiveSessions == 0
+		}, time.Second, 5*time.Millisecond)
+	})
+}
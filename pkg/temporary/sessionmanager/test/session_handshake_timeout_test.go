@@ -0,0 +1,96 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_HandshakeTimeout(t *testing.T) {
+	t.Run("Remove half-open sessions after the deadline", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithClock(func() time.Time { return now }),
+			sessionmanager.WithHandshakeTimeout(time.Hour),
+		)
+		defer sessionManager.Close()
+
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		sessions[0].IsAuthenticated = true
+		for _, session := range sessions {
+			sessionManager.AddSession(t.Context(), session)
+		}
+
+		// when
+		now = now.Add(2 * time.Hour)
+		fresh := sessionmanager.NewRandomPeerSession(t)
+		fresh.LastUpdate = now
+		sessionManager.AddSession(t.Context(), fresh)
+		removed, err := sessionManager.RemoveAbandonedHandshakes(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 2, removed)
+		require.False(t, sessionManager.HasSession(t.Context(), *sessions[1].SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *sessions[2].SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *sessions[0].SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *fresh.SessionNonce))
+
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[0], *retrievedSession)
+
+		stats := sessionManager.Stats()
+		require.Equal(t, 2, stats.AbandonedHandshakes)
+		require.Equal(t, 2, stats.ActiveSessions)
+	})
+
+	t.Run("Keep half-open sessions within the deadline", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithHandshakeTimeout(time.Hour))
+		defer sessionManager.Close()
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), session)
+
+		// when
+		removed, err := sessionManager.RemoveAbandonedHandshakes(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Zero(t, removed)
+		require.True(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("No timeout configured", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.LastUpdate = time.Now().Add(-24 * time.Hour)
+		sessionManager.AddSession(t.Context(), session)
+
+		// when
+		removed, err := sessionManager.RemoveAbandonedHandshakes(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Zero(t, removed)
+		require.True(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("Background removal", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithHandshakeTimeout(10 * time.Millisecond))
+		defer sessionManager.Close()
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), session)
+
+		// then
+		require.Eventually(t, func() bool {
+			return !sessionManager.HasSession(t.Context(), *session.SessionNonce)
+		}, time.Second, 5*time.Millisecond)
+	})
+}
@@ -0,0 +1,142 @@
+package auth_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+type hookCounters struct {
+	added, authenticated, removed, expired atomic.Int64
+}
+
+func registerCountingHooks(sessionManager *sessionmanager.SessionManager) *hookCounters {
+	counters := &hookCounters{}
+	sessionManager.OnAdded(func(sessionmanager.PeerSession) { counters.added.Add(1) })
+	sessionManager.OnAuthenticated(func(sessionmanager.PeerSession) { counters.authenticated.Add(1) })
+	sessionManager.OnRemoved(func(sessionmanager.PeerSession) { counters.removed.Add(1) })
+	sessionManager.OnExpired(func(sessionmanager.PeerSession) { counters.expired.Add(1) })
+	return counters
+}
+
+func TestSessionManager_Hooks(t *testing.T) {
+	t.Run("Lifecycle of a session", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		counters := registerCountingHooks(sessionManager)
+		session := sessionmanager.NewRandomPeerSession(t)
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// then
+		require.EqualValues(t, 1, counters.added.Load())
+		require.EqualValues(t, 0, counters.authenticated.Load())
+
+		// when
+		session.IsAuthenticated = true
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), session))
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), session))
+
+		// then
+		require.EqualValues(t, 1, counters.added.Load())
+		require.EqualValues(t, 1, counters.authenticated.Load())
+
+		// when
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+
+		// then
+		require.EqualValues(t, 1, counters.removed.Load())
+		require.EqualValues(t, 0, counters.expired.Load())
+	})
+
+	t.Run("Expired sessions fire OnExpired", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithSessionTTL(time.Hour),
+			sessionmanager.WithClock(func() time.Time { return now }),
+		)
+		t.Cleanup(sessionManager.Close)
+		counters := registerCountingHooks(sessionManager)
+		require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+
+		// when
+		now = now.Add(2 * time.Hour)
+		removed, err := sessionManager.RemoveExpiredSessions(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 1, removed)
+		require.EqualValues(t, 1, counters.expired.Load())
+		require.EqualValues(t, 0, counters.removed.Load())
+	})
+
+	t.Run("Evicted sessions fire OnRemoved", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(1, nil))
+		counters := registerCountingHooks(sessionManager)
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+		require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+
+		// then
+		require.EqualValues(t, 2, counters.added.Load())
+		require.EqualValues(t, 1, counters.removed.Load())
+	})
+
+	t.Run("Hooks may call back into the SessionManager", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		var found bool
+		sessionManager.OnAdded(func(added sessionmanager.PeerSession) {
+			found = sessionManager.HasSession(t.Context(), *added.SessionNonce)
+		})
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// then
+		require.True(t, found)
+	})
+
+	t.Run("Concurrent registration and invocation", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		counters := registerCountingHooks(sessionManager)
+		sessions := make([]sessionmanager.PeerSession, 100)
+		for i := range sessions {
+			sessions[i] = sessionmanager.NewRandomPeerSession(t)
+			sessions[i].IsAuthenticated = true
+		}
+
+		// when
+		var wg sync.WaitGroup
+		for _, session := range sessions {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				sessionManager.OnRemoved(func(sessionmanager.PeerSession) {})
+			}()
+			go func() {
+				defer wg.Done()
+				_ = sessionManager.AddSession(t.Context(), session)
+				_ = sessionManager.UpdateSession(t.Context(), session)
+				_ = sessionManager.RemoveSession(t.Context(), session)
+			}()
+		}
+		wg.Wait()
+
+		// then
+		require.EqualValues(t, len(sessions), counters.added.Load())
+		require.EqualValues(t, len(sessions), counters.authenticated.Load())
+		require.EqualValues(t, len(sessions), counters.removed.Load())
+	})
+}
@@ -0,0 +1,102 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_MaxSessionsPerIdentity(t *testing.T) {
+	t.Run("Flooding one identity key keeps memory bounded", func(t *testing.T) {
+		// given
+		store := sessionmanager.NewMemoryStore()
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 1000)
+		identityKey := *sessions[0].PeerIdentityKey
+
+		authenticated := sessions[0]
+		authenticated.IsAuthenticated = true
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), authenticated))
+		for _, session := range sessions[1:] {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+
+		// then
+		nonces, err := store.GetNoncesByIdentity(t.Context(), identityKey)
+		require.NoError(t, err)
+		require.Len(t, nonces, sessionmanager.DefaultMaxSessionsPerIdentity)
+
+		stored, err := store.List(t.Context())
+		require.NoError(t, err)
+		require.Len(t, stored, sessionmanager.DefaultMaxSessionsPerIdentity)
+		require.Equal(t, sessionmanager.DefaultMaxSessionsPerIdentity, sessionManager.Stats().ActiveSessions)
+
+		best, err := sessionManager.GetSession(t.Context(), identityKey)
+		require.NoError(t, err)
+		require.Equal(t, authenticated, *best)
+
+		latest, err := sessionManager.GetSession(t.Context(), *sessions[999].SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, sessions[999], *latest)
+	})
+
+	t.Run("Evicts oldest unauthenticated session first", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessionsPerIdentity(2))
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		base := time.Now().Round(0)
+		for i := range sessions {
+			sessions[i].LastUpdate = base.Add(time.Duration(i) * time.Second)
+		}
+		sessions[0].IsAuthenticated = true
+
+		// when
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+
+		// then
+		require.True(t, sessionManager.HasSession(t.Context(), *sessions[0].SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *sessions[1].SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *sessions[2].SessionNonce))
+	})
+
+	t.Run("Evicts oldest authenticated session when all are authenticated", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessionsPerIdentity(2))
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		base := time.Now().Round(0)
+		for i := range sessions {
+			sessions[i].LastUpdate = base.Add(time.Duration(i) * time.Second)
+			sessions[i].IsAuthenticated = true
+		}
+
+		// when
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+
+		// then
+		require.False(t, sessionManager.HasSession(t.Context(), *sessions[0].SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *sessions[1].SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *sessions[2].SessionNonce))
+	})
+
+	t.Run("Limit of zero disables the cap", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessionsPerIdentity(0))
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, sessionmanager.DefaultMaxSessionsPerIdentity+5)
+
+		// when
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+
+		// then
+		require.Equal(t, len(sessions), sessionManager.Stats().ActiveSessions)
+	})
+}
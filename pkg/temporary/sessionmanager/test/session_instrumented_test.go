@@ -0,0 +1,103 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumented(t *testing.T) {
+	t.Run("Scripted sequence of calls", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		sessionManager, err := sessionmanager.NewInstrumented(sessionmanager.NewSessionManager(), registry)
+		require.NoError(t, err)
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+
+		// when
+		for _, session := range sessions {
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		}
+		sessions[0].IsAuthenticated = true
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), sessions[0]))
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), sessions[1]))
+
+		_, err = sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		_, err = sessionManager.GetSession(t.Context(), *sessions[1].SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		_, err = sessionManager.GetSession(t.Context(), "non-existent-key")
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+
+		// then
+		metrics := gatherMetrics(t, registry)
+
+		require.Equal(t, 3.0, counterValue(t, metrics["bsv_auth_session_operations_total"], "add"))
+		require.Equal(t, 1.0, counterValue(t, metrics["bsv_auth_session_operations_total"], "update"))
+		require.Equal(t, 1.0, counterValue(t, metrics["bsv_auth_session_operations_total"], "remove"))
+		require.Equal(t, 2.0, metrics["bsv_auth_session_misses_total"].GetMetric()[0].GetCounter().GetValue())
+
+		require.EqualValues(t, 3, metrics["bsv_auth_session_get_duration_seconds"].GetMetric()[0].GetHistogram().GetSampleCount())
+
+		perIdentity := metrics["bsv_auth_session_sessions_per_identity"].GetMetric()[0].GetHistogram()
+		require.EqualValues(t, 3, perIdentity.GetSampleCount())
+		require.Equal(t, 1.0+2.0+3.0, perIdentity.GetSampleSum())
+
+		require.Equal(t, 2.0, metrics["bsv_auth_session_active_sessions"].GetMetric()[0].GetGauge().GetValue())
+		require.Equal(t, 1.0, metrics["bsv_auth_session_authenticated_sessions"].GetMetric()[0].GetGauge().GetValue())
+	})
+
+	t.Run("Gauges are skipped for managers without stats", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		withoutStats := struct{ sessionmanager.Interface }{sessionmanager.NewSessionManager()}
+
+		// when
+		_, err := sessionmanager.NewInstrumented(withoutStats, registry)
+
+		// then
+		require.NoError(t, err)
+		metrics := gatherMetrics(t, registry)
+		require.NotContains(t, metrics, "bsv_auth_session_active_sessions")
+	})
+
+	t.Run("Registering twice fails", func(t *testing.T) {
+		// given
+		registry := prometheus.NewRegistry()
+		_, err := sessionmanager.NewInstrumented(sessionmanager.NewSessionManager(), registry)
+		require.NoError(t, err)
+
+		// when
+		_, err = sessionmanager.NewInstrumented(sessionmanager.NewSessionManager(), registry)
+
+		// then
+		require.Error(t, err)
+	})
+}
+
+func gatherMetrics(t *testing.T, registry *prometheus.Registry) map[string]*dto.MetricFamily {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	metrics := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		metrics[family.GetName()] = family
+	}
+	return metrics
+}
+
+func counterValue(t *testing.T, family *dto.MetricFamily, operation string) float64 {
+	require.NotNil(t, family)
+	for _, metric := range family.GetMetric() {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "operation" && label.GetValue() == operation {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+	require.Failf(t, "missing counter", "operation %q", operation)
+	return 0
+}
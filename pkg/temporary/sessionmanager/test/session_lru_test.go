@@ -0,0 +1,130 @@
+package auth_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_MaxSessions(t *testing.T) {
+	t.Run("Inserting beyond capacity evicts the least recently used session", func(t *testing.T) {
+		// given
+		var evicted []sessionmanager.PeerSession
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(2, func(session sessionmanager.PeerSession) {
+			evicted = append(evicted, session)
+		}))
+		first, second, third := sessionmanager.NewRandomPeerSession(t), sessionmanager.NewRandomPeerSession(t), sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), first))
+		require.NoError(t, sessionManager.AddSession(t.Context(), second))
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), third))
+
+		// then
+		require.Equal(t, []sessionmanager.PeerSession{first}, evicted)
+		require.False(t, sessionManager.HasSession(t.Context(), *first.SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *first.PeerIdentityKey))
+		require.True(t, sessionManager.HasSession(t.Context(), *second.SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *third.SessionNonce))
+		require.Equal(t, 2, sessionManager.Stats().ActiveSessions)
+	})
+
+	t.Run("GetSession counts as use", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(2, nil))
+		first, second, third := sessionmanager.NewRandomPeerSession(t), sessionmanager.NewRandomPeerSession(t), sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), first))
+		require.NoError(t, sessionManager.AddSession(t.Context(), second))
+
+		// when
+		_, err := sessionManager.GetSession(t.Context(), *first.PeerIdentityKey)
+		require.NoError(t, err)
+		require.NoError(t, sessionManager.AddSession(t.Context(), third))
+
+		// then
+		require.True(t, sessionManager.HasSession(t.Context(), *first.SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *second.SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *third.SessionNonce))
+	})
+
+	t.Run("Removed sessions free capacity", func(t *testing.T) {
+		// given
+		var evicted []sessionmanager.PeerSession
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(2, func(session sessionmanager.PeerSession) {
+			evicted = append(evicted, session)
+		}))
+		first, second, third := sessionmanager.NewRandomPeerSession(t), sessionmanager.NewRandomPeerSession(t), sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), first))
+		require.NoError(t, sessionManager.AddSession(t.Context(), second))
+
+		// when
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), first))
+		require.NoError(t, sessionManager.AddSession(t.Context(), third))
+
+		// then
+		require.Empty(t, evicted)
+		require.True(t, sessionManager.HasSession(t.Context(), *second.SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), *third.SessionNonce))
+	})
+
+	t.Run("Eviction callback may call the SessionManager", func(t *testing.T) {
+		// given
+		var sessionManager *sessionmanager.SessionManager
+		var stillExists bool
+		sessionManager = sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(1, func(session sessionmanager.PeerSession) {
+			stillExists = sessionManager.HasSession(t.Context(), *session.SessionNonce)
+		}))
+		require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+
+		// when
+		require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+
+		// then
+		require.False(t, stillExists)
+	})
+}
+
+func BenchmarkSessionManager_AtCapacity(b *testing.B) {
+	for _, capacity := range []int{1000, 50000} {
+		b.Run(fmt.Sprintf("AddSession capacity %d", capacity), func(b *testing.B) {
+			sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(capacity, nil))
+			fillSessionManager(b, sessionManager, capacity)
+			sessions := newBenchmarkSessions(b, b.N)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = sessionManager.AddSession(b.Context(), sessions[i])
+			}
+		})
+
+		b.Run(fmt.Sprintf("GetSession capacity %d", capacity), func(b *testing.B) {
+			sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(capacity, nil))
+			sessions := fillSessionManager(b, sessionManager, capacity)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = sessionManager.GetSession(b.Context(), *sessions[i%capacity].SessionNonce)
+			}
+		})
+	}
+}
+
+func fillSessionManager(b *testing.B, sessionManager *sessionmanager.SessionManager, count int) []sessionmanager.PeerSession {
+	sessions := newBenchmarkSessions(b, count)
+	for _, session := range sessions {
+		require.NoError(b, sessionManager.AddSession(b.Context(), session))
+	}
+	return sessions
+}
+
+func newBenchmarkSessions(b *testing.B, count int) []sessionmanager.PeerSession {
+	sessions := make([]sessionmanager.PeerSession, count)
+	for i := range sessions {
+		nonce := fmt.Sprintf("nonce-%d-%d", b.N, i)
+		identityKey := fmt.Sprintf("identity-%d-%d", b.N, i)
+		sessions[i] = sessionmanager.PeerSession{SessionNonce: &nonce, PeerIdentityKey: &identityKey}
+	}
+	return sessions
+}
@@ -2,27 +2,36 @@ package auth_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
 	"github.com/stretchr/testify/require"
 )
 
 func TestSessionManager_HappyPath(t *testing.T) {
-	sessionManager := sessionmanager.NewSessionManager()
+	for name, newStore := range sessionStores() {
+		t.Run(name, func(t *testing.T) {
+			testSessionManagerHappyPath(t, sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(newStore(t))))
+		})
+	}
+}
 
+func testSessionManagerHappyPath(t *testing.T, sessionManager *sessionmanager.SessionManager) {
 	t.Run("Add and get session by both keys", func(t *testing.T) {
 		// given
-		session := sessionmanager.NewPeerSession(t)
+		session := sessionmanager.NewRandomPeerSession(t)
 
 		// when
-		sessionManager.AddSession(session)
+		sessionManager.AddSession(t.Context(), session)
 
 		// then
-		retrievedSession := sessionManager.GetSession(*session.SessionNonce)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, session, *retrievedSession)
 
-		retrievedSession = sessionManager.GetSession(*session.PeerIdentityKey)
+		retrievedSession, err = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, session, *retrievedSession)
 	})
@@ -33,116 +42,208 @@ func TestSessionManager_HappyPath(t *testing.T) {
 		identityKey := *sessions[0].PeerIdentityKey
 
 		// when
-		sessionManager.AddSession(sessions[0])
+		sessionManager.AddSession(t.Context(), sessions[0])
 
 		// then - the "best" session should be the only one
-		retrievedSession := sessionManager.GetSession(identityKey)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), identityKey)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, sessions[0], *retrievedSession)
 
 		// when
-		sessionManager.AddSession(sessions[1])
+		sessionManager.AddSession(t.Context(), sessions[1])
 
 		// then - the "best" session should be the most recent one
-		retrievedSession = sessionManager.GetSession(identityKey)
+		retrievedSession, err = sessionManager.GetSession(t.Context(), identityKey)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, sessions[1], *retrievedSession)
 
 		// when
 		sessions[2].IsAuthenticated = true
-		sessionManager.AddSession(sessions[2])
+		sessionManager.AddSession(t.Context(), sessions[2])
 
 		// then - the "best" session should be the authenticated one
-		retrievedSession = sessionManager.GetSession(identityKey)
+		retrievedSession, err = sessionManager.GetSession(t.Context(), identityKey)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, sessions[2], *retrievedSession)
 
 		// when
-		sessionManager.AddSession(sessions[3])
+		sessionManager.AddSession(t.Context(), sessions[3])
 
 		// then - the "best" session should still be the authenticated one
-		retrievedSession = sessionManager.GetSession(identityKey)
+		retrievedSession, err = sessionManager.GetSession(t.Context(), identityKey)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, sessions[2], *retrievedSession)
 
 		// when
 		sessions[4].IsAuthenticated = true
-		sessionManager.AddSession(sessions[4])
+		sessionManager.AddSession(t.Context(), sessions[4])
 
 		// then - the "best" session should be the most recent authenticated one
-		retrievedSession = sessionManager.GetSession(identityKey)
+		retrievedSession, err = sessionManager.GetSession(t.Context(), identityKey)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, sessions[4], *retrievedSession)
 	})
 
+	t.Run("Get all sessions by identity key", func(t *testing.T) {
+		// given
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		base := time.Now().UTC().Round(0)
+		for i := range sessions {
+			sessions[i].LastUpdate = base.Add(time.Duration(i) * time.Second)
+			require.NoError(t, sessionManager.AddSession(t.Context(), sessions[i]))
+		}
+
+		// when
+		retrievedSessions, err := sessionManager.GetSessionsByIdentityKey(t.Context(), *sessions[0].PeerIdentityKey)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []sessionmanager.PeerSession{sessions[2], sessions[1], sessions[0]}, retrievedSessions)
+
+		// when
+		retrievedSessions, err = sessionManager.GetSessionsByIdentityKey(t.Context(), "non-existent-key")
+
+		// then
+		require.NoError(t, err)
+		require.Empty(t, retrievedSessions)
+	})
+
 	t.Run("Update session", func(t *testing.T) {
 		// given
-		session := sessionmanager.NewPeerSession(t)
-		sessionManager.AddSession(session)
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), session)
 
 		// when
 		session.IsAuthenticated = true
-		sessionManager.UpdateSession(session)
+		sessionManager.UpdateSession(t.Context(), session)
 
 		// then
-		retrievedSession := sessionManager.GetSession(*session.SessionNonce)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
 		require.NotNil(t, retrievedSession)
 		require.Equal(t, session, *retrievedSession)
 	})
 
 	t.Run("Remove session", func(t *testing.T) {
 		// given
-		session := sessionmanager.NewPeerSession(t)
-		sessionManager.AddSession(session)
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager.AddSession(t.Context(), session)
 
 		// when
-		sessionManager.RemoveSession(session)
+		sessionManager.RemoveSession(t.Context(), session)
 
 		// then
-		retrievedSession := sessionManager.GetSession(*session.SessionNonce)
-		require.Nil(t, retrievedSession)
+		_, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
 
-		retrievedSession = sessionManager.GetSession(*session.PeerIdentityKey)
-		require.Nil(t, retrievedSession)
+		_, err = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
 	})
 }
 
 func TestSessionManager_ErrorPath(t *testing.T) {
-	sessionManager := sessionmanager.NewSessionManager()
+	for name, newStore := range sessionStores() {
+		t.Run(name, func(t *testing.T) {
+			testSessionManagerErrorPath(t, sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(newStore(t))))
+		})
+	}
+}
 
+func testSessionManagerErrorPath(t *testing.T, sessionManager *sessionmanager.SessionManager) {
 	t.Run("Get non-existent session", func(t *testing.T) {
 		// given
 		invalidKey := "non-existent-key"
 
 		// when
-		retrievedSession := sessionManager.GetSession(invalidKey)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), invalidKey)
 
 		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
 		require.Nil(t, retrievedSession)
 	})
 
 	t.Run("Remove non-existent session", func(t *testing.T) {
 		// given
-		session := sessionmanager.NewPeerSession(t)
+		session := sessionmanager.NewRandomPeerSession(t)
 
 		// when
-		sessionManager.RemoveSession(session)
+		sessionManager.RemoveSession(t.Context(), session)
 
 		// then
-		retrievedSession := sessionManager.GetSession(*session.SessionNonce)
-		require.Nil(t, retrievedSession)
+		_, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
 	})
 
 	t.Run("Update non-existent session", func(t *testing.T) {
 		// given
-		session := sessionmanager.NewPeerSession(t)
+		session := sessionmanager.NewRandomPeerSession(t)
 
 		// when
-		sessionManager.UpdateSession(session)
+		err := sessionManager.UpdateSession(t.Context(), session)
 
 		// then
-		retrievedSession := sessionManager.GetSession(*session.SessionNonce)
-		require.NotNil(t, retrievedSession)
-		require.Equal(t, session, *retrievedSession)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("Add session twice", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		err := sessionManager.AddSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionAlreadyExists)
+		sessions, err := sessionManager.GetSessionsByIdentityKey(t.Context(), *session.PeerIdentityKey)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+
+		// when
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+
+		// then
+		require.False(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+
+	t.Run("Update after remove", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+
+		// when
+		err := sessionManager.UpdateSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+		require.False(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+
+	t.Run("Update with changed identity key", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		previousIdentityKey := *session.PeerIdentityKey
+
+		// when
+		updated := session.Clone()
+		newIdentityKey := previousIdentityKey + "-changed"
+		updated.PeerIdentityKey = &newIdentityKey
+		err := sessionManager.UpdateSession(t.Context(), updated)
+
+		// then
+		require.NoError(t, err)
+		require.False(t, sessionManager.HasSession(t.Context(), previousIdentityKey))
+		sessions, err := sessionManager.GetSessionsByIdentityKey(t.Context(), newIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, []sessionmanager.PeerSession{updated}, sessions)
 	})
 }
@@ -0,0 +1,178 @@
+package auth_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_Metadata(t *testing.T) {
+	t.Run("Metadata persists across UpdateSession", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		retrievedSession.IsAuthenticated = true
+		retrievedSession.SetMeta("capabilities", []string{"payments"})
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), *retrievedSession))
+
+		// then
+		updatedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.True(t, updatedSession.IsAuthenticated)
+		tenant, ok := updatedSession.GetMeta("tenant")
+		require.True(t, ok)
+		require.Equal(t, "tenant-a", tenant)
+		capabilities, ok := updatedSession.GetMeta("capabilities")
+		require.True(t, ok)
+		require.Equal(t, []string{"payments"}, capabilities)
+	})
+
+	t.Run("TouchSession keeps the metadata written since the session was read", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		stale, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		concurrent := session.Clone()
+		concurrent.SetMeta("tenant", "tenant-a")
+		require.NoError(t, sessionManager.UpdateSession(t.Context(), concurrent))
+
+		// when
+		lastUpdate := stale.LastUpdate.Add(time.Minute)
+		err = sessionManager.TouchSession(t.Context(), stale.GetSessionNonce(), lastUpdate)
+
+		// then
+		require.NoError(t, err)
+		stored, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, lastUpdate, stored.LastUpdate)
+		tenant, ok := stored.GetMeta("tenant")
+		require.True(t, ok)
+		require.Equal(t, "tenant-a", tenant)
+	})
+
+	t.Run("TouchSession of a non-existent session", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+
+		// when
+		err := sessionManager.TouchSession(t.Context(), *session.SessionNonce, time.Now())
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+	})
+
+	t.Run("Copies returned by GetSession are isolated", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		first, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		second, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+
+		// when
+		first.Metadata["tenant"] = "tenant-b"
+		second.SetMeta("tenant", "tenant-c")
+
+		// then
+		stored, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		tenant, _ := stored.GetMeta("tenant")
+		require.Equal(t, "tenant-a", tenant)
+		tenant, _ = first.GetMeta("tenant")
+		require.Equal(t, "tenant-b", tenant)
+	})
+
+	t.Run("SetMeta doesn't modify copies of the session", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		shallowCopy := session
+
+		// when
+		shallowCopy.SetMeta("tenant", "tenant-b")
+
+		// then
+		tenant, _ := session.GetMeta("tenant")
+		require.Equal(t, "tenant-a", tenant)
+	})
+
+	t.Run("DeleteMeta doesn't modify copies of the session", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		shallowCopy := session
+
+		// when
+		shallowCopy.DeleteMeta("tenant")
+
+		// then
+		_, ok := shallowCopy.GetMeta("tenant")
+		require.False(t, ok)
+		tenant, _ := session.GetMeta("tenant")
+		require.Equal(t, "tenant-a", tenant)
+	})
+
+	t.Run("Concurrent metadata writers don't race", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.SetMeta("tenant", "tenant-a")
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		var wg sync.WaitGroup
+		for i := range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+				if err != nil {
+					return
+				}
+				retrievedSession.SetMeta("writer", i)
+				_ = sessionManager.UpdateSession(t.Context(), *retrievedSession)
+			}()
+		}
+		wg.Wait()
+
+		// then
+		stored, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		_, ok := stored.GetMeta("writer")
+		require.True(t, ok)
+	})
+
+	t.Run("Metadata survives encoding", func(t *testing.T) {
+		// given
+		session, err := sessionmanager.NewPeerSession("session-nonce", sessionmanager.WithMeta("tenant", "tenant-a"))
+		require.NoError(t, err)
+
+		// when
+		data, err := session.MarshalBinary()
+		require.NoError(t, err)
+		var decoded sessionmanager.PeerSession
+		require.NoError(t, decoded.UnmarshalBinary(data))
+
+		// then
+		tenant, ok := decoded.GetMeta("tenant")
+		require.True(t, ok)
+		require.Equal(t, "tenant-a", tenant)
+	})
+}
@@ -0,0 +1,148 @@
+package auth_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_ListSessions(t *testing.T) {
+	t.Run("Paginate through all sessions in nonce order", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		for i := 0; i < 250; i++ {
+			require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+		}
+
+		// when
+		var listed []sessionmanager.PeerSession
+		cursor := ""
+		pages := 0
+		for {
+			page, nextCursor, err := sessionManager.ListSessions(t.Context(), cursor, 0)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page), sessionmanager.DefaultPageSize)
+			listed = append(listed, page...)
+			pages++
+			if nextCursor == "" {
+				break
+			}
+			cursor = nextCursor
+		}
+
+		// then
+		require.Equal(t, 3, pages)
+		require.Len(t, listed, 250)
+		for i := 1; i < len(listed); i++ {
+			require.Less(t, *listed[i-1].SessionNonce, *listed[i].SessionNonce)
+		}
+	})
+
+	t.Run("Pages are copies", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		page, _, err := sessionManager.ListSessions(t.Context(), "", 10)
+		require.NoError(t, err)
+		*page[0].PeerIdentityKey = "modified"
+
+		// then
+		retrieved, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, session, *retrieved)
+	})
+
+	t.Run("Unknown cursor returns an empty page", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+
+		// when
+		page, nextCursor, err := sessionManager.ListSessions(t.Context(), "%%% not a cursor %%%", 10)
+
+		// then
+		require.NoError(t, err)
+		require.Empty(t, page)
+		require.Empty(t, nextCursor)
+	})
+
+	t.Run("Limit is capped", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		for i := 0; i < sessionmanager.MaxPageSize+1; i++ {
+			require.NoError(t, sessionManager.AddSession(t.Context(), newIndexedSession(i)))
+		}
+
+		// when
+		page, nextCursor, err := sessionManager.ListSessions(t.Context(), "", sessionmanager.MaxPageSize*10)
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, page, sessionmanager.MaxPageSize)
+		require.NotEmpty(t, nextCursor)
+	})
+}
+
+func TestSessionManager_ListSessionsWhileMutating(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager()
+	const stable = 10000
+	for i := 0; i < stable; i++ {
+		require.NoError(t, sessionManager.AddSession(t.Context(), newIndexedSession(i)))
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := stable; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			session := newIndexedSession(i)
+			_ = sessionManager.AddSession(t.Context(), session)
+			_ = sessionManager.RemoveSession(t.Context(), session)
+		}
+	}()
+
+	// when
+	seen := make(map[string]struct{}, stable)
+	cursor := ""
+	for {
+		page, nextCursor, err := sessionManager.ListSessions(t.Context(), cursor, 500)
+		require.NoError(t, err)
+		for _, session := range page {
+			_, duplicate := seen[*session.SessionNonce]
+			require.False(t, duplicate)
+			seen[*session.SessionNonce] = struct{}{}
+		}
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	close(done)
+	wg.Wait()
+
+	// then
+	for i := 0; i < stable; i++ {
+		_, listed := seen[*newIndexedSession(i).SessionNonce]
+		require.True(t, listed)
+	}
+}
+
+// newIndexedSession creates a session with a deterministic nonce and its own peerIdentityKey.
+func newIndexedSession(i int) sessionmanager.PeerSession {
+	nonce := fmt.Sprintf("nonce-%08d", i)
+	identityKey := fmt.Sprintf("identity-%08d", i)
+	return sessionmanager.PeerSession{SessionNonce: &nonce, PeerIdentityKey: &identityKey}
+}
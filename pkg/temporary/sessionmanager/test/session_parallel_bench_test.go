@@ -0,0 +1,50 @@
+package auth_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+)
+
+func BenchmarkGetSessionParallel(b *testing.B) {
+	sessionManager := sessionmanager.NewSessionManager()
+	sessions := newBenchmarkSessions(b, 10000)
+	for _, session := range sessions {
+		_ = sessionManager.AddSession(b.Context(), session)
+	}
+	var counter atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := counter.Add(1)
+			session := sessions[int(i)%len(sessions)]
+			if i%2 == 0 {
+				_, _ = sessionManager.GetSession(b.Context(), *session.SessionNonce)
+			} else {
+				_, _ = sessionManager.GetSession(b.Context(), *session.PeerIdentityKey)
+			}
+		}
+	})
+}
+
+func BenchmarkAddRemoveParallel(b *testing.B) {
+	sessionManager := sessionmanager.NewSessionManager()
+	var counter atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			i := counter.Add(1)
+			nonce := fmt.Sprintf("nonce-%d", i)
+			identityKey := fmt.Sprintf("identity-%d", i%1000)
+			session := sessionmanager.PeerSession{SessionNonce: &nonce, PeerIdentityKey: &identityKey}
+
+			_ = sessionManager.AddSession(b.Context(), session)
+			_, _ = sessionManager.GetSession(b.Context(), nonce)
+			_ = sessionManager.RemoveSession(b.Context(), session)
+		}
+	})
+}
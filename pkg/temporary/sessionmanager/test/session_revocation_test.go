@@ -0,0 +1,122 @@
+package auth_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_RemoveAllSessionsForPeer(t *testing.T) {
+	for name, newStore := range sessionStores() {
+		t.Run(name, func(t *testing.T) {
+			// given
+			store := newStore(t)
+			sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+			sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 4)
+			for _, session := range sessions {
+				require.NoError(t, sessionManager.AddSession(t.Context(), session))
+			}
+			other := sessionmanager.NewRandomPeerSession(t)
+			require.NoError(t, sessionManager.AddSession(t.Context(), other))
+			identityKey := *sessions[0].PeerIdentityKey
+
+			// when
+			removed, err := sessionManager.RemoveAllSessionsForPeer(t.Context(), identityKey)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, len(sessions), removed)
+			require.False(t, sessionManager.HasSession(t.Context(), identityKey))
+			for _, session := range sessions {
+				require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+			}
+			nonces, err := store.GetNoncesByIdentity(t.Context(), identityKey)
+			require.NoError(t, err)
+			require.Empty(t, nonces)
+			require.True(t, sessionManager.HasSession(t.Context(), *other.SessionNonce))
+
+			// when
+			removed, err = sessionManager.RemoveAllSessionsForPeer(t.Context(), identityKey)
+
+			// then
+			require.NoError(t, err)
+			require.Zero(t, removed)
+		})
+	}
+}
+
+func TestSessionManager_RemoveAllSessionsForPeerDanglingNonces(t *testing.T) {
+	// given
+	store := &danglingStore{SessionStore: sessionmanager.NewMemoryStore(), dangling: "dangling-nonce"}
+	sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(store))
+	session := sessionmanager.NewRandomPeerSession(t)
+	require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+	// when
+	removed, err := sessionManager.RemoveAllSessionsForPeer(t.Context(), *session.PeerIdentityKey)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+}
+
+func TestSessionManager_RemoveAllSessionsForPeerConcurrentAdds(t *testing.T) {
+	// given
+	store := sessionmanager.NewMemoryStore()
+	sessionManager := sessionmanager.NewSessionManager(
+		sessionmanager.WithSessionStore(store),
+		sessionmanager.WithMaxSessionsPerIdentity(0),
+	)
+	sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 200)
+	identityKey := *sessions[0].PeerIdentityKey
+
+	// when
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	totalRemoved := 0
+	for i, session := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, sessionManager.AddSession(t.Context(), session))
+			if i%10 == 0 {
+				removed, err := sessionManager.RemoveAllSessionsForPeer(t.Context(), identityKey)
+				require.NoError(t, err)
+				mu.Lock()
+				totalRemoved += removed
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// then
+	nonces, err := store.GetNoncesByIdentity(t.Context(), identityKey)
+	require.NoError(t, err)
+	stored, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stored, len(nonces))
+	for _, nonce := range nonces {
+		require.True(t, sessionManager.HasSession(t.Context(), nonce))
+	}
+	require.Equal(t, len(sessions), totalRemoved+len(nonces))
+	require.Equal(t, len(nonces), sessionManager.Stats().ActiveSessions)
+}
+
+// danglingStore is a SessionStore whose identity index also references a nonce without a session.
+type danglingStore struct {
+	sessionmanager.SessionStore
+	dangling string
+}
+
+func (s *danglingStore) GetNoncesByIdentity(ctx context.Context, identityKey string) ([]string, error) {
+	nonces, err := s.SessionStore.GetNoncesByIdentity(ctx, identityKey)
+	if err != nil || len(nonces) == 0 {
+		return nonces, err
+	}
+	return append(nonces, s.dangling), nil
+}
@@ -0,0 +1,183 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	walletFixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+// sequentialNonces creates distinct nonces, unlike the mock wallet which always returns the same one.
+type sequentialNonces struct {
+	next atomic.Int64
+}
+
+func (s *sequentialNonces) CreateNonce(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return fmt.Sprintf("rotated-nonce-%d", s.next.Add(1)), nil
+}
+
+func TestSessionManager_RotateSessionNonce(t *testing.T) {
+	t.Run("Rotation re-keys the session and keeps the old nonce resolvable", func(t *testing.T) {
+		// given
+		now := time.Now().Round(0)
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithNonceCreator(wallet.NewMockWallet(false)),
+			sessionmanager.WithClock(func() time.Time { return now }),
+		)
+		session := sessionmanager.NewRandomPeerSession(t)
+		session.IsAuthenticated = true
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		now = now.Add(time.Minute)
+
+		// when
+		newNonce, err := sessionManager.RotateSessionNonce(t.Context(), *session.SessionNonce)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, walletFixtures.MockNonce, newNonce)
+
+		rotated, err := sessionManager.GetSession(t.Context(), newNonce)
+		require.NoError(t, err)
+		require.Equal(t, newNonce, *rotated.SessionNonce)
+		require.Equal(t, *session.PeerIdentityKey, *rotated.PeerIdentityKey)
+		require.Equal(t, now, rotated.LastUpdate)
+		require.True(t, rotated.IsAuthenticated)
+
+		viaOldNonce, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.Equal(t, rotated, viaOldNonce)
+
+		sessions, err := sessionManager.GetSessionsByIdentityKey(t.Context(), *session.PeerIdentityKey)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, newNonce, *sessions[0].SessionNonce)
+		require.Equal(t, 1, sessionManager.Stats().ActiveSessions)
+	})
+
+	t.Run("Old nonce is forgotten after the grace period", func(t *testing.T) {
+		// given
+		now := time.Now()
+		sessionManager := sessionmanager.NewSessionManager(
+			sessionmanager.WithNonceCreator(&sequentialNonces{}),
+			sessionmanager.WithRotationGracePeriod(time.Second),
+			sessionmanager.WithClock(func() time.Time { return now }),
+		)
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		newNonce, err := sessionManager.RotateSessionNonce(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(2 * time.Second)
+
+		// then
+		_, err = sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.False(t, sessionManager.HasSession(t.Context(), *session.SessionNonce))
+		require.True(t, sessionManager.HasSession(t.Context(), newNonce))
+	})
+
+	t.Run("Update with the old nonce updates the rotated session", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithNonceCreator(&sequentialNonces{}))
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+		newNonce, err := sessionManager.RotateSessionNonce(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+
+		// when
+		session.IsAuthenticated = true
+		err = sessionManager.UpdateSession(t.Context(), session)
+
+		// then
+		require.NoError(t, err)
+		rotated, err := sessionManager.GetSession(t.Context(), newNonce)
+		require.NoError(t, err)
+		require.True(t, rotated.IsAuthenticated)
+		require.Equal(t, 1, sessionManager.Stats().ActiveSessions)
+	})
+
+	t.Run("Repeated rotation keeps every old nonce pointing to the latest session", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithNonceCreator(&sequentialNonces{}))
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		second, err := sessionManager.RotateSessionNonce(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		third, err := sessionManager.RotateSessionNonce(t.Context(), second)
+		require.NoError(t, err)
+
+		// then
+		for _, nonce := range []string{*session.SessionNonce, second, third} {
+			retrieved, err := sessionManager.GetSession(t.Context(), nonce)
+			require.NoError(t, err)
+			require.Equal(t, third, *retrieved.SessionNonce)
+		}
+	})
+
+	t.Run("Rotation errors", func(t *testing.T) {
+		// given
+		withoutCreator := sessionmanager.NewSessionManager()
+		withCreator := sessionmanager.NewSessionManager(sessionmanager.WithNonceCreator(&sequentialNonces{}))
+
+		// when
+		_, errNoCreator := withoutCreator.RotateSessionNonce(t.Context(), "nonce")
+		_, errNotFound := withCreator.RotateSessionNonce(t.Context(), "non-existent-nonce")
+
+		// then
+		require.ErrorIs(t, errNoCreator, sessionmanager.ErrNoNonceCreator)
+		require.ErrorIs(t, errNotFound, sessionmanager.ErrSessionNotFound)
+	})
+}
+
+func TestSessionManager_RotateSessionNonceConcurrentRequests(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager(
+		sessionmanager.WithNonceCreator(&sequentialNonces{}),
+		sessionmanager.WithRotationGracePeriod(time.Minute),
+	)
+	session := sessionmanager.NewRandomPeerSession(t)
+	require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+	// when
+	var wg sync.WaitGroup
+	var misses atomic.Int64
+	for r := 0; r < 8; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if _, err := sessionManager.GetSession(t.Context(), *session.SessionNonce); err != nil {
+					misses.Add(1)
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		nonce := *session.SessionNonce
+		for i := 0; i < 20; i++ {
+			rotated, err := sessionManager.RotateSessionNonce(t.Context(), nonce)
+			require.NoError(t, err)
+			nonce = rotated
+		}
+	}()
+	wg.Wait()
+
+	// then
+	require.Zero(t, misses.Load())
+}
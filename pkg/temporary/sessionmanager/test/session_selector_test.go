@@ -0,0 +1,77 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_SessionSelector(t *testing.T) {
+	t.Run("Use custom selector for identity key lookups", func(t *testing.T) {
+		// given
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		sessions[1].IsAuthenticated = true
+		pinnedNonce := *sessions[0].SessionNonce
+		selectPinned := func(candidates []sessionmanager.PeerSession) *sessionmanager.PeerSession {
+			for i := range candidates {
+				if *candidates[i].SessionNonce == pinnedNonce {
+					return &candidates[i]
+				}
+			}
+			return nil
+		}
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionSelector(selectPinned))
+
+		// when
+		for _, session := range sessions {
+			sessionManager.AddSession(t.Context(), session)
+		}
+
+		// then
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+		require.Equal(t, sessions[0], *retrievedSession)
+	})
+
+	t.Run("Selector returning nil means no session", func(t *testing.T) {
+		// given
+		session := sessionmanager.NewRandomPeerSession(t)
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionSelector(
+			func([]sessionmanager.PeerSession) *sessionmanager.PeerSession { return nil },
+		))
+
+		// when
+		sessionManager.AddSession(t.Context(), session)
+
+		// then
+		_, err := sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+		require.ErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.NotNil(t, retrievedSession)
+	})
+
+	t.Run("Default selector prefers authenticated sessions", func(t *testing.T) {
+		// given
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+		sessions[0].IsAuthenticated = true
+
+		// when
+		selected := sessionmanager.DefaultSessionSelector(sessions)
+
+		// then
+		require.NotNil(t, selected)
+		require.Equal(t, sessions[0], *selected)
+	})
+
+	t.Run("Default selector with no sessions", func(t *testing.T) {
+		// when
+		selected := sessionmanager.DefaultSessionSelector(nil)
+
+		// then
+		require.Nil(t, selected)
+	})
+}
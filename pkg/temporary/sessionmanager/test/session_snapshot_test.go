@@ -0,0 +1,151 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+// newUTCSessions creates sessions of a single peer with UTC based LastUpdate values,
+// so they survive the round trip through JSON unchanged.
+func newUTCSessions(t *testing.T, count int, base time.Time) []sessionmanager.PeerSession {
+	sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, count)
+	for i := range sessions {
+		sessions[i].LastUpdate = base.UTC().Add(time.Duration(i) * time.Minute)
+	}
+	return sessions
+}
+
+func TestSessionManager_Snapshot(t *testing.T) {
+	t.Run("Round trip restores sessions and identity index", func(t *testing.T) {
+		// given
+		source := sessionmanager.NewSessionManager()
+		sessions := newUTCSessions(t, 3, time.Now())
+		sessions[1].IsAuthenticated = true
+		other := sessionmanager.NewRandomPeerSession(t)
+		other.LastUpdate = other.LastUpdate.UTC()
+		for _, session := range append(sessions, other) {
+			require.NoError(t, source.AddSession(t.Context(), session))
+		}
+
+		// when
+		data, err := source.Export(t.Context())
+		require.NoError(t, err)
+
+		restored := sessionmanager.NewSessionManager()
+		err = restored.Import(t.Context(), data)
+
+		// then
+		require.NoError(t, err)
+		for _, session := range append(sessions, other) {
+			retrievedSession, err := restored.GetSession(t.Context(), *session.SessionNonce)
+			require.NoError(t, err)
+			require.Equal(t, session, *retrievedSession)
+		}
+
+		best, err := restored.GetSession(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, sessions[1], *best)
+
+		retrievedSessions, err := restored.GetSessionsByIdentityKey(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.Equal(t, []sessionmanager.PeerSession{sessions[2], sessions[1], sessions[0]}, retrievedSessions)
+		require.Equal(t, 4, restored.Stats().ActiveSessions)
+	})
+
+	t.Run("Expired sessions are skipped", func(t *testing.T) {
+		// given
+		now := time.Now()
+		source := sessionmanager.NewSessionManager()
+		sessions := newUTCSessions(t, 2, now.Add(-2*time.Hour))
+		sessions[1].LastUpdate = now.UTC()
+		for _, session := range sessions {
+			require.NoError(t, source.AddSession(t.Context(), session))
+		}
+		data, err := source.Export(t.Context())
+		require.NoError(t, err)
+
+		restored := sessionmanager.NewSessionManager(
+			sessionmanager.WithSessionTTL(time.Hour),
+			sessionmanager.WithClock(func() time.Time { return now }),
+		)
+		t.Cleanup(restored.Close)
+
+		// when
+		err = restored.Import(t.Context(), data)
+
+		// then
+		require.NoError(t, err)
+		require.False(t, restored.HasSession(t.Context(), *sessions[0].SessionNonce))
+		require.True(t, restored.HasSession(t.Context(), *sessions[1].SessionNonce))
+		require.Equal(t, 1, restored.Stats().ActiveSessions)
+	})
+
+	t.Run("Serialized index is not trusted", func(t *testing.T) {
+		// given
+		session := newUTCSessions(t, 1, time.Now())[0]
+		data, err := json.Marshal(map[string]any{
+			"version":               sessionmanager.SnapshotVersion,
+			"sessions":              []any{session},
+			"identityKeyToSessions": map[string][]string{"forged-identity": {*session.SessionNonce}},
+		})
+		require.NoError(t, err)
+		sessionManager := sessionmanager.NewSessionManager()
+
+		// when
+		err = sessionManager.Import(t.Context(), data)
+
+		// then
+		require.NoError(t, err)
+		require.False(t, sessionManager.HasSession(t.Context(), "forged-identity"))
+		require.True(t, sessionManager.HasSession(t.Context(), *session.PeerIdentityKey))
+	})
+}
+
+func TestSessionManager_SnapshotErrors(t *testing.T) {
+	tests := map[string]struct {
+		data        string
+		expectedErr error
+	}{
+		"corrupted input": {
+			data:        `{"version":1,"sessions":[{"sessionNonce":`,
+			expectedErr: sessionmanager.ErrInvalidSnapshot,
+		},
+		"not a snapshot": {
+			data:        `"sessions"`,
+			expectedErr: sessionmanager.ErrInvalidSnapshot,
+		},
+		"unsupported version": {
+			data:        `{"version":2,"sessions":[]}`,
+			expectedErr: sessionmanager.ErrUnsupportedSnapshotVersion,
+		},
+		"missing version": {
+			data:        `{"sessions":[]}`,
+			expectedErr: sessionmanager.ErrUnsupportedSnapshotVersion,
+		},
+		"session without nonce": {
+			data:        `{"version":1,"sessions":[{"sessionNonce":"valid"},{"peerIdentityKey":"key"}]}`,
+			expectedErr: sessionmanager.ErrInvalidSnapshot,
+		},
+		"authenticated session without identity key": {
+			data:        `{"version":1,"sessions":[{"sessionNonce":"valid","peerNonce":"peer","isAuthenticated":true}]}`,
+			expectedErr: sessionmanager.ErrInvalidSession,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			sessionManager := sessionmanager.NewSessionManager()
+
+			// when
+			err := sessionManager.Import(t.Context(), []byte(test.data))
+
+			// then
+			require.ErrorIs(t, err, test.expectedErr)
+			require.Zero(t, sessionManager.Stats().ActiveSessions)
+		})
+	}
+}
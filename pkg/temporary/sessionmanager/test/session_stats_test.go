@@ -0,0 +1,69 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionManager_Stats(t *testing.T) {
+	t.Run("Track active and authenticated sessions", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 3)
+
+		// when
+		for _, session := range sessions {
+			sessionManager.AddSession(t.Context(), session)
+		}
+		sessions[0].IsAuthenticated = true
+		sessionManager.UpdateSession(t.Context(), sessions[0])
+		sessionManager.RemoveSession(t.Context(), sessions[1])
+
+		// then
+		stats := sessionManager.Stats()
+		require.Equal(t, 2, stats.ActiveSessions)
+		require.Equal(t, 1, stats.AuthenticatedSessions)
+		require.Equal(t, 1, stats.DistinctIdentitiesToday)
+	})
+
+	t.Run("Count new sessions within window", func(t *testing.T) {
+		// given
+		now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithClock(func() time.Time { return now }))
+
+		// when
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+		now = now.Add(10 * time.Minute)
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+
+		// then
+		require.Equal(t, 2, sessionManager.NewSessionsWithin(5*time.Minute))
+		require.Equal(t, 3, sessionManager.NewSessionsWithin(15*time.Minute))
+
+		// when
+		now = now.Add(2 * time.Hour)
+
+		// then
+		require.Equal(t, 0, sessionManager.NewSessionsWithin(time.Hour))
+		require.Equal(t, 3, sessionManager.Stats().ActiveSessions)
+	})
+
+	t.Run("Reset distinct identities at day boundary", func(t *testing.T) {
+		// given
+		now := time.Date(2025, 3, 10, 23, 30, 0, 0, time.UTC)
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithClock(func() time.Time { return now }))
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+		sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t))
+
+		// when
+		now = now.Add(time.Hour)
+
+		// then
+		require.Equal(t, 0, sessionManager.Stats().DistinctIdentitiesToday)
+		require.Equal(t, 2, sessionManager.Stats().ActiveSessions)
+	})
+}
@@ -0,0 +1,81 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+// failingStore is a SessionStore whose every operation fails.
+type failingStore struct{}
+
+func (failingStore) Put(context.Context, sessionmanager.PeerSession) error {
+	return errStoreUnavailable
+}
+
+func (failingStore) GetByNonce(context.Context, string) (sessionmanager.PeerSession, bool, error) {
+	return sessionmanager.PeerSession{}, false, errStoreUnavailable
+}
+
+func (failingStore) GetNoncesByIdentity(context.Context, string) ([]string, error) {
+	return nil, errStoreUnavailable
+}
+
+func (failingStore) Delete(context.Context, string) error { return errStoreUnavailable }
+
+func (failingStore) List(context.Context) ([]sessionmanager.PeerSession, error) {
+	return nil, errStoreUnavailable
+}
+
+func TestSessionManager_StoreErrors(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithSessionStore(failingStore{}))
+	session := sessionmanager.NewRandomPeerSession(t)
+
+	t.Run("AddSession surfaces store failure", func(t *testing.T) {
+		// when
+		err := sessionManager.AddSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, errStoreUnavailable)
+	})
+
+	t.Run("UpdateSession surfaces store failure", func(t *testing.T) {
+		// when
+		err := sessionManager.UpdateSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, errStoreUnavailable)
+	})
+
+	t.Run("GetSession distinguishes failure from missing session", func(t *testing.T) {
+		// when
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+
+		// then
+		require.ErrorIs(t, err, errStoreUnavailable)
+		require.NotErrorIs(t, err, sessionmanager.ErrSessionNotFound)
+		require.Nil(t, retrievedSession)
+	})
+
+	t.Run("RemoveSession surfaces store failure", func(t *testing.T) {
+		// when
+		err := sessionManager.RemoveSession(t.Context(), session)
+
+		// then
+		require.ErrorIs(t, err, errStoreUnavailable)
+	})
+
+	t.Run("HasSession reports no session on failure", func(t *testing.T) {
+		// when
+		exists := sessionManager.HasSession(t.Context(), *session.SessionNonce)
+
+		// then
+		require.False(t, exists)
+	})
+}
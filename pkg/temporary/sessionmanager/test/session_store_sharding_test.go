@@ -0,0 +1,159 @@
+package auth_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_ConcurrentCrossShardOperations(t *testing.T) {
+	// given
+	store := sessionmanager.NewMemoryStore()
+	sessionManager := sessionmanager.NewSessionManager(
+		sessionmanager.WithSessionStore(store),
+		sessionmanager.WithMaxSessionsPerIdentity(0),
+	)
+	identities := make([][]sessionmanager.PeerSession, 8)
+	for i := range identities {
+		identities[i] = sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 50)
+	}
+
+	// when
+	var wg sync.WaitGroup
+	for _, sessions := range identities {
+		for i, session := range sessions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.NoError(t, sessionManager.AddSession(t.Context(), session))
+				_, _ = sessionManager.GetSession(t.Context(), *session.PeerIdentityKey)
+				if i%2 == 0 {
+					require.NoError(t, sessionManager.RemoveSession(t.Context(), session))
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	// then
+	stored, err := store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, stored, len(identities)*25)
+
+	for _, sessions := range identities {
+		nonces, err := store.GetNoncesByIdentity(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.Len(t, nonces, 25)
+		for i, session := range sessions {
+			_, exists, err := store.GetByNonce(t.Context(), *session.SessionNonce)
+			require.NoError(t, err)
+			require.Equal(t, i%2 == 1, exists)
+			if exists {
+				require.Contains(t, nonces, *session.SessionNonce)
+			}
+		}
+	}
+}
+
+func TestSessionManager_ConcurrentCapacityLimits(t *testing.T) {
+	t.Run("Concurrent adds of one identity respect the per identity cap", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessionsPerIdentity(5))
+		sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 100)
+
+		// when
+		var wg sync.WaitGroup
+		for _, session := range sessions {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.NoError(t, sessionManager.AddSession(t.Context(), session))
+			}()
+		}
+		wg.Wait()
+
+		// then
+		stored, err := sessionManager.GetSessionsByIdentityKey(t.Context(), *sessions[0].PeerIdentityKey)
+		require.NoError(t, err)
+		require.Len(t, stored, 5)
+		require.Equal(t, 5, sessionManager.Stats().ActiveSessions)
+	})
+
+	t.Run("Concurrent adds of many identities respect the global capacity", func(t *testing.T) {
+		// given
+		var evictedMu sync.Mutex
+		evicted := 0
+		sessionManager := sessionmanager.NewSessionManager(sessionmanager.WithMaxSessions(20, func(sessionmanager.PeerSession) {
+			evictedMu.Lock()
+			defer evictedMu.Unlock()
+			evicted++
+		}))
+
+		// when
+		var wg sync.WaitGroup
+		for range 200 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.NoError(t, sessionManager.AddSession(t.Context(), sessionmanager.NewRandomPeerSession(t)))
+			}()
+		}
+		wg.Wait()
+
+		// then
+		sessions, _, err := sessionManager.ListSessions(t.Context(), "", sessionmanager.MaxPageSize)
+		require.NoError(t, err)
+		require.Len(t, sessions, 20)
+		require.Equal(t, 180, evicted)
+		require.Equal(t, 20, sessionManager.Stats().ActiveSessions)
+	})
+}
+
+func TestSessionManager_ConcurrentUpdatesDuringRotation(t *testing.T) {
+	// given
+	sessionManager := sessionmanager.NewSessionManager(
+		sessionmanager.WithNonceCreator(&sequentialNonces{}),
+		sessionmanager.WithRotationGracePeriod(time.Minute),
+	)
+	session := sessionmanager.NewRandomPeerSession(t)
+	require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+	// when
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				updated := session.Clone()
+				updated.SetMeta(fmt.Sprintf("writer-%d", w), i)
+				require.NoError(t, sessionManager.UpdateSession(t.Context(), updated))
+				require.NoError(t, sessionManager.TouchSession(t.Context(), *session.SessionNonce, time.Now()))
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		nonce := *session.SessionNonce
+		for range 20 {
+			rotated, err := sessionManager.RotateSessionNonce(t.Context(), nonce)
+			require.NoError(t, err)
+			nonce = rotated
+		}
+	}()
+	wg.Wait()
+
+	// then
+	sessions, err := sessionManager.GetSessionsByIdentityKey(t.Context(), *session.PeerIdentityKey)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, 1, sessionManager.Stats().ActiveSessions)
+	_, err = sessionManager.GetSession(t.Context(), *session.SessionNonce)
+	require.NoError(t, err)
+}
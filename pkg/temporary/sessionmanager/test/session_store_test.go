@@ -0,0 +1,53 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore_ReindexOnIdentityChange(t *testing.T) {
+	// given
+	store := sessionmanager.NewMemoryStore()
+	session := sessionmanager.NewRandomPeerSession(t)
+	require.NoError(t, store.Put(t.Context(), session))
+	previousIdentityKey := *session.PeerIdentityKey
+
+	// when
+	moved := session.Clone()
+	newIdentityKey := "another-identity-key"
+	moved.PeerIdentityKey = &newIdentityKey
+	require.NoError(t, store.Put(t.Context(), moved))
+
+	// then
+	nonces, err := store.GetNoncesByIdentity(t.Context(), previousIdentityKey)
+	require.NoError(t, err)
+	require.Empty(t, nonces)
+
+	nonces, err = store.GetNoncesByIdentity(t.Context(), newIdentityKey)
+	require.NoError(t, err)
+	require.Equal(t, []string{*session.SessionNonce}, nonces)
+}
+
+func TestMemoryStore_PruneIndex(t *testing.T) {
+	// given
+	store := sessionmanager.NewMemoryStore()
+	sessions := sessionmanager.NewPeerSessionsForThisSameIdentityKey(t, 2)
+	for _, session := range sessions {
+		require.NoError(t, store.Put(t.Context(), session))
+	}
+	identityKey := *sessions[0].PeerIdentityKey
+
+	// when
+	err := store.PruneIndex(t.Context(), identityKey, []string{*sessions[0].SessionNonce, "unknown-nonce"})
+
+	// then
+	require.NoError(t, err)
+	nonces, err := store.GetNoncesByIdentity(t.Context(), identityKey)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{*sessions[0].SessionNonce, *sessions[1].SessionNonce}, nonces)
+	identityKeys, err := store.IdentityKeys(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []string{identityKey}, identityKeys)
+}
@@ -0,0 +1,161 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/sessionmanager"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPeerSession(t *testing.T) {
+	tests := map[string]struct {
+		sessionNonce string
+		opts         []sessionmanager.PeerSessionOption
+		expectedErr  error
+	}{
+		"only session nonce": {
+			sessionNonce: "session-nonce",
+		},
+		"unauthenticated without identity key": {
+			sessionNonce: "session-nonce",
+			opts:         []sessionmanager.PeerSessionOption{sessionmanager.WithPeerNonce("peer-nonce")},
+		},
+		"authenticated with identity key and peer nonce": {
+			sessionNonce: "session-nonce",
+			opts: []sessionmanager.PeerSessionOption{
+				sessionmanager.WithPeerNonce("peer-nonce"),
+				sessionmanager.WithPeerIdentityKey("identity-key"),
+				sessionmanager.WithAuthenticated(),
+			},
+		},
+		"empty session nonce": {
+			sessionNonce: "",
+			opts:         []sessionmanager.PeerSessionOption{sessionmanager.WithPeerIdentityKey("identity-key")},
+			expectedErr:  sessionmanager.ErrInvalidSession,
+		},
+		"authenticated without identity key": {
+			sessionNonce: "session-nonce",
+			opts: []sessionmanager.PeerSessionOption{
+				sessionmanager.WithPeerNonce("peer-nonce"),
+				sessionmanager.WithAuthenticated(),
+			},
+			expectedErr: sessionmanager.ErrInvalidSession,
+		},
+		"authenticated without peer nonce": {
+			sessionNonce: "session-nonce",
+			opts: []sessionmanager.PeerSessionOption{
+				sessionmanager.WithPeerIdentityKey("identity-key"),
+				sessionmanager.WithAuthenticated(),
+			},
+			expectedErr: sessionmanager.ErrInvalidSession,
+		},
+		"authenticated with empty identity key": {
+			sessionNonce: "session-nonce",
+			opts: []sessionmanager.PeerSessionOption{
+				sessionmanager.WithPeerNonce("peer-nonce"),
+				sessionmanager.WithPeerIdentityKey(""),
+				sessionmanager.WithAuthenticated(),
+			},
+			expectedErr: sessionmanager.ErrInvalidSession,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			session, err := sessionmanager.NewPeerSession(test.sessionNonce, test.opts...)
+
+			// then
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.sessionNonce, session.GetSessionNonce())
+			require.NoError(t, session.Validate())
+		})
+	}
+}
+
+func TestPeerSession_Accessors(t *testing.T) {
+	t.Run("Return the set values", func(t *testing.T) {
+		// given
+		lastUpdate := time.Now().Add(-time.Minute)
+
+		// when
+		session, err := sessionmanager.NewPeerSession("session-nonce",
+			sessionmanager.WithPeerNonce("peer-nonce"),
+			sessionmanager.WithPeerIdentityKey("identity-key"),
+			sessionmanager.WithAuthenticated(),
+			sessionmanager.WithLastUpdate(lastUpdate),
+		)
+
+		// then
+		require.NoError(t, err)
+		require.True(t, session.IsAuthenticated)
+		require.Equal(t, "session-nonce", session.GetSessionNonce())
+		require.Equal(t, "peer-nonce", session.GetPeerNonce())
+		require.Equal(t, "identity-key", session.GetPeerIdentityKey())
+		require.Equal(t, lastUpdate, session.LastUpdate)
+	})
+
+	t.Run("Return empty values for unset fields", func(t *testing.T) {
+		// given
+		before := time.Now()
+
+		// when
+		session, err := sessionmanager.NewPeerSession("session-nonce")
+
+		// then
+		require.NoError(t, err)
+		require.False(t, session.IsAuthenticated)
+		require.Empty(t, session.GetPeerNonce())
+		require.Empty(t, session.GetPeerIdentityKey())
+		require.False(t, session.LastUpdate.Before(before))
+		require.Empty(t, sessionmanager.PeerSession{}.GetSessionNonce())
+	})
+}
+
+func TestSessionManager_RejectInvalidSessions(t *testing.T) {
+	tests := map[string]func(session *sessionmanager.PeerSession){
+		"nil session nonce":                  func(session *sessionmanager.PeerSession) { session.SessionNonce = nil },
+		"empty session nonce":                func(session *sessionmanager.PeerSession) { *session.SessionNonce = "" },
+		"authenticated without identity key": func(session *sessionmanager.PeerSession) { session.PeerIdentityKey = nil },
+		"authenticated without peer nonce":   func(session *sessionmanager.PeerSession) { session.PeerNonce = nil },
+	}
+	for name, breakSession := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			sessionManager := sessionmanager.NewSessionManager()
+			session := sessionmanager.NewRandomPeerSession(t)
+			session.IsAuthenticated = true
+			breakSession(&session)
+
+			// when
+			err := sessionManager.AddSession(t.Context(), session)
+
+			// then
+			require.ErrorIs(t, err, sessionmanager.ErrInvalidSession)
+			require.Zero(t, sessionManager.Stats().ActiveSessions)
+		})
+	}
+
+	t.Run("Update to an invalid session", func(t *testing.T) {
+		// given
+		sessionManager := sessionmanager.NewSessionManager()
+		session := sessionmanager.NewRandomPeerSession(t)
+		require.NoError(t, sessionManager.AddSession(t.Context(), session))
+
+		// when
+		invalid := session.Clone()
+		invalid.IsAuthenticated = true
+		invalid.PeerNonce = nil
+		err := sessionManager.UpdateSession(t.Context(), invalid)
+
+		// then
+		require.ErrorIs(t, err, sessionmanager.ErrInvalidSession)
+		retrievedSession, err := sessionManager.GetSession(t.Context(), *session.SessionNonce)
+		require.NoError(t, err)
+		require.False(t, retrievedSession.IsAuthenticated)
+	})
+}
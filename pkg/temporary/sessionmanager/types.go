@@ -1,14 +1,166 @@
 package sessionmanager
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"time"
 )
 
-// PeerSession holds the session information for a peer
+// ErrInvalidSession is returned when a session breaks the rules checked by PeerSession.Validate.
+var ErrInvalidSession = errors.New("invalid session")
+
+// PeerSession holds the session information for a peer.
+//
+// The fields are kept exported for backward compatibility, prefer creating sessions with NewPeerSession
+// and reading them with the accessor methods, which don't require nil checks.
 type PeerSession struct {
 	IsAuthenticated bool
 	SessionNonce    *string
 	PeerNonce       *string
 	PeerIdentityKey *string
 	LastUpdate      time.Time
+	// Metadata holds application data attached to the session, e.g. the tenant id or the presented certificates.
+	// Prefer SetMeta, which copies the map on write, so copies of the session never share modifications.
+	// The values should be immutable and, for external stores, JSON serializable.
+	Metadata map[string]any
+}
+
+// PeerSessionOption configures a PeerSession created by NewPeerSession.
+type PeerSessionOption func(*PeerSession)
+
+// WithPeerNonce sets the nonce sent by the peer.
+func WithPeerNonce(peerNonce string) PeerSessionOption {
+	return func(s *PeerSession) {
+		s.PeerNonce = &peerNonce
+	}
+}
+
+// WithPeerIdentityKey sets the identity key of the peer.
+func WithPeerIdentityKey(identityKey string) PeerSessionOption {
+	return func(s *PeerSession) {
+		s.PeerIdentityKey = &identityKey
+	}
+}
+
+// WithAuthenticated marks the session as authenticated, which requires both the peer nonce and identity key.
+func WithAuthenticated() PeerSessionOption {
+	return func(s *PeerSession) {
+		s.IsAuthenticated = true
+	}
+}
+
+// WithLastUpdate overrides the LastUpdate, which defaults to the time of creation.
+func WithLastUpdate(lastUpdate time.Time) PeerSessionOption {
+	return func(s *PeerSession) {
+		s.LastUpdate = lastUpdate
+	}
+}
+
+// WithMeta attaches the metadata value under the key.
+func WithMeta(key string, value any) PeerSessionOption {
+	return func(s *PeerSession) {
+		s.SetMeta(key, value)
+	}
+}
+
+// NewPeerSession creates a session identified by the sessionNonce and validates it.
+func NewPeerSession(sessionNonce string, opts ...PeerSessionOption) (PeerSession, error) {
+	session := PeerSession{
+		SessionNonce: &sessionNonce,
+		LastUpdate:   time.Now(),
+	}
+	for _, opt := range opts {
+		opt(&session)
+	}
+
+	if err := session.Validate(); err != nil {
+		return PeerSession{}, err
+	}
+	return session, nil
+}
+
+// Validate checks that the session has a non-empty sessionNonce,
+// and that an authenticated session has both the peer nonce and the peer identity key.
+func (s PeerSession) Validate() error {
+	if s.GetSessionNonce() == "" {
+		return fmt.Errorf("%w: session nonce is empty", ErrInvalidSession)
+	}
+	if !s.IsAuthenticated {
+		return nil
+	}
+	if s.GetPeerIdentityKey() == "" {
+		return fmt.Errorf("%w: authenticated session has no peer identity key", ErrInvalidSession)
+	}
+	if s.GetPeerNonce() == "" {
+		return fmt.Errorf("%w: authenticated session has no peer nonce", ErrInvalidSession)
+	}
+	return nil
+}
+
+// GetSessionNonce returns the sessionNonce, or an empty string if it isn't set.
+func (s PeerSession) GetSessionNonce() string {
+	return valueOf(s.SessionNonce)
+}
+
+// GetPeerNonce returns the nonce sent by the peer, or an empty string if it isn't set.
+func (s PeerSession) GetPeerNonce() string {
+	return valueOf(s.PeerNonce)
+}
+
+// GetPeerIdentityKey returns the identity key of the peer, or an empty string if it isn't known yet.
+func (s PeerSession) GetPeerIdentityKey() string {
+	return valueOf(s.PeerIdentityKey)
+}
+
+// GetMeta returns the metadata value stored under the key.
+func (s PeerSession) GetMeta(key string) (any, bool) {
+	value, ok := s.Metadata[key]
+	return value, ok
+}
+
+// SetMeta stores the metadata value under the key. The metadata map is copied before the write,
+// so other copies of the session, e.g. the one held by the SessionManager, aren't affected until UpdateSession.
+func (s *PeerSession) SetMeta(key string, value any) {
+	metadata := maps.Clone(s.Metadata)
+	if metadata == nil {
+		metadata = make(map[string]any, 1)
+	}
+	metadata[key] = value
+	s.Metadata = metadata
+}
+
+// DeleteMeta removes the metadata value stored under the key, copying the metadata map like SetMeta does.
+func (s *PeerSession) DeleteMeta(key string) {
+	if _, ok := s.Metadata[key]; !ok {
+		return
+	}
+	metadata := maps.Clone(s.Metadata)
+	delete(metadata, key)
+	s.Metadata = metadata
+}
+
+// Clone returns a deep copy of the session, so the copy doesn't share the pointed values or the metadata map
+// with the original. The metadata values themselves are copied shallowly.
+func (s PeerSession) Clone() PeerSession {
+	s.SessionNonce = cloneString(s.SessionNonce)
+	s.PeerNonce = cloneString(s.PeerNonce)
+	s.PeerIdentityKey = cloneString(s.PeerIdentityKey)
+	s.Metadata = maps.Clone(s.Metadata)
+	return s
+}
+
+func cloneString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	clone := *value
+	return &clone
+}
+
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
 }
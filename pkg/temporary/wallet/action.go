@@ -0,0 +1,248 @@
+package wallet
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/money"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// ErrInvalidAction is returned when the args of CreateAction or InternalizeAction don't follow the BRC-100 rules.
+var ErrInvalidAction = errors.New("invalid action")
+
+// InternalizeProtocol is the BRC-100 protocol by which the wallet takes ownership of an output of an incoming transaction.
+type InternalizeProtocol string
+
+// BRC-100 internalization protocols.
+const (
+	// InternalizeProtocolWalletPayment adds the output paying a key derived for the sender to the wallet balance
+	InternalizeProtocolWalletPayment InternalizeProtocol = "wallet payment"
+	// InternalizeProtocolBasketInsertion stores the output in a basket, outside the wallet balance
+	InternalizeProtocolBasketInsertion InternalizeProtocol = "basket insertion"
+)
+
+// InternalizeActionArgs defines parameters for InternalizeAction, the BRC-100 internalizeAction args.
+type InternalizeActionArgs struct {
+	// Tx is the incoming transaction in the AtomicBEEF format
+	Tx []byte `json:"tx"`
+	// Outputs are the outputs of the transaction the wallet takes ownership of
+	Outputs []InternalizeOutput `json:"outputs"`
+	// Description is the human-readable description of the action, 5 to 50 bytes
+	Description string `json:"description"`
+	// Labels are the labels of the action
+	Labels []string `json:"labels,omitempty"`
+}
+
+// InternalizeOutput is an output of the incoming transaction and the protocol by which the wallet takes ownership of it.
+type InternalizeOutput struct {
+	// OutputIndex is the index of the output in the transaction
+	OutputIndex uint32 `json:"outputIndex"`
+	// Protocol is the internalization protocol, it decides which remittance is set
+	Protocol InternalizeProtocol `json:"protocol"`
+	// PaymentRemittance is set for the InternalizeProtocolWalletPayment outputs
+	PaymentRemittance *PaymentRemittance `json:"paymentRemittance,omitempty"`
+	// InsertionRemittance is set for the InternalizeProtocolBasketInsertion outputs
+	InsertionRemittance *BasketInsertion `json:"insertionRemittance,omitempty"`
+}
+
+// PaymentRemittance identifies the key the payment output is locked with, derived by BRC-29 from the sender's identity key.
+type PaymentRemittance struct {
+	// DerivationPrefix is the base64 encoded derivation prefix chosen by the payee, e.g. the nonce of a payment request
+	DerivationPrefix string `json:"derivationPrefix"`
+	// DerivationSuffix is the base64 encoded derivation suffix chosen by the sender
+	DerivationSuffix string `json:"derivationSuffix"`
+	// SenderIdentityKey is the identity key of the sender, the counterparty of the derivation
+	SenderIdentityKey string `json:"senderIdentityKey"`
+}
+
+// BasketInsertion identifies the basket an output is inserted into.
+type BasketInsertion struct {
+	// Basket is the name of the basket
+	Basket string `json:"basket"`
+	// CustomInstructions are the instructions to spend the output, stored with it
+	CustomInstructions string `json:"customInstructions,omitempty"`
+	// Tags are the tags of the output
+	Tags []string `json:"tags,omitempty"`
+}
+
+// InternalizeActionResult is the result of InternalizeAction.
+type InternalizeActionResult struct {
+	// Accepted is true if the wallet took ownership of the outputs
+	Accepted bool `json:"accepted"`
+}
+
+// Validate checks the args the way the TypeScript SDK's validateInternalizeActionArgs does.
+func (a InternalizeActionArgs) Validate() error {
+	if len(a.Tx) == 0 {
+		return fmt.Errorf("%w: tx is required", ErrInvalidAction)
+	}
+	if len(a.Outputs) == 0 {
+		return fmt.Errorf("%w: at least one output is required", ErrInvalidAction)
+	}
+	if err := validateDescription("description", a.Description); err != nil {
+		return err
+	}
+	for i, output := range a.Outputs {
+		if err := output.validate(); err != nil {
+			return fmt.Errorf("output %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (o InternalizeOutput) validate() error {
+	switch o.Protocol {
+	case InternalizeProtocolWalletPayment:
+		if o.PaymentRemittance == nil {
+			return fmt.Errorf("%w: payment remittance is required for the %s protocol", ErrInvalidAction, o.Protocol)
+		}
+		return o.PaymentRemittance.validate()
+	case InternalizeProtocolBasketInsertion:
+		if o.InsertionRemittance == nil {
+			return fmt.Errorf("%w: insertion remittance is required for the %s protocol", ErrInvalidAction, o.Protocol)
+		}
+		if o.InsertionRemittance.Basket == "" {
+			return fmt.Errorf("%w: basket is required", ErrInvalidAction)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: unknown protocol %q", ErrInvalidAction, o.Protocol)
+}
+
+func (r PaymentRemittance) validate() error {
+	if _, err := base64.StdEncoding.DecodeString(r.DerivationPrefix); err != nil || r.DerivationPrefix == "" {
+		return fmt.Errorf("%w: derivation prefix must be base64 encoded: %q", ErrInvalidAction, r.DerivationPrefix)
+	}
+	if _, err := base64.StdEncoding.DecodeString(r.DerivationSuffix); err != nil || r.DerivationSuffix == "" {
+		return fmt.Errorf("%w: derivation suffix must be base64 encoded: %q", ErrInvalidAction, r.DerivationSuffix)
+	}
+	if _, err := ec.PublicKeyFromString(r.SenderIdentityKey); err != nil {
+		return fmt.Errorf("%w: invalid sender identity key: %w", ErrInvalidAction, err)
+	}
+	return nil
+}
+
+// CreateActionArgs defines parameters for CreateAction, the BRC-100 createAction args without the options.
+type CreateActionArgs struct {
+	// Description is the human-readable description of the action, 5 to 50 bytes
+	Description string `json:"description"`
+	// InputBEEF is the BEEF of the transactions of the inputs not held by the wallet
+	InputBEEF []byte `json:"inputBEEF,omitempty"`
+	// Inputs are the inputs the action spends in addition to the ones the wallet funds it with
+	Inputs []CreateActionInput `json:"inputs,omitempty"`
+	// Outputs are the outputs of the action
+	Outputs []CreateActionOutput `json:"outputs,omitempty"`
+	// LockTime is the lock time of the transaction
+	LockTime uint32 `json:"lockTime,omitempty"`
+	// Version is the version of the transaction, zero means the wallet's default
+	Version uint32 `json:"version,omitempty"`
+	// Labels are the labels of the action
+	Labels []string `json:"labels,omitempty"`
+}
+
+// CreateActionInput is an input of the action.
+type CreateActionInput struct {
+	// Outpoint is the "txid.index" outpoint spent by the input
+	Outpoint string `json:"outpoint"`
+	// InputDescription is the human-readable description of the input, 5 to 50 bytes
+	InputDescription string `json:"inputDescription"`
+	// UnlockingScript is the hex encoded unlocking script, if it's known when the action is created
+	UnlockingScript string `json:"unlockingScript,omitempty"`
+	// UnlockingScriptLength is the length of the unlocking script provided later, required without the UnlockingScript
+	UnlockingScriptLength uint32 `json:"unlockingScriptLength,omitempty"`
+	// SequenceNumber is the sequence number of the input, zero means the wallet's default
+	SequenceNumber uint32 `json:"sequenceNumber,omitempty"`
+}
+
+// CreateActionOutput is an output of the action.
+type CreateActionOutput struct {
+	// LockingScript is the hex encoded locking script
+	LockingScript string `json:"lockingScript"`
+	// Satoshis is the amount locked in the output
+	Satoshis money.Satoshis `json:"satoshis"`
+	// OutputDescription is the human-readable description of the output, 5 to 50 bytes
+	OutputDescription string `json:"outputDescription"`
+	// Basket is the basket the output is inserted into, if the wallet keeps it
+	Basket string `json:"basket,omitempty"`
+	// CustomInstructions are the instructions to spend the output, stored with it
+	CustomInstructions string `json:"customInstructions,omitempty"`
+	// Tags are the tags of the output
+	Tags []string `json:"tags,omitempty"`
+}
+
+// CreateActionResult is the result of CreateAction.
+type CreateActionResult struct {
+	// Txid is the hex encoded id of the created transaction
+	Txid string `json:"txid,omitempty"`
+	// Tx is the created transaction in the AtomicBEEF format
+	Tx []byte `json:"tx,omitempty"`
+}
+
+// Validate checks the args the way the TypeScript SDK's validateCreateActionArgs does.
+func (a CreateActionArgs) Validate() error {
+	if err := validateDescription("description", a.Description); err != nil {
+		return err
+	}
+	if len(a.Inputs) == 0 && len(a.Outputs) == 0 {
+		return fmt.Errorf("%w: at least one input or output is required", ErrInvalidAction)
+	}
+	for i, input := range a.Inputs {
+		if err := input.validate(); err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	for i, output := range a.Outputs {
+		if err := output.validate(); err != nil {
+			return fmt.Errorf("output %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (i CreateActionInput) validate() error {
+	txID, index, found := strings.Cut(i.Outpoint, ".")
+	if _, err := strconv.ParseUint(index, 10, 32); !found || err != nil || !isHex(txID, 32) {
+		return fmt.Errorf("%w: outpoint must be txid.index: %q", ErrInvalidAction, i.Outpoint)
+	}
+	if err := validateDescription("input description", i.InputDescription); err != nil {
+		return err
+	}
+	if i.UnlockingScript == "" {
+		if i.UnlockingScriptLength == 0 {
+			return fmt.Errorf("%w: unlocking script or its length is required", ErrInvalidAction)
+		}
+		return nil
+	}
+	if !isHex(i.UnlockingScript, 0) {
+		return fmt.Errorf("%w: unlocking script must be hex encoded", ErrInvalidAction)
+	}
+	if i.UnlockingScriptLength != 0 && int(i.UnlockingScriptLength) != len(i.UnlockingScript)/2 {
+		return fmt.Errorf("%w: unlocking script length doesn't match the unlocking script", ErrInvalidAction)
+	}
+	return nil
+}
+
+func (o CreateActionOutput) validate() error {
+	if o.LockingScript == "" || !isHex(o.LockingScript, 0) {
+		return fmt.Errorf("%w: locking script must be hex encoded", ErrInvalidAction)
+	}
+	return validateDescription("output description", o.OutputDescription)
+}
+
+func validateDescription(name string, description string) error {
+	if len(description) < 5 || len(description) > 50 {
+		return fmt.Errorf("%w: %s must be 5 to 50 bytes: %q", ErrInvalidAction, name, description)
+	}
+	return nil
+}
+
+// isHex checks the value is hex encoded, and encodes the given number of bytes unless it's zero.
+func isHex(value string, size int) bool {
+	raw, err := hex.DecodeString(value)
+	return err == nil && (size == 0 || len(raw) == size)
+}
@@ -0,0 +1,242 @@
+package wallet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/internal/logging"
+)
+
+// auditedBytes is the most bytes of a signature or an HMAC written to the audit log.
+const auditedBytes = 8
+
+// Auditing decorates a wallet, logging the signing, encryption, certificate, key linkage and action calls
+// with their redacted params, the peer from the context, the duration and the error.
+// The data, plaintexts and ciphertexts are logged by their size only, the signatures and HMACs by their first 8 bytes
+// and the keyIDs, which hold the nonces of the BRC-104 signatures, by the first 8 bytes of their SHA-256 hash.
+// The other calls, including the public keys and nonces, pass through to the inner wallet unlogged.
+type Auditing struct {
+	Interface
+
+	logger *slog.Logger
+}
+
+type peerContextKey struct{}
+
+// WithPeer returns a copy of ctx carrying the identity key of the peer the wallet is called for, logged by Auditing.
+func WithPeer(ctx context.Context, identityKey string) context.Context {
+	return context.WithValue(ctx, peerContextKey{}, identityKey)
+}
+
+// PeerFromContext returns the identity key of the peer stored by WithPeer, if any.
+func PeerFromContext(ctx context.Context) (string, bool) {
+	identityKey, ok := ctx.Value(peerContextKey{}).(string)
+	return identityKey, ok && identityKey != ""
+}
+
+// NewAuditing creates a wallet logging the calls to the inner wallet, the default logger is used if the logger is nil.
+func NewAuditing(inner Interface, logger *slog.Logger) *Auditing {
+	return &Auditing{
+		Interface: inner,
+		logger:    logging.Child(logger, "wallet-audit"),
+	}
+}
+
+// CreateSignature logs the signature created by the inner wallet.
+func (a *Auditing) CreateSignature(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty, privilege Privilege) ([]byte, error) {
+	start := time.Now()
+	signature, err := a.Interface.CreateSignature(ctx, data, protocolID, keyID, counterparty, privilege)
+	a.audit(ctx, "CreateSignature", start, err,
+		protocolAttr(protocolID), keyIDAttr(keyID), counterpartyAttr(counterparty), slog.Int("data_size", len(data)),
+		slog.Bool("privileged", privilege.Privileged), slog.String("privileged_reason", privilege.Reason), redactedAttr("signature", signature))
+	return signature, err
+}
+
+// VerifySignature logs the signature verified by the inner wallet.
+func (a *Auditing) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error) {
+	start := time.Now()
+	valid, err := a.Interface.VerifySignature(ctx, data, signature, protocolID, keyID, counterparty)
+	a.audit(ctx, "VerifySignature", start, err,
+		protocolAttr(protocolID), keyIDAttr(keyID), counterpartyAttr(counterparty), slog.Int("data_size", len(data)),
+		redactedAttr("signature", signature), slog.Bool("valid", valid))
+	return valid, err
+}
+
+// VerifySignatures logs the number of signatures verified by the inner wallet and of the valid ones.
+func (a *Auditing) VerifySignatures(ctx context.Context, items []VerifyItem) ([]bool, error) {
+	start := time.Now()
+	results, err := a.Interface.VerifySignatures(ctx, items)
+	valid := 0
+	for _, result := range results {
+		if result {
+			valid++
+		}
+	}
+	a.audit(ctx, "VerifySignatures", start, err, slog.Int("items", len(items)), slog.Int("valid", valid))
+	return results, err
+}
+
+// CreateHMAC logs the HMAC created by the inner wallet.
+func (a *Auditing) CreateHMAC(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error) {
+	start := time.Now()
+	hmac, err := a.Interface.CreateHMAC(ctx, data, protocolID, keyID, counterparty)
+	a.audit(ctx, "CreateHMAC", start, err,
+		protocolAttr(protocolID), keyIDAttr(keyID), counterpartyAttr(counterparty), slog.Int("data_size", len(data)),
+		redactedAttr("hmac", hmac))
+	return hmac, err
+}
+
+// VerifyHMAC logs the HMAC verified by the inner wallet.
+func (a *Auditing) VerifyHMAC(ctx context.Context, data []byte, hmac []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error) {
+	start := time.Now()
+	valid, err := a.Interface.VerifyHMAC(ctx, data, hmac, protocolID, keyID, counterparty)
+	a.audit(ctx, "VerifyHMAC", start, err,
+		protocolAttr(protocolID), keyIDAttr(keyID), counterpartyAttr(counterparty), slog.Int("data_size", len(data)),
+		redactedAttr("hmac", hmac), slog.Bool("valid", valid))
+	return valid, err
+}
+
+// Encrypt logs the sizes of the plaintext and of the ciphertext created by the inner wallet.
+func (a *Auditing) Encrypt(ctx context.Context, plaintext []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error) {
+	start := time.Now()
+	ciphertext, err := a.Interface.Encrypt(ctx, plaintext, protocolID, keyID, counterparty)
+	a.audit(ctx, "Encrypt", start, err,
+		protocolAttr(protocolID), keyIDAttr(keyID), counterpartyAttr(counterparty),
+		slog.Int("plaintext_size", len(plaintext)), slog.Int("ciphertext_size", len(ciphertext)))
+	return ciphertext, err
+}
+
+// Decrypt logs the sizes of the ciphertext and of the plaintext decrypted by the inner wallet.
+func (a *Auditing) Decrypt(ctx context.Context, ciphertext []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error) {
+	start := time.Now()
+	plaintext, err := a.Interface.Decrypt(ctx, ciphertext, protocolID, keyID, counterparty)
+	a.audit(ctx, "Decrypt", start, err,
+		protocolAttr(protocolID), keyIDAttr(keyID), counterpartyAttr(counterparty),
+		slog.Int("ciphertext_size", len(ciphertext)), slog.Int("plaintext_size", len(plaintext)))
+	return plaintext, err
+}
+
+// ListCertificates logs the filters and the number of certificates listed by the inner wallet.
+func (a *Auditing) ListCertificates(ctx context.Context, options ListCertificatesOptions) (ListCertificatesResult, error) {
+	start := time.Now()
+	result, err := a.Interface.ListCertificates(ctx, options)
+	a.audit(ctx, "ListCertificates", start, err,
+		slog.Any("certifiers", options.Certifiers), slog.Any("types", options.Types), slog.Int("certificates", len(result.Certificates)))
+	return result, err
+}
+
+// AcquireCertificate logs the certificate acquired by the inner wallet, without its fields and keyring.
+func (a *Auditing) AcquireCertificate(ctx context.Context, certificate Certificate) error {
+	start := time.Now()
+	err := a.Interface.AcquireCertificate(ctx, certificate)
+	a.audit(ctx, "AcquireCertificate", start, err, certificateAttrs(certificate.Type, certificate.SerialNumber, certificate.Certifier)...)
+	return err
+}
+
+// RelinquishCertificate logs the certificate relinquished by the inner wallet.
+func (a *Auditing) RelinquishCertificate(ctx context.Context, certType string, serialNumber string, certifier string) error {
+	start := time.Now()
+	err := a.Interface.RelinquishCertificate(ctx, certType, serialNumber, certifier)
+	a.audit(ctx, "RelinquishCertificate", start, err, certificateAttrs(certType, serialNumber, certifier)...)
+	return err
+}
+
+// ProveCertificate logs the names of the fields revealed by the inner wallet to the verifier, never their keys.
+func (a *Auditing) ProveCertificate(ctx context.Context, certificate Certificate, verifier string, fieldsToReveal []string, privilege Privilege) (map[string]string, error) {
+	start := time.Now()
+	keyring, err := a.Interface.ProveCertificate(ctx, certificate, verifier, fieldsToReveal, privilege)
+	attrs := append(certificateAttrs(certificate.Type, certificate.SerialNumber, certificate.Certifier),
+		slog.String("verifier", verifier), slog.Any("fields", fieldsToReveal),
+		slog.Bool("privileged", privilege.Privileged), slog.String("privileged_reason", privilege.Reason))
+	a.audit(ctx, "ProveCertificate", start, err, attrs...)
+	return keyring, err
+}
+
+// RevealCounterpartyKeyLinkage logs the counterparty linkage revealed by the inner wallet to the verifier.
+func (a *Auditing) RevealCounterpartyKeyLinkage(ctx context.Context, counterparty string, verifier string) (KeyLinkageResult, error) {
+	start := time.Now()
+	result, err := a.Interface.RevealCounterpartyKeyLinkage(ctx, counterparty, verifier)
+	a.audit(ctx, "RevealCounterpartyKeyLinkage", start, err,
+		slog.String("counterparty", counterparty), slog.String("verifier", verifier))
+	return result, err
+}
+
+// RevealSpecificKeyLinkage logs the specific linkage revealed by the inner wallet to the verifier.
+func (a *Auditing) RevealSpecificKeyLinkage(ctx context.Context, counterparty string, verifier string, protocolID Protocol, keyID string) (KeyLinkageResult, error) {
+	start := time.Now()
+	result, err := a.Interface.RevealSpecificKeyLinkage(ctx, counterparty, verifier, protocolID, keyID)
+	a.audit(ctx, "RevealSpecificKeyLinkage", start, err,
+		slog.String("counterparty", counterparty), slog.String("verifier", verifier), protocolAttr(protocolID), keyIDAttr(keyID))
+	return result, err
+}
+
+// CreateAction logs the description of the action and the txid of the transaction created by the inner wallet.
+func (a *Auditing) CreateAction(ctx context.Context, args CreateActionArgs) (CreateActionResult, error) {
+	start := time.Now()
+	result, err := a.Interface.CreateAction(ctx, args)
+	a.audit(ctx, "CreateAction", start, err,
+		slog.String("description", args.Description), slog.Int("inputs", len(args.Inputs)), slog.Int("outputs", len(args.Outputs)),
+		slog.String("txid", result.Txid))
+	return result, err
+}
+
+// InternalizeAction logs the description of the action internalized by the inner wallet.
+func (a *Auditing) InternalizeAction(ctx context.Context, args InternalizeActionArgs) (InternalizeActionResult, error) {
+	start := time.Now()
+	result, err := a.Interface.InternalizeAction(ctx, args)
+	a.audit(ctx, "InternalizeAction", start, err,
+		slog.String("description", args.Description), slog.Int("outputs", len(args.Outputs)), slog.Bool("accepted", result.Accepted))
+	return result, err
+}
+
+// audit logs the call with the method, the params, the peer from the context, the duration and the error if it failed.
+func (a *Auditing) audit(ctx context.Context, method string, start time.Time, err error, params ...slog.Attr) {
+	attrs := make([]slog.Attr, 0, len(params)+4)
+	attrs = append(attrs, slog.String("method", method))
+	if peer, ok := PeerFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("peer", peer))
+	}
+	attrs = append(attrs, params...)
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)))
+
+	if err != nil {
+		a.logger.LogAttrs(ctx, slog.LevelWarn, "Wallet call failed", append(attrs, logging.Error(err))...)
+		return
+	}
+	a.logger.LogAttrs(ctx, slog.LevelInfo, "Wallet call", attrs...)
+}
+
+func protocolAttr(protocol Protocol) slog.Attr {
+	return slog.String("protocol", fmt.Sprintf("%d-%s", protocol.SecurityLevel, protocol.Protocol))
+}
+
+// keyIDAttr logs the hex of the first 8 bytes of the SHA-256 hash of the keyID, correlating the calls with the same keyID
+// without revealing it.
+func keyIDAttr(keyID string) slog.Attr {
+	hash := sha256.Sum256([]byte(keyID))
+	return slog.String("key_id_hash", hex.EncodeToString(hash[:auditedBytes]))
+}
+
+func counterpartyAttr(counterparty Counterparty) slog.Attr {
+	return slog.String("counterparty", counterparty.String())
+}
+
+func certificateAttrs(certType string, serialNumber string, certifier string) []slog.Attr {
+	return []slog.Attr{
+		slog.String("certificate_type", certType),
+		slog.String("serial_number", serialNumber),
+		slog.String("certifier", certifier),
+	}
+}
+
+// redactedAttr logs the hex of the first 8 bytes of the value, followed by "..." if it's longer.
+func redactedAttr(key string, value []byte) slog.Attr {
+	if len(value) <= auditedBytes {
+		return slog.String(key, hex.EncodeToString(value))
+	}
+	return slog.String(key, hex.EncodeToString(value[:auditedBytes])+"...")
+}
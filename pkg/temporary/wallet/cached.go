@@ -0,0 +1,142 @@
+package wallet
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Cached decorates a wallet, caching the public keys returned by GetPublicKey.
+// The other calls, including the nonces and signatures, pass through to the inner wallet uncached.
+type Cached struct {
+	Interface
+
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu sync.Mutex
+	// order holds the cachedPublicKey entries from the most to the least recently used
+	order   *list.List
+	entries map[publicKeyCacheKey]*list.Element
+}
+
+// CachedOption configures the Cached wallet.
+type CachedOption func(*Cached)
+
+// WithCacheClock overrides the clock used to expire the cached public keys.
+func WithCacheClock(now func() time.Time) CachedOption {
+	return func(c *Cached) {
+		c.now = now
+	}
+}
+
+// publicKeyCacheKey is the full option set of GetPublicKey, with the counterparty compared by its public key.
+type publicKeyCacheKey struct {
+	identityKey  bool
+	protocol     Protocol
+	keyID        string
+	counterparty string
+	forSelf      bool
+}
+
+type cachedPublicKey struct {
+	key       publicKeyCacheKey
+	publicKey string
+	expiresAt time.Time
+}
+
+// NewCached creates a wallet caching the public keys of the inner wallet for the ttl, up to maxEntries of them,
+// evicting the least recently used ones. A zero ttl never expires the public keys, a zero maxEntries doesn't limit them.
+// The errors and the privileged keys are never cached.
+func NewCached(inner Interface, ttl time.Duration, maxEntries int, opts ...CachedOption) *Cached {
+	c := &Cached{
+		Interface:  inner,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		order:      list.New(),
+		entries:    make(map[publicKeyCacheKey]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// GetPublicKey returns the cached public key, or gets it from the inner wallet and caches it.
+// Concurrent calls missing the same public key all get it from the inner wallet.
+func (c *Cached) GetPublicKey(ctx context.Context, options GetPublicKeyOptions) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if options.Privileged {
+		return c.Interface.GetPublicKey(ctx, options)
+	}
+
+	key := publicKeyCacheKey{
+		identityKey:  options.IdentityKey,
+		protocol:     options.ProtocolID,
+		keyID:        options.KeyID,
+		counterparty: options.Counterparty.String(),
+		forSelf:      options.ForSelf,
+	}
+	if publicKey, ok := c.lookup(key); ok {
+		return publicKey, nil
+	}
+
+	publicKey, err := c.Interface.GetPublicKey(ctx, options)
+	if err != nil {
+		return "", err
+	}
+	c.store(key, publicKey)
+	return publicKey, nil
+}
+
+// Len returns the number of cached public keys, including the expired ones not looked up since.
+func (c *Cached) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *Cached) lookup(key publicKeyCacheKey) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		return "", false
+	}
+	entry := element.Value.(*cachedPublicKey)
+	if c.ttl > 0 && !c.now().Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return entry.publicKey, true
+}
+
+func (c *Cached) store(key publicKeyCacheKey, publicKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cachedPublicKey{key: key, publicKey: publicKey, expiresAt: c.now().Add(c.ttl)}
+	if element, exists := c.entries[key]; exists {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedPublicKey).key)
+	}
+}
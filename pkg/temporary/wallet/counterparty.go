@@ -0,0 +1,144 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// ErrInvalidCounterparty is returned when the counterparty is neither "self", "anyone" nor a valid public key.
+var ErrInvalidCounterparty = errors.New("invalid counterparty")
+
+// CounterpartyType is the kind of the counterparty the keys are derived for.
+type CounterpartyType int
+
+// Counterparty types, the zero value lets the wallet method choose its default counterparty.
+const (
+	// CounterpartyTypeUninitialized means no counterparty was given
+	CounterpartyTypeUninitialized CounterpartyType = iota
+	// CounterpartyTypeSelf derives keys for the wallet owner
+	CounterpartyTypeSelf
+	// CounterpartyTypeAnyone derives keys anyone can derive, using the private key 1 as the counterparty
+	CounterpartyTypeAnyone
+	// CounterpartyTypeOther derives keys shared with the owner of the counterparty's public key
+	CounterpartyTypeOther
+)
+
+// String returns the name of the counterparty type.
+func (t CounterpartyType) String() string {
+	switch t {
+	case CounterpartyTypeUninitialized:
+		return "uninitialized"
+	case CounterpartyTypeSelf:
+		return "self"
+	case CounterpartyTypeAnyone:
+		return "anyone"
+	case CounterpartyTypeOther:
+		return "other"
+	}
+	return fmt.Sprintf("CounterpartyType(%d)", int(t))
+}
+
+// Counterparty is the party the keys are derived for, as required by BRC-42.
+// It's serialized to JSON as "self", "anyone" or the hex encoded public key, like in the TypeScript SDK.
+type Counterparty struct {
+	// Type is the kind of the counterparty
+	Type CounterpartyType
+	// PublicKey is the public key of the counterparty, set only for CounterpartyTypeOther
+	PublicKey *ec.PublicKey
+}
+
+// CounterpartySelf returns the counterparty deriving keys for the wallet owner.
+func CounterpartySelf() Counterparty {
+	return Counterparty{Type: CounterpartyTypeSelf}
+}
+
+// CounterpartyAnyone returns the counterparty deriving keys anyone can derive.
+func CounterpartyAnyone() Counterparty {
+	return Counterparty{Type: CounterpartyTypeAnyone}
+}
+
+// CounterpartyOf returns the counterparty deriving keys shared with the owner of the public key.
+func CounterpartyOf(publicKey *ec.PublicKey) Counterparty {
+	return Counterparty{Type: CounterpartyTypeOther, PublicKey: publicKey}
+}
+
+// ParseCounterparty parses "self", "anyone" or the hex encoded public key of the counterparty, e.g. the peer's identity key.
+func ParseCounterparty(counterparty string) (Counterparty, error) {
+	switch counterparty {
+	case "self":
+		return CounterpartySelf(), nil
+	case "anyone":
+		return CounterpartyAnyone(), nil
+	}
+
+	raw, err := hex.DecodeString(counterparty)
+	if err != nil {
+		return Counterparty{}, fmt.Errorf("%w: %q", ErrInvalidCounterparty, counterparty)
+	}
+	publicKey, err := ec.ParsePubKey(raw)
+	if err != nil {
+		return Counterparty{}, fmt.Errorf("%w: %w", ErrInvalidCounterparty, err)
+	}
+	return CounterpartyOf(publicKey), nil
+}
+
+// Validate checks the counterparty has a known type, and a public key if it's CounterpartyTypeOther.
+// The zero value is invalid, the wallet methods replace it with their default before validation.
+func (c Counterparty) Validate() error {
+	switch c.Type {
+	case CounterpartyTypeSelf, CounterpartyTypeAnyone:
+		return nil
+	case CounterpartyTypeOther:
+		if c.PublicKey == nil {
+			return fmt.Errorf("%w: public key is required for the %s counterparty", ErrInvalidCounterparty, c.Type)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s counterparty type", ErrInvalidCounterparty, c.Type)
+}
+
+// OrDefault returns the counterparty, or the fallback if no counterparty was given.
+func (c Counterparty) OrDefault(fallback Counterparty) Counterparty {
+	if c.Type == CounterpartyTypeUninitialized {
+		return fallback
+	}
+	return c
+}
+
+// String returns "self", "anyone" or the compressed hex encoded public key of the counterparty.
+func (c Counterparty) String() string {
+	if c.Type == CounterpartyTypeOther && c.PublicKey != nil {
+		return hex.EncodeToString(c.PublicKey.Compressed())
+	}
+	return c.Type.String()
+}
+
+// MarshalJSON encodes the counterparty as "self", "anyone" or the hex encoded public key.
+func (c Counterparty) MarshalJSON() ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(c.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode counterparty: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON decodes the counterparty from "self", "anyone" or the hex encoded public key.
+func (c *Counterparty) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCounterparty, err)
+	}
+	counterparty, err := ParseCounterparty(value)
+	if err != nil {
+		return err
+	}
+	*c = counterparty
+	return nil
+}
@@ -1,17 +1,48 @@
 package wallet
 
-import "context"
+import (
+	"context"
+	"errors"
+)
 
-// Interface defines the core functionality needed for authentication
+// ErrCertificateNotFound is returned by RelinquishCertificate when the wallet doesn't hold the certificate.
+var ErrCertificateNotFound = errors.New("certificate not found")
+
+// Networks reported by GetNetwork.
+const (
+	NetworkMainnet = "mainnet"
+	NetworkTestnet = "testnet"
+)
+
+// Interface defines the core functionality needed for authentication.
+// Implementations must be safe for concurrent use by multiple goroutines,
+// the middleware calls them from concurrent request handlers.
 type Interface interface {
 	// GetPublicKey returns a public key
 	GetPublicKey(ctx context.Context, options GetPublicKeyOptions) (string, error)
 
-	// CreateSignature signs data with specific protocol/key IDs
-	CreateSignature(ctx context.Context, data []byte, protocolID any, keyID string, counterparty string) ([]byte, error)
+	// CreateSignature signs data with specific protocol/key IDs, using the privileged keyring if the privilege asks for it
+	CreateSignature(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty, privilege Privilege) ([]byte, error)
 
 	// VerifySignature verifies a signature
-	VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID any, keyID string, counterparty string) (bool, error)
+	VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error)
+
+	// VerifySignatures verifies the signatures of the items, the result of an item is false if it's invalid or rejected.
+	// The error is returned only for failures of the wallet itself, like a lost connection to a remote wallet,
+	// the results of the items affected by it are false.
+	VerifySignatures(ctx context.Context, items []VerifyItem) ([]bool, error)
+
+	// CreateHMAC creates an HMAC of the data with a key derived for the specific protocol/key IDs and counterparty
+	CreateHMAC(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error)
+
+	// VerifyHMAC verifies an HMAC created by CreateHMAC with the same protocol/key IDs and counterparty
+	VerifyHMAC(ctx context.Context, data []byte, hmac []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error)
+
+	// Encrypt encrypts data with a key derived for the specific protocol/key IDs and counterparty
+	Encrypt(ctx context.Context, plaintext []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error)
+
+	// Decrypt decrypts data encrypted by Encrypt with the same protocol/key IDs and counterparty
+	Decrypt(ctx context.Context, ciphertext []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error)
 
 	// CreateNonce creates a nonce for challenge-response authentication
 	CreateNonce(ctx context.Context) (string, error)
@@ -19,9 +50,40 @@ type Interface interface {
 	// VerifyNonce verifies a nonce that was previously created
 	VerifyNonce(ctx context.Context, nonce string) (bool, error)
 
-	// ListCertificates is a stub for future certificate functionality
-	ListCertificates(ctx context.Context, certifiers []string, types []string) ([]Certificate, error)
+	// ListCertificates lists the certificates matching the options, a page of them if the limit or offset is set.
+	// The filters are combined with AND across dimensions and OR within a list, an empty list means no filter.
+	ListCertificates(ctx context.Context, options ListCertificatesOptions) (ListCertificatesResult, error)
+
+	// AcquireCertificate stores the certificate in the wallet, replacing the one with the same type, serial number and certifier
+	AcquireCertificate(ctx context.Context, certificate Certificate) error
+
+	// RelinquishCertificate removes the certificate from the wallet, it returns ErrCertificateNotFound for an unknown certificate
+	RelinquishCertificate(ctx context.Context, certType string, serialNumber string, certifier string) error
+
+	// ProveCertificate creates a keyring revealing the fields of the certificate to the verifier,
+	// it maps the field names to their keys encrypted to the verifier, decrypted with the privileged keyring if asked for
+	ProveCertificate(ctx context.Context, certificate Certificate, verifier string, fieldsToReveal []string, privilege Privilege) (map[string]string, error)
+
+	// RevealCounterpartyKeyLinkage reveals to the verifier the linkage of all the keys derived with the counterparty,
+	// both are identity keys
+	RevealCounterpartyKeyLinkage(ctx context.Context, counterparty string, verifier string) (KeyLinkageResult, error)
+
+	// RevealSpecificKeyLinkage reveals to the verifier the linkage of the key derived with the counterparty for the protocol and keyID
+	RevealSpecificKeyLinkage(ctx context.Context, counterparty string, verifier string, protocolID Protocol, keyID string) (KeyLinkageResult, error)
+
+	// GetNetwork returns the network the wallet operates on, NetworkMainnet or NetworkTestnet
+	GetNetwork(ctx context.Context) (string, error)
+
+	// GetVersion returns the version of the wallet, e.g. "vendor-1.0.0"
+	GetVersion(ctx context.Context) (string, error)
+
+	// GetHeight returns the height of the chain tip known to the wallet
+	GetHeight(ctx context.Context) (uint32, error)
+
+	// CreateAction creates a transaction with the outputs, funded and signed by the wallet
+	CreateAction(ctx context.Context, args CreateActionArgs) (CreateActionResult, error)
 
-	// ProveCertificate is a stub for future certificate functionality
-	ProveCertificate(ctx context.Context, certificate Certificate, verifier string, fieldsToReveal []string) (map[string]string, error)
+	// InternalizeAction takes ownership of the outputs of an incoming transaction, e.g. a payment to the wallet,
+	// it returns an error if the wallet rejects the transaction
+	InternalizeAction(ctx context.Context, args InternalizeActionArgs) (InternalizeActionResult, error)
 }
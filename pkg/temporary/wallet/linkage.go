@@ -0,0 +1,45 @@
+package wallet
+
+import "fmt"
+
+const (
+	// KeyLinkageProofNone is the ProofType of a linkage revealed without a proof of its correctness.
+	KeyLinkageProofNone byte = 0
+	// RevelationTimeFormat is the ISO 8601 format of the RevelationTime, the one of JavaScript's Date.toISOString.
+	RevelationTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// CounterpartyLinkageProtocol is the protocol of the key encrypting a counterparty linkage for the verifier,
+// the keyID is the revelation time.
+var CounterpartyLinkageProtocol = Protocol{SecurityLevel: SecurityLevelAppAndCounterparty, Protocol: "counterparty linkage revelation"}
+
+// SpecificLinkageProtocol returns the protocol of the key encrypting the specific linkage of the protocol for the verifier,
+// the keyID is the one of the revealed key.
+func SpecificLinkageProtocol(protocol Protocol) Protocol {
+	return Protocol{
+		SecurityLevel: SecurityLevelAppAndCounterparty,
+		Protocol:      fmt.Sprintf("specific linkage revelation %d %s", protocol.SecurityLevel, protocol.Protocol),
+	}
+}
+
+// KeyLinkageResult is a BRC-69 key linkage revealed by the prover to the verifier, encrypted for the verifier.
+// The counterparty linkage is the shared secret of the prover and the counterparty, linking all their derived keys,
+// the specific linkage is the offset of the single key derived for the protocol and keyID.
+type KeyLinkageResult struct {
+	// Prover is the identity key of the wallet revealing the linkage
+	Prover string `json:"prover"`
+	// Verifier is the identity key of the verifier the linkage is encrypted for
+	Verifier string `json:"verifier"`
+	// Counterparty is the identity key of the counterparty of the linked keys
+	Counterparty string `json:"counterparty"`
+	// EncryptedLinkage is the linkage encrypted for the verifier
+	EncryptedLinkage []byte `json:"encryptedLinkage"`
+	// ProofType is the type of the proof of the linkage, KeyLinkageProofNone if there's none
+	ProofType byte `json:"proofType"`
+	// RevelationTime is the ISO 8601 time of a counterparty linkage revelation, the keyID of its encryption
+	RevelationTime string `json:"revelationTime,omitempty"`
+	// ProtocolID is the protocol of the key of a specific linkage
+	ProtocolID Protocol `json:"protocolID,omitzero"`
+	// KeyID is the key ID of the key of a specific linkage
+	KeyID string `json:"keyID,omitempty"`
+}
@@ -0,0 +1,134 @@
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidProtocol is returned when the protocol isn't a valid BRC-43 security level and protocol name.
+var ErrInvalidProtocol = errors.New("invalid protocol")
+
+// SecurityLevel is the BRC-43 security level of a protocol, it decides which keys are shared between counterparties and apps.
+type SecurityLevel int
+
+// BRC-43 security levels.
+const (
+	// SecurityLevelSilent keys are used without asking the user, for any app and counterparty
+	SecurityLevelSilent SecurityLevel = 0
+	// SecurityLevelApp keys need the user's permission once per app
+	SecurityLevelApp SecurityLevel = 1
+	// SecurityLevelAppAndCounterparty keys need the user's permission once per app and counterparty
+	SecurityLevelAppAndCounterparty SecurityLevel = 2
+)
+
+var protocolNamePattern = regexp.MustCompile(`^[a-z0-9 ]+$`)
+
+// Protocol identifies the BRC-43 protocol the keys are derived for.
+// It's serialized to JSON as the [securityLevel, protocolName] pair used by the TypeScript SDK.
+type Protocol struct {
+	// SecurityLevel is the security level of the protocol
+	SecurityLevel SecurityLevel
+	// Protocol is the name of the protocol, e.g. "auth message signature"
+	Protocol string
+}
+
+// Normalize returns the protocol with its name lowercased and trimmed, the form used in BRC-43 invoice numbers.
+func (p Protocol) Normalize() Protocol {
+	return Protocol{SecurityLevel: p.SecurityLevel, Protocol: strings.ToLower(strings.TrimSpace(p.Protocol))}
+}
+
+// Validate checks the protocol against the BRC-43 rules, the same way the TypeScript SDK does.
+// The name is validated after normalization.
+func (p Protocol) Validate() error {
+	if p.SecurityLevel < SecurityLevelSilent || p.SecurityLevel > SecurityLevelAppAndCounterparty {
+		return fmt.Errorf("%w: security level must be 0, 1, or 2", ErrInvalidProtocol)
+	}
+
+	name := p.Normalize().Protocol
+	switch {
+	case len(name) > 400:
+		return fmt.Errorf("%w: protocol names must be 400 characters or less", ErrInvalidProtocol)
+	case len(name) < 5:
+		return fmt.Errorf("%w: protocol names must be 5 characters or more", ErrInvalidProtocol)
+	case strings.Contains(name, "  "):
+		return fmt.Errorf("%w: protocol names cannot contain multiple consecutive spaces", ErrInvalidProtocol)
+	case !protocolNamePattern.MatchString(name):
+		return fmt.Errorf("%w: protocol names can only contain letters, numbers and spaces", ErrInvalidProtocol)
+	case strings.HasSuffix(name, " protocol"):
+		return fmt.Errorf("%w: no need to end the protocol name with \" protocol\"", ErrInvalidProtocol)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the protocol as the [securityLevel, protocolName] pair.
+func (p Protocol) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal([2]any{p.SecurityLevel, p.Protocol})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode protocol: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON decodes the protocol from the [securityLevel, protocolName] pair.
+func (p *Protocol) UnmarshalJSON(data []byte) error {
+	var pair []any
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidProtocol, err)
+	}
+	protocol, err := parseProtocolPair(pair)
+	if err != nil {
+		return err
+	}
+	*p = protocol
+	return nil
+}
+
+// FromLegacy converts the untyped protocolID, the [securityLevel, protocolName] pair given as []any or [2]any,
+// to a Protocol. The security level can be an int or a float64, as decoded from JSON.
+// It doesn't validate the protocol, see Validate.
+//
+// Deprecated: FromLegacy is kept for migrating code passing untyped protocol IDs and will be removed in the next release.
+func FromLegacy(protocolID any) (Protocol, error) {
+	return parseProtocolPair(protocolID)
+}
+
+func parseProtocolPair(protocolID any) (Protocol, error) {
+	var parts []any
+	switch value := protocolID.(type) {
+	case Protocol:
+		return value, nil
+	case []any:
+		parts = value
+	case [2]any:
+		parts = value[:]
+	default:
+		return Protocol{}, fmt.Errorf("%w: expected [securityLevel, protocolName], got %T", ErrInvalidProtocol, protocolID)
+	}
+	if len(parts) != 2 {
+		return Protocol{}, fmt.Errorf("%w: expected [securityLevel, protocolName]", ErrInvalidProtocol)
+	}
+
+	var securityLevel SecurityLevel
+	switch level := parts[0].(type) {
+	case int:
+		securityLevel = SecurityLevel(level)
+	case SecurityLevel:
+		securityLevel = level
+	case float64:
+		if level != float64(int(level)) {
+			return Protocol{}, fmt.Errorf("%w: security level must be an integer", ErrInvalidProtocol)
+		}
+		securityLevel = SecurityLevel(level)
+	default:
+		return Protocol{}, fmt.Errorf("%w: security level must be an integer, got %T", ErrInvalidProtocol, parts[0])
+	}
+
+	name, ok := parts[1].(string)
+	if !ok {
+		return Protocol{}, fmt.Errorf("%w: protocol name must be a string, got %T", ErrInvalidProtocol, parts[1])
+	}
+	return Protocol{SecurityLevel: securityLevel, Protocol: name}, nil
+}
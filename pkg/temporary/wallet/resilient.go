@@ -0,0 +1,203 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"time"
+)
+
+// Resilient defaults.
+const (
+	DefaultCallTimeout    = 5 * time.Second
+	DefaultMaxRetries     = 2
+	DefaultRetryBaseDelay = 100 * time.Millisecond
+	DefaultRetryMaxDelay  = 2 * time.Second
+)
+
+// ErrTransient marks the errors of the wallet calls that may succeed when retried, see IsTransient.
+var ErrTransient = errors.New("transient wallet failure")
+
+// Resilient decorates a remote wallet, bounding its calls with a timeout and retrying the reads
+// (GetPublicKey, VerifySignature and ListCertificates) failed with a transient error with an exponential backoff and jitter.
+// The permanent errors, e.g. a malformed signature or an unknown protocol, are returned at once.
+// CreateNonce and CreateSignature are bounded with the timeout too, but retried only WithRetriedWrites.
+// VerifyNonce is never retried, the single-use nonces are consumed by the first attempt that reaches the inner wallet.
+// The other calls pass through to the inner wallet as they are.
+// The cancellation of the context of the call always stops the retries.
+type Resilient struct {
+	Interface
+
+	timeout       time.Duration
+	maxRetries    int
+	baseDelay     time.Duration
+	maxDelay      time.Duration
+	retriedWrites bool
+	retryable     func(err error) bool
+}
+
+// ResilientOption configures the Resilient wallet.
+type ResilientOption func(*Resilient)
+
+// WithCallTimeout bounds every attempt of a call to the inner wallet with the timeout, a zero timeout doesn't bound them.
+func WithCallTimeout(timeout time.Duration) ResilientOption {
+	return func(r *Resilient) {
+		r.timeout = timeout
+	}
+}
+
+// WithMaxRetries sets how many times a failed call is retried, zero disables the retries.
+func WithMaxRetries(maxRetries int) ResilientOption {
+	return func(r *Resilient) {
+		r.maxRetries = maxRetries
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry, doubled before every next one up to the maxDelay.
+func WithRetryBackoff(baseDelay time.Duration, maxDelay time.Duration) ResilientOption {
+	return func(r *Resilient) {
+		r.baseDelay = baseDelay
+		r.maxDelay = maxDelay
+	}
+}
+
+// WithRetriedWrites retries the failed CreateNonce and CreateSignature calls too.
+// A retried call may create a nonce or a signature twice, if the failed attempt reached the inner wallet.
+func WithRetriedWrites() ResilientOption {
+	return func(r *Resilient) {
+		r.retriedWrites = true
+	}
+}
+
+// WithRetryable overrides the check of the errors worth retrying, IsTransient by default.
+func WithRetryable(retryable func(err error) bool) ResilientOption {
+	return func(r *Resilient) {
+		r.retryable = retryable
+	}
+}
+
+// NewResilient creates a wallet bounding and retrying the calls to the inner wallet.
+func NewResilient(inner Interface, opts ...ResilientOption) *Resilient {
+	r := &Resilient{
+		Interface:  inner,
+		timeout:    DefaultCallTimeout,
+		maxRetries: DefaultMaxRetries,
+		baseDelay:  DefaultRetryBaseDelay,
+		maxDelay:   DefaultRetryMaxDelay,
+		retryable:  IsTransient,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// GetPublicKey gets the public key from the inner wallet, retrying it if it fails.
+func (r *Resilient) GetPublicKey(ctx context.Context, options GetPublicKeyOptions) (string, error) {
+	return retry(ctx, r, true, func(ctx context.Context) (string, error) {
+		return r.Interface.GetPublicKey(ctx, options)
+	})
+}
+
+// CreateSignature creates the signature with the inner wallet, retrying it only WithRetriedWrites.
+func (r *Resilient) CreateSignature(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty, privilege Privilege) ([]byte, error) {
+	return retry(ctx, r, r.retriedWrites, func(ctx context.Context) ([]byte, error) {
+		return r.Interface.CreateSignature(ctx, data, protocolID, keyID, counterparty, privilege)
+	})
+}
+
+// VerifySignature verifies the signature with the inner wallet, retrying it if it fails.
+func (r *Resilient) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error) {
+	return retry(ctx, r, true, func(ctx context.Context) (bool, error) {
+		return r.Interface.VerifySignature(ctx, data, signature, protocolID, keyID, counterparty)
+	})
+}
+
+// CreateNonce creates the nonce with the inner wallet, retrying it only WithRetriedWrites.
+func (r *Resilient) CreateNonce(ctx context.Context) (string, error) {
+	return retry(ctx, r, r.retriedWrites, r.Interface.CreateNonce)
+}
+
+// VerifyNonce verifies the nonce with the inner wallet without retrying it,
+// a retry of an attempt that timed out after consuming the nonce would reject it as already used.
+func (r *Resilient) VerifyNonce(ctx context.Context, nonce string) (bool, error) {
+	return retry(ctx, r, false, func(ctx context.Context) (bool, error) {
+		return r.Interface.VerifyNonce(ctx, nonce)
+	})
+}
+
+// ListCertificates lists the certificates of the inner wallet, retrying it if it fails.
+func (r *Resilient) ListCertificates(ctx context.Context, options ListCertificatesOptions) (ListCertificatesResult, error) {
+	return retry(ctx, r, true, func(ctx context.Context) (ListCertificatesResult, error) {
+		return r.Interface.ListCertificates(ctx, options)
+	})
+}
+
+// IsTransient reports whether the failed wallet call may succeed when retried: the error wraps ErrTransient,
+// has a Transient method reporting true, like the 5xx responses of a remote wallet, is a network error,
+// or the attempt timed out.
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrTransient) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var transient interface{ Transient() bool }
+	if errors.As(err, &transient) {
+		return transient.Transient()
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retry calls the inner wallet until it succeeds, fails with an error that isn't retryable, the retries run out or the ctx is done,
+// returning the error of the last attempt, or the error of the ctx if it's done.
+func retry[T any](ctx context.Context, r *Resilient, retried bool, call func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil {
+			return zero, fmt.Errorf("ctx err: %w", ctx.Err())
+		}
+
+		result, err := callWithTimeout(ctx, r.timeout, call)
+		if err == nil || !retried || attempt >= r.maxRetries || !r.retryable(err) {
+			return result, err
+		}
+
+		timer := time.NewTimer(r.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, fmt.Errorf("ctx err: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func callWithTimeout[T any](ctx context.Context, timeout time.Duration, call func(ctx context.Context) (T, error)) (T, error) {
+	if timeout <= 0 {
+		return call(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return call(ctx)
+}
+
+// backoff returns the delay before the retry following the attempt, the base delay doubled for every previous retry,
+// capped at the max delay, of which a random half is jittered.
+func (r *Resilient) backoff(attempt int) time.Duration {
+	delay := r.baseDelay
+	for range attempt {
+		if delay >= r.maxDelay/2 {
+			delay = r.maxDelay
+			break
+		}
+		delay *= 2
+	}
+	delay = min(delay, r.maxDelay)
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
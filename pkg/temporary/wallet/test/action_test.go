@@ -0,0 +1,197 @@
+package wallet_test
+
+import (
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+const txID = "b1fc0f44ba629dbdffab9e34fcc4faf9dbde3560a7365c55c26fe4daab052aac"
+
+func paymentArgs() wallet.InternalizeActionArgs {
+	return wallet.InternalizeActionArgs{
+		Tx: []byte("atomic beef"),
+		Outputs: []wallet.InternalizeOutput{{
+			OutputIndex: 0,
+			Protocol:    wallet.InternalizeProtocolWalletPayment,
+			PaymentRemittance: &wallet.PaymentRemittance{
+				DerivationPrefix:  "cHJlZml4",
+				DerivationSuffix:  "c3VmZml4",
+				SenderIdentityKey: fixtures.PeerIdentityKey,
+			},
+		}},
+		Description: "payment for the request",
+	}
+}
+
+func createArgs() wallet.CreateActionArgs {
+	return wallet.CreateActionArgs{
+		Description: "refund of the payment",
+		Inputs: []wallet.CreateActionInput{{
+			Outpoint:              txID + ".1",
+			InputDescription:      "payment output",
+			UnlockingScriptLength: 108,
+		}},
+		Outputs: []wallet.CreateActionOutput{{
+			LockingScript:     "76a914000000000000000000000000000000000000000088ac",
+			Satoshis:          1000,
+			OutputDescription: "refund output",
+		}},
+	}
+}
+
+func TestInternalizeActionArgs_Validate(t *testing.T) {
+	t.Run("valid args", func(t *testing.T) {
+		tests := map[string]func(args *wallet.InternalizeActionArgs){
+			"wallet payment": func(*wallet.InternalizeActionArgs) {},
+			"basket insertion": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0] = wallet.InternalizeOutput{
+					Protocol:            wallet.InternalizeProtocolBasketInsertion,
+					InsertionRemittance: &wallet.BasketInsertion{Basket: "tokens", Tags: []string{"token"}},
+				}
+			},
+			"labels": func(args *wallet.InternalizeActionArgs) {
+				args.Labels = []string{"payment"}
+			},
+		}
+
+		for name, modify := range tests {
+			t.Run(name, func(t *testing.T) {
+				// given
+				args := paymentArgs()
+				modify(&args)
+
+				// when
+				err := args.Validate()
+
+				// then
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("invalid args", func(t *testing.T) {
+		tests := map[string]func(args *wallet.InternalizeActionArgs){
+			"no tx": func(args *wallet.InternalizeActionArgs) {
+				args.Tx = nil
+			},
+			"no outputs": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs = nil
+			},
+			"description too short": func(args *wallet.InternalizeActionArgs) {
+				args.Description = "pay"
+			},
+			"unknown protocol": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0].Protocol = "gift"
+			},
+			"wallet payment without remittance": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0].PaymentRemittance = nil
+			},
+			"derivation prefix not base64": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0].PaymentRemittance.DerivationPrefix = "not base64!"
+			},
+			"no derivation suffix": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0].PaymentRemittance.DerivationSuffix = ""
+			},
+			"invalid sender identity key": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0].PaymentRemittance.SenderIdentityKey = fixtures.IdentityKeyMock
+			},
+			"basket insertion without basket": func(args *wallet.InternalizeActionArgs) {
+				args.Outputs[0] = wallet.InternalizeOutput{
+					Protocol:            wallet.InternalizeProtocolBasketInsertion,
+					InsertionRemittance: &wallet.BasketInsertion{},
+				}
+			},
+		}
+
+		for name, modify := range tests {
+			t.Run(name, func(t *testing.T) {
+				// given
+				args := paymentArgs()
+				modify(&args)
+
+				// when
+				err := args.Validate()
+
+				// then
+				require.ErrorIs(t, err, wallet.ErrInvalidAction)
+			})
+		}
+	})
+}
+
+func TestCreateActionArgs_Validate(t *testing.T) {
+	t.Run("valid args", func(t *testing.T) {
+		tests := map[string]func(args *wallet.CreateActionArgs){
+			"inputs and outputs": func(*wallet.CreateActionArgs) {},
+			"only outputs": func(args *wallet.CreateActionArgs) {
+				args.Inputs = nil
+			},
+			"unlocking script": func(args *wallet.CreateActionArgs) {
+				args.Inputs[0].UnlockingScript = "0000"
+				args.Inputs[0].UnlockingScriptLength = 2
+			},
+		}
+
+		for name, modify := range tests {
+			t.Run(name, func(t *testing.T) {
+				// given
+				args := createArgs()
+				modify(&args)
+
+				// when
+				err := args.Validate()
+
+				// then
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("invalid args", func(t *testing.T) {
+		tests := map[string]func(args *wallet.CreateActionArgs){
+			"description too long": func(args *wallet.CreateActionArgs) {
+				args.Description = "a description longer than the fifty bytes allowed by BRC-100"
+			},
+			"no inputs and outputs": func(args *wallet.CreateActionArgs) {
+				args.Inputs = nil
+				args.Outputs = nil
+			},
+			"outpoint without index": func(args *wallet.CreateActionArgs) {
+				args.Inputs[0].Outpoint = txID
+			},
+			"outpoint with short txid": func(args *wallet.CreateActionArgs) {
+				args.Inputs[0].Outpoint = "abcd.0"
+			},
+			"no unlocking script nor its length": func(args *wallet.CreateActionArgs) {
+				args.Inputs[0].UnlockingScriptLength = 0
+			},
+			"unlocking script length mismatch": func(args *wallet.CreateActionArgs) {
+				args.Inputs[0].UnlockingScript = "0000"
+				args.Inputs[0].UnlockingScriptLength = 3
+			},
+			"locking script not hex": func(args *wallet.CreateActionArgs) {
+				args.Outputs[0].LockingScript = "not hex"
+			},
+			"no output description": func(args *wallet.CreateActionArgs) {
+				args.Outputs[0].OutputDescription = ""
+			},
+		}
+
+		for name, modify := range tests {
+			t.Run(name, func(t *testing.T) {
+				// given
+				args := createArgs()
+				modify(&args)
+
+				// when
+				err := args.Validate()
+
+				// then
+				require.ErrorIs(t, err, wallet.ErrInvalidAction)
+			})
+		}
+	})
+}
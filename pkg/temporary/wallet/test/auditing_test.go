@@ -0,0 +1,185 @@
+package wallet_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/internal/logging"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditing(inner wallet.Interface) (*wallet.Auditing, *logging.TestWriter) {
+	writer := &logging.TestWriter{}
+	logger := slog.New(slog.NewJSONHandler(writer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return wallet.NewAuditing(inner, logger), writer
+}
+
+// auditRecords decodes the JSON records written by the audit logger.
+func auditRecords(t *testing.T, writer *logging.TestWriter) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.Lines(writer.String()) {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestAuditing_CreateSignature(t *testing.T) {
+	t.Run("logs the call with the truncated signature", func(t *testing.T) {
+		// given
+		w, writer := newAuditing(wallet.NewMockWallet(fixtures.WithKeyDeriver))
+		ctx := wallet.WithPeer(context.Background(), fixtures.PeerIdentityKey)
+
+		// when
+		signature, err := w.CreateSignature(ctx, []byte("secret data"), authProtocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+
+		// then
+		require.NoError(t, err)
+		records := auditRecords(t, writer)
+		require.Len(t, records, 1)
+		record := records[0]
+		require.Equal(t, "INFO", record["level"])
+		require.Equal(t, "Wallet call", record["msg"])
+		require.Equal(t, "wallet-audit", record["service"])
+		require.Equal(t, "CreateSignature", record["method"])
+		require.Equal(t, fixtures.PeerIdentityKey, record["peer"])
+		require.Equal(t, "2-auth message", record["protocol"])
+		keyIDHash := sha256.Sum256([]byte("1"))
+		require.Equal(t, hex.EncodeToString(keyIDHash[:8]), record["key_id_hash"])
+		require.NotContains(t, record, "key_id")
+		require.Equal(t, "self", record["counterparty"])
+		require.InDelta(t, len("secret data"), record["data_size"], 0)
+		require.Equal(t, hex.EncodeToString(signature[:8])+"...", record["signature"])
+		require.Contains(t, record, "duration")
+		require.NotContains(t, record, "error")
+		require.NotContains(t, writer.String(), "secret data")
+		require.NotContains(t, writer.String(), hex.EncodeToString(signature))
+	})
+
+	t.Run("logs the failed call with its error", func(t *testing.T) {
+		// given
+		w, writer := newAuditing(wallet.NewMockWallet(fixtures.WithKeyDeriver))
+
+		// when
+		_, err := w.CreateSignature(context.Background(), []byte("data"), authProtocol, "1", wallet.CounterpartySelf(), wallet.Privilege{Privileged: true, Reason: "audit"})
+
+		// then
+		require.EqualError(t, err, fixtures.ErrorNoPrivilege)
+		records := auditRecords(t, writer)
+		require.Len(t, records, 1)
+		record := records[0]
+		require.Equal(t, "WARN", record["level"])
+		require.Equal(t, "Wallet call failed", record["msg"])
+		require.Equal(t, "CreateSignature", record["method"])
+		require.Equal(t, fixtures.ErrorNoPrivilege, record["error"])
+		require.Equal(t, true, record["privileged"])
+		require.Equal(t, "audit", record["privileged_reason"])
+		require.Empty(t, record["signature"])
+		require.NotContains(t, record, "peer")
+	})
+}
+
+func TestAuditing_Redaction(t *testing.T) {
+	tests := map[string]struct {
+		call     func(w wallet.Interface) error
+		method   string
+		key      string
+		expected string
+	}{
+		"truncates the verified signature": {
+			call: func(w wallet.Interface) error {
+				_, err := w.VerifySignature(context.Background(), []byte("data"), []byte("0123456789abcdef"), authProtocol, "1", wallet.CounterpartySelf())
+				return err
+			},
+			method:   "VerifySignature",
+			key:      "signature",
+			expected: hex.EncodeToString([]byte("01234567")) + "...",
+		},
+		"truncates the verified HMAC": {
+			call: func(w wallet.Interface) error {
+				_, err := w.VerifyHMAC(context.Background(), []byte("data"), []byte("0123456789abcdef"), authProtocol, "1", wallet.CounterpartySelf())
+				return err
+			},
+			method:   "VerifyHMAC",
+			key:      "hmac",
+			expected: hex.EncodeToString([]byte("01234567")) + "...",
+		},
+		"keeps a short signature whole": {
+			call: func(w wallet.Interface) error {
+				_, err := w.VerifySignature(context.Background(), []byte("data"), []byte("short"), authProtocol, "1", wallet.CounterpartySelf())
+				return err
+			},
+			method:   "VerifySignature",
+			key:      "signature",
+			expected: hex.EncodeToString([]byte("short")),
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			w, writer := newAuditing(wallet.NewMockWallet(fixtures.WithKeyDeriver))
+
+			// when
+			_ = test.call(w)
+
+			// then
+			records := auditRecords(t, writer)
+			require.Len(t, records, 1)
+			require.Equal(t, test.method, records[0]["method"])
+			require.Equal(t, test.expected, records[0][test.key])
+		})
+	}
+
+	t.Run("hashes the keyID holding the nonces of a general message", func(t *testing.T) {
+		// given
+		w, writer := newAuditing(wallet.NewMockWallet(fixtures.WithKeyDeriver))
+		keyID := "cmVxdWVzdCBub25jZQ== c2Vzc2lvbiBub25jZQ=="
+
+		// when
+		_, err := w.CreateSignature(context.Background(), []byte("data"), authProtocol, keyID, wallet.CounterpartySelf(), wallet.Privilege{})
+
+		// then
+		require.NoError(t, err)
+		require.NotContains(t, writer.String(), "cmVxdWVzdCBub25jZQ==")
+		require.NotContains(t, writer.String(), "c2Vzc2lvbiBub25jZQ==")
+	})
+
+	t.Run("logs the sizes of the plaintext and ciphertext only", func(t *testing.T) {
+		// given
+		w, writer := newAuditing(wallet.NewMockWallet(fixtures.WithKeyDeriver))
+		plaintext := []byte("very secret plaintext")
+
+		// when
+		ciphertext, err := w.Encrypt(context.Background(), plaintext, authProtocol, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		record := auditRecords(t, writer)[0]
+		require.InDelta(t, len(plaintext), record["plaintext_size"], 0)
+		require.InDelta(t, len(ciphertext), record["ciphertext_size"], 0)
+		require.NotContains(t, writer.String(), string(plaintext))
+		require.NotContains(t, writer.String(), hex.EncodeToString(plaintext))
+	})
+}
+
+func TestAuditing_PassThrough(t *testing.T) {
+	// given
+	w, writer := newAuditing(wallet.NewCached(wallet.NewMockWallet(fixtures.WithKeyDeriver), time.Minute, 10))
+
+	// when
+	_, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+
+	// then
+	require.NoError(t, err)
+	require.Empty(t, writer.String())
+}
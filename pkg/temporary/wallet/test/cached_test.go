@@ -0,0 +1,173 @@
+package wallet_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWallet returns a new public key on every GetPublicKey call, so a cached one can be told apart.
+type countingWallet struct {
+	wallet.Interface
+	calls int
+	err   error
+}
+
+func (w *countingWallet) GetPublicKey(_ context.Context, _ wallet.GetPublicKeyOptions) (string, error) {
+	w.calls++
+	if w.err != nil {
+		return "", w.err
+	}
+	return fmt.Sprintf("public-key-%d", w.calls), nil
+}
+
+func newCountingWallet() *countingWallet {
+	return &countingWallet{Interface: wallet.NewMockWallet(fixtures.WithKeyDeriver)}
+}
+
+func keyOptions(keyID string) wallet.GetPublicKeyOptions {
+	return wallet.GetPublicKeyOptions{ProtocolID: authProtocol, KeyID: keyID, Counterparty: wallet.CounterpartySelf()}
+}
+
+func TestCached_GetPublicKey(t *testing.T) {
+	t.Run("caches the public key by the options", func(t *testing.T) {
+		// given
+		inner := newCountingWallet()
+		w := wallet.NewCached(inner, time.Minute, 10)
+
+		// when
+		first, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.NoError(t, err)
+		second, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.NoError(t, err)
+		other, err := w.GetPublicKey(context.Background(), keyOptions("2"))
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, first, second)
+		require.NotEqual(t, first, other)
+		require.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("compares the counterparties by their public keys", func(t *testing.T) {
+		// given
+		inner := newCountingWallet()
+		w := wallet.NewCached(inner, time.Minute, 10)
+		options := keyOptions("1")
+		options.Counterparty = counterpartyOf(t, fixtures.PeerIdentityKey)
+		sameOptions := keyOptions("1")
+		sameOptions.Counterparty = counterpartyOf(t, fixtures.PeerIdentityKey)
+
+		// when
+		first, err := w.GetPublicKey(context.Background(), options)
+		require.NoError(t, err)
+		second, err := w.GetPublicKey(context.Background(), sameOptions)
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, first, second)
+		require.Equal(t, 1, inner.calls)
+	})
+
+	t.Run("expires the public key after the ttl", func(t *testing.T) {
+		// given
+		now := time.Now()
+		inner := newCountingWallet()
+		w := wallet.NewCached(inner, time.Minute, 10, wallet.WithCacheClock(func() time.Time { return now }))
+		first, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(59 * time.Second)
+		cached, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.NoError(t, err)
+		now = now.Add(time.Second)
+		expired, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, first, cached)
+		require.NotEqual(t, first, expired)
+		require.Equal(t, 2, inner.calls)
+	})
+
+	t.Run("evicts the least recently used public key", func(t *testing.T) {
+		// given
+		inner := newCountingWallet()
+		w := wallet.NewCached(inner, time.Minute, 2)
+		for _, keyID := range []string{"1", "2", "1", "3"} {
+			_, err := w.GetPublicKey(context.Background(), keyOptions(keyID))
+			require.NoError(t, err)
+		}
+		require.Equal(t, 3, inner.calls)
+
+		// when
+		_, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.NoError(t, err)
+		_, err = w.GetPublicKey(context.Background(), keyOptions("2"))
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, 4, inner.calls)
+		require.Equal(t, 2, w.Len())
+	})
+
+	t.Run("bypasses the cache for privileged keys", func(t *testing.T) {
+		// given
+		inner := newCountingWallet()
+		w := wallet.NewCached(inner, time.Minute, 10)
+		options := keyOptions("1")
+		options.Privileged = true
+
+		// when
+		first, err := w.GetPublicKey(context.Background(), options)
+		require.NoError(t, err)
+		second, err := w.GetPublicKey(context.Background(), options)
+		require.NoError(t, err)
+
+		// then
+		require.NotEqual(t, first, second)
+		require.Equal(t, 2, inner.calls)
+		require.Zero(t, w.Len())
+	})
+
+	t.Run("doesn't cache errors", func(t *testing.T) {
+		// given
+		inner := newCountingWallet()
+		inner.err = errors.New("wallet unavailable")
+		w := wallet.NewCached(inner, time.Minute, 10)
+		_, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+		require.Error(t, err)
+
+		// when
+		inner.err = nil
+		publicKey, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, "public-key-2", publicKey)
+	})
+}
+
+func TestCached_PassThrough(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewCached(wallet.NewMockWallet(fixtures.WithKeyDeriver), time.Minute, 10)
+
+	// when
+	nonce, err := w.CreateNonce(ctx)
+	require.NoError(t, err)
+	validNonce, err := w.VerifyNonce(ctx, nonce)
+	require.NoError(t, err)
+	reusedNonce, _ := w.VerifyNonce(ctx, nonce)
+
+	// then
+	require.True(t, validNonce)
+	require.False(t, reusedNonce)
+}
@@ -2,13 +2,19 @@ package wallet_test
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
 	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
 	"github.com/stretchr/testify/require"
 )
 
+var authProtocol = wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "auth message"}
+
 // Test GetPublicKey for valid cases
 func TestMockWallet_GetPublicKey_HappyPath(t *testing.T) {
 	// given
@@ -25,7 +31,7 @@ func TestMockWallet_GetPublicKey_HappyPath(t *testing.T) {
 	// when
 	derivedKey, err := w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{
 		IdentityKey: false,
-		ProtocolID:  "auth-protocol",
+		ProtocolID:  authProtocol,
 		KeyID:       "key123",
 	})
 
@@ -75,16 +81,16 @@ func TestMockWallet_CreateAndVerifySignature_HappyPath(t *testing.T) {
 	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
 
 	data := []byte("test-data")
-	protocolID := "auth-protocol"
+	protocolID := authProtocol
 	keyID := "key123"
-	counterparty := "peer"
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
 
 	// when
-	signature, err := w.CreateSignature(ctx, data, protocolID, keyID, counterparty)
+	signature, err := w.CreateSignature(ctx, data, protocolID, keyID, counterparty, wallet.Privilege{})
 
 	// then
 	require.NoError(t, err)
-	require.Equal(t, []byte(fixtures.MockSignature), signature)
+	require.True(t, strings.HasPrefix(string(signature), fixtures.MockSignature+":"+fixtures.PeerIdentityKey+":"))
 
 	// when
 	isValid, err := w.VerifySignature(ctx, data, signature, protocolID, keyID, counterparty)
@@ -94,6 +100,71 @@ func TestMockWallet_CreateAndVerifySignature_HappyPath(t *testing.T) {
 	require.True(t, isValid)
 }
 
+// Test VerifySignatures
+func TestMockWallet_VerifySignatures(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+	signature, err := w.CreateSignature(ctx, []byte("test-data"), authProtocol, "key123", counterparty, wallet.Privilege{})
+	require.NoError(t, err)
+
+	// when
+	results, err := w.VerifySignatures(ctx, []wallet.VerifyItem{
+		{Data: []byte("test-data"), Signature: signature, ProtocolID: authProtocol, KeyID: "key123", Counterparty: counterparty},
+		{Data: []byte("test-data"), Signature: []byte("invalid-signature"), ProtocolID: authProtocol, KeyID: "key123", Counterparty: counterparty},
+	})
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, []bool{true, false}, results)
+}
+
+// Test GetNetwork, GetVersion and GetHeight
+func TestMockWallet_NetworkVersionHeight(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+		// when
+		network, networkErr := w.GetNetwork(ctx)
+		version, versionErr := w.GetVersion(ctx)
+		height, heightErr := w.GetHeight(ctx)
+
+		// then
+		require.NoError(t, networkErr)
+		require.NoError(t, versionErr)
+		require.NoError(t, heightErr)
+		require.Equal(t, wallet.NetworkMainnet, network)
+		require.Equal(t, fixtures.MockVersion, version)
+		require.Equal(t, uint32(fixtures.MockHeight), height)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver,
+			wallet.WithMockNetwork(wallet.NetworkTestnet),
+			wallet.WithMockVersion("test-2.0.0"),
+			wallet.WithMockHeight(42),
+		)
+
+		// when
+		network, networkErr := w.GetNetwork(ctx)
+		version, versionErr := w.GetVersion(ctx)
+		height, heightErr := w.GetHeight(ctx)
+
+		// then
+		require.NoError(t, networkErr)
+		require.NoError(t, versionErr)
+		require.NoError(t, heightErr)
+		require.Equal(t, wallet.NetworkTestnet, network)
+		require.Equal(t, "test-2.0.0", version)
+		require.Equal(t, uint32(42), height)
+	})
+}
+
 // Test CreateNonce and VerifyNonce
 func TestMockWallet_CreateAndVerifyNonce_HappyPath(t *testing.T) {
 	// given
@@ -115,16 +186,128 @@ func TestMockWallet_CreateAndVerifyNonce_HappyPath(t *testing.T) {
 	require.True(t, isValid)
 }
 
+// Test CreateSignature echoes the counterparty
+func TestMockWallet_CreateSignature_Counterparty(t *testing.T) {
+	tests := map[string]struct {
+		counterparty      wallet.Counterparty
+		expectedSignature string
+		expectedError     error
+	}{
+		"self": {
+			counterparty:      wallet.CounterpartySelf(),
+			expectedSignature: fixtures.MockSignature + ":self:",
+		},
+		"anyone": {
+			counterparty:      wallet.CounterpartyAnyone(),
+			expectedSignature: fixtures.MockSignature + ":anyone:",
+		},
+		"other": {
+			counterparty:      counterpartyOf(t, fixtures.PeerIdentityKey),
+			expectedSignature: fixtures.MockSignature + ":" + fixtures.PeerIdentityKey + ":",
+		},
+		"uninitialized": {
+			counterparty:  wallet.Counterparty{},
+			expectedError: wallet.ErrInvalidCounterparty,
+		},
+		"other without public key": {
+			counterparty:  wallet.Counterparty{Type: wallet.CounterpartyTypeOther},
+			expectedError: wallet.ErrInvalidCounterparty,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+			// when
+			signature, err := w.CreateSignature(context.Background(), []byte("test-data"), authProtocol, "key123", tc.counterparty, wallet.Privilege{})
+
+			// then
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(string(signature), tc.expectedSignature))
+		})
+	}
+}
+
 // Test VerifySignature for invalid cases
 func TestMockWallet_VerifySignature_UnhappyPath(t *testing.T) {
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+	data := []byte("test-data")
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+	signature, err := w.CreateSignature(ctx, data, authProtocol, "key123", counterparty, wallet.Privilege{})
+	require.NoError(t, err)
+
+	mutated := []byte("test-data")
+	mutated[0] ^= 0x01
+
+	tests := map[string]struct {
+		data         []byte
+		keyID        string
+		counterparty wallet.Counterparty
+	}{
+		"one byte of the data mutated": {
+			data:         mutated,
+			keyID:        "key123",
+			counterparty: counterparty,
+		},
+		"other keyID": {
+			data:         data,
+			keyID:        "key124",
+			counterparty: counterparty,
+		},
+		"other counterparty": {
+			data:         data,
+			keyID:        "key123",
+			counterparty: counterpartyOf(t, fixtures.OtherPeerIdentityKey),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			isValid, err := w.VerifySignature(ctx, tc.data, signature, authProtocol, tc.keyID, tc.counterparty)
+
+			// then
+			require.NoError(t, err)
+			require.False(t, isValid)
+		})
+	}
+}
+
+// Test the constant signatures of WithMockConstantSignatures
+func TestMockWallet_ConstantSignatures(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockConstantSignatures())
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+
+	// when
+	signature, err := w.CreateSignature(ctx, []byte("test-data"), authProtocol, "key123", counterparty, wallet.Privilege{})
+	require.NoError(t, err)
+	isValid, err := w.VerifySignature(ctx, []byte("other-data"), signature, authProtocol, "key124", counterparty)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, []byte(fixtures.MockSignature+":"+fixtures.PeerIdentityKey), signature)
+	require.True(t, isValid)
+}
+
+// Test VerifySignature of a signature not created by the wallet
+func TestMockWallet_VerifySignature_InvalidSignature(t *testing.T) {
 	// given
 	ctx := context.Background()
 	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
 
 	data := []byte("test-data")
-	protocolID := "auth-protocol"
+	protocolID := authProtocol
 	keyID := "key123"
-	counterparty := "peer"
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
 
 	// when
 	isValid, err := w.VerifySignature(ctx, data, []byte("invalid-signature"), protocolID, keyID, counterparty)
@@ -133,3 +316,751 @@ func TestMockWallet_VerifySignature_UnhappyPath(t *testing.T) {
 	require.NoError(t, err)
 	require.False(t, isValid)
 }
+
+// Test CreateHMAC and VerifyHMAC
+func TestMockWallet_CreateAndVerifyHMAC_HappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+	data := []byte("test-data")
+	protocolID := authProtocol
+	keyID := "key123"
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+
+	// when
+	hmac, err := w.CreateHMAC(ctx, data, protocolID, keyID, counterparty)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, hmac, 32)
+
+	// when
+	isValid, err := w.VerifyHMAC(ctx, data, hmac, protocolID, keyID, counterparty)
+
+	// then
+	require.NoError(t, err)
+	require.True(t, isValid)
+}
+
+// Test VerifyHMAC and CreateHMAC for invalid cases
+func TestMockWallet_HMAC_UnhappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+	data := []byte("test-data")
+	protocolID := authProtocol
+	keyID := "key123"
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+	hmac, err := w.CreateHMAC(ctx, data, protocolID, keyID, counterparty)
+	require.NoError(t, err)
+
+	// when
+	tampered, err := w.VerifyHMAC(ctx, []byte("test-date"), hmac, protocolID, keyID, counterparty)
+
+	// then
+	require.NoError(t, err)
+	require.False(t, tampered)
+
+	// when
+	wrongCounterparty, err := w.VerifyHMAC(ctx, data, hmac, protocolID, keyID, counterpartyOf(t, fixtures.OtherPeerIdentityKey))
+
+	// then
+	require.NoError(t, err)
+	require.False(t, wrongCounterparty)
+
+	// when
+	_, err = w.CreateHMAC(ctx, nil, protocolID, keyID, counterparty)
+
+	// then
+	require.Error(t, err)
+	require.Equal(t, fixtures.ErrorInvalidInput, err.Error())
+}
+
+// Test Encrypt and Decrypt
+func TestMockWallet_EncryptAndDecrypt_HappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+	plaintext := []byte("test-data")
+	protocolID := authProtocol
+	keyID := "key123"
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+
+	// when
+	ciphertext, err := w.Encrypt(ctx, plaintext, protocolID, keyID, counterparty)
+
+	// then
+	require.NoError(t, err)
+	require.NotEqual(t, plaintext, ciphertext)
+
+	// when
+	decrypted, err := w.Decrypt(ctx, ciphertext, protocolID, keyID, counterparty)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+}
+
+// Test Encrypt and Decrypt for invalid cases
+func TestMockWallet_EncryptAndDecrypt_UnhappyPath(t *testing.T) {
+	ctx := context.Background()
+	protocolID := authProtocol
+	keyID := "key123"
+	counterparty := counterpartyOf(t, fixtures.PeerIdentityKey)
+
+	ciphertext, err := wallet.NewMockWallet(fixtures.WithKeyDeriver).Encrypt(ctx, []byte("test-data"), protocolID, keyID, counterparty)
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		wallet        wallet.Interface
+		ciphertext    []byte
+		protocolID    wallet.Protocol
+		keyID         string
+		counterparty  wallet.Counterparty
+		expectedError string
+	}{
+		"wrong counterparty": {
+			wallet:        wallet.NewMockWallet(fixtures.WithKeyDeriver),
+			ciphertext:    ciphertext,
+			protocolID:    protocolID,
+			keyID:         keyID,
+			counterparty:  counterpartyOf(t, fixtures.OtherPeerIdentityKey),
+			expectedError: fixtures.ErrorDecryption,
+		},
+		"wrong key ID": {
+			wallet:        wallet.NewMockWallet(fixtures.WithKeyDeriver),
+			ciphertext:    ciphertext,
+			protocolID:    protocolID,
+			keyID:         "key456",
+			counterparty:  counterparty,
+			expectedError: fixtures.ErrorDecryption,
+		},
+		"empty ciphertext": {
+			wallet:        wallet.NewMockWallet(fixtures.WithKeyDeriver),
+			ciphertext:    nil,
+			protocolID:    protocolID,
+			keyID:         keyID,
+			counterparty:  counterparty,
+			expectedError: fixtures.ErrorInvalidInput,
+		},
+		"missing protocol ID": {
+			wallet:        wallet.NewMockWallet(fixtures.WithKeyDeriver),
+			ciphertext:    ciphertext,
+			protocolID:    wallet.Protocol{},
+			keyID:         keyID,
+			counterparty:  counterparty,
+			expectedError: fixtures.ErrorMissingParams,
+		},
+		"missing key ID": {
+			wallet:        wallet.NewMockWallet(fixtures.WithKeyDeriver),
+			ciphertext:    ciphertext,
+			protocolID:    protocolID,
+			keyID:         " ",
+			counterparty:  counterparty,
+			expectedError: fixtures.ErrorMissingParams,
+		},
+		"without key deriver": {
+			wallet:        wallet.NewMockWallet(fixtures.WithoutKeyDeriver),
+			ciphertext:    ciphertext,
+			protocolID:    protocolID,
+			keyID:         keyID,
+			counterparty:  counterparty,
+			expectedError: fixtures.ErrorKeyDeriver,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			_, err := tc.wallet.Decrypt(ctx, tc.ciphertext, tc.protocolID, tc.keyID, tc.counterparty)
+
+			// then
+			require.Error(t, err)
+			require.Equal(t, tc.expectedError, err.Error())
+		})
+	}
+}
+
+// Test VerifyNonce rejects replayed and expired nonces
+func TestMockWallet_VerifyNonce_UnhappyPath(t *testing.T) {
+	t.Run("verify twice", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+		nonce, err := w.CreateNonce(ctx)
+		require.NoError(t, err)
+
+		// when
+		isValid, err := w.VerifyNonce(ctx, nonce)
+
+		// then
+		require.NoError(t, err)
+		require.True(t, isValid)
+
+		// when
+		isValid, err = w.VerifyNonce(ctx, nonce)
+
+		// then
+		require.Error(t, err)
+		require.Equal(t, fixtures.ErrorNonceUsed, err.Error())
+		require.False(t, isValid)
+	})
+
+	t.Run("verify twice reusable nonce", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockReusableNonces())
+		nonce, err := w.CreateNonce(ctx)
+		require.NoError(t, err)
+
+		// when
+		_, err = w.VerifyNonce(ctx, nonce)
+		require.NoError(t, err)
+		isValid, err := w.VerifyNonce(ctx, nonce)
+
+		// then
+		require.NoError(t, err)
+		require.True(t, isValid)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+		now := time.Now()
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver,
+			wallet.WithMockNonceMaxAge(time.Minute), wallet.WithMockClock(func() time.Time { return now }))
+		nonce, err := w.CreateNonce(ctx)
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(2 * time.Minute)
+		isValid, err := w.VerifyNonce(ctx, nonce)
+
+		// then
+		require.Error(t, err)
+		require.Equal(t, fixtures.ErrorNonceExpired, err.Error())
+		require.False(t, isValid)
+	})
+
+	t.Run("concurrent double-spend", func(t *testing.T) {
+		// given
+		ctx := context.Background()
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+		nonce, err := w.CreateNonce(ctx)
+		require.NoError(t, err)
+
+		// when
+		results := make([]bool, 2)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i], _ = w.VerifyNonce(ctx, nonce)
+			}()
+		}
+		wg.Wait()
+
+		// then
+		require.NotEqual(t, results[0], results[1])
+	})
+}
+
+// Test CreateNonce and VerifyNonce from concurrent handlers, run with -race
+func TestMockWallet_Nonces_Concurrent(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockReusableNonces())
+
+	// when
+	results := make([]bool, 100)
+	errs := make([]error, 100)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nonce, err := w.CreateNonce(ctx)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i], errs[i] = w.VerifyNonce(ctx, nonce)
+		}()
+	}
+	wg.Wait()
+
+	// then
+	for i := range results {
+		require.NoError(t, errs[i])
+		require.True(t, results[i])
+	}
+}
+
+// Test ProveCertificate keyring round-trip
+func TestMockWallet_ProveCertificate_HappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockCertificateFieldKeys(map[string][]byte{
+		"email": []byte("email-field-key"),
+	}))
+	certificate := wallet.Certificate{
+		SerialNumber: "serial-1",
+		Fields:       map[string]any{"email": "encrypted-email", "name": "encrypted-name", "age": "encrypted-age"},
+	}
+
+	// when
+	keyring, err := w.ProveCertificate(ctx, certificate, "verifier", []string{"email", "name"}, wallet.Privilege{})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, keyring, 2)
+
+	// when
+	fieldKeys, err := wallet.DecryptMockKeyring(certificate, "verifier", keyring)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{
+		"email": []byte("email-field-key"),
+		"name":  []byte(fixtures.MockFieldKey + "name"),
+	}, fieldKeys)
+
+	// when
+	keyring, err = w.ProveCertificate(ctx, certificate, "verifier", nil, wallet.Privilege{})
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, keyring)
+	require.Empty(t, keyring)
+}
+
+// Test ProveCertificate for invalid cases
+func TestMockWallet_ProveCertificate_UnhappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+	certificate := wallet.Certificate{
+		SerialNumber: "serial-1",
+		Fields:       map[string]any{"email": "encrypted-email"},
+	}
+
+	// when
+	_, err := w.ProveCertificate(ctx, certificate, "verifier", []string{"email", "phone"}, wallet.Privilege{})
+
+	// then
+	require.Error(t, err)
+	require.Contains(t, err.Error(), fixtures.ErrorUnknownField)
+
+	// when
+	keyring, err := w.ProveCertificate(ctx, certificate, "verifier", []string{"email"}, wallet.Privilege{})
+	require.NoError(t, err)
+	_, err = wallet.DecryptMockKeyring(certificate, "other-verifier", keyring)
+
+	// then
+	require.Error(t, err)
+	require.Equal(t, fixtures.ErrorDecryption, err.Error())
+}
+
+// Test the privileged calls allowed for a reason
+func TestMockWallet_Privileged_HappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	const reason = "prove the identity to the verifier"
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockPrivilegedReasons(reason))
+	privilege := wallet.Privilege{Privileged: true, Reason: reason}
+	certificate := wallet.Certificate{SerialNumber: "serial-1", Fields: map[string]any{"email": "encrypted-email"}}
+
+	// when
+	publicKey, err := w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true, Privileged: true, PrivilegedReason: reason})
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, fixtures.PrivilegedKeyMock, publicKey)
+
+	// when
+	signature, err := w.CreateSignature(ctx, []byte("test-data"), authProtocol, "key123", wallet.CounterpartySelf(), privilege)
+
+	// then
+	require.NoError(t, err)
+	require.NotEmpty(t, signature)
+
+	// when
+	keyring, err := w.ProveCertificate(ctx, certificate, "verifier", []string{"email"}, privilege)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, keyring, 1)
+}
+
+// Test the privileged calls rejected without privileges, without a reason or for another reason
+func TestMockWallet_Privileged_UnhappyPath(t *testing.T) {
+	tests := map[string]struct {
+		opts          []wallet.MockOption
+		reason        string
+		expectedError string
+	}{
+		"privileges not enabled": {
+			reason:        "prove the identity to the verifier",
+			expectedError: fixtures.ErrorNoPrivilege,
+		},
+		"no reason": {
+			opts:          []wallet.MockOption{wallet.WithMockPrivilegedReasons("prove the identity to the verifier")},
+			expectedError: fixtures.ErrorNoPrivilegedReason,
+		},
+		"reason not allowed": {
+			opts:          []wallet.MockOption{wallet.WithMockPrivilegedReasons("prove the identity to the verifier")},
+			reason:        "spend the funds",
+			expectedError: fixtures.ErrorPrivilegedReasonNotAllowed,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			ctx := context.Background()
+			w := wallet.NewMockWallet(fixtures.WithKeyDeriver, test.opts...)
+			privilege := wallet.Privilege{Privileged: true, Reason: test.reason}
+			certificate := wallet.Certificate{SerialNumber: "serial-1", Fields: map[string]any{"email": "encrypted-email"}}
+
+			// when
+			_, keyErr := w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true, Privileged: true, PrivilegedReason: test.reason})
+			_, signatureErr := w.CreateSignature(ctx, []byte("test-data"), authProtocol, "key123", wallet.CounterpartySelf(), privilege)
+			_, proveErr := w.ProveCertificate(ctx, certificate, "verifier", []string{"email"}, privilege)
+
+			// then
+			require.ErrorContains(t, keyErr, test.expectedError)
+			require.ErrorContains(t, signatureErr, test.expectedError)
+			require.ErrorContains(t, proveErr, test.expectedError)
+		})
+	}
+}
+
+// Test the fake key linkages revealed by RevealCounterpartyKeyLinkage and RevealSpecificKeyLinkage
+func TestMockWallet_RevealKeyLinkage_HappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	now := time.Date(2025, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockClock(func() time.Time { return now }))
+
+	// when
+	result, err := w.RevealCounterpartyKeyLinkage(ctx, fixtures.PeerIdentityKey, fixtures.OtherPeerIdentityKey)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, wallet.KeyLinkageResult{
+		Prover:           fixtures.IdentityKeyMock,
+		Verifier:         fixtures.OtherPeerIdentityKey,
+		Counterparty:     fixtures.PeerIdentityKey,
+		EncryptedLinkage: []byte(fixtures.MockLinkage + ":" + fixtures.PeerIdentityKey + ":" + fixtures.OtherPeerIdentityKey),
+		ProofType:        wallet.KeyLinkageProofNone,
+		RevelationTime:   "2025-01-02T03:04:05.006Z",
+	}, result)
+
+	// when
+	result, err = w.RevealSpecificKeyLinkage(ctx, fixtures.PeerIdentityKey, fixtures.OtherPeerIdentityKey, authProtocol, "key123")
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, wallet.KeyLinkageResult{
+		Prover:           fixtures.IdentityKeyMock,
+		Verifier:         fixtures.OtherPeerIdentityKey,
+		Counterparty:     fixtures.PeerIdentityKey,
+		EncryptedLinkage: []byte(fixtures.MockLinkage + ":" + fixtures.PeerIdentityKey + ":" + fixtures.OtherPeerIdentityKey + ":2-auth message-key123"),
+		ProofType:        wallet.KeyLinkageProofNone,
+		ProtocolID:       authProtocol,
+		KeyID:            "key123",
+	}, result)
+}
+
+// Test RevealCounterpartyKeyLinkage and RevealSpecificKeyLinkage for invalid cases
+func TestMockWallet_RevealKeyLinkage_UnhappyPath(t *testing.T) {
+	tests := map[string]struct {
+		enableKeyDeriver bool
+		counterparty     string
+		verifier         string
+		expectedError    string
+	}{
+		"missing counterparty": {
+			enableKeyDeriver: fixtures.WithKeyDeriver,
+			verifier:         fixtures.OtherPeerIdentityKey,
+			expectedError:    fixtures.ErrorMissingLinkageParams,
+		},
+		"missing verifier": {
+			enableKeyDeriver: fixtures.WithKeyDeriver,
+			counterparty:     fixtures.PeerIdentityKey,
+			expectedError:    fixtures.ErrorMissingLinkageParams,
+		},
+		"without key deriver": {
+			enableKeyDeriver: fixtures.WithoutKeyDeriver,
+			counterparty:     fixtures.PeerIdentityKey,
+			verifier:         fixtures.OtherPeerIdentityKey,
+			expectedError:    fixtures.ErrorKeyDeriver,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			ctx := context.Background()
+			w := wallet.NewMockWallet(test.enableKeyDeriver)
+
+			// when
+			_, counterpartyErr := w.RevealCounterpartyKeyLinkage(ctx, test.counterparty, test.verifier)
+			_, specificErr := w.RevealSpecificKeyLinkage(ctx, test.counterparty, test.verifier, authProtocol, "key123")
+
+			// then
+			require.EqualError(t, counterpartyErr, test.expectedError)
+			require.EqualError(t, specificErr, test.expectedError)
+		})
+	}
+
+	t.Run("missing keyID of the specific linkage", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+		// when
+		_, err := w.RevealSpecificKeyLinkage(context.Background(), fixtures.PeerIdentityKey, fixtures.OtherPeerIdentityKey, authProtocol, "")
+
+		// then
+		require.EqualError(t, err, fixtures.ErrorMissingParams)
+	})
+}
+
+// Test ListCertificates filtering and paging
+func TestMockWallet_ListCertificates(t *testing.T) {
+	certificates := []wallet.Certificate{
+		{SerialNumber: "1", Certifier: "certifier-a", Type: "email"},
+		{SerialNumber: "2", Certifier: "certifier-a", Type: "phone"},
+		{SerialNumber: "3", Certifier: "certifier-b", Type: "email"},
+		{SerialNumber: "4", Certifier: "certifier-c", Type: "address"},
+	}
+
+	tests := map[string]struct {
+		options         wallet.ListCertificatesOptions
+		expectedSerials []string
+		expectedTotal   int
+	}{
+		"no filters": {
+			options:         wallet.ListCertificatesOptions{},
+			expectedSerials: []string{"1", "2", "3", "4"},
+			expectedTotal:   4,
+		},
+		"filter by certifier": {
+			options:         wallet.ListCertificatesOptions{Certifiers: []string{"certifier-a"}},
+			expectedSerials: []string{"1", "2"},
+			expectedTotal:   2,
+		},
+		"filter by types, OR within the list": {
+			options:         wallet.ListCertificatesOptions{Types: []string{"email", "address"}},
+			expectedSerials: []string{"1", "3", "4"},
+			expectedTotal:   3,
+		},
+		"filter by certifiers and types, AND across the dimensions": {
+			options:         wallet.ListCertificatesOptions{Certifiers: []string{"certifier-a", "certifier-c"}, Types: []string{"email"}},
+			expectedSerials: []string{"1"},
+			expectedTotal:   1,
+		},
+		"limit": {
+			options:         wallet.ListCertificatesOptions{Limit: 2},
+			expectedSerials: []string{"1", "2"},
+			expectedTotal:   4,
+		},
+		"limit and offset": {
+			options:         wallet.ListCertificatesOptions{Limit: 2, Offset: 2},
+			expectedSerials: []string{"3", "4"},
+			expectedTotal:   4,
+		},
+		"offset of the filtered certificates": {
+			options:         wallet.ListCertificatesOptions{Types: []string{"email"}, Offset: 1},
+			expectedSerials: []string{"3"},
+			expectedTotal:   2,
+		},
+		"offset past the end": {
+			options:         wallet.ListCertificatesOptions{Offset: 10},
+			expectedSerials: []string{},
+			expectedTotal:   4,
+		},
+		"no matching certificates": {
+			options:         wallet.ListCertificatesOptions{Certifiers: []string{"certifier-b"}, Types: []string{"phone"}},
+			expectedSerials: []string{},
+			expectedTotal:   0,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			w := wallet.NewMockWalletWithCertificates(certificates)
+
+			// when
+			result, err := w.ListCertificates(context.Background(), tc.options)
+
+			// then
+			require.NoError(t, err)
+			require.NotNil(t, result.Certificates)
+			serials := make([]string, 0, len(result.Certificates))
+			for _, certificate := range result.Certificates {
+				serials = append(serials, certificate.SerialNumber)
+			}
+			require.Equal(t, tc.expectedSerials, serials)
+			require.Equal(t, tc.expectedTotal, result.TotalCount)
+		})
+	}
+}
+
+// Test ListCertificates for invalid cases
+func TestMockWallet_ListCertificates_UnhappyPath(t *testing.T) {
+	// given
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+	// when
+	_, err := w.ListCertificates(context.Background(), wallet.ListCertificatesOptions{Limit: -1})
+
+	// then
+	require.Error(t, err)
+	require.Equal(t, fixtures.ErrorInvalidInput, err.Error())
+}
+
+// Test AcquireCertificate and RelinquishCertificate
+func TestMockWallet_AcquireAndRelinquishCertificate(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+	email := wallet.Certificate{Type: "email", SerialNumber: "1", Certifier: "certifier", Signature: "first"}
+	phone := wallet.Certificate{Type: "phone", SerialNumber: "2", Certifier: "certifier"}
+
+	// when
+	require.NoError(t, w.AcquireCertificate(ctx, email))
+	require.NoError(t, w.AcquireCertificate(ctx, phone))
+	email.Signature = "second"
+	require.NoError(t, w.AcquireCertificate(ctx, email))
+	result, err := w.ListCertificates(ctx, wallet.ListCertificatesOptions{})
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, []wallet.Certificate{email, phone}, result.Certificates)
+
+	// when
+	err = w.RelinquishCertificate(ctx, "email", "1", "certifier")
+	require.NoError(t, err)
+	result, err = w.ListCertificates(ctx, wallet.ListCertificatesOptions{})
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, []wallet.Certificate{phone}, result.Certificates)
+}
+
+// Test AcquireCertificate and RelinquishCertificate for invalid cases
+func TestMockWallet_AcquireAndRelinquishCertificate_UnhappyPath(t *testing.T) {
+	// given
+	ctx := context.Background()
+	w := wallet.NewMockWalletWithCertificates([]wallet.Certificate{{Type: "email", SerialNumber: "1", Certifier: "certifier"}})
+
+	// when
+	err := w.AcquireCertificate(ctx, wallet.Certificate{Type: "email", Certifier: "certifier"})
+
+	// then
+	require.Error(t, err)
+	require.Equal(t, fixtures.ErrorInvalidInput, err.Error())
+
+	// when
+	err = w.RelinquishCertificate(ctx, "email", "1", "other-certifier")
+
+	// then
+	require.ErrorIs(t, err, wallet.ErrCertificateNotFound)
+}
+
+// Test CreateAction
+func TestMockWallet_CreateAction(t *testing.T) {
+	t.Run("valid action", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+		// when
+		result, err := w.CreateAction(context.Background(), createArgs())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, fixtures.MockTxID, result.Txid)
+	})
+
+	t.Run("invalid action", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+		// when
+		_, err := w.CreateAction(context.Background(), wallet.CreateActionArgs{Description: "empty action"})
+
+		// then
+		require.ErrorIs(t, err, wallet.ErrInvalidAction)
+	})
+}
+
+// Test InternalizeAction accepting and rejecting the actions
+func TestMockWallet_InternalizeAction(t *testing.T) {
+	t.Run("accepted by default", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+
+		// when
+		result, err := w.InternalizeAction(context.Background(), paymentArgs())
+
+		// then
+		require.NoError(t, err)
+		require.True(t, result.Accepted)
+	})
+
+	t.Run("accepted by the function", func(t *testing.T) {
+		// given
+		var internalized []wallet.InternalizeActionArgs
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockInternalizeAction(func(args wallet.InternalizeActionArgs) error {
+			internalized = append(internalized, args)
+			return nil
+		}))
+
+		// when
+		result, err := w.InternalizeAction(context.Background(), paymentArgs())
+
+		// then
+		require.NoError(t, err)
+		require.True(t, result.Accepted)
+		require.Equal(t, []wallet.InternalizeActionArgs{paymentArgs()}, internalized)
+	})
+
+	t.Run("rejected by the function", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockInternalizeAction(func(wallet.InternalizeActionArgs) error {
+			return errors.New(fixtures.ErrorActionRejected)
+		}))
+
+		// when
+		result, err := w.InternalizeAction(context.Background(), paymentArgs())
+
+		// then
+		require.EqualError(t, err, fixtures.ErrorActionRejected)
+		require.False(t, result.Accepted)
+	})
+
+	t.Run("invalid action", func(t *testing.T) {
+		// given
+		w := wallet.NewMockWallet(fixtures.WithKeyDeriver)
+		args := paymentArgs()
+		args.Tx = nil
+
+		// when
+		_, err := w.InternalizeAction(context.Background(), args)
+
+		// then
+		require.ErrorIs(t, err, wallet.ErrInvalidAction)
+	})
+}
+
+func counterpartyOf(t *testing.T, identityKey string) wallet.Counterparty {
+	counterparty, err := wallet.ParseCounterparty(identityKey)
+	require.NoError(t, err)
+	return counterparty
+}
@@ -0,0 +1,86 @@
+package wallet_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCounterparty(t *testing.T) {
+	t.Run("valid counterparties", func(t *testing.T) {
+		tests := map[string]struct {
+			counterparty string
+			expectedType wallet.CounterpartyType
+		}{
+			"self": {
+				counterparty: "self",
+				expectedType: wallet.CounterpartyTypeSelf,
+			},
+			"anyone": {
+				counterparty: "anyone",
+				expectedType: wallet.CounterpartyTypeAnyone,
+			},
+			"public key": {
+				counterparty: fixtures.PeerIdentityKey,
+				expectedType: wallet.CounterpartyTypeOther,
+			},
+		}
+
+		for name, tc := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				counterparty, err := wallet.ParseCounterparty(tc.counterparty)
+
+				// then
+				require.NoError(t, err)
+				require.Equal(t, tc.expectedType, counterparty.Type)
+				require.NoError(t, counterparty.Validate())
+				require.Equal(t, tc.counterparty, counterparty.String())
+			})
+		}
+	})
+
+	t.Run("invalid counterparties", func(t *testing.T) {
+		tests := map[string]string{
+			"empty":              "",
+			"unknown name":       "peer",
+			"not on the curve":   "02" + "ff00000000000000000000000000000000000000000000000000000000000000",
+			"truncated key":      fixtures.PeerIdentityKey[:40],
+			"uppercase keywords": "SELF",
+		}
+
+		for name, counterparty := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				_, err := wallet.ParseCounterparty(counterparty)
+
+				// then
+				require.ErrorIs(t, err, wallet.ErrInvalidCounterparty)
+			})
+		}
+	})
+}
+
+func TestCounterparty_JSON(t *testing.T) {
+	// given
+	options := wallet.GetPublicKeyOptions{Counterparty: counterpartyOf(t, fixtures.PeerIdentityKey)}
+
+	// when
+	data, err := json.Marshal(options)
+
+	// then
+	require.NoError(t, err)
+	require.JSONEq(t, `{"identityKey": false, "counterparty": "`+fixtures.PeerIdentityKey+`"}`, string(data))
+
+	// when
+	var decoded wallet.GetPublicKeyOptions
+	err = json.Unmarshal(data, &decoded)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, options.Counterparty.String(), decoded.Counterparty.String())
+	require.Equal(t, wallet.CounterpartyTypeOther, decoded.Counterparty.Type)
+}
@@ -6,21 +6,55 @@ const (
 	IdentityKeyMock = "02mockidentitykey0000000000000000000000000000000000000000000000000000000"
 	// DerivedKeyMock is the expected derived key
 	DerivedKeyMock = "02mockderivedkey0000000000000000000000000000000000000000000000000000000"
+	// PrivilegedKeyMock is the expected privileged key
+	PrivilegedKeyMock = "02mockprivilegedkey0000000000000000000000000000000000000000000000000000"
+	// PeerIdentityKey is a valid identity key of a peer, to use as the counterparty
+	PeerIdentityKey = "0294c479f762f6baa97fbcd4393564c1d7bd8336ebd15928135bbcf575cd1a71a1"
+	// OtherPeerIdentityKey is a valid identity key of another peer
+	OtherPeerIdentityKey = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
 	// MockSignature is the expected signature
 	MockSignature = "mocksignaturedata"
+	// MockHMACKey is the key of the fake HMACs created by the mock wallet
+	MockHMACKey = "mockhmackey"
+	// MockFieldKey is the prefix of the fake certificate field keys revealed by the mock wallet
+	MockFieldKey = "mockfieldkey-"
+	// MockLinkage is the prefix of the fake key linkages revealed by the mock wallet
+	MockLinkage = "mocklinkage"
 	// MockNonce is the expected nonce
 	MockNonce = "mocknonce12345"
+	// MockVersion is the default version reported by the mock wallet
+	MockVersion = "mock-1.0.0"
+	// MockHeight is the default chain height reported by the mock wallet
+	MockHeight = 850000
+	// MockTxID is the id of the transactions created by the mock wallet
+	MockTxID = "b1fc0f44ba629dbdffab9e34fcc4faf9dbde3560a7365c55c26fe4daab052aac"
 
 	// TODO: be replaced with actual error messages from the wallet package
 
 	// ErrorNoPrivilege is the error message for no privilege support
 	ErrorNoPrivilege = "no privilege support"
+	// ErrorNoPrivilegedReason is the error message for a privileged call without a reason
+	ErrorNoPrivilegedReason = "privileged reason is required"
+	// ErrorPrivilegedReasonNotAllowed is the error message for a privileged call with a reason the wallet doesn't allow
+	ErrorPrivilegedReasonNotAllowed = "privileged reason not allowed"
 	// ErrorKeyDeriver is the error message for key deriver not initialized
 	ErrorKeyDeriver = "keyDeriver is not initialized"
 	// ErrorMissingParams is the error message for missing parameters
 	ErrorMissingParams = "protocolID and keyID are required if identityKey is false or undefined"
+	// ErrorMissingLinkageParams is the error message for revealing a key linkage without the counterparty or the verifier
+	ErrorMissingLinkageParams = "counterparty and verifier are required"
 	// ErrorInvalidInput is the error message for invalid input
 	ErrorInvalidInput = "invalid input"
+	// ErrorDecryption is the error message for ciphertext not encrypted for the given protocol/key IDs and counterparty
+	ErrorDecryption = "decryption failed"
+	// ErrorNonceUsed is the error message for a single-use nonce verified again
+	ErrorNonceUsed = "nonce already used"
+	// ErrorNonceExpired is the error message for a nonce older than its max age
+	ErrorNonceExpired = "nonce expired"
+	// ErrorUnknownField is the error message for revealing a field the certificate doesn't have
+	ErrorUnknownField = "unknown certificate field"
+	// ErrorActionRejected is the error message for an action the wallet refused to internalize
+	ErrorActionRejected = "action rejected"
 )
 
 // Constants for mock setup
@@ -0,0 +1,128 @@
+package wallet_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocol_Validate(t *testing.T) {
+	t.Run("valid protocols", func(t *testing.T) {
+		tests := map[string]wallet.Protocol{
+			"silent":                  {SecurityLevel: wallet.SecurityLevelSilent, Protocol: "auth message"},
+			"app":                     {SecurityLevel: wallet.SecurityLevelApp, Protocol: "auth message"},
+			"app and counterparty":    {SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "auth message"},
+			"shortest name":           {Protocol: "abcde"},
+			"longest name":            {Protocol: strings.Repeat("a", 400)},
+			"numbers":                 {Protocol: "brc103 auth"},
+			"uppercase is normalized": {Protocol: "Auth Message"},
+			"surrounding spaces":      {Protocol: "  auth message  "},
+		}
+
+		for name, protocol := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				err := protocol.Validate()
+
+				// then
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("invalid protocols", func(t *testing.T) {
+		tests := map[string]wallet.Protocol{
+			"negative security level":     {SecurityLevel: -1, Protocol: "auth message"},
+			"security level too high":     {SecurityLevel: 3, Protocol: "auth message"},
+			"empty name":                  {Protocol: ""},
+			"name too short":              {Protocol: "auth"},
+			"name too short when trimmed": {Protocol: "  auth  "},
+			"name too long":               {Protocol: strings.Repeat("a", 401)},
+			"consecutive spaces":          {Protocol: "auth  message"},
+			"dash":                        {Protocol: "auth-message"},
+			"underscore":                  {Protocol: "auth_message"},
+			"non ascii letters":           {Protocol: "authentification àéè"},
+			"protocol suffix":             {Protocol: "auth message protocol"},
+		}
+
+		for name, protocol := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				err := protocol.Validate()
+
+				// then
+				require.ErrorIs(t, err, wallet.ErrInvalidProtocol)
+			})
+		}
+	})
+}
+
+func TestProtocol_JSON(t *testing.T) {
+	// given
+	protocol := wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "auth message"}
+
+	// when
+	data, err := json.Marshal(protocol)
+
+	// then
+	require.NoError(t, err)
+	require.JSONEq(t, `[2, "auth message"]`, string(data))
+
+	// when
+	var decoded wallet.Protocol
+	err = json.Unmarshal(data, &decoded)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, protocol, decoded)
+}
+
+func TestFromLegacy(t *testing.T) {
+	expected := wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "auth message"}
+
+	t.Run("supported shapes", func(t *testing.T) {
+		tests := map[string]any{
+			"slice":                  []any{2, "auth message"},
+			"array":                  [2]any{2, "auth message"},
+			"float security level":   []any{2.0, "auth message"},
+			"typed security level":   []any{wallet.SecurityLevelAppAndCounterparty, "auth message"},
+			"already typed protocol": expected,
+		}
+
+		for name, protocolID := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				protocol, err := wallet.FromLegacy(protocolID)
+
+				// then
+				require.NoError(t, err)
+				require.Equal(t, expected, protocol)
+			})
+		}
+	})
+
+	t.Run("unsupported shapes", func(t *testing.T) {
+		tests := map[string]any{
+			"nil":                   nil,
+			"string":                "auth message",
+			"too short":             []any{2},
+			"too long":              []any{2, "auth message", "extra"},
+			"string security level": []any{"2", "auth message"},
+			"fractional level":      []any{1.5, "auth message"},
+			"non string name":       []any{2, 42},
+		}
+
+		for name, protocolID := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				_, err := wallet.FromLegacy(protocolID)
+
+				// then
+				require.ErrorIs(t, err, wallet.ErrInvalidProtocol)
+			})
+		}
+	})
+}
@@ -0,0 +1,326 @@
+package wallet_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/stretchr/testify/require"
+)
+
+var errConnectionReset = fmt.Errorf("%w: connection reset by peer", wallet.ErrTransient)
+
+// flakyWallet fails the first failures calls, calling onFailure on every failed one, before passing them to the mock wallet.
+type flakyWallet struct {
+	wallet.Interface
+
+	mu        sync.Mutex
+	failures  int
+	calls     int
+	err       error
+	onFailure func()
+}
+
+func newFlakyWallet(failures int) *flakyWallet {
+	return &flakyWallet{Interface: wallet.NewMockWallet(fixtures.WithKeyDeriver), failures: failures, err: errConnectionReset}
+}
+
+func (w *flakyWallet) GetPublicKey(ctx context.Context, options wallet.GetPublicKeyOptions) (string, error) {
+	if err := w.fail(); err != nil {
+		return "", err
+	}
+	return w.Interface.GetPublicKey(ctx, options)
+}
+
+func (w *flakyWallet) CreateSignature(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty, privilege wallet.Privilege) ([]byte, error) {
+	if err := w.fail(); err != nil {
+		return nil, err
+	}
+	return w.Interface.CreateSignature(ctx, data, protocolID, keyID, counterparty, privilege)
+}
+
+func (w *flakyWallet) CreateNonce(ctx context.Context) (string, error) {
+	if err := w.fail(); err != nil {
+		return "", err
+	}
+	return w.Interface.CreateNonce(ctx)
+}
+
+func (w *flakyWallet) VerifyNonce(ctx context.Context, nonce string) (bool, error) {
+	if err := w.fail(); err != nil {
+		return false, err
+	}
+	return w.Interface.VerifyNonce(ctx, nonce)
+}
+
+func (w *flakyWallet) fail() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.calls++
+	if w.calls > w.failures {
+		return nil
+	}
+	if w.onFailure != nil {
+		w.onFailure()
+	}
+	return w.err
+}
+
+func (w *flakyWallet) Calls() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.calls
+}
+
+// slowWallet blocks GetPublicKey until the ctx is done.
+type slowWallet struct {
+	wallet.Interface
+}
+
+func (w *slowWallet) GetPublicKey(ctx context.Context, _ wallet.GetPublicKeyOptions) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func fastRetries(opts ...wallet.ResilientOption) []wallet.ResilientOption {
+	return append([]wallet.ResilientOption{wallet.WithRetryBackoff(time.Millisecond, 4*time.Millisecond)}, opts...)
+}
+
+func TestResilient_Retries(t *testing.T) {
+	tests := map[string]struct {
+		failures      int
+		opts          []wallet.ResilientOption
+		expectedCalls int
+		expectedErr   error
+	}{
+		"succeeds at once": {
+			failures:      0,
+			expectedCalls: 1,
+		},
+		"retries until it succeeds": {
+			failures:      2,
+			expectedCalls: 3,
+		},
+		"gives up after the max retries": {
+			failures:      3,
+			expectedCalls: 3,
+			expectedErr:   errConnectionReset,
+		},
+		"retries up to the configured max retries": {
+			failures:      4,
+			opts:          []wallet.ResilientOption{wallet.WithMaxRetries(4)},
+			expectedCalls: 5,
+		},
+		"doesn't retry with zero max retries": {
+			failures:      1,
+			opts:          []wallet.ResilientOption{wallet.WithMaxRetries(0)},
+			expectedCalls: 1,
+			expectedErr:   errConnectionReset,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			inner := newFlakyWallet(test.failures)
+			w := wallet.NewResilient(inner, fastRetries(test.opts...)...)
+
+			// when
+			publicKey, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+
+			// then
+			require.Equal(t, test.expectedCalls, inner.Calls())
+			if test.expectedErr != nil {
+				require.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NotEmpty(t, publicKey)
+		})
+	}
+}
+
+// transientError is an error with a Transient method, like the ResponseError of the remote wallet.
+type transientError bool
+
+func (e transientError) Error() string {
+	return fmt.Sprintf("transient: %t", bool(e))
+}
+
+func (e transientError) Transient() bool {
+	return bool(e)
+}
+
+func TestResilient_RetryableErrors(t *testing.T) {
+	errPermanent := errors.New("invalid signature")
+	tests := map[string]struct {
+		err           error
+		opts          []wallet.ResilientOption
+		expectedCalls int
+	}{
+		"retries an error wrapping ErrTransient": {
+			err:           errConnectionReset,
+			expectedCalls: 2,
+		},
+		"retries an error reporting itself transient": {
+			err:           fmt.Errorf("wallet call failed: %w", transientError(true)),
+			expectedCalls: 2,
+		},
+		"retries a network error": {
+			err:           &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			expectedCalls: 2,
+		},
+		"retries a timed out attempt": {
+			err:           fmt.Errorf("ctx err: %w", context.DeadlineExceeded),
+			expectedCalls: 2,
+		},
+		"doesn't retry a permanent error": {
+			err:           errPermanent,
+			expectedCalls: 1,
+		},
+		"doesn't retry an error reporting itself permanent": {
+			err:           transientError(false),
+			expectedCalls: 1,
+		},
+		"retries the errors of the configured check": {
+			err:           errPermanent,
+			opts:          []wallet.ResilientOption{wallet.WithRetryable(func(err error) bool { return errors.Is(err, errPermanent) })},
+			expectedCalls: 2,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			inner := newFlakyWallet(1)
+			inner.err = test.err
+			w := wallet.NewResilient(inner, fastRetries(test.opts...)...)
+
+			// when
+			_, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+
+			// then
+			require.Equal(t, test.expectedCalls, inner.Calls())
+			if test.expectedCalls == 1 {
+				require.ErrorIs(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestResilient_VerifyNonce(t *testing.T) {
+	// given
+	inner := newFlakyWallet(1)
+	w := wallet.NewResilient(inner, fastRetries()...)
+
+	// when
+	_, err := w.VerifyNonce(context.Background(), "nonce")
+
+	// then
+	require.ErrorIs(t, err, errConnectionReset)
+	require.Equal(t, 1, inner.Calls())
+}
+
+func TestResilient_Writes(t *testing.T) {
+	t.Run("doesn't retry the writes by default", func(t *testing.T) {
+		// given
+		inner := newFlakyWallet(1)
+		w := wallet.NewResilient(inner, fastRetries()...)
+
+		// when
+		_, nonceErr := w.CreateNonce(context.Background())
+		_, signatureErr := w.CreateSignature(context.Background(), []byte("data"), authProtocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+
+		// then
+		require.ErrorIs(t, nonceErr, errConnectionReset)
+		require.NoError(t, signatureErr)
+		require.Equal(t, 2, inner.Calls())
+	})
+
+	t.Run("retries the writes when opted in", func(t *testing.T) {
+		// given
+		inner := newFlakyWallet(2)
+		w := wallet.NewResilient(inner, fastRetries(wallet.WithRetriedWrites())...)
+
+		// when
+		nonce, err := w.CreateNonce(context.Background())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 3, inner.Calls())
+		valid, err := w.VerifyNonce(context.Background(), nonce)
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+}
+
+func TestResilient_Cancellation(t *testing.T) {
+	t.Run("stops retrying as soon as the ctx is cancelled", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		inner := newFlakyWallet(10)
+		inner.onFailure = cancel
+		w := wallet.NewResilient(inner, wallet.WithMaxRetries(10), wallet.WithRetryBackoff(time.Hour, time.Hour))
+
+		// when
+		_, err := w.GetPublicKey(ctx, keyOptions("1"))
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		require.Equal(t, 1, inner.Calls())
+	})
+
+	t.Run("stops waiting for the retry when the ctx is cancelled", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		inner := newFlakyWallet(10)
+		w := wallet.NewResilient(inner, wallet.WithMaxRetries(10), wallet.WithRetryBackoff(time.Hour, time.Hour))
+
+		// when
+		start := time.Now()
+		_, err := w.GetPublicKey(ctx, keyOptions("1"))
+
+		// then
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Second)
+		require.Equal(t, 1, inner.Calls())
+	})
+
+	t.Run("doesn't call the inner wallet with a cancelled ctx", func(t *testing.T) {
+		// given
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		inner := newFlakyWallet(0)
+		w := wallet.NewResilient(inner)
+
+		// when
+		_, err := w.GetPublicKey(ctx, keyOptions("1"))
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+		require.Zero(t, inner.Calls())
+	})
+}
+
+func TestResilient_Timeout(t *testing.T) {
+	// given
+	w := wallet.NewResilient(&slowWallet{}, fastRetries(wallet.WithCallTimeout(10*time.Millisecond), wallet.WithMaxRetries(1))...)
+
+	// when
+	start := time.Now()
+	_, err := w.GetPublicKey(context.Background(), keyOptions("1"))
+
+	// then
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), time.Second)
+}
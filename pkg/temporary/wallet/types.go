@@ -1,5 +1,10 @@
 package wallet
 
+import (
+	"maps"
+	"slices"
+)
+
 // Certificate is a placeholder for the certificate data structure
 type Certificate struct {
 	// Type is the type of certificate
@@ -16,20 +21,95 @@ type Certificate struct {
 	Fields map[string]any `json:"fields"`
 	// Signature is the signature of the certificate
 	Signature string `json:"signature"`
+	// Keyring is the subject's master keyring, the base64 encoded field keys encrypted by the certifier to the subject
+	Keyring map[string]string `json:"keyring,omitempty"`
+}
+
+// Clone returns a copy of the certificate not sharing its fields and keyring maps with the original,
+// the field values themselves are copied shallowly.
+func (c Certificate) Clone() Certificate {
+	c.Fields = maps.Clone(c.Fields)
+	c.Keyring = maps.Clone(c.Keyring)
+	return c
+}
+
+// ListCertificatesOptions defines parameters for ListCertificates.
+// The filters are combined with AND across dimensions and OR within a list, an empty list means no filter.
+type ListCertificatesOptions struct {
+	// Certifiers are the identity keys of the certifiers to list the certificates of
+	Certifiers []string `json:"certifiers,omitempty"`
+	// Types are the types of the certificates to list
+	Types []string `json:"types,omitempty"`
+	// Limit is the maximum number of certificates to return, zero means no limit
+	Limit int `json:"limit,omitempty"`
+	// Offset is the number of matching certificates to skip
+	Offset int `json:"offset,omitempty"`
+}
+
+// Apply returns the page of the certificates matching the options.
+func (o ListCertificatesOptions) Apply(certificates []Certificate) ListCertificatesResult {
+	matching := make([]Certificate, 0, len(certificates))
+	for _, certificate := range certificates {
+		if matchesAny(o.Certifiers, certificate.Certifier) && matchesAny(o.Types, certificate.Type) {
+			matching = append(matching, certificate)
+		}
+	}
+
+	page := matching[min(max(o.Offset, 0), len(matching)):]
+	if o.Limit > 0 && o.Limit < len(page) {
+		page = page[:o.Limit]
+	}
+	return ListCertificatesResult{Certificates: page, TotalCount: len(matching)}
+}
+
+func matchesAny(filter []string, value string) bool {
+	return len(filter) == 0 || slices.Contains(filter, value)
+}
+
+// ListCertificatesResult is the page of certificates returned by ListCertificates
+type ListCertificatesResult struct {
+	// Certificates are the matching certificates within the limit and offset
+	Certificates []Certificate `json:"certificates"`
+	// TotalCount is the number of all the matching certificates, regardless of the limit and offset
+	TotalCount int `json:"totalCertificates"`
+}
+
+// VerifyItem is a signature to verify with VerifySignatures, with the same parameters as VerifySignature.
+type VerifyItem struct {
+	// Data is the signed data
+	Data []byte
+	// Signature is the DER encoded signature of the data
+	Signature []byte
+	// ProtocolID is the protocol of the key
+	ProtocolID Protocol
+	// KeyID is the key ID of the key
+	KeyID string
+	// Counterparty is the counterparty of the key, the one who made the signature
+	Counterparty Counterparty
 }
 
 // GetPublicKeyOptions defines parameters for GetPublicKey
 type GetPublicKeyOptions struct {
 	// IdentityKey is a flag to return the identity key
 	IdentityKey bool `json:"identityKey"`
-	// ProtocolID is the protocol ID for the key
-	ProtocolID any `json:"protocolID,omitempty"`
+	// ProtocolID is the protocol of the key
+	ProtocolID Protocol `json:"protocolID,omitzero"`
 	// KeyID is the key ID for the key
 	KeyID string `json:"keyID,omitempty"`
 	// Counterparty is the counterparty for the key
-	Counterparty string `json:"counterparty,omitempty"`
+	Counterparty Counterparty `json:"counterparty,omitzero"`
 	// Privileged is a flag to return a privileged key
 	Privileged bool `json:"privileged,omitempty"`
+	// PrivilegedReason is the reason for the privileged key shown to the user, required with Privileged
+	PrivilegedReason string `json:"privilegedReason,omitempty"`
 	// ForSelf is a flag to return a key for self
 	ForSelf bool `json:"forSelf,omitempty"`
 }
+
+// Privilege selects the privileged keyring for a call, the zero value uses the everyday one.
+type Privilege struct {
+	// Privileged is a flag to use the privileged keyring
+	Privileged bool
+	// Reason is the reason for the privileged call shown to the user, required with Privileged
+	Reason string
+}
@@ -1,27 +1,151 @@
 package wallet
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
 )
 
-// Wallet provides a simple mock implementation of Interface.
+// Wallet provides a simple mock implementation of Interface, safe for concurrent use.
 type Wallet struct {
-	identityKey string
-	keyDeriver  bool
-	validNonces map[string]bool
+	// the fields up to mu are set on creation and never changed
+	identityKey        string
+	keyDeriver         bool
+	nonceMaxAge        time.Duration
+	reusableNonces     bool
+	constantSignatures bool
+	now                func() time.Time
+	fieldKeys          map[string][]byte
+	network            string
+	version            string
+	height             uint32
+	internalize        MockInternalizeFunc
+	privilegedReasons  []string
+
+	// mu guards the nonces and certificates changed by the calls
+	mu           sync.Mutex
+	validNonces  map[string]time.Time
+	usedNonces   map[string]bool
+	certificates []Certificate
+}
+
+// defaultMockNonceMaxAge matches the nonce.DefaultMaxAge of the real wallets.
+const defaultMockNonceMaxAge = 5 * time.Minute
+
+// MockOption configures the mock wallet.
+type MockOption func(*Wallet)
+
+// WithMockNonceMaxAge overrides the time window in which a nonce can be verified, zero disables the expiration.
+func WithMockNonceMaxAge(maxAge time.Duration) MockOption {
+	return func(m *Wallet) {
+		m.nonceMaxAge = maxAge
+	}
+}
+
+// WithMockReusableNonces allows verifying the same nonce any number of times.
+func WithMockReusableNonces() MockOption {
+	return func(m *Wallet) {
+		m.reusableNonces = true
+	}
+}
+
+// WithMockConstantSignatures makes CreateSignature ignore the data and keyID,
+// and VerifySignature accept any signature starting with the MockSignature.
+func WithMockConstantSignatures() MockOption {
+	return func(m *Wallet) {
+		m.constantSignatures = true
+	}
+}
+
+// WithMockClock overrides the clock used to expire the nonces.
+func WithMockClock(now func() time.Time) MockOption {
+	return func(m *Wallet) {
+		m.now = now
+	}
+}
+
+// WithMockCertificateFieldKeys sets the field keys revealed by ProveCertificate, by field name.
+// The fields without a key get the MockFieldKey followed by the field name.
+func WithMockCertificateFieldKeys(fieldKeys map[string][]byte) MockOption {
+	return func(m *Wallet) {
+		m.fieldKeys = fieldKeys
+	}
+}
+
+// WithMockNetwork overrides the network reported by GetNetwork, NetworkMainnet by default.
+func WithMockNetwork(network string) MockOption {
+	return func(m *Wallet) {
+		m.network = network
+	}
+}
+
+// WithMockVersion overrides the version reported by GetVersion.
+func WithMockVersion(version string) MockOption {
+	return func(m *Wallet) {
+		m.version = version
+	}
+}
+
+// WithMockHeight overrides the chain height reported by GetHeight.
+func WithMockHeight(height uint32) MockOption {
+	return func(m *Wallet) {
+		m.height = height
+	}
+}
+
+// MockInternalizeFunc decides whether the mock wallet accepts the action, it's rejected with the returned error.
+type MockInternalizeFunc func(args InternalizeActionArgs) error
+
+// WithMockInternalizeAction sets the function deciding whether InternalizeAction accepts a valid action,
+// all the valid actions are accepted by default.
+func WithMockInternalizeAction(internalize MockInternalizeFunc) MockOption {
+	return func(m *Wallet) {
+		m.internalize = internalize
+	}
+}
+
+// WithMockPrivilegedReasons enables the privileged calls made for one of the reasons,
+// the privileged calls are rejected by default.
+func WithMockPrivilegedReasons(reasons ...string) MockOption {
+	return func(m *Wallet) {
+		m.privilegedReasons = reasons
+	}
+}
+
+// NewMockWalletWithCertificates creates a new mock wallet with keyDeriver, listing the certificates.
+func NewMockWalletWithCertificates(certificates []Certificate, opts ...MockOption) Interface {
+	m := NewMockWallet(true, opts...).(*Wallet)
+	m.certificates = slices.Clone(certificates)
+	return m
 }
 
 // NewMockWallet creates a new mock wallet with or without keyDeriver.
-func NewMockWallet(enableKeyDeriver bool) Interface {
-	return &Wallet{
+// Like the real wallets, its nonces are single-use and expire after 5 minutes by default.
+func NewMockWallet(enableKeyDeriver bool, opts ...MockOption) Interface {
+	m := &Wallet{
 		identityKey: wallet.IdentityKeyMock,
 		keyDeriver:  enableKeyDeriver,
-		validNonces: make(map[string]bool),
+		nonceMaxAge: defaultMockNonceMaxAge,
+		now:         time.Now,
+		network:     NetworkMainnet,
+		version:     wallet.MockVersion,
+		height:      wallet.MockHeight,
+		validNonces: make(map[string]time.Time),
+		usedNonces:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // GetPublicKey returns a mock public key while validating required parameters.
@@ -31,7 +155,10 @@ func (m *Wallet) GetPublicKey(ctx context.Context, options GetPublicKeyOptions)
 	}
 
 	if options.Privileged {
-		return "", errors.New(wallet.ErrorNoPrivilege)
+		if err := m.checkPrivilege(Privilege{Privileged: true, Reason: options.PrivilegedReason}); err != nil {
+			return "", err
+		}
+		return wallet.PrivilegedKeyMock, nil
 	}
 
 	if options.IdentityKey {
@@ -41,10 +168,14 @@ func (m *Wallet) GetPublicKey(ctx context.Context, options GetPublicKeyOptions)
 		return m.identityKey, nil
 	}
 
-	if options.ProtocolID == nil || options.KeyID == "" || options.KeyID == " " {
+	if options.ProtocolID.Protocol == "" || options.KeyID == "" || options.KeyID == " " {
 		return "", errors.New(wallet.ErrorMissingParams)
 	}
 
+	if err := options.ProtocolID.Validate(); err != nil {
+		return "", err
+	}
+
 	if !m.keyDeriver {
 		return "", errors.New(wallet.ErrorKeyDeriver)
 	}
@@ -52,62 +183,453 @@ func (m *Wallet) GetPublicKey(ctx context.Context, options GetPublicKeyOptions)
 	return wallet.DerivedKeyMock, nil
 }
 
-// CreateSignature returns a mock signature.
-func (m *Wallet) CreateSignature(ctx context.Context, data []byte, protocolID any, keyID string, counterparty string) ([]byte, error) {
+// CreateSignature returns a deterministic mock signature of the data, keyID and counterparty,
+// the MockSignature followed by ":", the counterparty, ":" and the hex of a fake MAC of the data and keyID.
+func (m *Wallet) CreateSignature(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty, privilege Privilege) ([]byte, error) {
 	if ctx.Err() != nil {
 		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
 	}
 
-	if len(data) == 0 || keyID == "" || counterparty == "" {
+	if err := m.checkPrivilege(privilege); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || keyID == "" {
 		return nil, errors.New(wallet.ErrorInvalidInput)
 	}
 
-	return []byte(wallet.MockSignature), nil
+	if err := protocolID.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := counterparty.Validate(); err != nil {
+		return nil, err
+	}
+
+	if m.constantSignatures {
+		return constantMockSignature(counterparty), nil
+	}
+	return mockSignature(data, keyID, counterparty), nil
 }
 
-// VerifySignature returns true if the signature matches expected mock data.
-func (m *Wallet) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID any, keyID string, counterparty string) (bool, error) {
+// VerifySignature recomputes the mock signature and compares it with the given one.
+func (m *Wallet) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error) {
 	if ctx.Err() != nil {
 		return false, fmt.Errorf("ctx err: %w", ctx.Err())
 	}
 
-	return string(signature) == wallet.MockSignature, nil
+	if m.constantSignatures {
+		return bytes.HasPrefix(signature, []byte(wallet.MockSignature)), nil
+	}
+	return hmac.Equal(signature, mockSignature(data, keyID, counterparty)), nil
+}
+
+// VerifySignatures verifies the items one by one with VerifySignature.
+func (m *Wallet) VerifySignatures(ctx context.Context, items []VerifyItem) ([]bool, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	results := make([]bool, len(items))
+	for i, item := range items {
+		results[i], _ = m.VerifySignature(ctx, item.Data, item.Signature, item.ProtocolID, item.KeyID, item.Counterparty)
+	}
+	return results, nil
 }
 
-// CreateNonce generates a deterministic nonce.
+// CreateHMAC returns a deterministic fake HMAC of the data, keyID and counterparty.
+func (m *Wallet) CreateHMAC(ctx context.Context, data []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if len(data) == 0 || keyID == "" {
+		return nil, errors.New(wallet.ErrorInvalidInput)
+	}
+
+	if err := protocolID.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := counterparty.Validate(); err != nil {
+		return nil, err
+	}
+
+	return mockHMAC(data, keyID, counterparty.String()), nil
+}
+
+// VerifyHMAC recomputes the fake HMAC and compares it with the given one.
+func (m *Wallet) VerifyHMAC(ctx context.Context, data []byte, hmacValue []byte, protocolID Protocol, keyID string, counterparty Counterparty) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if len(data) == 0 || keyID == "" {
+		return false, errors.New(wallet.ErrorInvalidInput)
+	}
+
+	if err := counterparty.Validate(); err != nil {
+		return false, err
+	}
+
+	return hmac.Equal(hmacValue, mockHMAC(data, keyID, counterparty.String())), nil
+}
+
+// Encrypt returns a reversible fake ciphertext, the plaintext prefixed with a tag bound to the keyID and counterparty.
+func (m *Wallet) Encrypt(ctx context.Context, plaintext []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := m.validateKeyParams(protocolID, keyID); err != nil {
+		return nil, err
+	}
+
+	if err := counterparty.Validate(); err != nil {
+		return nil, err
+	}
+
+	return append(mockCipherTag(keyID, counterparty.String()), plaintext...), nil
+}
+
+// Decrypt reverses Encrypt, failing when the tag doesn't match the keyID and counterparty.
+func (m *Wallet) Decrypt(ctx context.Context, ciphertext []byte, protocolID Protocol, keyID string, counterparty Counterparty) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := m.validateKeyParams(protocolID, keyID); err != nil {
+		return nil, err
+	}
+
+	if err := counterparty.Validate(); err != nil {
+		return nil, err
+	}
+
+	tag := mockCipherTag(keyID, counterparty.String())
+	if len(ciphertext) < len(tag) {
+		return nil, errors.New(wallet.ErrorInvalidInput)
+	}
+	if !hmac.Equal(ciphertext[:len(tag)], tag) {
+		return nil, errors.New(wallet.ErrorDecryption)
+	}
+
+	return append([]byte{}, ciphertext[len(tag):]...), nil
+}
+
+// CreateNonce generates a deterministic nonce, creating it again makes it valid again.
 func (m *Wallet) CreateNonce(ctx context.Context) (string, error) {
 	if ctx.Err() != nil {
 		return "", fmt.Errorf("ctx err: %w", ctx.Err())
 	}
 
-	m.validNonces[wallet.MockNonce] = true
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validNonces[wallet.MockNonce] = m.now()
+	delete(m.usedNonces, wallet.MockNonce)
 	return wallet.MockNonce, nil
 }
 
-// VerifyNonce checks if the nonce exists.
+// VerifyNonce checks if the nonce exists, isn't expired and wasn't verified before.
 func (m *Wallet) VerifyNonce(ctx context.Context, nonce string) (bool, error) {
 	if ctx.Err() != nil {
 		return false, fmt.Errorf("ctx err: %w", ctx.Err())
 	}
 
-	_, exists := m.validNonces[nonce]
-	return exists, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.usedNonces[nonce] {
+		return false, errors.New(wallet.ErrorNonceUsed)
+	}
+	createdAt, exists := m.validNonces[nonce]
+	if !exists {
+		return false, nil
+	}
+	if m.nonceMaxAge > 0 && m.now().After(createdAt.Add(m.nonceMaxAge)) {
+		return false, errors.New(wallet.ErrorNonceExpired)
+	}
+	if !m.reusableNonces {
+		m.usedNonces[nonce] = true
+	}
+	return true, nil
 }
 
-// ListCertificates returns an empty list.
-func (m *Wallet) ListCertificates(ctx context.Context, certifiers []string, types []string) ([]Certificate, error) {
+// ListCertificates filters the certificates the wallet was created with or acquired, see NewMockWalletWithCertificates.
+func (m *Wallet) ListCertificates(ctx context.Context, options ListCertificatesOptions) (ListCertificatesResult, error) {
 	if ctx.Err() != nil {
-		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+		return ListCertificatesResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if options.Limit < 0 || options.Offset < 0 {
+		return ListCertificatesResult{}, errors.New(wallet.ErrorInvalidInput)
 	}
 
-	return []Certificate{}, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return options.Apply(m.certificates), nil
 }
 
-// ProveCertificate returns an empty map.
-func (m *Wallet) ProveCertificate(ctx context.Context, certificate Certificate, verifier string, fieldsToReveal []string) (map[string]string, error) {
+// AcquireCertificate adds the certificate to the listed ones, replacing the one with the same type, serial number and certifier.
+func (m *Wallet) AcquireCertificate(ctx context.Context, certificate Certificate) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if certificate.Type == "" || certificate.SerialNumber == "" || certificate.Certifier == "" {
+		return errors.New(wallet.ErrorInvalidInput)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := m.indexOfCertificate(certificate.Type, certificate.SerialNumber, certificate.Certifier); i >= 0 {
+		m.certificates[i] = certificate
+		return nil
+	}
+	m.certificates = append(m.certificates, certificate)
+	return nil
+}
+
+// RelinquishCertificate removes the certificate from the listed ones.
+func (m *Wallet) RelinquishCertificate(ctx context.Context, certType string, serialNumber string, certifier string) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	i := m.indexOfCertificate(certType, serialNumber, certifier)
+	if i < 0 {
+		return ErrCertificateNotFound
+	}
+	m.certificates = slices.Delete(m.certificates, i, i+1)
+	return nil
+}
+
+// ProveCertificate returns a fake keyring revealing the fields of the certificate to the verifier.
+// The keys are fake ciphertexts bound to the serial number, field name and verifier, see DecryptMockKeyring.
+func (m *Wallet) ProveCertificate(ctx context.Context, certificate Certificate, verifier string, fieldsToReveal []string, privilege Privilege) (map[string]string, error) {
 	if ctx.Err() != nil {
 		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
 	}
 
-	return map[string]string{}, nil
+	if err := m.checkPrivilege(privilege); err != nil {
+		return nil, err
+	}
+
+	if verifier == "" {
+		return nil, errors.New(wallet.ErrorInvalidInput)
+	}
+
+	keyring := make(map[string]string, len(fieldsToReveal))
+	for _, fieldName := range fieldsToReveal {
+		if _, ok := certificate.Fields[fieldName]; !ok {
+			return nil, fmt.Errorf("%s: %s", wallet.ErrorUnknownField, fieldName)
+		}
+
+		fieldKey, ok := m.fieldKeys[fieldName]
+		if !ok {
+			fieldKey = []byte(wallet.MockFieldKey + fieldName)
+		}
+		encrypted := append(mockCipherTag(certificate.SerialNumber+" "+fieldName, verifier), fieldKey...)
+		keyring[fieldName] = base64.StdEncoding.EncodeToString(encrypted)
+	}
+	return keyring, nil
+}
+
+// RevealCounterpartyKeyLinkage returns a fake linkage, the MockLinkage followed by ":", the counterparty, ":" and the verifier.
+func (m *Wallet) RevealCounterpartyKeyLinkage(ctx context.Context, counterparty string, verifier string) (KeyLinkageResult, error) {
+	if ctx.Err() != nil {
+		return KeyLinkageResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := m.validateLinkageParams(counterparty, verifier); err != nil {
+		return KeyLinkageResult{}, err
+	}
+
+	return KeyLinkageResult{
+		Prover:           m.identityKey,
+		Verifier:         verifier,
+		Counterparty:     counterparty,
+		EncryptedLinkage: fmt.Appendf(nil, "%s:%s:%s", wallet.MockLinkage, counterparty, verifier),
+		ProofType:        KeyLinkageProofNone,
+		RevelationTime:   m.now().UTC().Format(RevelationTimeFormat),
+	}, nil
+}
+
+// RevealSpecificKeyLinkage returns a fake linkage, the MockLinkage followed by ":", the counterparty, ":", the verifier,
+// ":" and the security level, protocol and keyID joined by "-".
+func (m *Wallet) RevealSpecificKeyLinkage(ctx context.Context, counterparty string, verifier string, protocolID Protocol, keyID string) (KeyLinkageResult, error) {
+	if ctx.Err() != nil {
+		return KeyLinkageResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := m.validateLinkageParams(counterparty, verifier); err != nil {
+		return KeyLinkageResult{}, err
+	}
+
+	if err := m.validateKeyParams(protocolID, keyID); err != nil {
+		return KeyLinkageResult{}, err
+	}
+
+	return KeyLinkageResult{
+		Prover:           m.identityKey,
+		Verifier:         verifier,
+		Counterparty:     counterparty,
+		EncryptedLinkage: fmt.Appendf(nil, "%s:%s:%s:%d-%s-%s", wallet.MockLinkage, counterparty, verifier, protocolID.SecurityLevel, protocolID.Protocol, keyID),
+		ProofType:        KeyLinkageProofNone,
+		ProtocolID:       protocolID,
+		KeyID:            keyID,
+	}, nil
+}
+
+// DecryptMockKeyring decrypts the field keys of the keyring created by the mock ProveCertificate for the verifier.
+func DecryptMockKeyring(certificate Certificate, verifier string, keyring map[string]string) (map[string][]byte, error) {
+	fieldKeys := make(map[string][]byte, len(keyring))
+	for fieldName, encryptedKey := range keyring {
+		encrypted, err := base64.StdEncoding.DecodeString(encryptedKey)
+		if err != nil {
+			return nil, errors.New(wallet.ErrorInvalidInput)
+		}
+
+		tag := mockCipherTag(certificate.SerialNumber+" "+fieldName, verifier)
+		if len(encrypted) < len(tag) || !hmac.Equal(encrypted[:len(tag)], tag) {
+			return nil, errors.New(wallet.ErrorDecryption)
+		}
+		fieldKeys[fieldName] = encrypted[len(tag):]
+	}
+	return fieldKeys, nil
+}
+
+// GetNetwork returns the configured network.
+func (m *Wallet) GetNetwork(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	return m.network, nil
+}
+
+// GetVersion returns the configured version.
+func (m *Wallet) GetVersion(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	return m.version, nil
+}
+
+// GetHeight returns the configured chain height.
+func (m *Wallet) GetHeight(ctx context.Context) (uint32, error) {
+	if ctx.Err() != nil {
+		return 0, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	return m.height, nil
+}
+
+// CreateAction validates the args and returns the MockTxID.
+func (m *Wallet) CreateAction(ctx context.Context, args CreateActionArgs) (CreateActionResult, error) {
+	if ctx.Err() != nil {
+		return CreateActionResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := args.Validate(); err != nil {
+		return CreateActionResult{}, err
+	}
+
+	return CreateActionResult{Txid: wallet.MockTxID}, nil
+}
+
+// InternalizeAction validates the args and accepts the action, unless rejected by the WithMockInternalizeAction function.
+func (m *Wallet) InternalizeAction(ctx context.Context, args InternalizeActionArgs) (InternalizeActionResult, error) {
+	if ctx.Err() != nil {
+		return InternalizeActionResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := args.Validate(); err != nil {
+		return InternalizeActionResult{}, err
+	}
+
+	if m.internalize != nil {
+		if err := m.internalize(args); err != nil {
+			return InternalizeActionResult{}, err
+		}
+	}
+	return InternalizeActionResult{Accepted: true}, nil
+}
+
+func (m *Wallet) indexOfCertificate(certType string, serialNumber string, certifier string) int {
+	return slices.IndexFunc(m.certificates, func(certificate Certificate) bool {
+		return certificate.Type == certType && certificate.SerialNumber == serialNumber && certificate.Certifier == certifier
+	})
+}
+
+// checkPrivilege rejects a privileged call without a reason or with a reason not enabled by WithMockPrivilegedReasons.
+func (m *Wallet) checkPrivilege(privilege Privilege) error {
+	if !privilege.Privileged {
+		return nil
+	}
+	if len(m.privilegedReasons) == 0 {
+		return errors.New(wallet.ErrorNoPrivilege)
+	}
+	if privilege.Reason == "" {
+		return errors.New(wallet.ErrorNoPrivilegedReason)
+	}
+	if !slices.Contains(m.privilegedReasons, privilege.Reason) {
+		return fmt.Errorf("%s: %q", wallet.ErrorPrivilegedReasonNotAllowed, privilege.Reason)
+	}
+	return nil
+}
+
+func (m *Wallet) validateLinkageParams(counterparty string, verifier string) error {
+	if counterparty == "" || verifier == "" {
+		return errors.New(wallet.ErrorMissingLinkageParams)
+	}
+
+	if !m.keyDeriver {
+		return errors.New(wallet.ErrorKeyDeriver)
+	}
+
+	return nil
+}
+
+func (m *Wallet) validateKeyParams(protocolID Protocol, keyID string) error {
+	if protocolID.Protocol == "" || keyID == "" || keyID == " " {
+		return errors.New(wallet.ErrorMissingParams)
+	}
+
+	if err := protocolID.Validate(); err != nil {
+		return err
+	}
+
+	if !m.keyDeriver {
+		return errors.New(wallet.ErrorKeyDeriver)
+	}
+
+	return nil
+}
+
+// mockSignature echoes the counterparty, so the tests can assert which one was used.
+func mockSignature(data []byte, keyID string, counterparty Counterparty) []byte {
+	mac := mockMAC(wallet.MockSignature, data, keyID, counterparty.String())
+	return fmt.Appendf(nil, "%s:%s:%x", wallet.MockSignature, counterparty.String(), mac)
+}
+
+func constantMockSignature(counterparty Counterparty) []byte {
+	return []byte(wallet.MockSignature + ":" + counterparty.String())
+}
+
+func mockCipherTag(keyID string, counterparty string) []byte {
+	return mockHMAC(nil, keyID, counterparty)
+}
+
+func mockHMAC(data []byte, keyID string, counterparty string) []byte {
+	return mockMAC(wallet.MockHMACKey, data, keyID, counterparty)
+}
+
+func mockMAC(key string, data []byte, keyID string, counterparty string) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(keyID))
+	mac.Write([]byte{0})
+	mac.Write([]byte(counterparty))
+	mac.Write([]byte{0})
+	mac.Write(data)
+	return mac.Sum(nil)
 }
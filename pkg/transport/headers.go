@@ -0,0 +1,226 @@
+// Package transport holds the wire format of the BRC-104 general messages shared by the clients and the servers,
+// the x-bsv-auth headers here and the signed payloads in the payload package.
+package transport
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport/payload"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// BRC-104 headers of a general message.
+const (
+	HeaderVersion     = "x-bsv-auth-version"
+	HeaderIdentityKey = "x-bsv-auth-identity-key"
+	HeaderNonce       = "x-bsv-auth-nonce"
+	HeaderYourNonce   = "x-bsv-auth-your-nonce"
+	HeaderSignature   = "x-bsv-auth-signature"
+	HeaderRequestID   = "x-bsv-auth-request-id"
+)
+
+// HeaderChallenge is the response header with a fresh nonce of the server, sent when the session of a general message
+// is gone, the client sends it as the yourNonce of its next initialRequest.
+const HeaderChallenge = "x-bsv-auth-challenge"
+
+// AuthHeaderPrefix is the prefix of all the BRC-104 auth headers.
+const AuthHeaderPrefix = "x-bsv-auth"
+
+// AuthVersion is the only supported version of the BRC-104 auth protocol, the one of the TypeScript SDK.
+const AuthVersion = "0.1"
+
+// Sizes of the decoded nonces, wide enough for the 48 bytes ones of the TypeScript SDK and the 56 bytes ones of the keywallet.
+const (
+	MinNonceSize = 16
+	MaxNonceSize = 128
+)
+
+// Sizes of the decoded DER signatures.
+const (
+	minSignatureSize = 8
+	maxSignatureSize = 73
+)
+
+// compressedPublicKeySize is the size of a hex encoded compressed public key, 33 bytes starting with 02 or 03.
+const compressedPublicKeySize = 66
+
+// Errors wrapped by the HeaderError.
+var (
+	ErrMissingHeader      = errors.New("header is missing")
+	ErrRepeatedHeader     = errors.New("header is repeated")
+	ErrMalformedHeader    = errors.New("header is malformed")
+	ErrUnsupportedVersion = errors.New("unsupported version")
+)
+
+// HeaderError is returned by ParseAuthHeaders for the first invalid header, named with its lowercase name.
+type HeaderError struct {
+	Header string
+	Err    error
+}
+
+// Error names the header and the reason it's invalid.
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Header, e.Err)
+}
+
+// Unwrap returns the reason, e.g. ErrMissingHeader.
+func (e *HeaderError) Unwrap() error {
+	return e.Err
+}
+
+// AuthHeaders are the validated x-bsv-auth headers of a general message.
+type AuthHeaders struct {
+	Version string
+	// IdentityKey is the identity key of the sender, lowercase hex encoded
+	IdentityKey string
+	PublicKey   *ec.PublicKey
+	// Nonce is the fresh request nonce of the sender
+	Nonce string
+	// YourNonce is the session nonce of the receiver
+	YourNonce string
+	RequestID []byte
+	Signature []byte
+}
+
+// ParseAuthHeaders extracts and validates the x-bsv-auth headers of a general message,
+// matching their names in any case. The first invalid header is reported with a HeaderError.
+func ParseAuthHeaders(header http.Header) (*AuthHeaders, error) {
+	version, err := lookup(header, HeaderVersion)
+	if err != nil {
+		return nil, err
+	}
+	if version != AuthVersion {
+		return nil, &HeaderError{Header: HeaderVersion, Err: fmt.Errorf("%w %q, expected %q", ErrUnsupportedVersion, version, AuthVersion)}
+	}
+
+	identityKey, publicKey, err := parseIdentityKey(header)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := parseNonce(header, HeaderNonce)
+	if err != nil {
+		return nil, err
+	}
+	yourNonce, err := parseNonce(header, HeaderYourNonce)
+	if err != nil {
+		return nil, err
+	}
+	requestID, err := parseBase64(header, HeaderRequestID, payload.RequestIDSize, payload.RequestIDSize)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := parseSignature(header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthHeaders{
+		Version:     version,
+		IdentityKey: identityKey,
+		PublicKey:   publicKey,
+		Nonce:       nonce,
+		YourNonce:   yourNonce,
+		RequestID:   requestID,
+		Signature:   signature,
+	}, nil
+}
+
+// HasAuthHeaders reports whether any of the headers is an x-bsv-auth one.
+func HasAuthHeaders(header http.Header) bool {
+	for name := range header {
+		if strings.HasPrefix(strings.ToLower(name), AuthHeaderPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the single non-empty value of the header, whose name is matched in any case,
+// because the headers set directly in the map aren't canonicalized.
+func lookup(header http.Header, name string) (string, error) {
+	var values []string
+	for key, keyValues := range header {
+		if strings.EqualFold(key, name) {
+			values = append(values, keyValues...)
+		}
+	}
+	switch {
+	case len(values) == 0 || (len(values) == 1 && values[0] == ""):
+		return "", &HeaderError{Header: name, Err: ErrMissingHeader}
+	case len(values) > 1:
+		return "", &HeaderError{Header: name, Err: ErrRepeatedHeader}
+	}
+	return values[0], nil
+}
+
+func parseIdentityKey(header http.Header) (string, *ec.PublicKey, error) {
+	value, err := lookup(header, HeaderIdentityKey)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(value) != compressedPublicKeySize || (value[:2] != "02" && value[:2] != "03") {
+		return "", nil, &HeaderError{Header: HeaderIdentityKey, Err: fmt.Errorf("%w: not a hex encoded compressed public key", ErrMalformedHeader)}
+	}
+	publicKey, err := ec.PublicKeyFromString(value)
+	if err != nil {
+		return "", nil, &HeaderError{Header: HeaderIdentityKey, Err: fmt.Errorf("%w: %w", ErrMalformedHeader, err)}
+	}
+	return publicKey.ToDERHex(), publicKey, nil
+}
+
+func parseNonce(header http.Header, name string) (string, error) {
+	value, err := lookup(header, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := decodeBase64(name, value, MinNonceSize, MaxNonceSize); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+func parseBase64(header http.Header, name string, minSize int, maxSize int) ([]byte, error) {
+	value, err := lookup(header, name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeBase64(name, value, minSize, maxSize)
+}
+
+func decodeBase64(name string, value string, minSize int, maxSize int) ([]byte, error) {
+	decoded, err := base64.StdEncoding.Strict().DecodeString(value)
+	if err != nil {
+		return nil, &HeaderError{Header: name, Err: fmt.Errorf("%w: not base64 encoded", ErrMalformedHeader)}
+	}
+	if len(decoded) < minSize || len(decoded) > maxSize {
+		return nil, &HeaderError{Header: name, Err: fmt.Errorf("%w: %d bytes, expected %s", ErrMalformedHeader, len(decoded), sizeRange(minSize, maxSize))}
+	}
+	return decoded, nil
+}
+
+func parseSignature(header http.Header) ([]byte, error) {
+	value, err := lookup(header, HeaderSignature)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, &HeaderError{Header: HeaderSignature, Err: fmt.Errorf("%w: not hex encoded", ErrMalformedHeader)}
+	}
+	if len(signature) < minSignatureSize || len(signature) > maxSignatureSize {
+		return nil, &HeaderError{Header: HeaderSignature, Err: fmt.Errorf("%w: %d bytes, expected %s", ErrMalformedHeader, len(signature), sizeRange(minSignatureSize, maxSignatureSize))}
+	}
+	return signature, nil
+}
+
+func sizeRange(minSize int, maxSize int) string {
+	if minSize == maxSize {
+		return fmt.Sprintf("%d", minSize)
+	}
+	return fmt.Sprintf("%d to %d", minSize, maxSize)
+}
@@ -0,0 +1,200 @@
+// Package payload builds the canonical BRC-104 payloads of the general messages, the bytes signed by the sender
+// and verified by the receiver, laid out exactly like the TypeScript SDK does, so Go and TypeScript peers agree.
+//
+// Every variable-length part is prefixed with its Bitcoin varint length, the missing optional parts are written
+// as the varint -1 (ff ffffffffffffffff). The signed headers are the x-bsv-* ones other than x-bsv-auth-*
+// and the authorization, plus the media type of the content-type of a request, lowercased and sorted by name,
+// unless WithSignedHeaders replaces them with an allowlist.
+package payload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bsv-blockchain/go-sdk/transaction"
+)
+
+// RequestIDSize is the size of the request ID, the random requestNonce of the TypeScript SDK
+// sent base64 encoded in the x-bsv-auth-request-id header.
+const RequestIDSize = 32
+
+// DefaultMaxBodySize is the size of the largest request body BuildRequestPayload reads, unless WithMaxBodySize overrides it.
+const DefaultMaxBodySize = 10 << 20
+
+var (
+	// ErrInvalidRequestID is returned when the request ID isn't RequestIDSize bytes long.
+	ErrInvalidRequestID = errors.New("invalid request ID")
+	// ErrBodyTooLarge is returned by BuildRequestPayload for a request body larger than the max body size.
+	ErrBodyTooLarge = errors.New("request body too large")
+)
+
+// Option configures BuildRequestPayload and BuildResponsePayload.
+type Option func(*options)
+
+type options struct {
+	maxBodySize   int64
+	signedHeaders []string
+}
+
+// WithMaxBodySize overrides the size of the largest request body BuildRequestPayload reads, DefaultMaxBodySize by default.
+func WithMaxBodySize(size int64) Option {
+	return func(o *options) {
+		o.maxBodySize = size
+	}
+}
+
+// WithSignedHeaders signs only the headers of the allowlist, given by their case-insensitive names, instead of the ones
+// BRC-104 signs, e.g. to sign a header the clients rely on or to keep one a proxy rewrites out of the signature.
+// The x-bsv-auth-* headers are never signed, the signed ones are still ordered by their lowercase names.
+// The peer has to build its payload with the same allowlist.
+func WithSignedHeaders(names ...string) Option {
+	return func(o *options) {
+		o.signedHeaders = make([]string, len(names))
+		for i, name := range names {
+			o.signedHeaders[i] = strings.ToLower(name)
+		}
+	}
+}
+
+// authHeaderPrefix is the prefix of the BRC-104 auth headers, never signed.
+const authHeaderPrefix = "x-bsv-auth"
+
+// BuildRequestPayload serializes the request: the request ID, the method, the path as it's sent, percent-encoded,
+// the query with its "?", the signed headers and the body, -1 for an empty query or body.
+// The body is read and replaced with an unread copy, so the request can still be sent or handled.
+// A body larger than the max body size fails with ErrBodyTooLarge without being read any further, still left unread.
+func BuildRequestPayload(r *http.Request, requestID []byte, opts ...Option) ([]byte, error) {
+	if len(requestID) != RequestIDSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidRequestID, len(requestID))
+	}
+	o := options{maxBodySize: DefaultMaxBodySize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, o.maxBodySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if int64(len(body)) > o.maxBodySize {
+			// the request is left unread, the rest of the body behind the part read
+			r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, o.maxBodySize)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(requestID)
+	writeString(&buf, r.Method)
+	// the TypeScript SDK signs the pathname of the URL, which keeps the percent-encoding
+	path := r.URL.EscapedPath()
+	if path == "" {
+		// the request is sent with the path "/", which the URL of the TypeScript SDK reports too
+		path = "/"
+	}
+	writeOptionalString(&buf, path)
+	query := ""
+	if r.URL.RawQuery != "" {
+		query = "?" + r.URL.RawQuery
+	}
+	writeOptionalString(&buf, query)
+	writeHeaders(&buf, signedHeaders(r.Header, true, o.signedHeaders))
+	writeOptionalBytes(&buf, body)
+	return buf.Bytes(), nil
+}
+
+// BuildResponsePayload serializes the response to the request with the request ID: the request ID, the status,
+// the signed headers and the body, -1 for an empty body.
+func BuildResponsePayload(requestID []byte, status int, header http.Header, body []byte, opts ...Option) ([]byte, error) {
+	if len(requestID) != RequestIDSize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidRequestID, len(requestID))
+	}
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var buf bytes.Buffer
+	buf.Write(requestID)
+	buf.Write(transaction.VarInt(status).Bytes())
+	writeHeaders(&buf, signedHeaders(header, false, o.signedHeaders))
+	writeOptionalBytes(&buf, body)
+	return buf.Bytes(), nil
+}
+
+// signedHeaders returns the x-bsv-* headers other than x-bsv-auth-*, the authorization
+// and, if asked for, the media type of the content-type, sorted by their lowercase names.
+// With an allowlist, only its headers other than x-bsv-auth-* are returned, the content-type still reduced to its media type.
+func signedHeaders(header http.Header, withContentType bool, allowlist []string) [][2]string {
+	var headers [][2]string
+	for name, values := range header {
+		name = strings.ToLower(name)
+		value := strings.Join(values, ", ")
+		switch {
+		case strings.HasPrefix(name, authHeaderPrefix):
+			continue
+		case allowlist != nil:
+			if !slices.Contains(allowlist, name) {
+				continue
+			}
+			if name == "content-type" {
+				value = mediaType(value)
+			}
+			headers = append(headers, [2]string{name, value})
+		case strings.HasPrefix(name, "x-bsv-"), name == "authorization":
+			headers = append(headers, [2]string{name, value})
+		case withContentType && name == "content-type":
+			headers = append(headers, [2]string{name, mediaType(value)})
+		}
+	}
+	slices.SortFunc(headers, func(a, b [2]string) int { return strings.Compare(a[0], b[0]) })
+	return headers
+}
+
+// mediaType returns the media type of the content-type, without its parameters.
+func mediaType(contentType string) string {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	return strings.TrimSpace(mediaType)
+}
+
+func writeHeaders(buf *bytes.Buffer, headers [][2]string) {
+	buf.Write(transaction.VarInt(len(headers)).Bytes())
+	for _, header := range headers {
+		writeString(buf, header[0])
+		writeString(buf, header[1])
+	}
+}
+
+func writeString(buf *bytes.Buffer, value string) {
+	buf.Write(transaction.VarInt(len(value)).Bytes())
+	buf.WriteString(value)
+}
+
+func writeOptionalString(buf *bytes.Buffer, value string) {
+	writeOptionalBytes(buf, []byte(value))
+}
+
+func writeOptionalBytes(buf *bytes.Buffer, value []byte) {
+	if len(value) == 0 {
+		buf.Write(transaction.VarInt(math.MaxUint64).Bytes())
+		return
+	}
+	buf.Write(transaction.VarInt(len(value)).Bytes())
+	buf.Write(value)
+}
+
+// readCloser reads the Reader and closes the Closer, the original body of the request.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,240 @@
+package payload_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport/payload"
+	"github.com/stretchr/testify/require"
+)
+
+// requestIDHex is the hex of the fixture request ID, the bytes 0x00 to 0x1f.
+const requestIDHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+// emptyHex is the varint -1 written for a missing optional part.
+const emptyHex = "ffffffffffffffffff"
+
+func requestID(t *testing.T) []byte {
+	t.Helper()
+	id, err := hex.DecodeString(requestIDHex)
+	require.NoError(t, err)
+	return id
+}
+
+func TestBuildRequestPayload(t *testing.T) {
+	tests := map[string]struct {
+		method          string
+		url             string
+		headers         map[string]string
+		body            string
+		opts            []payload.Option
+		expectedPayload string
+	}{
+		"POST with a query, signed headers and a body": {
+			method: http.MethodPost,
+			url:    "https://example.com/api/orders?page=2&sort=asc",
+			headers: map[string]string{
+				"Content-Type":     "application/json; charset=utf-8",
+				"X-Bsv-Tenant":     "acme",
+				"Authorization":    "Bearer token",
+				"X-Bsv-Auth-Nonce": "not signed",
+				"Accept":           "not signed",
+			},
+			body: `{"a":1}`,
+			expectedPayload: requestIDHex +
+				"04" + "504f5354" + // POST
+				"0b" + "2f6170692f6f7264657273" + // /api/orders
+				"10" + "3f706167653d3226736f72743d617363" + // ?page=2&sort=asc
+				"03" + // 3 headers
+				"0d" + "617574686f72697a6174696f6e" + "0c" + "42656172657220746f6b656e" + // authorization: Bearer token
+				"0c" + "636f6e74656e742d74797065" + "10" + "6170706c69636174696f6e2f6a736f6e" + // content-type: application/json
+				"0c" + "782d6273762d74656e616e74" + "04" + "61636d65" + // x-bsv-tenant: acme
+				"07" + "7b2261223a317d", // {"a":1}
+		},
+		"GET with an allowlist of signed headers": {
+			method: http.MethodGet,
+			url:    "https://example.com/",
+			headers: map[string]string{
+				"Accept":        "text/plain",
+				"X-Bsv-Tenant":  "acme",
+				"Authorization": "Bearer token",
+			},
+			opts: []payload.Option{payload.WithSignedHeaders("Accept")},
+			expectedPayload: requestIDHex +
+				"03" + "474554" + // GET
+				"01" + "2f" + // /
+				emptyHex + // no query
+				"01" + // 1 header
+				"06" + "616363657074" + "0a" + "746578742f706c61696e" + // accept: text/plain
+				emptyHex, // no body
+		},
+		"GET of the root without a query, headers or body": {
+			method: http.MethodGet,
+			url:    "https://example.com/",
+			expectedPayload: requestIDHex +
+				"03" + "474554" + // GET
+				"01" + "2f" + // /
+				emptyHex + // no query
+				"00" + // no headers
+				emptyHex, // no body
+		},
+		"GET of a percent-encoded path signed as it's sent": {
+			method: http.MethodGet,
+			url:    "https://example.com/files/a%20b%2Fc",
+			expectedPayload: requestIDHex +
+				"03" + "474554" + // GET
+				"10" + "2f66696c65732f612532306225324663" + // /files/a%20b%2Fc
+				emptyHex + // no query
+				"00" + // no headers
+				emptyHex, // no body
+		},
+		"GET without a path signed as the root": {
+			method: http.MethodGet,
+			url:    "https://example.com",
+			expectedPayload: requestIDHex +
+				"03" + "474554" + // GET
+				"01" + "2f" + // /
+				emptyHex + // no query
+				"00" + // no headers
+				emptyHex, // no body
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			request, err := http.NewRequestWithContext(t.Context(), test.method, test.url, strings.NewReader(test.body))
+			require.NoError(t, err)
+			for name, value := range test.headers {
+				request.Header.Set(name, value)
+			}
+
+			// when
+			built, err := payload.BuildRequestPayload(request, requestID(t), test.opts...)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, test.expectedPayload, hex.EncodeToString(built))
+			body, err := io.ReadAll(request.Body)
+			require.NoError(t, err)
+			require.Equal(t, test.body, string(body))
+		})
+	}
+
+	t.Run("Read a body of the max body size", func(t *testing.T) {
+		// given
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, "https://example.com/", strings.NewReader("1234"))
+		require.NoError(t, err)
+
+		// when
+		_, err = payload.BuildRequestPayload(request, requestID(t), payload.WithMaxBodySize(4))
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("Reject a body larger than the max body size", func(t *testing.T) {
+		// given
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodPost, "https://example.com/", strings.NewReader("12345"))
+		require.NoError(t, err)
+
+		// when
+		_, err = payload.BuildRequestPayload(request, requestID(t), payload.WithMaxBodySize(4))
+
+		// then
+		require.ErrorIs(t, err, payload.ErrBodyTooLarge)
+		body, err := io.ReadAll(request.Body)
+		require.NoError(t, err)
+		require.Equal(t, "12345", string(body))
+	})
+
+	t.Run("Reject a request ID of the wrong size", func(t *testing.T) {
+		// given
+		request, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "https://example.com/", nil)
+		require.NoError(t, err)
+
+		// when
+		_, err = payload.BuildRequestPayload(request, []byte("short"))
+
+		// then
+		require.ErrorIs(t, err, payload.ErrInvalidRequestID)
+	})
+}
+
+func TestBuildResponsePayload(t *testing.T) {
+	tests := map[string]struct {
+		status          int
+		headers         map[string]string
+		body            []byte
+		opts            []payload.Option
+		expectedPayload string
+	}{
+		"Response with signed headers and a body": {
+			status: http.StatusCreated,
+			headers: map[string]string{
+				"Content-Type":         "text/plain",
+				"X-Bsv-Price":          "100",
+				"X-Bsv-Auth-Signature": "not signed",
+			},
+			body: []byte("hello"),
+			expectedPayload: requestIDHex +
+				"c9" + // 201
+				"01" + // 1 header
+				"0b" + "782d6273762d7072696365" + "03" + "313030" + // x-bsv-price: 100
+				"05" + "68656c6c6f", // hello
+		},
+		"Response with an allowlist of signed headers": {
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Content-Type":         "text/plain; charset=utf-8",
+				"X-Request-Id":         "abc",
+				"X-Bsv-Price":          "100",
+				"Via":                  "1.1 proxy",
+				"X-Bsv-Auth-Signature": "not signed",
+			},
+			opts: []payload.Option{payload.WithSignedHeaders("X-Request-Id", "content-type", "x-bsv-auth-signature")},
+			expectedPayload: requestIDHex +
+				"c8" + // 200
+				"02" + // 2 headers
+				"0c" + "636f6e74656e742d74797065" + "0a" + "746578742f706c61696e" + // content-type: text/plain
+				"0c" + "782d726571756573742d6964" + "03" + "616263" + // x-request-id: abc
+				emptyHex,
+		},
+		"Empty response": {
+			status:          http.StatusOK,
+			expectedPayload: requestIDHex + "c8" + "00" + emptyHex,
+		},
+		"Status written as a multi-byte varint": {
+			status:          http.StatusInternalServerError,
+			body:            bytes.Repeat([]byte{0xab}, 3),
+			expectedPayload: requestIDHex + "fdf401" + "00" + "03" + "ababab",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			header := http.Header{}
+			for name, value := range test.headers {
+				header.Set(name, value)
+			}
+
+			// when
+			built, err := payload.BuildResponsePayload(requestID(t), test.status, header, test.body, test.opts...)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, test.expectedPayload, hex.EncodeToString(built))
+		})
+	}
+
+	t.Run("Reject a request ID of the wrong size", func(t *testing.T) {
+		// when
+		_, err := payload.BuildResponsePayload(nil, http.StatusOK, http.Header{}, nil)
+
+		// then
+		require.ErrorIs(t, err, payload.ErrInvalidRequestID)
+	})
+}
@@ -0,0 +1,294 @@
+package transport_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	identityKey = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	nonce       = "ERERERERERERERERERERERERERERERERERERERERERE="
+	yourNonce   = "AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="
+	signature   = "3044022001020304050607080910111213141516171819202122232425262728293031320220010203040506070809101112131415161718192021222324252627282930313233"
+)
+
+var requestID = bytes.Repeat([]byte{7}, 32)
+
+// validHeaders returns the x-bsv-auth headers of a general message, with their canonical names.
+func validHeaders() http.Header {
+	header := http.Header{}
+	header.Set(transport.HeaderVersion, transport.AuthVersion)
+	header.Set(transport.HeaderIdentityKey, identityKey)
+	header.Set(transport.HeaderNonce, nonce)
+	header.Set(transport.HeaderYourNonce, yourNonce)
+	header.Set(transport.HeaderRequestID, base64.StdEncoding.EncodeToString(requestID))
+	header.Set(transport.HeaderSignature, signature)
+	return header
+}
+
+func TestParseAuthHeaders(t *testing.T) {
+	t.Run("Parse the valid headers", func(t *testing.T) {
+		// when
+		headers, err := transport.ParseAuthHeaders(validHeaders())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, transport.AuthVersion, headers.Version)
+		require.Equal(t, identityKey, headers.IdentityKey)
+		require.Equal(t, identityKey, headers.PublicKey.ToDERHex())
+		require.Equal(t, nonce, headers.Nonce)
+		require.Equal(t, yourNonce, headers.YourNonce)
+		require.Equal(t, requestID, headers.RequestID)
+		require.Len(t, headers.Signature, 71)
+	})
+
+	t.Run("Normalize an uppercase identity key", func(t *testing.T) {
+		// given
+		header := validHeaders()
+		header.Set(transport.HeaderIdentityKey, strings.ToUpper(identityKey))
+
+		// when
+		headers, err := transport.ParseAuthHeaders(header)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, identityKey, headers.IdentityKey)
+	})
+}
+
+func TestParseAuthHeaders_HeaderNames(t *testing.T) {
+	tests := map[string]struct {
+		name func(name string) string
+	}{
+		"Lowercase names": {
+			name: strings.ToLower,
+		},
+		"Uppercase names": {
+			name: strings.ToUpper,
+		},
+		"Canonical names": {
+			name: http.CanonicalHeaderKey,
+		},
+		"Mixed case names": {
+			name: func(name string) string {
+				return "X-BSV-Auth" + name[len("x-bsv-auth"):]
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			header := http.Header{}
+			for key, values := range validHeaders() {
+				header[test.name(key)] = values
+			}
+
+			// when
+			headers, err := transport.ParseAuthHeaders(header)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, identityKey, headers.IdentityKey)
+			require.True(t, transport.HasAuthHeaders(header))
+		})
+	}
+}
+
+func TestParseAuthHeaders_Malformed(t *testing.T) {
+	tests := map[string]struct {
+		header         string
+		value          *string
+		repeated       string
+		expectedHeader string
+		expectedErr    error
+	}{
+		"Missing version": {
+			header:         transport.HeaderVersion,
+			expectedHeader: transport.HeaderVersion,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Unsupported version": {
+			header:         transport.HeaderVersion,
+			value:          ptr("1.0"),
+			expectedHeader: transport.HeaderVersion,
+			expectedErr:    transport.ErrUnsupportedVersion,
+		},
+		"Missing identity key": {
+			header:         transport.HeaderIdentityKey,
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Empty identity key": {
+			header:         transport.HeaderIdentityKey,
+			value:          ptr(""),
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Identity key too short": {
+			header:         transport.HeaderIdentityKey,
+			value:          ptr(identityKey[:64]),
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Uncompressed identity key prefix": {
+			header:         transport.HeaderIdentityKey,
+			value:          ptr("04" + identityKey[2:]),
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Identity key not hex": {
+			header:         transport.HeaderIdentityKey,
+			value:          ptr("02" + strings.Repeat("zz", 32)),
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Identity key not on the curve": {
+			header:         transport.HeaderIdentityKey,
+			value:          ptr("02" + strings.Repeat("00", 31) + "05"),
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Repeated identity key": {
+			header:         transport.HeaderIdentityKey,
+			value:          ptr(identityKey),
+			repeated:       strings.ToLower(transport.HeaderIdentityKey),
+			expectedHeader: transport.HeaderIdentityKey,
+			expectedErr:    transport.ErrRepeatedHeader,
+		},
+		"Missing nonce": {
+			header:         transport.HeaderNonce,
+			expectedHeader: transport.HeaderNonce,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Nonce not base64": {
+			header:         transport.HeaderNonce,
+			value:          ptr("request-nonce-1"),
+			expectedHeader: transport.HeaderNonce,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Nonce too short": {
+			header:         transport.HeaderNonce,
+			value:          ptr(base64.StdEncoding.EncodeToString([]byte("short"))),
+			expectedHeader: transport.HeaderNonce,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Nonce too long": {
+			header:         transport.HeaderNonce,
+			value:          ptr(base64.StdEncoding.EncodeToString(make([]byte, transport.MaxNonceSize+1))),
+			expectedHeader: transport.HeaderNonce,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Missing your nonce": {
+			header:         transport.HeaderYourNonce,
+			expectedHeader: transport.HeaderYourNonce,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Your nonce without padding": {
+			header:         transport.HeaderYourNonce,
+			value:          ptr(strings.TrimSuffix(yourNonce, "=")),
+			expectedHeader: transport.HeaderYourNonce,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Missing request ID": {
+			header:         transport.HeaderRequestID,
+			expectedHeader: transport.HeaderRequestID,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Request ID of 16 bytes": {
+			header:         transport.HeaderRequestID,
+			value:          ptr(base64.StdEncoding.EncodeToString(requestID[:16])),
+			expectedHeader: transport.HeaderRequestID,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Request ID hex encoded": {
+			header:         transport.HeaderRequestID,
+			value:          ptr(strings.Repeat("07", 32)),
+			expectedHeader: transport.HeaderRequestID,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Missing signature": {
+			header:         transport.HeaderSignature,
+			expectedHeader: transport.HeaderSignature,
+			expectedErr:    transport.ErrMissingHeader,
+		},
+		"Signature base64 encoded": {
+			header:         transport.HeaderSignature,
+			value:          ptr("MEQCIAECAwQFBgcICQoLDA0ODxA="),
+			expectedHeader: transport.HeaderSignature,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Signature too short": {
+			header:         transport.HeaderSignature,
+			value:          ptr("3006"),
+			expectedHeader: transport.HeaderSignature,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+		"Signature too long": {
+			header:         transport.HeaderSignature,
+			value:          ptr(strings.Repeat("30", 74)),
+			expectedHeader: transport.HeaderSignature,
+			expectedErr:    transport.ErrMalformedHeader,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			header := validHeaders()
+			header.Del(test.header)
+			if test.value != nil {
+				header.Set(test.header, *test.value)
+			}
+			if test.repeated != "" {
+				header[test.repeated] = []string{*test.value}
+			}
+
+			// when
+			headers, err := transport.ParseAuthHeaders(header)
+
+			// then
+			require.Nil(t, headers)
+			require.ErrorIs(t, err, test.expectedErr)
+			var headerErr *transport.HeaderError
+			require.ErrorAs(t, err, &headerErr)
+			require.Equal(t, test.expectedHeader, headerErr.Header)
+			require.True(t, strings.HasPrefix(err.Error(), test.expectedHeader+": "))
+		})
+	}
+}
+
+func TestHasAuthHeaders(t *testing.T) {
+	tests := map[string]struct {
+		header   http.Header
+		expected bool
+	}{
+		"No headers": {
+			header: http.Header{},
+		},
+		"Only the signed x-bsv headers": {
+			header: http.Header{"X-Bsv-Tenant": {"tenant-a"}, "Authorization": {"Bearer token"}},
+		},
+		"Any of the auth headers": {
+			header:   http.Header{"x-bsv-auth-nonce": {nonce}},
+			expected: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			has := transport.HasAuthHeaders(test.header)
+
+			// then
+			require.Equal(t, test.expected, has)
+		})
+	}
+}
+
+func ptr(value string) *string {
+	return &value
+}
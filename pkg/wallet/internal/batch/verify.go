@@ -0,0 +1,51 @@
+// Package batch verifies batches of signatures with the wallets lacking a batch call.
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// DefaultWorkers is the default number of signatures of a batch verified concurrently.
+const DefaultWorkers = 8
+
+// VerifyFunc verifies a single item, usually with a call to the wallet's VerifySignature.
+type VerifyFunc func(ctx context.Context, item wallet.VerifyItem) (bool, error)
+
+// VerifySignatures verifies the items with up to workers concurrent calls of verify.
+// The result of an item is false if verify fails, the errors for which fatal returns true are joined into the returned error.
+func VerifySignatures(ctx context.Context, items []wallet.VerifyItem, workers int, verify VerifyFunc, fatal func(error) bool) ([]bool, error) {
+	results := make([]bool, len(items))
+	errs := make([]error, len(items))
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(workers, 1), len(items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				valid, err := verify(ctx, items[i])
+				results[i] = valid && err == nil
+				if err != nil && fatal(err) {
+					errs[i] = err
+				}
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results, errors.Join(errs...)
+}
+
+// IsContextError returns true for the errors of a canceled or expired context.
+func IsContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
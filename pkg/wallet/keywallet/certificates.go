@@ -0,0 +1,127 @@
+package keywallet
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+var (
+	// ErrUnknownCertificateField is returned by ProveCertificate when a field to reveal isn't in the certificate's keyring.
+	ErrUnknownCertificateField = errors.New("unknown certificate field")
+	// ErrInvalidKeyring is returned by ProveCertificate when a field key of the keyring can't be decrypted.
+	ErrInvalidKeyring = errors.New("invalid certificate keyring")
+	// ErrInvalidCertificate is returned by AcquireCertificate when the certificate has no type, serial number or certifier.
+	ErrInvalidCertificate = errors.New("certificate type, serial number and certifier are required")
+)
+
+// certificateFieldProtocolName is the BRC-53 protocol of the keys the certificate field keys are encrypted with.
+const certificateFieldProtocolName = "certificate field encryption"
+
+// ListCertificates lists the certificates acquired by the wallet, they are kept in memory only.
+func (w *Wallet) ListCertificates(ctx context.Context, options wallet.ListCertificatesOptions) (wallet.ListCertificatesResult, error) {
+	if ctx.Err() != nil {
+		return wallet.ListCertificatesResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return options.Apply(w.certificates), nil
+}
+
+// AcquireCertificate keeps the certificate in memory, replacing the one with the same type, serial number and certifier.
+func (w *Wallet) AcquireCertificate(ctx context.Context, certificate wallet.Certificate) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if certificate.Type == "" || certificate.SerialNumber == "" || certificate.Certifier == "" {
+		return ErrInvalidCertificate
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if i := w.indexOfCertificate(certificate.Type, certificate.SerialNumber, certificate.Certifier); i >= 0 {
+		w.certificates[i] = certificate
+		return nil
+	}
+	w.certificates = append(w.certificates, certificate)
+	return nil
+}
+
+// RelinquishCertificate forgets the certificate, it returns wallet.ErrCertificateNotFound for an unknown certificate.
+func (w *Wallet) RelinquishCertificate(ctx context.Context, certType string, serialNumber string, certifier string) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	i := w.indexOfCertificate(certType, serialNumber, certifier)
+	if i < 0 {
+		return wallet.ErrCertificateNotFound
+	}
+	w.certificates = slices.Delete(w.certificates, i, i+1)
+	return nil
+}
+
+// ProveCertificate creates the BRC-53 keyring revealing the fields of the certificate to the verifier.
+// The field keys of the certificate's master keyring, encrypted by the certifier to this wallet, are decrypted
+// and encrypted again to the verifier, with the "<serialNumber> <fieldName>" keyID.
+// The keyring maps the field names to the base64 encoded encrypted keys, it's empty when no fields are revealed.
+func (w *Wallet) ProveCertificate(ctx context.Context, certificate wallet.Certificate, verifier string, fieldsToReveal []string, privilege wallet.Privilege) (map[string]string, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if privilege.Privileged {
+		return nil, ErrPrivilegedNotSupported
+	}
+
+	certifierCounterparty, err := wallet.ParseCounterparty(certificate.Certifier)
+	if err != nil {
+		return nil, fmt.Errorf("%w: certifier: %w", ErrInvalidKeyring, err)
+	}
+	verifierCounterparty, err := wallet.ParseCounterparty(verifier)
+	if err != nil {
+		return nil, fmt.Errorf("invalid verifier: %w", err)
+	}
+
+	keyring := make(map[string]string, len(fieldsToReveal))
+	for _, fieldName := range fieldsToReveal {
+		encryptedMasterKey, ok := certificate.Keyring[fieldName]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCertificateField, fieldName)
+		}
+
+		masterKey, err := base64.StdEncoding.DecodeString(encryptedMasterKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidKeyring, fieldName, err)
+		}
+		fieldKey, err := w.Decrypt(ctx, masterKey, certificateFieldProtocolID(), fieldName, certifierCounterparty)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %q: %w", ErrInvalidKeyring, fieldName, err)
+		}
+
+		verifierKey, err := w.Encrypt(ctx, fieldKey, certificateFieldProtocolID(), certificate.SerialNumber+" "+fieldName, verifierCounterparty)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt key of field %q for the verifier: %w", fieldName, err)
+		}
+		keyring[fieldName] = base64.StdEncoding.EncodeToString(verifierKey)
+	}
+	return keyring, nil
+}
+
+func (w *Wallet) indexOfCertificate(certType string, serialNumber string, certifier string) int {
+	return slices.IndexFunc(w.certificates, func(certificate wallet.Certificate) bool {
+		return certificate.Type == certType && certificate.SerialNumber == serialNumber && certificate.Certifier == certifier
+	})
+}
+
+func certificateFieldProtocolID() wallet.Protocol {
+	return wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: certificateFieldProtocolName}
+}
@@ -0,0 +1,145 @@
+package keywallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// ErrInvalidKeyID is returned when the keyID is empty or longer than 800 characters.
+var ErrInvalidKeyID = errors.New("invalid key ID")
+
+// keyDeriver derives the child keys of the root key according to BRC-42, with BRC-43 invoice numbers.
+type keyDeriver struct {
+	rootKey *ec.PrivateKey
+}
+
+// derivePrivateKey derives the private key used by the wallet owner towards the counterparty.
+func (d keyDeriver) derivePrivateKey(protocol wallet.Protocol, keyID string, counterparty wallet.Counterparty) (*ec.PrivateKey, error) {
+	invoiceNumber, err := computeInvoiceNumber(protocol, keyID)
+	if err != nil {
+		return nil, err
+	}
+	counterpartyKey, err := d.counterpartyKey(counterparty)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := d.rootKey.DeriveChild(counterpartyKey, invoiceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive private key: %w", err)
+	}
+	return key, nil
+}
+
+// derivePublicKey derives the public key of the counterparty, or the wallet owner's own public key when forSelf is set.
+func (d keyDeriver) derivePublicKey(protocol wallet.Protocol, keyID string, counterparty wallet.Counterparty, forSelf bool) (*ec.PublicKey, error) {
+	if forSelf {
+		key, err := d.derivePrivateKey(protocol, keyID, counterparty)
+		if err != nil {
+			return nil, err
+		}
+		return key.PubKey(), nil
+	}
+
+	invoiceNumber, err := computeInvoiceNumber(protocol, keyID)
+	if err != nil {
+		return nil, err
+	}
+	counterpartyKey, err := d.counterpartyKey(counterparty)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := counterpartyKey.DeriveChild(d.rootKey, invoiceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive public key: %w", err)
+	}
+	return key, nil
+}
+
+// deriveSymmetricKey derives the key shared by the wallet owner and the counterparty,
+// the x coordinate of the shared secret of the derived private key and the counterparty's derived public key.
+// The key isn't padded, it's the minimal big-endian encoding like BigNumber.toArray() of the TypeScript SDK.
+func (d keyDeriver) deriveSymmetricKey(protocol wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	privateKey, err := d.derivePrivateKey(protocol, keyID, counterparty)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := d.derivePublicKey(protocol, keyID, counterparty, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedSecret, err := privateKey.DeriveSharedSecret(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive symmetric key: %w", err)
+	}
+	return sharedSecret.X.Bytes(), nil
+}
+
+// counterpartySecret returns the BRC-42 shared secret of the root key and the counterparty, the compressed ECDH point
+// linking all the keys derived with the counterparty.
+func (d keyDeriver) counterpartySecret(counterparty *ec.PublicKey) ([]byte, error) {
+	sharedSecret, err := d.rootKey.DeriveSharedSecret(counterparty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive shared secret: %w", err)
+	}
+	return sharedSecret.Compressed(), nil
+}
+
+// specificSecret returns the offset of the key derived with the counterparty for the protocol and keyID from the root key,
+// the HMAC-SHA256 of the invoice number keyed with the counterparty secret.
+func (d keyDeriver) specificSecret(counterparty *ec.PublicKey, protocol wallet.Protocol, keyID string) ([]byte, error) {
+	invoiceNumber, err := computeInvoiceNumber(protocol, keyID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := d.counterpartySecret(counterparty)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(invoiceNumber))
+	return mac.Sum(nil), nil
+}
+
+func (d keyDeriver) counterpartyKey(counterparty wallet.Counterparty) (*ec.PublicKey, error) {
+	if err := counterparty.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch counterparty.Type {
+	case wallet.CounterpartyTypeSelf:
+		return d.rootKey.PubKey(), nil
+	case wallet.CounterpartyTypeAnyone:
+		anyone, _ := ec.PrivateKeyFromBytes(big.NewInt(1).Bytes())
+		return anyone.PubKey(), nil
+	default:
+		return counterparty.PublicKey, nil
+	}
+}
+
+// computeInvoiceNumber builds the BRC-43 invoice number "<securityLevel>-<protocolName>-<keyID>",
+// validating the parts the same way the TypeScript SDK does.
+func computeInvoiceNumber(protocol wallet.Protocol, keyID string) (string, error) {
+	if err := protocol.Validate(); err != nil {
+		return "", err
+	}
+
+	if len(keyID) < 1 {
+		return "", fmt.Errorf("%w: key IDs must be 1 character or more", ErrInvalidKeyID)
+	}
+	if len(keyID) > 800 {
+		return "", fmt.Errorf("%w: key IDs must be 800 characters or less", ErrInvalidKeyID)
+	}
+
+	protocol = protocol.Normalize()
+	return fmt.Sprintf("%d-%s-%s", protocol.SecurityLevel, protocol.Protocol, keyID), nil
+}
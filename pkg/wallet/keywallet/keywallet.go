@@ -0,0 +1,304 @@
+package keywallet
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+var (
+	// ErrPrivilegedNotSupported is returned for privileged operations, the wallet holds a single, everyday root key.
+	ErrPrivilegedNotSupported = errors.New("privileged operations are not supported")
+	// ErrEmptyData is returned when there is no data to sign or authenticate.
+	ErrEmptyData = errors.New("data is empty")
+	// ErrInvalidCiphertext is returned by Decrypt when the ciphertext is too short to hold the IV and the authentication tag.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	// ErrDecryptionFailed is returned by Decrypt when the ciphertext wasn't encrypted for this wallet, protocolID, keyID and counterparty.
+	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrChainNotAvailable is returned by GetHeight, the wallet has no access to the chain.
+	ErrChainNotAvailable = errors.New("chain is not available")
+	// ErrActionsNotSupported is returned by CreateAction and InternalizeAction, the wallet keeps no outputs.
+	ErrActionsNotSupported = errors.New("actions are not supported")
+)
+
+// Version is the version reported by GetVersion.
+const Version = "keywallet-1.0.0"
+
+// The AES-GCM ciphertext of the TypeScript SDK is a 32 byte IV, the encrypted data and a 16 byte authentication tag.
+const (
+	ivSize            = 32
+	tagSize           = 16
+	symmetricKeySize  = 32
+	minCiphertextSize = ivSize + tagSize
+)
+
+var _ wallet.Interface = (*Wallet)(nil)
+
+// Wallet is a wallet.Interface implementation backed by a single BSV private key.
+// All keys are derived from the root key with BRC-42, using BRC-43 invoice numbers built from the protocolID and keyID,
+// so it interoperates with the wallets of the TypeScript SDK.
+type Wallet struct {
+	deriver keyDeriver
+	nonces  *nonce.Manager
+	network string
+
+	mu           sync.Mutex
+	certificates []wallet.Certificate
+}
+
+// NewKeyWallet creates a wallet deriving all its keys from the private key.
+func NewKeyWallet(privKey *ec.PrivateKey, opts ...Option) *Wallet {
+	w := &Wallet{
+		deriver: keyDeriver{rootKey: privKey},
+	}
+	cfg := config{network: wallet.NetworkMainnet}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	w.network = cfg.network
+	w.nonces = nonce.NewManager(w, cfg.nonceOptions...)
+	return w
+}
+
+// GetPublicKey returns the compressed hex encoded identity key, or a key derived for the protocolID, keyID and counterparty.
+// The counterparty defaults to "self".
+func (w *Wallet) GetPublicKey(ctx context.Context, options wallet.GetPublicKeyOptions) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if options.Privileged {
+		return "", ErrPrivilegedNotSupported
+	}
+
+	if options.IdentityKey {
+		return hex.EncodeToString(w.deriver.rootKey.PubKey().Compressed()), nil
+	}
+
+	key, err := w.deriver.derivePublicKey(options.ProtocolID, options.KeyID, options.Counterparty.OrDefault(wallet.CounterpartySelf()), options.ForSelf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key.Compressed()), nil
+}
+
+// CreateSignature signs the SHA-256 hash of the data with ECDSA, using the key derived for the counterparty.
+// The counterparty defaults to "anyone" and the signature is DER encoded.
+func (w *Wallet) CreateSignature(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty, privilege wallet.Privilege) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if privilege.Privileged {
+		return nil, ErrPrivilegedNotSupported
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmptyData
+	}
+
+	key, err := w.deriver.derivePrivateKey(protocolID, keyID, counterparty.OrDefault(wallet.CounterpartyAnyone()))
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(data)
+	signature, err := key.Sign(hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign data: %w", err)
+	}
+	der, err := signature.ToDER()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signature: %w", err)
+	}
+	return der, nil
+}
+
+// VerifySignature verifies the DER encoded signature of the data made by the counterparty towards this wallet.
+// The counterparty defaults to "self". A malformed signature is reported as invalid.
+func (w *Wallet) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	key, err := w.deriver.derivePublicKey(protocolID, keyID, counterparty.OrDefault(wallet.CounterpartySelf()), false)
+	if err != nil {
+		return false, err
+	}
+
+	parsed, err := ec.ParseDERSignature(signature)
+	if err != nil {
+		return false, nil
+	}
+	hash := sha256.Sum256(data)
+	return parsed.Verify(hash[:], key), nil
+}
+
+// VerifySignatures verifies the items one by one, an item failing to verify, e.g. with an invalid protocol, is invalid.
+func (w *Wallet) VerifySignatures(ctx context.Context, items []wallet.VerifyItem) ([]bool, error) {
+	results := make([]bool, len(items))
+	for i, item := range items {
+		if ctx.Err() != nil {
+			return results, fmt.Errorf("ctx err: %w", ctx.Err())
+		}
+		results[i], _ = w.VerifySignature(ctx, item.Data, item.Signature, item.ProtocolID, item.KeyID, item.Counterparty)
+	}
+	return results, nil
+}
+
+// CreateHMAC creates an HMAC-SHA256 of the data with the symmetric key shared with the counterparty.
+// The counterparty defaults to "self".
+func (w *Wallet) CreateHMAC(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if len(data) == 0 {
+		return nil, ErrEmptyData
+	}
+
+	key, err := w.deriver.deriveSymmetricKey(protocolID, keyID, counterparty.OrDefault(wallet.CounterpartySelf()))
+	if err != nil {
+		return nil, err
+	}
+	return computeHMAC(key, data), nil
+}
+
+// VerifyHMAC verifies the HMAC of the data created by the counterparty, or by this wallet, with the same key.
+// The counterparty defaults to "self".
+func (w *Wallet) VerifyHMAC(ctx context.Context, data []byte, hmacValue []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if len(data) == 0 {
+		return false, ErrEmptyData
+	}
+
+	key, err := w.deriver.deriveSymmetricKey(protocolID, keyID, counterparty.OrDefault(wallet.CounterpartySelf()))
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(hmacValue, computeHMAC(key, data)), nil
+}
+
+// Encrypt encrypts the plaintext with AES-GCM, using the symmetric key shared with the counterparty.
+// The counterparty defaults to "self".
+func (w *Wallet) Encrypt(ctx context.Context, plaintext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	key, err := w.deriver.deriveSymmetricKey(protocolID, keyID, counterparty.OrDefault(wallet.CounterpartySelf()))
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := ec.NewSymmetricKey(padKey(key)).Encrypt(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data: %w", err)
+	}
+	return ciphertext, nil
+}
+
+// Decrypt decrypts the ciphertext created by Encrypt of the counterparty, or of this wallet, with the same key.
+// The counterparty defaults to "self".
+func (w *Wallet) Decrypt(ctx context.Context, ciphertext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if len(ciphertext) < minCiphertextSize {
+		return nil, fmt.Errorf("%w: expected at least %d bytes, got %d", ErrInvalidCiphertext, minCiphertextSize, len(ciphertext))
+	}
+
+	key, err := w.deriver.deriveSymmetricKey(protocolID, keyID, counterparty.OrDefault(wallet.CounterpartySelf()))
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := ec.NewSymmetricKey(padKey(key)).Decrypt(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+	return plaintext, nil
+}
+
+// CreateNonce creates a nonce authenticated with an HMAC of the wallet's own key, see nonce.Manager.
+// The nonce isn't stored, so it can be verified by any wallet with the same root key, e.g. by other replicas.
+func (w *Wallet) CreateNonce(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	return w.nonces.Create(ctx)
+}
+
+// VerifyNonce recomputes the HMAC of the nonce to check it was created by a wallet with the same root key.
+// By default a nonce is valid once and for nonce.DefaultMaxAge, see WithNonceOptions.
+// The reason of a rejection is reported with the errors of the nonce package, e.g. nonce.ErrAlreadyUsed.
+func (w *Wallet) VerifyNonce(ctx context.Context, value string) (bool, error) {
+	if ctx.Err() != nil {
+		return false, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	if err := w.nonces.Verify(ctx, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetNetwork returns the network set with WithNetwork.
+func (w *Wallet) GetNetwork(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	return w.network, nil
+}
+
+// GetVersion returns the Version.
+func (w *Wallet) GetVersion(ctx context.Context) (string, error) {
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+	return Version, nil
+}
+
+// GetHeight returns ErrChainNotAvailable, the wallet holds only a key.
+func (w *Wallet) GetHeight(_ context.Context) (uint32, error) {
+	return 0, ErrChainNotAvailable
+}
+
+// CreateAction returns ErrActionsNotSupported, the wallet has no outputs to fund the action with.
+func (w *Wallet) CreateAction(_ context.Context, _ wallet.CreateActionArgs) (wallet.CreateActionResult, error) {
+	return wallet.CreateActionResult{}, ErrActionsNotSupported
+}
+
+// InternalizeAction returns ErrActionsNotSupported, the wallet can't keep the outputs.
+func (w *Wallet) InternalizeAction(_ context.Context, _ wallet.InternalizeActionArgs) (wallet.InternalizeActionResult, error) {
+	return wallet.InternalizeActionResult{}, ErrActionsNotSupported
+}
+
+func computeHMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// padKey left pads the symmetric key to the 32 bytes of an AES-256 key, like SymmetricKey.toArray('be', 32) of the TypeScript SDK.
+func padKey(key []byte) []byte {
+	if len(key) >= symmetricKeySize {
+		return key
+	}
+	padded := make([]byte, symmetricKeySize)
+	copy(padded[symmetricKeySize-len(key):], key)
+	return padded
+}
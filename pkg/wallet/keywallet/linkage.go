@@ -0,0 +1,98 @@
+package keywallet
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// RevealCounterpartyKeyLinkage reveals the BRC-69 counterparty linkage, the shared secret of the root key and the counterparty,
+// encrypted to the verifier with the wallet.CounterpartyLinkageProtocol and the revelation time as the keyID.
+// The linkage is revealed without a proof, the counterparty can't be "self" and the result has its public key.
+func (w *Wallet) RevealCounterpartyKeyLinkage(ctx context.Context, counterparty string, verifier string) (wallet.KeyLinkageResult, error) {
+	if ctx.Err() != nil {
+		return wallet.KeyLinkageResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	counterpartyKey, verifierCounterparty, err := w.parseLinkageParams(counterparty, verifier)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+
+	linkage, err := w.deriver.counterpartySecret(counterpartyKey)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	revelationTime := time.Now().UTC().Format(wallet.RevelationTimeFormat)
+	encryptedLinkage, err := w.Encrypt(ctx, linkage, wallet.CounterpartyLinkageProtocol, revelationTime, verifierCounterparty)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+
+	return wallet.KeyLinkageResult{
+		Prover:           hex.EncodeToString(w.deriver.rootKey.PubKey().Compressed()),
+		Verifier:         verifier,
+		Counterparty:     hex.EncodeToString(counterpartyKey.Compressed()),
+		EncryptedLinkage: encryptedLinkage,
+		ProofType:        wallet.KeyLinkageProofNone,
+		RevelationTime:   revelationTime,
+	}, nil
+}
+
+// RevealSpecificKeyLinkage reveals the BRC-69 specific linkage, the offset of the key derived with the counterparty
+// for the protocol and keyID, encrypted to the verifier with the wallet.SpecificLinkageProtocol and the same keyID.
+// The linkage is revealed without a proof, the counterparty can't be "self" and the result has its public key.
+func (w *Wallet) RevealSpecificKeyLinkage(ctx context.Context, counterparty string, verifier string, protocolID wallet.Protocol, keyID string) (wallet.KeyLinkageResult, error) {
+	if ctx.Err() != nil {
+		return wallet.KeyLinkageResult{}, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	counterpartyKey, verifierCounterparty, err := w.parseLinkageParams(counterparty, verifier)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+
+	linkage, err := w.deriver.specificSecret(counterpartyKey, protocolID, keyID)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	encryptedLinkage, err := w.Encrypt(ctx, linkage, wallet.SpecificLinkageProtocol(protocolID), keyID, verifierCounterparty)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+
+	return wallet.KeyLinkageResult{
+		Prover:           hex.EncodeToString(w.deriver.rootKey.PubKey().Compressed()),
+		Verifier:         verifier,
+		Counterparty:     hex.EncodeToString(counterpartyKey.Compressed()),
+		EncryptedLinkage: encryptedLinkage,
+		ProofType:        wallet.KeyLinkageProofNone,
+		ProtocolID:       protocolID,
+		KeyID:            keyID,
+	}, nil
+}
+
+// parseLinkageParams parses the counterparty, which can be "anyone" but not the wallet itself, and the verifier.
+func (w *Wallet) parseLinkageParams(counterparty string, verifier string) (*ec.PublicKey, wallet.Counterparty, error) {
+	parsedCounterparty, err := wallet.ParseCounterparty(counterparty)
+	if err != nil {
+		return nil, wallet.Counterparty{}, err
+	}
+	counterpartyKey, err := w.deriver.counterpartyKey(parsedCounterparty)
+	if err != nil {
+		return nil, wallet.Counterparty{}, err
+	}
+	if counterpartyKey.IsEqual(w.deriver.rootKey.PubKey()) {
+		return nil, wallet.Counterparty{}, fmt.Errorf("%w: the linkage can't be revealed for self", wallet.ErrInvalidCounterparty)
+	}
+
+	verifierCounterparty, err := wallet.ParseCounterparty(verifier)
+	if err != nil {
+		return nil, wallet.Counterparty{}, fmt.Errorf("invalid verifier: %w", err)
+	}
+	return counterpartyKey, verifierCounterparty, nil
+}
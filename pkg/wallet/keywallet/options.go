@@ -0,0 +1,25 @@
+package keywallet
+
+import "github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+
+// Option configures the Wallet.
+type Option func(*config)
+
+type config struct {
+	nonceOptions []nonce.Option
+	network      string
+}
+
+// WithNetwork sets the network reported by GetNetwork, wallet.NetworkMainnet by default.
+func WithNetwork(network string) Option {
+	return func(c *config) {
+		c.network = network
+	}
+}
+
+// WithNonceOptions configures the nonces created and verified by the wallet, e.g. their max age and reuse.
+func WithNonceOptions(opts ...nonce.Option) Option {
+	return func(c *config) {
+		c.nonceOptions = append(c.nonceOptions, opts...)
+	}
+}
@@ -0,0 +1,862 @@
+package keywallet_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+// anyoneKey is the private key 1, the root key of the TypeScript SDK's ProtoWallet("anyone") used by the BRC compliance vectors.
+func anyoneKey() *ec.PrivateKey {
+	key, _ := ec.PrivateKeyFromBytes(big.NewInt(1).Bytes())
+	return key
+}
+
+func protocol(name string) wallet.Protocol {
+	return wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: name}
+}
+
+func newKey(t *testing.T) *ec.PrivateKey {
+	key, err := ec.NewPrivateKey()
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeyWallet_Interop(t *testing.T) {
+	t.Run("Verify BRC-3 compliance signature", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(anyoneKey())
+		signature := []byte{48, 68, 2, 32, 43, 34, 58, 156, 219, 32, 50, 70, 29, 240, 155, 137, 88, 60, 200, 95, 243, 198, 201, 21, 56, 82, 141, 112, 69, 196, 170, 73, 156, 6, 44, 48, 2, 32, 118, 125, 254, 201, 44, 87, 177, 170, 93, 11, 193, 134, 18, 70, 9, 31, 234, 27, 170, 177, 54, 96, 181, 140, 166, 196, 144, 14, 230, 118, 106, 105}
+
+		// when
+		valid, err := w.VerifySignature(t.Context(), []byte("BRC-3 Compliance Validated!"), signature,
+			protocol("BRC3 Test"), "42", parseCounterparty(t, "0294c479f762f6baa97fbcd4393564c1d7bd8336ebd15928135bbcf575cd1a71a1"))
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Derive BRC-42 private key vectors", func(t *testing.T) {
+		// given
+		var vectors []struct {
+			SenderPublicKey     string `json:"senderPublicKey"`
+			RecipientPrivateKey string `json:"recipientPrivateKey"`
+			InvoiceNumber       string `json:"invoiceNumber"`
+			PrivateKey          string `json:"privateKey"`
+		}
+		readVectors(t, "BRC42.private.vectors.json", &vectors)
+
+		for _, vector := range vectors {
+			senderKey, err := ec.PublicKeyFromString(vector.SenderPublicKey)
+			require.NoError(t, err)
+			recipientKey, err := ec.PrivateKeyFromHex(vector.RecipientPrivateKey)
+			require.NoError(t, err)
+
+			// when
+			derived, err := recipientKey.DeriveChild(senderKey, vector.InvoiceNumber)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, vector.PrivateKey, hex.EncodeToString(derived.Serialize()))
+		}
+	})
+
+	t.Run("Derive BRC-42 public key vectors", func(t *testing.T) {
+		// given
+		var vectors []struct {
+			SenderPrivateKey   string `json:"senderPrivateKey"`
+			RecipientPublicKey string `json:"recipientPublicKey"`
+			InvoiceNumber      string `json:"invoiceNumber"`
+			PublicKey          string `json:"publicKey"`
+		}
+		readVectors(t, "BRC42.public.vectors.json", &vectors)
+
+		for _, vector := range vectors {
+			senderKey, err := ec.PrivateKeyFromHex(vector.SenderPrivateKey)
+			require.NoError(t, err)
+			recipientKey, err := ec.PublicKeyFromString(vector.RecipientPublicKey)
+			require.NoError(t, err)
+
+			// when
+			derived, err := recipientKey.DeriveChild(senderKey, vector.InvoiceNumber)
+
+			// then
+			require.NoError(t, err)
+			require.Equal(t, vector.PublicKey, hex.EncodeToString(derived.Compressed()))
+		}
+	})
+}
+
+func TestKeyWallet_GetPublicKey(t *testing.T) {
+	t.Run("Identity key is the compressed root public key", func(t *testing.T) {
+		// given
+		key := newKey(t)
+		w := keywallet.NewKeyWallet(key)
+
+		// when
+		identityKey, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, hex.EncodeToString(key.PubKey().Compressed()), identityKey)
+		require.Len(t, identityKey, 66)
+	})
+
+	t.Run("Both peers derive the same key for each other", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		aliceIdentity := counterpartyOf(t, alice)
+		bobIdentity := counterpartyOf(t, bob)
+		protocolID := protocol("auth message signature")
+
+		// when
+		aliceOwnKey, err := alice.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{
+			ProtocolID: protocolID, KeyID: "1", Counterparty: bobIdentity, ForSelf: true,
+		})
+		require.NoError(t, err)
+		aliceKeyByBob, err := bob.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{
+			ProtocolID: protocolID, KeyID: "1", Counterparty: aliceIdentity,
+		})
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, aliceOwnKey, aliceKeyByBob)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		tests := map[string]struct {
+			options     wallet.GetPublicKeyOptions
+			expectedErr error
+		}{
+			"privileged": {
+				options:     wallet.GetPublicKeyOptions{IdentityKey: true, Privileged: true},
+				expectedErr: keywallet.ErrPrivilegedNotSupported,
+			},
+			"missing protocol": {
+				options:     wallet.GetPublicKeyOptions{KeyID: "1"},
+				expectedErr: wallet.ErrInvalidProtocol,
+			},
+			"security level out of range": {
+				options:     wallet.GetPublicKeyOptions{ProtocolID: wallet.Protocol{SecurityLevel: 3, Protocol: "auth message signature"}, KeyID: "1"},
+				expectedErr: wallet.ErrInvalidProtocol,
+			},
+			"short protocol name": {
+				options:     wallet.GetPublicKeyOptions{ProtocolID: protocol("auth"), KeyID: "1"},
+				expectedErr: wallet.ErrInvalidProtocol,
+			},
+			"protocol name with special characters": {
+				options:     wallet.GetPublicKeyOptions{ProtocolID: protocol("auth-message"), KeyID: "1"},
+				expectedErr: wallet.ErrInvalidProtocol,
+			},
+			"missing key ID": {
+				options:     wallet.GetPublicKeyOptions{ProtocolID: protocol("auth message signature")},
+				expectedErr: keywallet.ErrInvalidKeyID,
+			},
+			"invalid counterparty": {
+				options:     wallet.GetPublicKeyOptions{ProtocolID: protocol("auth message signature"), KeyID: "1", Counterparty: wallet.Counterparty{Type: wallet.CounterpartyTypeOther}},
+				expectedErr: wallet.ErrInvalidCounterparty,
+			},
+		}
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				_, err := w.GetPublicKey(t.Context(), test.options)
+
+				// then
+				require.ErrorIs(t, err, test.expectedErr)
+			})
+		}
+	})
+}
+
+func TestKeyWallet_Signatures(t *testing.T) {
+	t.Run("Peer verifies the signature", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		data := []byte("request payload")
+		protocolID := protocol("auth message signature")
+
+		// when
+		signature, err := alice.CreateSignature(t.Context(), data, protocolID, "nonce-1", counterpartyOf(t, bob), wallet.Privilege{})
+		require.NoError(t, err)
+		valid, err := bob.VerifySignature(t.Context(), data, signature, protocolID, "nonce-1", counterpartyOf(t, alice))
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Verify a batch of signatures", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		data := []byte("request payload")
+		protocolID := protocol("auth message signature")
+		signature, err := alice.CreateSignature(t.Context(), data, protocolID, "1", counterpartyOf(t, bob), wallet.Privilege{})
+		require.NoError(t, err)
+		item := wallet.VerifyItem{Data: data, Signature: signature, ProtocolID: protocolID, KeyID: "1", Counterparty: counterpartyOf(t, alice)}
+		tampered := item
+		tampered.Data = []byte("tampered payload")
+		invalidProtocol := item
+		invalidProtocol.ProtocolID = protocol("x")
+
+		// when
+		results, err := bob.VerifySignatures(t.Context(), []wallet.VerifyItem{item, tampered, invalidProtocol})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []bool{true, false, false}, results)
+	})
+
+	t.Run("Signature is verifiable with the derived public key alone", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bobKey := newKey(t)
+		bob := keywallet.NewKeyWallet(bobKey)
+		data := []byte("request payload")
+		protocolID := protocol("auth message signature")
+
+		// when
+		signature, err := alice.CreateSignature(t.Context(), data, protocolID, "1", counterpartyOf(t, bob), wallet.Privilege{})
+		require.NoError(t, err)
+		signingKey, err := bob.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{
+			ProtocolID: protocolID, KeyID: "1", Counterparty: counterpartyOf(t, alice),
+		})
+		require.NoError(t, err)
+
+		// then - this is the plain ECDSA check the TypeScript SDK verifier does
+		publicKey, err := ec.PublicKeyFromString(signingKey)
+		require.NoError(t, err)
+		parsed, err := ec.ParseDERSignature(signature)
+		require.NoError(t, err)
+		require.True(t, publicKey.Verify(data, parsed))
+	})
+
+	t.Run("Signatures are deterministic", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		first, err := w.CreateSignature(t.Context(), []byte("data"), protocol("auth message signature"), "1", wallet.Counterparty{}, wallet.Privilege{})
+		require.NoError(t, err)
+		second, err := w.CreateSignature(t.Context(), []byte("data"), protocol("auth message signature"), "1", wallet.Counterparty{}, wallet.Privilege{})
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, first, second)
+	})
+
+	t.Run("Reject tampered data, wrong key ID and wrong counterparty", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		mallory := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("auth message signature")
+		signature, err := alice.CreateSignature(t.Context(), []byte("data"), protocolID, "1", counterpartyOf(t, bob), wallet.Privilege{})
+		require.NoError(t, err)
+
+		// when
+		tampered, err := bob.VerifySignature(t.Context(), []byte("date"), signature, protocolID, "1", counterpartyOf(t, alice))
+		require.NoError(t, err)
+		wrongKeyID, err := bob.VerifySignature(t.Context(), []byte("data"), signature, protocolID, "2", counterpartyOf(t, alice))
+		require.NoError(t, err)
+		wrongCounterparty, err := bob.VerifySignature(t.Context(), []byte("data"), signature, protocolID, "1", counterpartyOf(t, mallory))
+		require.NoError(t, err)
+		malformed, err := bob.VerifySignature(t.Context(), []byte("data"), []byte("invalid-signature"), protocolID, "1", counterpartyOf(t, alice))
+		require.NoError(t, err)
+
+		// then
+		require.False(t, tampered)
+		require.False(t, wrongKeyID)
+		require.False(t, wrongCounterparty)
+		require.False(t, malformed)
+	})
+
+	t.Run("Reject empty data", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		_, err := w.CreateSignature(t.Context(), nil, protocol("auth message signature"), "1", wallet.Counterparty{}, wallet.Privilege{})
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrEmptyData)
+	})
+
+	t.Run("Reject a privileged signature", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		privilege := wallet.Privilege{Privileged: true, Reason: "sign the certificate request"}
+
+		// when
+		_, err := w.CreateSignature(t.Context(), []byte("data"), protocol("auth message signature"), "1", wallet.Counterparty{}, privilege)
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrPrivilegedNotSupported)
+	})
+
+	t.Run("Cancelled context", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+
+		// when
+		_, err := w.CreateSignature(ctx, []byte("data"), protocol("auth message signature"), "1", wallet.Counterparty{}, wallet.Privilege{})
+
+		// then
+		require.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestKeyWallet_HMAC(t *testing.T) {
+	t.Run("Peer verifies the HMAC", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		data := []byte("request payload")
+		protocolID := protocol("auth message hmac")
+
+		// when
+		hmac, err := alice.CreateHMAC(t.Context(), data, protocolID, "1", counterpartyOf(t, bob))
+		require.NoError(t, err)
+		valid, err := bob.VerifyHMAC(t.Context(), data, hmac, protocolID, "1", counterpartyOf(t, alice))
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+		require.Len(t, hmac, 32)
+	})
+
+	t.Run("Wallet verifies its own HMAC", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("auth message hmac")
+
+		// when
+		hmac, err := w.CreateHMAC(t.Context(), []byte("data"), protocolID, "1", wallet.Counterparty{})
+		require.NoError(t, err)
+		valid, err := w.VerifyHMAC(t.Context(), []byte("data"), hmac, protocolID, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Reject tampered data, wrong key ID and wrong counterparty", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		mallory := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("auth message hmac")
+		hmac, err := alice.CreateHMAC(t.Context(), []byte("data"), protocolID, "1", counterpartyOf(t, bob))
+		require.NoError(t, err)
+
+		// when
+		tampered, err := bob.VerifyHMAC(t.Context(), []byte("date"), hmac, protocolID, "1", counterpartyOf(t, alice))
+		require.NoError(t, err)
+		wrongKeyID, err := bob.VerifyHMAC(t.Context(), []byte("data"), hmac, protocolID, "2", counterpartyOf(t, alice))
+		require.NoError(t, err)
+		wrongCounterparty, err := bob.VerifyHMAC(t.Context(), []byte("data"), hmac, protocolID, "1", counterpartyOf(t, mallory))
+		require.NoError(t, err)
+		truncated, err := bob.VerifyHMAC(t.Context(), []byte("data"), hmac[:16], protocolID, "1", counterpartyOf(t, alice))
+		require.NoError(t, err)
+
+		// then
+		require.False(t, tampered)
+		require.False(t, wrongKeyID)
+		require.False(t, wrongCounterparty)
+		require.False(t, truncated)
+	})
+
+	t.Run("Reject empty data and key ID", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("auth message hmac")
+
+		// when
+		_, emptyDataErr := w.CreateHMAC(t.Context(), nil, protocolID, "1", wallet.Counterparty{})
+		_, emptyKeyIDErr := w.VerifyHMAC(t.Context(), []byte("data"), []byte("hmac"), protocolID, "", wallet.Counterparty{})
+
+		// then
+		require.ErrorIs(t, emptyDataErr, keywallet.ErrEmptyData)
+		require.ErrorIs(t, emptyKeyIDErr, keywallet.ErrInvalidKeyID)
+	})
+}
+
+func TestKeyWallet_Encryption(t *testing.T) {
+	t.Run("Peer decrypts the ciphertext", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		plaintext := []byte("certificate field value")
+		protocolID := protocol("certificate field encryption")
+
+		// when
+		ciphertext, err := alice.Encrypt(t.Context(), plaintext, protocolID, "email", counterpartyOf(t, bob))
+		require.NoError(t, err)
+		decrypted, err := bob.Decrypt(t.Context(), ciphertext, protocolID, "email", counterpartyOf(t, alice))
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+		require.NotContains(t, string(ciphertext), string(plaintext))
+	})
+
+	t.Run("Wallet decrypts its own ciphertext", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("certificate field encryption")
+
+		// when
+		ciphertext, err := w.Encrypt(t.Context(), []byte("data"), protocolID, "1", wallet.Counterparty{})
+		require.NoError(t, err)
+		decrypted, err := w.Decrypt(t.Context(), ciphertext, protocolID, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []byte("data"), decrypted)
+	})
+
+	t.Run("Ciphertexts are randomized", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("certificate field encryption")
+
+		// when
+		first, err := w.Encrypt(t.Context(), []byte("data"), protocolID, "1", wallet.Counterparty{})
+		require.NoError(t, err)
+		second, err := w.Encrypt(t.Context(), []byte("data"), protocolID, "1", wallet.Counterparty{})
+		require.NoError(t, err)
+
+		// then
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("Reject wrong counterparty, wrong key ID and tampered ciphertext", func(t *testing.T) {
+		// given
+		alice := keywallet.NewKeyWallet(newKey(t))
+		bob := keywallet.NewKeyWallet(newKey(t))
+		mallory := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("certificate field encryption")
+		ciphertext, err := alice.Encrypt(t.Context(), []byte("data"), protocolID, "1", counterpartyOf(t, bob))
+		require.NoError(t, err)
+		tampered := append([]byte{}, ciphertext...)
+		tampered[len(tampered)-1] ^= 0xff
+
+		// when
+		_, wrongCounterpartyErr := bob.Decrypt(t.Context(), ciphertext, protocolID, "1", counterpartyOf(t, mallory))
+		_, wrongKeyIDErr := bob.Decrypt(t.Context(), ciphertext, protocolID, "2", counterpartyOf(t, alice))
+		_, tamperedErr := bob.Decrypt(t.Context(), tampered, protocolID, "1", counterpartyOf(t, alice))
+
+		// then
+		require.ErrorIs(t, wrongCounterpartyErr, keywallet.ErrDecryptionFailed)
+		require.ErrorIs(t, wrongKeyIDErr, keywallet.ErrDecryptionFailed)
+		require.ErrorIs(t, tamperedErr, keywallet.ErrDecryptionFailed)
+	})
+
+	t.Run("Reject empty and truncated ciphertext", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		protocolID := protocol("certificate field encryption")
+		ciphertext, err := w.Encrypt(t.Context(), []byte("data"), protocolID, "1", wallet.Counterparty{})
+		require.NoError(t, err)
+
+		// when
+		_, emptyErr := w.Decrypt(t.Context(), nil, protocolID, "1", wallet.Counterparty{})
+		_, truncatedErr := w.Decrypt(t.Context(), ciphertext[:40], protocolID, "1", wallet.Counterparty{})
+
+		// then
+		require.ErrorIs(t, emptyErr, keywallet.ErrInvalidCiphertext)
+		require.ErrorIs(t, truncatedErr, keywallet.ErrInvalidCiphertext)
+	})
+}
+
+func TestKeyWallet_Nonces(t *testing.T) {
+	t.Run("Verify nonce across wallets with the same key", func(t *testing.T) {
+		// given
+		key := newKey(t)
+		w := keywallet.NewKeyWallet(key)
+		replica := keywallet.NewKeyWallet(key)
+
+		// when
+		first, err := w.CreateNonce(t.Context())
+		require.NoError(t, err)
+		second, err := w.CreateNonce(t.Context())
+		require.NoError(t, err)
+
+		// then
+		require.NotEqual(t, first, second)
+		valid, err := w.VerifyNonce(t.Context(), first)
+		require.NoError(t, err)
+		require.True(t, valid)
+		valid, err = replica.VerifyNonce(t.Context(), second)
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Reject replayed nonce", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		value, err := w.CreateNonce(t.Context())
+		require.NoError(t, err)
+		_, err = w.VerifyNonce(t.Context(), value)
+		require.NoError(t, err)
+
+		// when
+		valid, err := w.VerifyNonce(t.Context(), value)
+
+		// then
+		require.ErrorIs(t, err, nonce.ErrAlreadyUsed)
+		require.False(t, valid)
+	})
+
+	t.Run("Apply nonce options", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t), keywallet.WithNonceOptions(nonce.WithReusableNonces()))
+		value, err := w.CreateNonce(t.Context())
+		require.NoError(t, err)
+		_, err = w.VerifyNonce(t.Context(), value)
+		require.NoError(t, err)
+
+		// when
+		valid, err := w.VerifyNonce(t.Context(), value)
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Reject nonce of another wallet", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		other := keywallet.NewKeyWallet(newKey(t))
+		value, err := other.CreateNonce(t.Context())
+		require.NoError(t, err)
+
+		// when
+		valid, err := w.VerifyNonce(t.Context(), value)
+
+		// then
+		require.ErrorIs(t, err, nonce.ErrInvalidHMAC)
+		require.False(t, valid)
+	})
+}
+
+func TestKeyWallet_Certificates(t *testing.T) {
+	t.Run("List acquired certificates", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+		email := wallet.Certificate{Type: "email", SerialNumber: "1", Certifier: "certifier"}
+		phone := wallet.Certificate{Type: "phone", SerialNumber: "2", Certifier: "certifier"}
+		require.NoError(t, w.AcquireCertificate(t.Context(), email))
+		require.NoError(t, w.AcquireCertificate(t.Context(), phone))
+
+		// when
+		require.NoError(t, w.RelinquishCertificate(t.Context(), "email", "1", "certifier"))
+		result, err := w.ListCertificates(t.Context(), wallet.ListCertificatesOptions{})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []wallet.Certificate{phone}, result.Certificates)
+		require.Equal(t, 1, result.TotalCount)
+	})
+
+	t.Run("Reject invalid and unknown certificates", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		acquireErr := w.AcquireCertificate(t.Context(), wallet.Certificate{Type: "email"})
+		relinquishErr := w.RelinquishCertificate(t.Context(), "email", "1", "certifier")
+
+		// then
+		require.ErrorIs(t, acquireErr, keywallet.ErrInvalidCertificate)
+		require.ErrorIs(t, relinquishErr, wallet.ErrCertificateNotFound)
+	})
+}
+
+func TestKeyWallet_ProveCertificate(t *testing.T) {
+	protocolID := protocol("certificate field encryption")
+
+	// newCertificate issues a certificate with the master keyring the certifier encrypted to the subject
+	newCertificate := func(t *testing.T, certifier, subject *keywallet.Wallet, fieldKeys map[string][]byte) wallet.Certificate {
+		certificate := wallet.Certificate{
+			SerialNumber: "c2VyaWFsLW51bWJlcg==",
+			Subject:      identityKeyOf(t, subject),
+			Certifier:    identityKeyOf(t, certifier),
+			Fields:       map[string]any{},
+			Keyring:      map[string]string{},
+		}
+		for fieldName, fieldKey := range fieldKeys {
+			encrypted, err := certifier.Encrypt(t.Context(), fieldKey, protocolID, fieldName, counterpartyOf(t, subject))
+			require.NoError(t, err)
+			certificate.Fields[fieldName] = "encrypted " + fieldName
+			certificate.Keyring[fieldName] = base64.StdEncoding.EncodeToString(encrypted)
+		}
+		return certificate
+	}
+
+	t.Run("Verifier decrypts the revealed field keys", func(t *testing.T) {
+		// given
+		certifier := keywallet.NewKeyWallet(newKey(t))
+		subject := keywallet.NewKeyWallet(newKey(t))
+		verifier := keywallet.NewKeyWallet(newKey(t))
+		certificate := newCertificate(t, certifier, subject, map[string][]byte{
+			"email": []byte("email field key"),
+			"name":  []byte("name field key"),
+		})
+
+		// when
+		keyring, err := subject.ProveCertificate(t.Context(), certificate, identityKeyOf(t, verifier), []string{"email"}, wallet.Privilege{})
+
+		// then
+		require.NoError(t, err)
+		require.Len(t, keyring, 1)
+		encrypted, err := base64.StdEncoding.DecodeString(keyring["email"])
+		require.NoError(t, err)
+		fieldKey, err := verifier.Decrypt(t.Context(), encrypted, protocolID, certificate.SerialNumber+" email", counterpartyOf(t, subject))
+		require.NoError(t, err)
+		require.Equal(t, []byte("email field key"), fieldKey)
+	})
+
+	t.Run("Return empty keyring for no fields", func(t *testing.T) {
+		// given
+		certifier := keywallet.NewKeyWallet(newKey(t))
+		subject := keywallet.NewKeyWallet(newKey(t))
+		certificate := newCertificate(t, certifier, subject, map[string][]byte{"email": []byte("email field key")})
+
+		// when
+		keyring, err := subject.ProveCertificate(t.Context(), certificate, identityKeyOf(t, certifier), nil, wallet.Privilege{})
+
+		// then
+		require.NoError(t, err)
+		require.NotNil(t, keyring)
+		require.Empty(t, keyring)
+	})
+
+	t.Run("Reject unknown field", func(t *testing.T) {
+		// given
+		certifier := keywallet.NewKeyWallet(newKey(t))
+		subject := keywallet.NewKeyWallet(newKey(t))
+		certificate := newCertificate(t, certifier, subject, map[string][]byte{"email": []byte("email field key")})
+
+		// when
+		_, err := subject.ProveCertificate(t.Context(), certificate, identityKeyOf(t, certifier), []string{"email", "phone"}, wallet.Privilege{})
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrUnknownCertificateField)
+	})
+
+	t.Run("Reject keyring of another subject", func(t *testing.T) {
+		// given
+		certifier := keywallet.NewKeyWallet(newKey(t))
+		subject := keywallet.NewKeyWallet(newKey(t))
+		other := keywallet.NewKeyWallet(newKey(t))
+		certificate := newCertificate(t, certifier, subject, map[string][]byte{"email": []byte("email field key")})
+
+		// when
+		_, err := other.ProveCertificate(t.Context(), certificate, identityKeyOf(t, certifier), []string{"email"}, wallet.Privilege{})
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrInvalidKeyring)
+	})
+}
+
+func TestKeyWallet_NetworkVersionHeight(t *testing.T) {
+	t.Run("Defaults to mainnet", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		network, err := w.GetNetwork(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, wallet.NetworkMainnet, network)
+	})
+
+	t.Run("Configured network", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t), keywallet.WithNetwork(wallet.NetworkTestnet))
+
+		// when
+		network, err := w.GetNetwork(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, wallet.NetworkTestnet, network)
+	})
+
+	t.Run("Version", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		version, err := w.GetVersion(t.Context())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, keywallet.Version, version)
+	})
+
+	t.Run("Height without chain access", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		_, err := w.GetHeight(t.Context())
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrChainNotAvailable)
+	})
+}
+
+func TestKeyWallet_Actions(t *testing.T) {
+	t.Run("Create action", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		_, err := w.CreateAction(t.Context(), wallet.CreateActionArgs{})
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrActionsNotSupported)
+	})
+
+	t.Run("Internalize action", func(t *testing.T) {
+		// given
+		w := keywallet.NewKeyWallet(newKey(t))
+
+		// when
+		_, err := w.InternalizeAction(t.Context(), wallet.InternalizeActionArgs{})
+
+		// then
+		require.ErrorIs(t, err, keywallet.ErrActionsNotSupported)
+	})
+}
+
+func TestKeyWallet_RevealKeyLinkage(t *testing.T) {
+	aliceKey, bobKey := newKey(t), newKey(t)
+	alice := keywallet.NewKeyWallet(aliceKey)
+	bob := keywallet.NewKeyWallet(bobKey)
+	verifier := keywallet.NewKeyWallet(newKey(t))
+	protocolID := protocol("auth message signature")
+
+	t.Run("Reveal the counterparty linkage to the verifier", func(t *testing.T) {
+		// when
+		result, err := alice.RevealCounterpartyKeyLinkage(t.Context(), identityKeyOf(t, bob), identityKeyOf(t, verifier))
+		require.NoError(t, err)
+		linkage, err := verifier.Decrypt(t.Context(), result.EncryptedLinkage, wallet.CounterpartyLinkageProtocol, result.RevelationTime, counterpartyOf(t, alice))
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, identityKeyOf(t, alice), result.Prover)
+		require.Equal(t, identityKeyOf(t, verifier), result.Verifier)
+		require.Equal(t, identityKeyOf(t, bob), result.Counterparty)
+		require.Equal(t, wallet.KeyLinkageProofNone, result.ProofType)
+		sharedSecret, err := bobKey.DeriveSharedSecret(aliceKey.PubKey())
+		require.NoError(t, err)
+		require.Equal(t, sharedSecret.Compressed(), linkage)
+	})
+
+	t.Run("Reveal the specific linkage to the verifier", func(t *testing.T) {
+		// when
+		result, err := alice.RevealSpecificKeyLinkage(t.Context(), identityKeyOf(t, bob), identityKeyOf(t, verifier), protocolID, "1")
+		require.NoError(t, err)
+		linkage, err := verifier.Decrypt(t.Context(), result.EncryptedLinkage, wallet.SpecificLinkageProtocol(protocolID), "1", counterpartyOf(t, alice))
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, protocolID, result.ProtocolID)
+		require.Equal(t, "1", result.KeyID)
+		// the linkage is the offset of alice's derived key from her identity key
+		offset, _ := ec.PrivateKeyFromBytes(linkage)
+		x, y := ec.S256().Add(aliceKey.PubKey().X, aliceKey.PubKey().Y, offset.PubKey().X, offset.PubKey().Y)
+		derivedKey, err := alice.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{ProtocolID: protocolID, KeyID: "1", Counterparty: counterpartyOf(t, bob), ForSelf: true})
+		require.NoError(t, err)
+		require.Equal(t, derivedKey, hex.EncodeToString((&ec.PublicKey{Curve: ec.S256(), X: x, Y: y}).Compressed()))
+	})
+
+	t.Run("Reject invalid parameters", func(t *testing.T) {
+		tests := map[string]struct {
+			counterparty string
+			verifier     string
+		}{
+			"self counterparty": {
+				counterparty: "self",
+				verifier:     identityKeyOf(t, verifier),
+			},
+			"own identity key as counterparty": {
+				counterparty: identityKeyOf(t, alice),
+				verifier:     identityKeyOf(t, verifier),
+			},
+			"missing counterparty": {
+				verifier: identityKeyOf(t, verifier),
+			},
+			"invalid verifier": {
+				counterparty: identityKeyOf(t, bob),
+				verifier:     "verifier",
+			},
+		}
+
+		for name, test := range tests {
+			t.Run(name, func(t *testing.T) {
+				// when
+				_, counterpartyErr := alice.RevealCounterpartyKeyLinkage(t.Context(), test.counterparty, test.verifier)
+				_, specificErr := alice.RevealSpecificKeyLinkage(t.Context(), test.counterparty, test.verifier, protocolID, "1")
+
+				// then
+				require.ErrorIs(t, counterpartyErr, wallet.ErrInvalidCounterparty)
+				require.ErrorIs(t, specificErr, wallet.ErrInvalidCounterparty)
+			})
+		}
+	})
+
+	t.Run("Reject an invalid protocol of the specific linkage", func(t *testing.T) {
+		// when
+		_, err := alice.RevealSpecificKeyLinkage(t.Context(), identityKeyOf(t, bob), identityKeyOf(t, verifier), protocol("auth"), "1")
+
+		// then
+		require.ErrorIs(t, err, wallet.ErrInvalidProtocol)
+	})
+}
+
+func counterpartyOf(t *testing.T, w *keywallet.Wallet) wallet.Counterparty {
+	return parseCounterparty(t, identityKeyOf(t, w))
+}
+
+func parseCounterparty(t *testing.T, counterparty string) wallet.Counterparty {
+	parsed, err := wallet.ParseCounterparty(counterparty)
+	require.NoError(t, err)
+	return parsed
+}
+
+func identityKeyOf(t *testing.T, w *keywallet.Wallet) string {
+	identityKey, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+	require.NoError(t, err)
+	return identityKey
+}
+
+func readVectors(t *testing.T, name string, vectors any) {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, vectors))
+}
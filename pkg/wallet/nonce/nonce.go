@@ -0,0 +1,178 @@
+package nonce
+
+import (
+	"container/heap"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+var (
+	// ErrInvalidEncoding is returned by Verify when the nonce isn't base64 encoded.
+	ErrInvalidEncoding = errors.New("nonce is not base64 encoded")
+	// ErrInvalidLength is returned by Verify when the nonce is truncated or has trailing data.
+	ErrInvalidLength = errors.New("invalid nonce length")
+	// ErrInvalidHMAC is returned by Verify when the nonce wasn't created with the wallet's key for the counterparty.
+	ErrInvalidHMAC = errors.New("nonce HMAC doesn't match")
+	// ErrExpired is returned by Verify when the nonce is older than the max age.
+	ErrExpired = errors.New("nonce expired")
+	// ErrAlreadyUsed is returned by Verify when a single-use nonce was already verified.
+	ErrAlreadyUsed = errors.New("nonce already used")
+)
+
+// DefaultMaxAge is the default time window in which a nonce can be verified.
+const DefaultMaxAge = 5 * time.Minute
+
+const (
+	timestampSize = 8
+	randomSize    = 16
+	dataSize      = timestampSize + randomSize
+	hmacSize      = 32
+	nonceSize     = dataSize + hmacSize
+)
+
+// protocolName is the protocol of the key the nonces are authenticated with, the same one the TypeScript SDK uses.
+const protocolName = "server hmac"
+
+// HMACWallet is the part of the wallet.Interface needed to create and verify nonces.
+type HMACWallet interface {
+	CreateHMAC(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error)
+	VerifyHMAC(ctx context.Context, data []byte, hmac []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error)
+}
+
+// Manager creates BRC-31 style nonces authenticated with an HMAC of a wallet key,
+// so they can be verified by any wallet with the same key without storing them, e.g. by other replicas.
+//
+// The nonce is the creation time and 16 random bytes, followed by their HMAC, base64 encoded.
+// By default a nonce can be verified only once and only within DefaultMaxAge of its creation.
+// The used nonces are remembered by the Manager until they expire, so replays are rejected per process only.
+type Manager struct {
+	wallet       HMACWallet
+	counterparty wallet.Counterparty
+	maxAge       time.Duration
+	reusable     bool
+	now          func() time.Time
+
+	mu   sync.Mutex
+	used map[string]time.Time
+	// expiries holds the used nonces by their expiration, the earliest first, so the expired ones are forgotten without a scan
+	expiries usedNonces
+}
+
+// NewManager creates a Manager authenticating the nonces with the wallet's key for the "self" counterparty.
+func NewManager(w HMACWallet, opts ...Option) *Manager {
+	m := &Manager{
+		wallet:       w,
+		counterparty: wallet.CounterpartySelf(),
+		maxAge:       DefaultMaxAge,
+		now:          time.Now,
+		used:         make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Create creates a new nonce.
+func (m *Manager) Create(ctx context.Context) (string, error) {
+	data := make([]byte, dataSize)
+	binary.BigEndian.PutUint64(data[:timestampSize], uint64(m.now().UnixMilli()))
+	if _, err := rand.Read(data[timestampSize:]); err != nil {
+		return "", fmt.Errorf("failed to create nonce: %w", err)
+	}
+
+	hmac, err := m.wallet.CreateHMAC(ctx, data, protocolID(), string(data), m.counterparty)
+	if err != nil {
+		return "", fmt.Errorf("failed to create nonce HMAC: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(append(data, hmac...)), nil
+}
+
+// Verify checks that the nonce was created by a Manager with the same wallet key and counterparty,
+// it isn't expired and, unless WithReusableNonces is set, it wasn't verified before.
+// A successfully verified single-use nonce is consumed, so concurrent verifications of the same nonce succeed only once.
+func (m *Manager) Verify(ctx context.Context, nonce string) error {
+	decoded, err := base64.StdEncoding.Strict().DecodeString(nonce)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEncoding, err)
+	}
+	if len(decoded) != nonceSize {
+		return fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidLength, nonceSize, len(decoded))
+	}
+
+	data, hmac := decoded[:dataSize], decoded[dataSize:]
+	valid, err := m.wallet.VerifyHMAC(ctx, data, hmac, protocolID(), string(data), m.counterparty)
+	if err != nil {
+		return fmt.Errorf("failed to verify nonce HMAC: %w", err)
+	}
+	if !valid {
+		return ErrInvalidHMAC
+	}
+
+	now := m.now()
+	expiresAt := time.UnixMilli(int64(binary.BigEndian.Uint64(data[:timestampSize]))).Add(m.maxAge)
+	if m.maxAge > 0 && now.After(expiresAt) {
+		return ErrExpired
+	}
+
+	if m.reusable {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, used := m.used[string(data)]; used {
+		return ErrAlreadyUsed
+	}
+	m.pruneUsed(now)
+	m.used[string(data)] = expiresAt
+	if m.maxAge > 0 {
+		heap.Push(&m.expiries, usedNonce{data: string(data), expiresAt: expiresAt})
+	}
+	return nil
+}
+
+// pruneUsed forgets the used nonces which already expired, they are rejected by the expiration check instead.
+// Without a max age the nonces never expire and are remembered forever.
+func (m *Manager) pruneUsed(now time.Time) {
+	for m.expiries.Len() > 0 && now.After(m.expiries[0].expiresAt) {
+		expired := heap.Pop(&m.expiries).(usedNonce)
+		delete(m.used, expired.data)
+	}
+}
+
+type usedNonce struct {
+	data      string
+	expiresAt time.Time
+}
+
+// usedNonces is a heap.Interface of the used nonces ordered by their expiration.
+type usedNonces []usedNonce
+
+func (h usedNonces) Len() int           { return len(h) }
+func (h usedNonces) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h usedNonces) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *usedNonces) Push(x any) {
+	*h = append(*h, x.(usedNonce))
+}
+
+func (h *usedNonces) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+func protocolID() wallet.Protocol {
+	return wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: protocolName}
+}
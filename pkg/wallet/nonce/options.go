@@ -0,0 +1,39 @@
+package nonce
+
+import (
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// Option configures the Manager.
+type Option func(*Manager)
+
+// WithMaxAge overrides the time window in which a nonce can be verified, zero disables the expiration.
+// Without the expiration the used single-use nonces are remembered forever.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(m *Manager) {
+		m.maxAge = maxAge
+	}
+}
+
+// WithReusableNonces allows verifying the same nonce any number of times within its max age.
+func WithReusableNonces() Option {
+	return func(m *Manager) {
+		m.reusable = true
+	}
+}
+
+// WithCounterparty overrides the counterparty of the key the nonces are authenticated with.
+func WithCounterparty(counterparty wallet.Counterparty) Option {
+	return func(m *Manager) {
+		m.counterparty = counterparty
+	}
+}
+
+// WithClock overrides the clock used by the Manager, it's mostly useful for testing.
+func WithClock(now func() time.Time) Option {
+	return func(m *Manager) {
+		m.now = now
+	}
+}
@@ -0,0 +1,227 @@
+package nonce_test
+
+import (
+	"encoding/base64"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_CreateAndVerify(t *testing.T) {
+	t.Run("Verify nonce created by another replica with the same key", func(t *testing.T) {
+		// given
+		key := newKey(t)
+		replica1 := nonce.NewManager(keywallet.NewKeyWallet(key))
+		replica2 := nonce.NewManager(keywallet.NewKeyWallet(key))
+
+		// when
+		value, err := replica1.Create(t.Context())
+		require.NoError(t, err)
+		err = replica2.Verify(t.Context(), value)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("Nonces are random", func(t *testing.T) {
+		// given
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)))
+
+		// when
+		first, err := m.Create(t.Context())
+		require.NoError(t, err)
+		second, err := m.Create(t.Context())
+		require.NoError(t, err)
+
+		// then
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("Reject nonce verified twice", func(t *testing.T) {
+		// given
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)))
+		value, err := m.Create(t.Context())
+		require.NoError(t, err)
+
+		// when
+		firstErr := m.Verify(t.Context(), value)
+		secondErr := m.Verify(t.Context(), value)
+
+		// then
+		require.NoError(t, firstErr)
+		require.ErrorIs(t, secondErr, nonce.ErrAlreadyUsed)
+	})
+
+	t.Run("Verify reusable nonce twice", func(t *testing.T) {
+		// given
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)), nonce.WithReusableNonces())
+		value, err := m.Create(t.Context())
+		require.NoError(t, err)
+
+		// when
+		firstErr := m.Verify(t.Context(), value)
+		secondErr := m.Verify(t.Context(), value)
+
+		// then
+		require.NoError(t, firstErr)
+		require.NoError(t, secondErr)
+	})
+
+	t.Run("Reject expired nonce", func(t *testing.T) {
+		// given
+		now := time.Now()
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)), nonce.WithMaxAge(time.Minute), nonce.WithClock(func() time.Time { return now }))
+		expired, err := m.Create(t.Context())
+		require.NoError(t, err)
+		now = now.Add(30 * time.Second)
+		fresh, err := m.Create(t.Context())
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(31 * time.Second)
+		expiredErr := m.Verify(t.Context(), expired)
+		freshErr := m.Verify(t.Context(), fresh)
+
+		// then
+		require.ErrorIs(t, expiredErr, nonce.ErrExpired)
+		require.NoError(t, freshErr)
+	})
+
+	t.Run("Keep rejecting a used nonce after the older used ones expire", func(t *testing.T) {
+		// given
+		now := time.Now()
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)), nonce.WithMaxAge(time.Minute), nonce.WithClock(func() time.Time { return now }))
+		older, err := m.Create(t.Context())
+		require.NoError(t, err)
+		now = now.Add(30 * time.Second)
+		newer, err := m.Create(t.Context())
+		require.NoError(t, err)
+		require.NoError(t, m.Verify(t.Context(), newer))
+		require.NoError(t, m.Verify(t.Context(), older))
+
+		// when
+		now = now.Add(31 * time.Second)
+		third, err := m.Create(t.Context())
+		require.NoError(t, err)
+		thirdErr := m.Verify(t.Context(), third)
+		replayErr := m.Verify(t.Context(), newer)
+
+		// then
+		require.NoError(t, thirdErr)
+		require.ErrorIs(t, replayErr, nonce.ErrAlreadyUsed)
+	})
+
+	t.Run("Verify old nonce without expiration", func(t *testing.T) {
+		// given
+		now := time.Now()
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)), nonce.WithMaxAge(0), nonce.WithClock(func() time.Time { return now }))
+		value, err := m.Create(t.Context())
+		require.NoError(t, err)
+
+		// when
+		now = now.Add(24 * time.Hour)
+		err = m.Verify(t.Context(), value)
+
+		// then
+		require.NoError(t, err)
+	})
+
+	t.Run("Accept concurrently double-spent nonce only once", func(t *testing.T) {
+		// given
+		m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)))
+		value, err := m.Create(t.Context())
+		require.NoError(t, err)
+
+		// when
+		errs := make([]error, 2)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = m.Verify(t.Context(), value)
+			}()
+		}
+		wg.Wait()
+
+		// then
+		if errs[0] == nil {
+			require.ErrorIs(t, errs[1], nonce.ErrAlreadyUsed)
+		} else {
+			require.ErrorIs(t, errs[0], nonce.ErrAlreadyUsed)
+			require.NoError(t, errs[1])
+		}
+	})
+}
+
+func TestManager_Verify_UnhappyPath(t *testing.T) {
+	m := nonce.NewManager(keywallet.NewKeyWallet(newKey(t)))
+	valid, err := m.Create(t.Context())
+	require.NoError(t, err)
+	decoded, err := base64.StdEncoding.DecodeString(valid)
+	require.NoError(t, err)
+	tampered := append([]byte{}, decoded...)
+	tampered[len(tampered)-33] ^= 0xff
+
+	tests := map[string]struct {
+		nonce         string
+		expectedError error
+	}{
+		"created with a different key": {
+			nonce:         createNonce(t, nonce.NewManager(keywallet.NewKeyWallet(newKey(t)))),
+			expectedError: nonce.ErrInvalidHMAC,
+		},
+		"created for a different counterparty": {
+			nonce:         createNonce(t, nonce.NewManager(keywallet.NewKeyWallet(newKey(t)), nonce.WithCounterparty(wallet.CounterpartyAnyone()))),
+			expectedError: nonce.ErrInvalidHMAC,
+		},
+		"tampered random bytes": {
+			nonce:         base64.StdEncoding.EncodeToString(tampered),
+			expectedError: nonce.ErrInvalidHMAC,
+		},
+		"truncated": {
+			nonce:         base64.StdEncoding.EncodeToString(decoded[:40]),
+			expectedError: nonce.ErrInvalidLength,
+		},
+		"trailing data": {
+			nonce:         base64.StdEncoding.EncodeToString(append(decoded, 0)),
+			expectedError: nonce.ErrInvalidLength,
+		},
+		"empty": {
+			nonce:         "",
+			expectedError: nonce.ErrInvalidLength,
+		},
+		"not base64": {
+			nonce:         "not a base64 nonce!",
+			expectedError: nonce.ErrInvalidEncoding,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			// when
+			err := m.Verify(t.Context(), tc.nonce)
+
+			// then
+			require.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func createNonce(t *testing.T, m *nonce.Manager) string {
+	value, err := m.Create(t.Context())
+	require.NoError(t, err)
+	return value
+}
+
+func newKey(t *testing.T) *ec.PrivateKey {
+	key, err := ec.NewPrivateKey()
+	require.NoError(t, err)
+	return key
+}
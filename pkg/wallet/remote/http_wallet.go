@@ -0,0 +1,409 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/internal/batch"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+)
+
+var (
+	// ErrTransport is returned when the wallet daemon can't be reached or the response can't be read.
+	// It's transient, the wallet.Resilient retries the reads failed with it.
+	ErrTransport error = transportError("wallet transport failed")
+	// ErrMalformedResponse is returned when the wallet daemon responds with an unexpected body.
+	ErrMalformedResponse = errors.New("malformed wallet response")
+)
+
+// DefaultTimeout is the default timeout of a single call to the wallet daemon.
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseSize limits the size of the responses read from the wallet daemon.
+const maxResponseSize = 10 << 20
+
+// ResponseError is returned when the wallet daemon responds with a non-2xx status.
+type ResponseError struct {
+	// Call is the name of the wallet call, e.g. "createSignature"
+	Call string
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+	// Message is the error message sent by the wallet daemon, if any
+	Message string
+}
+
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("wallet call %s failed with status %d", e.Call, e.StatusCode)
+	}
+	return fmt.Sprintf("wallet call %s failed with status %d: %s", e.Call, e.StatusCode, e.Message)
+}
+
+// Transient reports whether the status tells the call may succeed when retried: the daemon is unavailable, overloaded
+// or behind a failing gateway. The 500s, which the daemon answers the failed calls with, aren't transient.
+func (e *ResponseError) Transient() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+var _ wallet.Interface = (*HTTPWallet)(nil)
+
+// HTTPWallet is a wallet.Interface implementation proxying the calls to a wallet daemon
+// speaking the HTTP JSON wallet substrate of the TypeScript SDK: every call is a POST to <baseURL>/<call>
+// with the JSON arguments in the body, and binary data encoded as arrays of numbers.
+//
+// The substrate has no nonce calls, so the nonces are created and verified locally,
+// authenticated with HMACs created by the wallet daemon, see nonce.Manager.
+type HTTPWallet struct {
+	baseURL       string
+	client        *http.Client
+	timeout       time.Duration
+	originator    string
+	verifyWorkers int
+	nonces        *nonce.Manager
+}
+
+// NewHTTPWallet creates a wallet proxying the calls to the wallet daemon at the baseURL.
+func NewHTTPWallet(baseURL string, opts ...Option) *HTTPWallet {
+	w := &HTTPWallet{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		client:        http.DefaultClient,
+		timeout:       DefaultTimeout,
+		verifyWorkers: batch.DefaultWorkers,
+	}
+	var nonceOptions []nonce.Option
+	for _, opt := range opts {
+		opt(w, &nonceOptions)
+	}
+	w.nonces = nonce.NewManager(w, nonceOptions...)
+	return w
+}
+
+// GetPublicKey calls getPublicKey.
+func (w *HTTPWallet) GetPublicKey(ctx context.Context, options wallet.GetPublicKeyOptions) (string, error) {
+	var result getPublicKeyResult
+	if err := w.call(ctx, "getPublicKey", options, &result); err != nil {
+		return "", err
+	}
+	return result.PublicKey, nil
+}
+
+// CreateSignature calls createSignature.
+func (w *HTTPWallet) CreateSignature(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty, privilege wallet.Privilege) ([]byte, error) {
+	var result createSignatureResult
+	args := createSignatureArgs{
+		Data:             data,
+		ProtocolID:       protocolID,
+		KeyID:            keyID,
+		Counterparty:     counterparty,
+		Privileged:       privilege.Privileged,
+		PrivilegedReason: privilege.Reason,
+	}
+	if err := w.call(ctx, "createSignature", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Signature, nil
+}
+
+// VerifySignature calls verifySignature.
+func (w *HTTPWallet) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	var result validResult
+	args := verifySignatureArgs{Data: data, Signature: signature, ProtocolID: protocolID, KeyID: keyID, Counterparty: counterparty}
+	if err := w.call(ctx, "verifySignature", args, &result); err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
+
+// VerifySignatures calls verifySignature for every item, concurrently, the substrate has no batch call.
+// The item rejected by the daemon is invalid, the transport failures and malformed responses are returned as the error.
+func (w *HTTPWallet) VerifySignatures(ctx context.Context, items []wallet.VerifyItem) ([]bool, error) {
+	verify := func(ctx context.Context, item wallet.VerifyItem) (bool, error) {
+		return w.VerifySignature(ctx, item.Data, item.Signature, item.ProtocolID, item.KeyID, item.Counterparty)
+	}
+	return batch.VerifySignatures(ctx, items, w.verifyWorkers, verify, isWalletFailure)
+}
+
+// CreateHMAC calls createHmac.
+func (w *HTTPWallet) CreateHMAC(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	var result createHMACResult
+	args := createHMACArgs{Data: data, ProtocolID: protocolID, KeyID: keyID, Counterparty: counterparty}
+	if err := w.call(ctx, "createHmac", args, &result); err != nil {
+		return nil, err
+	}
+	return result.HMAC, nil
+}
+
+// VerifyHMAC calls verifyHmac.
+func (w *HTTPWallet) VerifyHMAC(ctx context.Context, data []byte, hmac []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	var result validResult
+	args := verifyHMACArgs{Data: data, HMAC: hmac, ProtocolID: protocolID, KeyID: keyID, Counterparty: counterparty}
+	if err := w.call(ctx, "verifyHmac", args, &result); err != nil {
+		return false, err
+	}
+	return result.Valid, nil
+}
+
+// Encrypt calls encrypt.
+func (w *HTTPWallet) Encrypt(ctx context.Context, plaintext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	var result encryptResult
+	args := encryptArgs{Plaintext: plaintext, ProtocolID: protocolID, KeyID: keyID, Counterparty: counterparty}
+	if err := w.call(ctx, "encrypt", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Ciphertext, nil
+}
+
+// Decrypt calls decrypt.
+func (w *HTTPWallet) Decrypt(ctx context.Context, ciphertext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	var result decryptResult
+	args := decryptArgs{Ciphertext: ciphertext, ProtocolID: protocolID, KeyID: keyID, Counterparty: counterparty}
+	if err := w.call(ctx, "decrypt", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Plaintext, nil
+}
+
+// CreateNonce creates a nonce locally, authenticated with an HMAC created by the wallet daemon.
+func (w *HTTPWallet) CreateNonce(ctx context.Context) (string, error) {
+	return w.nonces.Create(ctx)
+}
+
+// VerifyNonce verifies the nonce locally, with an HMAC verified by the wallet daemon.
+func (w *HTTPWallet) VerifyNonce(ctx context.Context, value string) (bool, error) {
+	if err := w.nonces.Verify(ctx, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListCertificates calls listCertificates.
+func (w *HTTPWallet) ListCertificates(ctx context.Context, options wallet.ListCertificatesOptions) (wallet.ListCertificatesResult, error) {
+	args := listCertificatesArgs{
+		Certifiers: orEmpty(options.Certifiers),
+		Types:      orEmpty(options.Types),
+		Limit:      options.Limit,
+		Offset:     options.Offset,
+	}
+	var result wallet.ListCertificatesResult
+	if err := w.call(ctx, "listCertificates", args, &result); err != nil {
+		return wallet.ListCertificatesResult{}, err
+	}
+	if result.Certificates == nil {
+		result.Certificates = []wallet.Certificate{}
+	}
+	return result, nil
+}
+
+// AcquireCertificate calls acquireCertificate with the "direct" acquisition protocol.
+func (w *HTTPWallet) AcquireCertificate(ctx context.Context, certificate wallet.Certificate) error {
+	args := acquireCertificateArgs{
+		Type:                certificate.Type,
+		Certifier:           certificate.Certifier,
+		AcquisitionProtocol: "direct",
+		Fields:              certificate.Fields,
+		SerialNumber:        certificate.SerialNumber,
+		RevocationOutpoint:  certificate.RevocationOutpoint,
+		Signature:           certificate.Signature,
+		KeyringRevealer:     "certifier",
+		KeyringForSubject:   certificate.Keyring,
+	}
+	return w.call(ctx, "acquireCertificate", args, nil)
+}
+
+// RelinquishCertificate calls relinquishCertificate, it returns wallet.ErrCertificateNotFound
+// when the daemon doesn't relinquish the certificate.
+func (w *HTTPWallet) RelinquishCertificate(ctx context.Context, certType string, serialNumber string, certifier string) error {
+	var result relinquishCertificateResult
+	args := relinquishCertificateArgs{Type: certType, SerialNumber: serialNumber, Certifier: certifier}
+	if err := w.call(ctx, "relinquishCertificate", args, &result); err != nil {
+		return err
+	}
+	if !result.Relinquished {
+		return wallet.ErrCertificateNotFound
+	}
+	return nil
+}
+
+// ProveCertificate calls proveCertificate.
+func (w *HTTPWallet) ProveCertificate(ctx context.Context, certificate wallet.Certificate, verifier string, fieldsToReveal []string, privilege wallet.Privilege) (map[string]string, error) {
+	var result proveCertificateResult
+	args := proveCertificateArgs{
+		Certificate:      certificate,
+		FieldsToReveal:   orEmpty(fieldsToReveal),
+		Verifier:         verifier,
+		Privileged:       privilege.Privileged,
+		PrivilegedReason: privilege.Reason,
+	}
+	if err := w.call(ctx, "proveCertificate", args, &result); err != nil {
+		return nil, err
+	}
+	if result.KeyringForVerifier == nil {
+		result.KeyringForVerifier = map[string]string{}
+	}
+	return result.KeyringForVerifier, nil
+}
+
+// RevealCounterpartyKeyLinkage calls revealCounterpartyKeyLinkage.
+func (w *HTTPWallet) RevealCounterpartyKeyLinkage(ctx context.Context, counterparty string, verifier string) (wallet.KeyLinkageResult, error) {
+	var result keyLinkageResult
+	args := revealCounterpartyKeyLinkageArgs{Counterparty: counterparty, Verifier: verifier}
+	if err := w.call(ctx, "revealCounterpartyKeyLinkage", args, &result); err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	return result.toResult(), nil
+}
+
+// RevealSpecificKeyLinkage calls revealSpecificKeyLinkage.
+func (w *HTTPWallet) RevealSpecificKeyLinkage(ctx context.Context, counterparty string, verifier string, protocolID wallet.Protocol, keyID string) (wallet.KeyLinkageResult, error) {
+	var result keyLinkageResult
+	args := revealSpecificKeyLinkageArgs{Counterparty: counterparty, Verifier: verifier, ProtocolID: protocolID, KeyID: keyID}
+	if err := w.call(ctx, "revealSpecificKeyLinkage", args, &result); err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	return result.toResult(), nil
+}
+
+// GetNetwork calls getNetwork.
+func (w *HTTPWallet) GetNetwork(ctx context.Context) (string, error) {
+	var result getNetworkResult
+	if err := w.call(ctx, "getNetwork", struct{}{}, &result); err != nil {
+		return "", err
+	}
+	return result.Network, nil
+}
+
+// GetVersion calls getVersion.
+func (w *HTTPWallet) GetVersion(ctx context.Context) (string, error) {
+	var result getVersionResult
+	if err := w.call(ctx, "getVersion", struct{}{}, &result); err != nil {
+		return "", err
+	}
+	return result.Version, nil
+}
+
+// GetHeight calls getHeight.
+func (w *HTTPWallet) GetHeight(ctx context.Context) (uint32, error) {
+	var result getHeightResult
+	if err := w.call(ctx, "getHeight", struct{}{}, &result); err != nil {
+		return 0, err
+	}
+	return result.Height, nil
+}
+
+// CreateAction calls createAction.
+func (w *HTTPWallet) CreateAction(ctx context.Context, args wallet.CreateActionArgs) (wallet.CreateActionResult, error) {
+	var result createActionResult
+	callArgs := createActionArgs{
+		Description: args.Description,
+		InputBEEF:   args.InputBEEF,
+		Inputs:      args.Inputs,
+		Outputs:     args.Outputs,
+		LockTime:    args.LockTime,
+		Version:     args.Version,
+		Labels:      args.Labels,
+	}
+	if err := w.call(ctx, "createAction", callArgs, &result); err != nil {
+		return wallet.CreateActionResult{}, err
+	}
+	return wallet.CreateActionResult{Txid: result.Txid, Tx: result.Tx}, nil
+}
+
+// InternalizeAction calls internalizeAction.
+func (w *HTTPWallet) InternalizeAction(ctx context.Context, args wallet.InternalizeActionArgs) (wallet.InternalizeActionResult, error) {
+	var result wallet.InternalizeActionResult
+	callArgs := internalizeActionArgs{
+		Tx:          args.Tx,
+		Outputs:     args.Outputs,
+		Description: args.Description,
+		Labels:      args.Labels,
+	}
+	if err := w.call(ctx, "internalizeAction", callArgs, &result); err != nil {
+		return wallet.InternalizeActionResult{}, err
+	}
+	return result, nil
+}
+
+// call POSTs the args to the call endpoint and decodes the response into the result, if it's not nil.
+func (w *HTTPWallet) call(ctx context.Context, call string, args any, result any) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	body, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s arguments: %w", call, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.baseURL+"/"+call, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", call, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	if w.originator != "" {
+		req.Header.Set("Originator", w.originator)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrTransport, call, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrTransport, call, err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		var errResult errorResult
+		_ = json.Unmarshal(data, &errResult)
+		return &ResponseError{Call: call, StatusCode: res.StatusCode, Message: errResult.Message}
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrMalformedResponse, call, err)
+	}
+	return nil
+}
+
+type transportError string
+
+func (e transportError) Error() string {
+	return string(e)
+}
+
+// Transient reports the transport failures as transient.
+func (transportError) Transient() bool {
+	return true
+}
+
+// isWalletFailure returns true for the errors which aren't the daemon's answer to the call.
+func isWalletFailure(err error) bool {
+	return errors.Is(err, ErrTransport) || errors.Is(err, ErrMalformedResponse) || batch.IsContextError(err)
+}
+
+func orEmpty(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,46 @@
+package remote
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+)
+
+// Option configures the HTTPWallet.
+type Option func(w *HTTPWallet, nonceOptions *[]nonce.Option)
+
+// WithHTTPClient overrides the HTTP client used to call the wallet daemon, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(w *HTTPWallet, _ *[]nonce.Option) {
+		w.client = client
+	}
+}
+
+// WithTimeout overrides the timeout of a single call to the wallet daemon, DefaultTimeout by default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(w *HTTPWallet, _ *[]nonce.Option) {
+		w.timeout = timeout
+	}
+}
+
+// WithOriginator sets the Originator header sent to the wallet daemon, the domain of the app using the wallet.
+func WithOriginator(originator string) Option {
+	return func(w *HTTPWallet, _ *[]nonce.Option) {
+		w.originator = originator
+	}
+}
+
+// WithVerifyConcurrency overrides the number of concurrent calls verifying the signatures of VerifySignatures.
+func WithVerifyConcurrency(workers int) Option {
+	return func(w *HTTPWallet, _ *[]nonce.Option) {
+		w.verifyWorkers = workers
+	}
+}
+
+// WithNonceOptions configures the nonces created and verified by the wallet, e.g. their max age and reuse.
+func WithNonceOptions(opts ...nonce.Option) Option {
+	return func(_ *HTTPWallet, nonceOptions *[]nonce.Option) {
+		*nonceOptions = append(*nonceOptions, opts...)
+	}
+}
@@ -0,0 +1,222 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// byteArray is encoded as a JSON array of numbers, the way the TypeScript wallet substrate encodes binary data.
+type byteArray []byte
+
+func (b byteArray) MarshalJSON() ([]byte, error) {
+	numbers := make([]int, len(b))
+	for i, value := range b {
+		numbers[i] = int(value)
+	}
+	return json.Marshal(numbers)
+}
+
+func (b *byteArray) UnmarshalJSON(data []byte) error {
+	var numbers []int
+	if err := json.Unmarshal(data, &numbers); err != nil {
+		return err
+	}
+	decoded := make([]byte, len(numbers))
+	for i, value := range numbers {
+		if value < 0 || value > 255 {
+			return fmt.Errorf("byte value out of range: %d", value)
+		}
+		decoded[i] = byte(value)
+	}
+	*b = decoded
+	return nil
+}
+
+type getPublicKeyResult struct {
+	PublicKey string `json:"publicKey"`
+}
+
+type createSignatureArgs struct {
+	Data             byteArray           `json:"data"`
+	ProtocolID       wallet.Protocol     `json:"protocolID"`
+	KeyID            string              `json:"keyID"`
+	Counterparty     wallet.Counterparty `json:"counterparty,omitzero"`
+	Privileged       bool                `json:"privileged,omitempty"`
+	PrivilegedReason string              `json:"privilegedReason,omitempty"`
+}
+
+type createSignatureResult struct {
+	Signature byteArray `json:"signature"`
+}
+
+type verifySignatureArgs struct {
+	Data         byteArray           `json:"data"`
+	Signature    byteArray           `json:"signature"`
+	ProtocolID   wallet.Protocol     `json:"protocolID"`
+	KeyID        string              `json:"keyID"`
+	Counterparty wallet.Counterparty `json:"counterparty,omitzero"`
+}
+
+type createHMACArgs struct {
+	Data         byteArray           `json:"data"`
+	ProtocolID   wallet.Protocol     `json:"protocolID"`
+	KeyID        string              `json:"keyID"`
+	Counterparty wallet.Counterparty `json:"counterparty,omitzero"`
+}
+
+type createHMACResult struct {
+	HMAC byteArray `json:"hmac"`
+}
+
+type verifyHMACArgs struct {
+	Data         byteArray           `json:"data"`
+	HMAC         byteArray           `json:"hmac"`
+	ProtocolID   wallet.Protocol     `json:"protocolID"`
+	KeyID        string              `json:"keyID"`
+	Counterparty wallet.Counterparty `json:"counterparty,omitzero"`
+}
+
+type validResult struct {
+	Valid bool `json:"valid"`
+}
+
+type encryptArgs struct {
+	Plaintext    byteArray           `json:"plaintext"`
+	ProtocolID   wallet.Protocol     `json:"protocolID"`
+	KeyID        string              `json:"keyID"`
+	Counterparty wallet.Counterparty `json:"counterparty,omitzero"`
+}
+
+type encryptResult struct {
+	Ciphertext byteArray `json:"ciphertext"`
+}
+
+type decryptArgs struct {
+	Ciphertext   byteArray           `json:"ciphertext"`
+	ProtocolID   wallet.Protocol     `json:"protocolID"`
+	KeyID        string              `json:"keyID"`
+	Counterparty wallet.Counterparty `json:"counterparty,omitzero"`
+}
+
+type decryptResult struct {
+	Plaintext byteArray `json:"plaintext"`
+}
+
+type listCertificatesArgs struct {
+	Certifiers []string `json:"certifiers"`
+	Types      []string `json:"types"`
+	Limit      int      `json:"limit,omitempty"`
+	Offset     int      `json:"offset,omitempty"`
+}
+
+type acquireCertificateArgs struct {
+	Type                string            `json:"type"`
+	Certifier           string            `json:"certifier"`
+	AcquisitionProtocol string            `json:"acquisitionProtocol"`
+	Fields              map[string]any    `json:"fields"`
+	SerialNumber        string            `json:"serialNumber"`
+	RevocationOutpoint  string            `json:"revocationOutpoint"`
+	Signature           string            `json:"signature"`
+	KeyringRevealer     string            `json:"keyringRevealer"`
+	KeyringForSubject   map[string]string `json:"keyringForSubject"`
+}
+
+type relinquishCertificateArgs struct {
+	Type         string `json:"type"`
+	SerialNumber string `json:"serialNumber"`
+	Certifier    string `json:"certifier"`
+}
+
+type relinquishCertificateResult struct {
+	Relinquished bool `json:"relinquished"`
+}
+
+type proveCertificateArgs struct {
+	Certificate      wallet.Certificate `json:"certificate"`
+	FieldsToReveal   []string           `json:"fieldsToReveal"`
+	Verifier         string             `json:"verifier"`
+	Privileged       bool               `json:"privileged,omitempty"`
+	PrivilegedReason string             `json:"privilegedReason,omitempty"`
+}
+
+type proveCertificateResult struct {
+	KeyringForVerifier map[string]string `json:"keyringForVerifier"`
+}
+
+type revealCounterpartyKeyLinkageArgs struct {
+	Counterparty string `json:"counterparty"`
+	Verifier     string `json:"verifier"`
+}
+
+type revealSpecificKeyLinkageArgs struct {
+	Counterparty string          `json:"counterparty"`
+	Verifier     string          `json:"verifier"`
+	ProtocolID   wallet.Protocol `json:"protocolID"`
+	KeyID        string          `json:"keyID"`
+}
+
+// keyLinkageResult is the result of both linkage revelations, the counterparty one has no proof type, protocol and keyID.
+type keyLinkageResult struct {
+	Prover           string          `json:"prover"`
+	Verifier         string          `json:"verifier"`
+	Counterparty     string          `json:"counterparty"`
+	EncryptedLinkage byteArray       `json:"encryptedLinkage"`
+	ProofType        byte            `json:"proofType"`
+	RevelationTime   string          `json:"revelationTime"`
+	ProtocolID       wallet.Protocol `json:"protocolID"`
+	KeyID            string          `json:"keyID"`
+}
+
+func (r keyLinkageResult) toResult() wallet.KeyLinkageResult {
+	return wallet.KeyLinkageResult{
+		Prover:           r.Prover,
+		Verifier:         r.Verifier,
+		Counterparty:     r.Counterparty,
+		EncryptedLinkage: r.EncryptedLinkage,
+		ProofType:        r.ProofType,
+		RevelationTime:   r.RevelationTime,
+		ProtocolID:       r.ProtocolID,
+		KeyID:            r.KeyID,
+	}
+}
+
+type getNetworkResult struct {
+	Network string `json:"network"`
+}
+
+type getVersionResult struct {
+	Version string `json:"version"`
+}
+
+type getHeightResult struct {
+	Height uint32 `json:"height"`
+}
+
+type createActionArgs struct {
+	Description string                      `json:"description"`
+	InputBEEF   byteArray                   `json:"inputBEEF,omitempty"`
+	Inputs      []wallet.CreateActionInput  `json:"inputs,omitempty"`
+	Outputs     []wallet.CreateActionOutput `json:"outputs,omitempty"`
+	LockTime    uint32                      `json:"lockTime,omitempty"`
+	Version     uint32                      `json:"version,omitempty"`
+	Labels      []string                    `json:"labels,omitempty"`
+}
+
+type createActionResult struct {
+	Txid string    `json:"txid"`
+	Tx   byteArray `json:"tx"`
+}
+
+type internalizeActionArgs struct {
+	Tx          byteArray                  `json:"tx"`
+	Outputs     []wallet.InternalizeOutput `json:"outputs"`
+	Description string                     `json:"description"`
+	Labels      []string                   `json:"labels,omitempty"`
+}
+
+// errorResult is the body of a non-2xx response.
+type errorResult struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,600 @@
+package remote_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/remote"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+// daemonArgs are the arguments of all the calls handled by the test daemon, binary data is sent as arrays of numbers.
+type daemonArgs struct {
+	IdentityKey  bool                `json:"identityKey"`
+	Data         []int               `json:"data"`
+	Signature    []int               `json:"signature"`
+	HMAC         []int               `json:"hmac"`
+	Plaintext    []int               `json:"plaintext"`
+	Ciphertext   []int               `json:"ciphertext"`
+	ProtocolID   wallet.Protocol     `json:"protocolID"`
+	KeyID        string              `json:"keyID"`
+	Counterparty wallet.Counterparty `json:"counterparty"`
+}
+
+const daemonHeight = 850000
+
+// newDaemon starts a server playing the wallet daemon, backed by a key wallet.
+func newDaemon(t testing.TB, w *keywallet.Wallet) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var args daemonArgs
+		if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+			writeJSON(rw, http.StatusBadRequest, map[string]any{"message": err.Error()})
+			return
+		}
+
+		var result any
+		var err error
+		ctx := r.Context()
+		switch r.URL.Path {
+		case "/getPublicKey":
+			var publicKey string
+			publicKey, err = w.GetPublicKey(ctx, wallet.GetPublicKeyOptions{
+				IdentityKey:  args.IdentityKey,
+				ProtocolID:   args.ProtocolID,
+				KeyID:        args.KeyID,
+				Counterparty: args.Counterparty,
+			})
+			result = map[string]any{"publicKey": publicKey}
+		case "/createSignature":
+			var signature []byte
+			signature, err = w.CreateSignature(ctx, toBytes(args.Data), args.ProtocolID, args.KeyID, args.Counterparty, wallet.Privilege{})
+			result = map[string]any{"signature": toNumbers(signature)}
+		case "/verifySignature":
+			var valid bool
+			valid, err = w.VerifySignature(ctx, toBytes(args.Data), toBytes(args.Signature), args.ProtocolID, args.KeyID, args.Counterparty)
+			result = map[string]any{"valid": valid}
+		case "/createHmac":
+			var hmac []byte
+			hmac, err = w.CreateHMAC(ctx, toBytes(args.Data), args.ProtocolID, args.KeyID, args.Counterparty)
+			result = map[string]any{"hmac": toNumbers(hmac)}
+		case "/verifyHmac":
+			var valid bool
+			valid, err = w.VerifyHMAC(ctx, toBytes(args.Data), toBytes(args.HMAC), args.ProtocolID, args.KeyID, args.Counterparty)
+			result = map[string]any{"valid": valid}
+		case "/encrypt":
+			var ciphertext []byte
+			ciphertext, err = w.Encrypt(ctx, toBytes(args.Plaintext), args.ProtocolID, args.KeyID, args.Counterparty)
+			result = map[string]any{"ciphertext": toNumbers(ciphertext)}
+		case "/decrypt":
+			var plaintext []byte
+			plaintext, err = w.Decrypt(ctx, toBytes(args.Ciphertext), args.ProtocolID, args.KeyID, args.Counterparty)
+			result = map[string]any{"plaintext": toNumbers(plaintext)}
+		case "/relinquishCertificate":
+			result = map[string]any{"relinquished": false}
+		case "/getNetwork":
+			var network string
+			network, err = w.GetNetwork(ctx)
+			result = map[string]any{"network": network}
+		case "/getVersion":
+			var version string
+			version, err = w.GetVersion(ctx)
+			result = map[string]any{"version": version}
+		case "/getHeight":
+			// the key wallet has no chain access
+			result = map[string]any{"height": daemonHeight}
+		default:
+			writeJSON(rw, http.StatusNotFound, map[string]any{"message": "unknown call"})
+			return
+		}
+
+		if err != nil {
+			writeJSON(rw, http.StatusInternalServerError, map[string]any{"message": err.Error()})
+			return
+		}
+		writeJSON(rw, http.StatusOK, result)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPWallet_Calls(t *testing.T) {
+	key, err := ec.NewPrivateKey()
+	require.NoError(t, err)
+	server := newDaemon(t, keywallet.NewKeyWallet(key))
+	w := remote.NewHTTPWallet(server.URL)
+	protocol := wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "remote wallet test"}
+
+	t.Run("Get the identity key", func(t *testing.T) {
+		// when
+		identityKey, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, key.PubKey().ToDERHex(), identityKey)
+	})
+
+	t.Run("Create and verify a signature", func(t *testing.T) {
+		// given
+		data := []byte("signed data")
+
+		// when
+		signature, err := w.CreateSignature(t.Context(), data, protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+		require.NoError(t, err)
+		valid, err := w.VerifySignature(t.Context(), data, signature, protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Create and verify an HMAC", func(t *testing.T) {
+		// given
+		data := []byte("authenticated data")
+
+		// when
+		hmac, err := w.CreateHMAC(t.Context(), data, protocol, "1", wallet.Counterparty{})
+		require.NoError(t, err)
+		valid, err := w.VerifyHMAC(t.Context(), data, hmac, protocol, "1", wallet.Counterparty{})
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Encrypt and decrypt", func(t *testing.T) {
+		// given
+		plaintext := []byte("secret")
+
+		// when
+		ciphertext, err := w.Encrypt(t.Context(), plaintext, protocol, "1", wallet.CounterpartySelf())
+		require.NoError(t, err)
+		decrypted, err := w.Decrypt(t.Context(), ciphertext, protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	})
+
+	t.Run("Create and verify a nonce with HMACs from the daemon", func(t *testing.T) {
+		// when
+		value, err := w.CreateNonce(t.Context())
+		require.NoError(t, err)
+		valid, err := w.VerifyNonce(t.Context(), value)
+
+		// then
+		require.NoError(t, err)
+		require.True(t, valid)
+	})
+
+	t.Run("Map a not relinquished certificate to ErrCertificateNotFound", func(t *testing.T) {
+		// when
+		err := w.RelinquishCertificate(t.Context(), "type", "serial", "certifier")
+
+		// then
+		require.ErrorIs(t, err, wallet.ErrCertificateNotFound)
+	})
+
+	t.Run("Get the network, version and height", func(t *testing.T) {
+		// when
+		network, networkErr := w.GetNetwork(t.Context())
+		version, versionErr := w.GetVersion(t.Context())
+		height, heightErr := w.GetHeight(t.Context())
+
+		// then
+		require.NoError(t, networkErr)
+		require.NoError(t, versionErr)
+		require.NoError(t, heightErr)
+		require.Equal(t, wallet.NetworkMainnet, network)
+		require.Equal(t, keywallet.Version, version)
+		require.Equal(t, uint32(daemonHeight), height)
+	})
+
+	t.Run("Return the daemon error as ResponseError", func(t *testing.T) {
+		// when
+		_, err := w.Decrypt(t.Context(), []byte("not a ciphertext"), protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		var responseErr *remote.ResponseError
+		require.ErrorAs(t, err, &responseErr)
+		require.Equal(t, "decrypt", responseErr.Call)
+		require.Equal(t, http.StatusInternalServerError, responseErr.StatusCode)
+		require.NotEmpty(t, responseErr.Message)
+		require.False(t, wallet.IsTransient(err))
+	})
+}
+
+func TestHTTPWallet_VerifySignatures(t *testing.T) {
+	protocol := wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "remote wallet test"}
+
+	t.Run("Return the result of every item", func(t *testing.T) {
+		// given
+		key, err := ec.NewPrivateKey()
+		require.NoError(t, err)
+		keyWallet := keywallet.NewKeyWallet(key)
+		w := remote.NewHTTPWallet(newDaemon(t, keyWallet).URL)
+		data := []byte("signed data")
+		signature, err := keyWallet.CreateSignature(t.Context(), data, protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+		require.NoError(t, err)
+		item := wallet.VerifyItem{Data: data, Signature: signature, ProtocolID: protocol, KeyID: "1", Counterparty: wallet.CounterpartySelf()}
+		tampered := item
+		tampered.Data = []byte("tampered data")
+		rejected := item
+		rejected.ProtocolID = wallet.Protocol{SecurityLevel: wallet.SecurityLevelApp, Protocol: "x"}
+
+		// when
+		results, err := w.VerifySignatures(t.Context(), []wallet.VerifyItem{item, tampered, rejected, item})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []bool{true, false, false, true}, results)
+	})
+
+	t.Run("Return the transport failure", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		w := remote.NewHTTPWallet(server.URL)
+		item := wallet.VerifyItem{Data: []byte("data"), Signature: []byte("signature"), ProtocolID: protocol, KeyID: "1"}
+
+		// when
+		results, err := w.VerifySignatures(t.Context(), []wallet.VerifyItem{item, item})
+
+		// then
+		require.ErrorIs(t, err, remote.ErrTransport)
+		require.True(t, wallet.IsTransient(err))
+		require.Equal(t, []bool{false, false}, results)
+	})
+
+	t.Run("Limit the concurrent calls", func(t *testing.T) {
+		// given
+		var inFlight, maxInFlight atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				observed := maxInFlight.Load()
+				if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			writeJSON(rw, http.StatusOK, map[string]any{"valid": true})
+		}))
+		defer server.Close()
+		w := remote.NewHTTPWallet(server.URL, remote.WithVerifyConcurrency(2))
+		items := make([]wallet.VerifyItem, 10)
+		for i := range items {
+			items[i] = wallet.VerifyItem{Data: []byte("data"), Signature: []byte("signature"), ProtocolID: protocol, KeyID: "1"}
+		}
+
+		// when
+		results, err := w.VerifySignatures(t.Context(), items)
+
+		// then
+		require.NoError(t, err)
+		require.NotContains(t, results, false)
+		require.Equal(t, int32(2), maxInFlight.Load())
+	})
+}
+
+func TestHTTPWallet_Requests(t *testing.T) {
+	t.Run("Send the call as JSON with the originator", func(t *testing.T) {
+		// given
+		var request *http.Request
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			request = r
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writeJSON(rw, http.StatusOK, map[string]any{"hmac": []int{1, 2, 3}})
+		}))
+		defer server.Close()
+		w := remote.NewHTTPWallet(server.URL+"/", remote.WithOriginator("example.com"))
+
+		// when
+		hmac, err := w.CreateHMAC(t.Context(), []byte{0, 255}, wallet.Protocol{SecurityLevel: wallet.SecurityLevelApp, Protocol: "test protocol"}, "1", wallet.CounterpartyAnyone())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []byte{1, 2, 3}, hmac)
+		require.Equal(t, http.MethodPost, request.Method)
+		require.Equal(t, "/createHmac", request.URL.Path)
+		require.Equal(t, "application/json", request.Header.Get("Content-Type"))
+		require.Equal(t, "example.com", request.Header.Get("Originator"))
+		require.Equal(t, map[string]any{
+			"data":         []any{float64(0), float64(255)},
+			"protocolID":   []any{float64(1), "test protocol"},
+			"keyID":        "1",
+			"counterparty": "anyone",
+		}, body)
+	})
+
+	t.Run("Send the privileged flag with its reason", func(t *testing.T) {
+		// given
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writeJSON(rw, http.StatusOK, map[string]any{"signature": []int{1}})
+		}))
+		defer server.Close()
+		w := remote.NewHTTPWallet(server.URL)
+		privilege := wallet.Privilege{Privileged: true, Reason: "sign the certificate request"}
+
+		// when
+		_, err := w.CreateSignature(t.Context(), []byte{7}, wallet.Protocol{SecurityLevel: wallet.SecurityLevelApp, Protocol: "test protocol"}, "1", wallet.CounterpartySelf(), privilege)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, true, body["privileged"])
+		require.Equal(t, "sign the certificate request", body["privilegedReason"])
+	})
+
+	t.Run("Send the specific key linkage revelation and decode the linkage", func(t *testing.T) {
+		// given
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writeJSON(rw, http.StatusOK, map[string]any{
+				"prover":                "prover",
+				"verifier":              "verifier",
+				"counterparty":          "counterparty",
+				"protocolID":            []any{2, "test protocol"},
+				"keyID":                 "1",
+				"encryptedLinkage":      []int{5, 6},
+				"encryptedLinkageProof": []int{},
+				"proofType":             0,
+			})
+		}))
+		defer server.Close()
+		w := remote.NewHTTPWallet(server.URL)
+		protocolID := wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "test protocol"}
+
+		// when
+		result, err := w.RevealSpecificKeyLinkage(t.Context(), "counterparty", "verifier", protocolID, "1")
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, wallet.KeyLinkageResult{
+			Prover:           "prover",
+			Verifier:         "verifier",
+			Counterparty:     "counterparty",
+			EncryptedLinkage: []byte{5, 6},
+			ProofType:        wallet.KeyLinkageProofNone,
+			ProtocolID:       protocolID,
+			KeyID:            "1",
+		}, result)
+		require.Equal(t, map[string]any{
+			"counterparty": "counterparty",
+			"verifier":     "verifier",
+			"protocolID":   []any{float64(2), "test protocol"},
+			"keyID":        "1",
+		}, body)
+	})
+
+	t.Run("Send the internalized action with the transaction as numbers", func(t *testing.T) {
+		// given
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writeJSON(rw, http.StatusOK, map[string]any{"accepted": true})
+		}))
+		defer server.Close()
+		w := remote.NewHTTPWallet(server.URL)
+
+		// when
+		result, err := w.InternalizeAction(t.Context(), wallet.InternalizeActionArgs{
+			Tx: []byte{1, 2},
+			Outputs: []wallet.InternalizeOutput{{
+				OutputIndex: 1,
+				Protocol:    wallet.InternalizeProtocolWalletPayment,
+				PaymentRemittance: &wallet.PaymentRemittance{
+					DerivationPrefix:  "cHJlZml4",
+					DerivationSuffix:  "c3VmZml4",
+					SenderIdentityKey: "sender",
+				},
+			}},
+			Description: "payment for the request",
+		})
+
+		// then
+		require.NoError(t, err)
+		require.True(t, result.Accepted)
+		require.Equal(t, map[string]any{
+			"tx": []any{float64(1), float64(2)},
+			"outputs": []any{map[string]any{
+				"outputIndex": float64(1),
+				"protocol":    "wallet payment",
+				"paymentRemittance": map[string]any{
+					"derivationPrefix":  "cHJlZml4",
+					"derivationSuffix":  "c3VmZml4",
+					"senderIdentityKey": "sender",
+				},
+			}},
+			"description": "payment for the request",
+		}, body)
+	})
+
+	t.Run("Send the created action and decode the transaction", func(t *testing.T) {
+		// given
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writeJSON(rw, http.StatusOK, map[string]any{"txid": "txid", "tx": []int{3, 4}})
+		}))
+		defer server.Close()
+		w := remote.NewHTTPWallet(server.URL)
+
+		// when
+		result, err := w.CreateAction(t.Context(), wallet.CreateActionArgs{
+			Description: "refund of the payment",
+			Outputs:     []wallet.CreateActionOutput{{LockingScript: "51", Satoshis: 1000, OutputDescription: "refund output"}},
+		})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, wallet.CreateActionResult{Txid: "txid", Tx: []byte{3, 4}}, result)
+		require.Equal(t, map[string]any{
+			"description": "refund of the payment",
+			"outputs": []any{map[string]any{
+				"lockingScript":     "51",
+				"satoshis":          float64(1000),
+				"outputDescription": "refund output",
+			}},
+		}, body)
+	})
+}
+
+func TestHTTPWallet_Failures(t *testing.T) {
+	protocol := wallet.Protocol{SecurityLevel: wallet.SecurityLevelApp, Protocol: "test protocol"}
+
+	tests := map[string]struct {
+		handler     http.HandlerFunc
+		expectedErr error
+	}{
+		"malformed JSON": {
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				_, _ = rw.Write([]byte("{not json"))
+			},
+			expectedErr: remote.ErrMalformedResponse,
+		},
+		"byte out of range": {
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				writeJSON(rw, http.StatusOK, map[string]any{"signature": []int{256}})
+			},
+			expectedErr: remote.ErrMalformedResponse,
+		},
+		"connection closed": {
+			handler: func(rw http.ResponseWriter, _ *http.Request) {
+				conn, _, _ := rw.(http.Hijacker).Hijack()
+				_ = conn.Close()
+			},
+			expectedErr: remote.ErrTransport,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+			w := remote.NewHTTPWallet(server.URL)
+
+			// when
+			_, err := w.CreateSignature(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+
+			// then
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+
+	t.Run("Fail when the daemon is unreachable", func(t *testing.T) {
+		// given
+		server := httptest.NewServer(http.NotFoundHandler())
+		server.Close()
+		w := remote.NewHTTPWallet(server.URL)
+
+		// when
+		_, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+
+		// then
+		require.ErrorIs(t, err, remote.ErrTransport)
+	})
+
+	t.Run("Time out a slow call", func(t *testing.T) {
+		// given
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+		w := remote.NewHTTPWallet(server.URL, remote.WithTimeout(50*time.Millisecond))
+
+		// when
+		_, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+
+		// then
+		require.ErrorIs(t, err, remote.ErrTransport)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func BenchmarkHTTPWallet_GetPublicKey(b *testing.B) {
+	key, err := ec.NewPrivateKey()
+	require.NoError(b, err)
+	server := newDaemon(b, keywallet.NewKeyWallet(key))
+	options := wallet.GetPublicKeyOptions{
+		ProtocolID:   wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "auth message signature"},
+		KeyID:        "nonce session",
+		Counterparty: wallet.CounterpartyOf(key.PubKey()),
+	}
+	wallets := map[string]wallet.Interface{
+		"uncached": remote.NewHTTPWallet(server.URL),
+		"cached":   wallet.NewCached(remote.NewHTTPWallet(server.URL), time.Minute, 1000),
+	}
+
+	for name, w := range wallets {
+		b.Run(name, func(b *testing.B) {
+			for b.Loop() {
+				_, err := w.GetPublicKey(b.Context(), options)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body any) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(body)
+}
+
+func toBytes(numbers []int) []byte {
+	data := make([]byte, len(numbers))
+	for i, value := range numbers {
+		data[i] = byte(value)
+	}
+	return data
+}
+
+func toNumbers(data []byte) []int {
+	numbers := make([]int, len(data))
+	for i, value := range data {
+		numbers[i] = int(value)
+	}
+	return numbers
+}
+
+func TestResponseError_Transient(t *testing.T) {
+	tests := map[string]struct {
+		statusCode int
+		expected   bool
+	}{
+		"Daemon failure":      {statusCode: http.StatusInternalServerError},
+		"Bad request":         {statusCode: http.StatusBadRequest},
+		"Unauthorized":        {statusCode: http.StatusUnauthorized},
+		"Service unavailable": {statusCode: http.StatusServiceUnavailable, expected: true},
+		"Bad gateway":         {statusCode: http.StatusBadGateway, expected: true},
+		"Gateway timeout":     {statusCode: http.StatusGatewayTimeout, expected: true},
+		"Too many requests":   {statusCode: http.StatusTooManyRequests, expected: true},
+		"Request timeout":     {statusCode: http.StatusRequestTimeout, expected: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			err := &remote.ResponseError{Call: "decrypt", StatusCode: test.statusCode, Message: "failed"}
+
+			// when
+			transient := wallet.IsTransient(err)
+
+			// then
+			require.Equal(t, test.expected, transient)
+		})
+	}
+}
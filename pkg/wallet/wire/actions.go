@@ -0,0 +1,323 @@
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/money"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+const (
+	internalizeWalletPayment   = 1
+	internalizeBasketInsertion = 2
+)
+
+// noLength writes the TypeScript SDK's -1 varint, in place of an absent string, list or number.
+func (w *writer) noLength() {
+	w.varInt(math.MaxUint64)
+}
+
+func (w *writer) optionalString(value string) {
+	if value == "" {
+		w.noLength()
+		return
+	}
+	w.string(value)
+}
+
+func (w *writer) optionalStrings(values []string) {
+	if len(values) == 0 {
+		w.noLength()
+		return
+	}
+	w.varInt(uint64(len(values)))
+	for _, value := range values {
+		w.string(value)
+	}
+}
+
+func (w *writer) optionalBytes(value []byte) {
+	if len(value) == 0 {
+		w.noLength()
+		return
+	}
+	w.varBytes(value)
+}
+
+// optionalUint32 writes a varint, or the -1 varint for the zero value.
+func (w *writer) optionalUint32(value uint32) {
+	if value == 0 {
+		w.noLength()
+		return
+	}
+	w.varInt(uint64(value))
+}
+
+func (w *encodingWriter) varBase64(name string, value string) {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		w.fail(fmt.Errorf("%s must be base64 encoded: %q", name, value))
+		return
+	}
+	w.varBytes(raw)
+}
+
+// optionalLength reads a length written by the writer or the -1 varint, ok is false for the latter.
+func (r *reader) optionalLength() (length int, ok bool) {
+	value := r.varInt()
+	if r.err != nil || value == math.MaxUint64 {
+		return 0, false
+	}
+	if value > uint64(len(r.data)) {
+		r.fail("length %d exceeds the %d bytes left", value, len(r.data))
+		return 0, false
+	}
+	return int(value), true
+}
+
+func (r *reader) optionalString() string {
+	length, ok := r.optionalLength()
+	if !ok {
+		return ""
+	}
+	return string(r.bytes(length))
+}
+
+// optionalStrings reads a list of strings, nil for an empty or absent one.
+func (r *reader) optionalStrings() []string {
+	count, ok := r.optionalLength()
+	if !ok || count == 0 {
+		return nil
+	}
+	values := make([]string, 0, count)
+	for range count {
+		values = append(values, r.string())
+	}
+	return values
+}
+
+func (r *reader) optionalBytes() []byte {
+	length, ok := r.optionalLength()
+	if !ok || length == 0 {
+		return nil
+	}
+	return r.bytes(length)
+}
+
+func (r *reader) uint32() uint32 {
+	value := r.varInt()
+	if value > math.MaxUint32 {
+		r.fail("value out of range: %d", value)
+		return 0
+	}
+	return uint32(value)
+}
+
+func (r *reader) optionalUint32() uint32 {
+	value := r.varInt()
+	if value == math.MaxUint64 {
+		return 0
+	}
+	if value > math.MaxUint32 {
+		r.fail("value out of range: %d", value)
+		return 0
+	}
+	return uint32(value)
+}
+
+// encodeCreateAction encodes the args of createAction, the options are never sent.
+func encodeCreateAction(args wallet.CreateActionArgs) ([]byte, error) {
+	w := &encodingWriter{}
+	w.string(args.Description)
+	w.optionalBytes(args.InputBEEF)
+
+	if len(args.Inputs) == 0 {
+		w.noLength()
+	} else {
+		w.varInt(uint64(len(args.Inputs)))
+	}
+	for _, input := range args.Inputs {
+		w.outpoint("input outpoint", input.Outpoint)
+		if input.UnlockingScript != "" {
+			w.varHex("unlocking script", input.UnlockingScript)
+		} else {
+			w.noLength()
+			w.varInt(uint64(input.UnlockingScriptLength))
+		}
+		w.string(input.InputDescription)
+		w.optionalUint32(input.SequenceNumber)
+	}
+
+	if len(args.Outputs) == 0 {
+		w.noLength()
+	} else {
+		w.varInt(uint64(len(args.Outputs)))
+	}
+	for _, output := range args.Outputs {
+		w.varHex("locking script", output.LockingScript)
+		w.varInt(uint64(output.Satoshis))
+		w.string(output.OutputDescription)
+		w.optionalString(output.Basket)
+		w.optionalString(output.CustomInstructions)
+		w.optionalStrings(output.Tags)
+	}
+
+	w.optionalUint32(args.LockTime)
+	w.optionalUint32(args.Version)
+	w.optionalStrings(args.Labels)
+	// options
+	w.byte(0)
+	return w.result(), w.err
+}
+
+func decodeCreateAction(params []byte) (wallet.CreateActionArgs, error) {
+	r := &reader{data: params}
+	var args wallet.CreateActionArgs
+	args.Description = r.string()
+	args.InputBEEF = r.optionalBytes()
+
+	inputs, _ := r.optionalLength()
+	for range inputs {
+		var input wallet.CreateActionInput
+		input.Outpoint = r.outpoint()
+		if script, ok := r.optionalLength(); ok {
+			input.UnlockingScript = hex.EncodeToString(r.bytes(script))
+		} else {
+			input.UnlockingScriptLength = r.uint32()
+		}
+		input.InputDescription = r.string()
+		input.SequenceNumber = r.optionalUint32()
+		args.Inputs = append(args.Inputs, input)
+	}
+
+	outputs, _ := r.optionalLength()
+	for range outputs {
+		output := wallet.CreateActionOutput{
+			LockingScript:      hex.EncodeToString(r.varBytes()),
+			Satoshis:           money.Satoshis(r.varInt()),
+			OutputDescription:  r.string(),
+			Basket:             r.optionalString(),
+			CustomInstructions: r.optionalString(),
+			Tags:               r.optionalStrings(),
+		}
+		args.Outputs = append(args.Outputs, output)
+	}
+
+	args.LockTime = r.optionalUint32()
+	args.Version = r.optionalUint32()
+	args.Labels = r.optionalStrings()
+	if options := r.byte(); r.err == nil && options != 0 {
+		return wallet.CreateActionArgs{}, fmt.Errorf("%w: create action options are not supported", ErrMalformedFrame)
+	}
+	return args, r.done()
+}
+
+// encodeCreateActionResult encodes the txid and the transaction, the result never has the change outputs
+// not sent, the results of the transactions sent with it or a transaction to sign.
+func encodeCreateActionResult(result wallet.CreateActionResult) ([]byte, error) {
+	w := &encodingWriter{}
+	w.bool(result.Txid != "")
+	if result.Txid != "" {
+		w.hexValue("txid", result.Txid, txIDSize)
+	}
+	w.bool(len(result.Tx) > 0)
+	if len(result.Tx) > 0 {
+		w.varBytes(result.Tx)
+	}
+	// noSendChange
+	w.noLength()
+	// sendWithResults
+	w.noLength()
+	// signableTransaction
+	w.byte(0)
+	return w.result(), w.err
+}
+
+func decodeCreateActionResult(data []byte) (wallet.CreateActionResult, error) {
+	r := &reader{data: data}
+	var result wallet.CreateActionResult
+	if r.bool() {
+		result.Txid = r.hexValue(txIDSize)
+	}
+	if r.bool() {
+		result.Tx = r.varBytes()
+	}
+	// the outpoints of noSendChange and the txids and statuses of sendWithResults aren't part of the result
+	noSendChange, _ := r.optionalLength()
+	for range noSendChange {
+		r.outpoint()
+	}
+	sendWithResults, _ := r.optionalLength()
+	for range sendWithResults {
+		r.bytes(txIDSize)
+		r.byte()
+	}
+	if signable := r.byte(); r.err == nil && signable != 0 {
+		return wallet.CreateActionResult{}, fmt.Errorf("%w: signable transactions are not supported", ErrMalformedFrame)
+	}
+	return result, r.done()
+}
+
+func encodeInternalizeAction(args wallet.InternalizeActionArgs) ([]byte, error) {
+	w := &encodingWriter{}
+	w.varBytes(args.Tx)
+	w.varInt(uint64(len(args.Outputs)))
+	for _, output := range args.Outputs {
+		w.varInt(uint64(output.OutputIndex))
+		switch {
+		case output.Protocol == wallet.InternalizeProtocolWalletPayment && output.PaymentRemittance != nil:
+			w.byte(internalizeWalletPayment)
+			w.hexValue("sender identity key", output.PaymentRemittance.SenderIdentityKey, publicKeySize)
+			w.varBase64("derivation prefix", output.PaymentRemittance.DerivationPrefix)
+			w.varBase64("derivation suffix", output.PaymentRemittance.DerivationSuffix)
+		case output.Protocol == wallet.InternalizeProtocolBasketInsertion && output.InsertionRemittance != nil:
+			w.byte(internalizeBasketInsertion)
+			w.string(output.InsertionRemittance.Basket)
+			w.optionalString(output.InsertionRemittance.CustomInstructions)
+			w.optionalStrings(output.InsertionRemittance.Tags)
+		default:
+			w.fail(fmt.Errorf("output %d has no remittance for the %q protocol", output.OutputIndex, output.Protocol))
+		}
+	}
+	w.strings(args.Labels)
+	w.string(args.Description)
+	// seekPermission
+	w.byte(noValue)
+	return w.result(), w.err
+}
+
+func decodeInternalizeAction(params []byte) (wallet.InternalizeActionArgs, error) {
+	r := &reader{data: params}
+	var args wallet.InternalizeActionArgs
+	args.Tx = r.varBytes()
+	for range r.length() {
+		output := wallet.InternalizeOutput{OutputIndex: r.uint32()}
+		switch protocol := r.byte(); protocol {
+		case internalizeWalletPayment:
+			output.Protocol = wallet.InternalizeProtocolWalletPayment
+			output.PaymentRemittance = &wallet.PaymentRemittance{
+				SenderIdentityKey: r.hexValue(publicKeySize),
+				DerivationPrefix:  base64.StdEncoding.EncodeToString(r.varBytes()),
+				DerivationSuffix:  base64.StdEncoding.EncodeToString(r.varBytes()),
+			}
+		case internalizeBasketInsertion:
+			output.Protocol = wallet.InternalizeProtocolBasketInsertion
+			output.InsertionRemittance = &wallet.BasketInsertion{
+				Basket:             r.string(),
+				CustomInstructions: r.optionalString(),
+				Tags:               r.optionalStrings(),
+			}
+		default:
+			r.fail("unknown internalize protocol %d", protocol)
+		}
+		args.Outputs = append(args.Outputs, output)
+	}
+	args.Labels = r.optionalStrings()
+	args.Description = r.string()
+	// seekPermission
+	r.byte()
+	return args, r.done()
+}
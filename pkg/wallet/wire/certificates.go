@@ -0,0 +1,373 @@
+package wire
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+const (
+	certificateTypeSize = 32
+	serialNumberSize    = 32
+	txIDSize            = 32
+
+	acquisitionProtocolDirect = 1
+	keyringRevealerCertifier  = 11
+)
+
+// proveArgs are the params of proveCertificate.
+type proveArgs struct {
+	certificate    wallet.Certificate
+	fieldsToReveal []string
+	verifier       string
+	privilege      wallet.Privilege
+}
+
+// relinquishArgs are the params of relinquishCertificate.
+type relinquishArgs struct {
+	certType     string
+	serialNumber string
+	certifier    string
+}
+
+// encodingWriter wraps the writer, keeping the first failure to encode a value.
+type encodingWriter struct {
+	writer
+	err error
+}
+
+func (w *encodingWriter) fail(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// base64Value writes the base64 encoded value as raw bytes of the given size.
+func (w *encodingWriter) base64Value(name string, value string, size int) {
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(raw) != size {
+		w.fail(fmt.Errorf("%s must be %d base64 encoded bytes: %q", name, size, value))
+		return
+	}
+	w.bytes(raw)
+}
+
+// hexValue writes the hex encoded value as raw bytes of the given size.
+func (w *encodingWriter) hexValue(name string, value string, size int) {
+	raw, err := hex.DecodeString(value)
+	if err != nil || len(raw) != size {
+		w.fail(fmt.Errorf("%s must be %d hex encoded bytes: %q", name, size, value))
+		return
+	}
+	w.bytes(raw)
+}
+
+func (w *encodingWriter) varHex(name string, value string) {
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		w.fail(fmt.Errorf("%s must be hex encoded: %q", name, value))
+		return
+	}
+	w.varBytes(raw)
+}
+
+// outpoint writes the "txid.index" outpoint as the txid bytes followed by the varint output index.
+func (w *encodingWriter) outpoint(name string, value string) {
+	txID, index, found := strings.Cut(value, ".")
+	outputIndex, err := strconv.ParseUint(index, 10, 32)
+	if !found || err != nil {
+		w.fail(fmt.Errorf("%s must be txid.index: %q", name, value))
+		return
+	}
+	w.hexValue(name+" txid", txID, txIDSize)
+	w.varInt(outputIndex)
+}
+
+func (w *encodingWriter) fields(fields map[string]any) {
+	w.varInt(uint64(len(fields)))
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		value, ok := fields[name].(string)
+		if !ok {
+			w.fail(fmt.Errorf("certificate field %s must be a string", name))
+			return
+		}
+		w.string(name)
+		w.string(value)
+	}
+}
+
+func (w *encodingWriter) strings(values []string) {
+	w.varInt(uint64(len(values)))
+	for _, value := range values {
+		w.string(value)
+	}
+}
+
+// keyring writes the keyring with base64 encoded values as raw bytes.
+func (w *encodingWriter) keyring(keyring map[string]string) {
+	w.varInt(uint64(len(keyring)))
+	for _, name := range slices.Sorted(maps.Keys(keyring)) {
+		raw, err := base64.StdEncoding.DecodeString(keyring[name])
+		if err != nil {
+			w.fail(fmt.Errorf("keyring value of field %s must be base64 encoded", name))
+			return
+		}
+		w.string(name)
+		w.varBytes(raw)
+	}
+}
+
+func (r *reader) base64Value(size int) string {
+	return base64.StdEncoding.EncodeToString(r.bytes(size))
+}
+
+func (r *reader) hexValue(size int) string {
+	return hex.EncodeToString(r.bytes(size))
+}
+
+func (r *reader) outpoint() string {
+	txID := r.hexValue(txIDSize)
+	return txID + "." + strconv.FormatUint(r.varInt(), 10)
+}
+
+func (r *reader) fields() map[string]any {
+	count := r.length()
+	fields := make(map[string]any, count)
+	for range count {
+		name := r.string()
+		fields[name] = r.string()
+	}
+	return fields
+}
+
+func (r *reader) strings() []string {
+	count := r.length()
+	values := make([]string, 0, count)
+	for range count {
+		values = append(values, r.string())
+	}
+	return values
+}
+
+func (r *reader) keyring() map[string]string {
+	count := r.length()
+	keyring := make(map[string]string, count)
+	for range count {
+		name := r.string()
+		keyring[name] = base64.StdEncoding.EncodeToString(r.varBytes())
+	}
+	return keyring
+}
+
+// CertificateSigningData returns the data the certifier signs, the certificate in the binary format
+// of the TypeScript SDK's Certificate.toBinary without the signature.
+func CertificateSigningData(certificate wallet.Certificate) ([]byte, error) {
+	w := &encodingWriter{}
+	w.unsignedCertificate(certificate)
+	return w.result(), w.err
+}
+
+func (w *encodingWriter) unsignedCertificate(certificate wallet.Certificate) {
+	w.base64Value("certificate type", certificate.Type, certificateTypeSize)
+	w.base64Value("serial number", certificate.SerialNumber, serialNumberSize)
+	w.hexValue("subject", certificate.Subject, publicKeySize)
+	w.hexValue("certifier", certificate.Certifier, publicKeySize)
+	w.outpoint("revocation outpoint", certificate.RevocationOutpoint)
+	w.fields(certificate.Fields)
+}
+
+// encodeCertificate encodes the certificate in the binary format of the TypeScript SDK's Certificate.toBinary.
+func encodeCertificate(certificate wallet.Certificate) ([]byte, error) {
+	w := &encodingWriter{}
+	w.unsignedCertificate(certificate)
+	raw, err := hex.DecodeString(certificate.Signature)
+	if err != nil {
+		w.fail(fmt.Errorf("signature must be hex encoded: %q", certificate.Signature))
+	}
+	w.bytes(raw)
+	return w.result(), w.err
+}
+
+func decodeCertificate(data []byte) (wallet.Certificate, error) {
+	r := &reader{data: data}
+	certificate := wallet.Certificate{
+		Type:               r.base64Value(certificateTypeSize),
+		SerialNumber:       r.base64Value(serialNumberSize),
+		Subject:            r.hexValue(publicKeySize),
+		Certifier:          r.hexValue(publicKeySize),
+		RevocationOutpoint: r.outpoint(),
+		Fields:             r.fields(),
+	}
+	certificate.Signature = hex.EncodeToString(r.rest())
+	return certificate, r.err
+}
+
+func encodeListCertificates(options wallet.ListCertificatesOptions) ([]byte, error) {
+	w := &encodingWriter{}
+	w.varInt(uint64(len(options.Certifiers)))
+	for _, certifier := range options.Certifiers {
+		w.hexValue("certifier", certifier, publicKeySize)
+	}
+	w.varInt(uint64(len(options.Types)))
+	for _, certType := range options.Types {
+		w.base64Value("certificate type", certType, certificateTypeSize)
+	}
+	w.optionalVarInt(options.Limit)
+	w.optionalVarInt(options.Offset)
+	w.privileged(wallet.Privilege{})
+	return w.result(), w.err
+}
+
+func decodeListCertificates(params []byte) (wallet.ListCertificatesOptions, error) {
+	r := &reader{data: params}
+	var options wallet.ListCertificatesOptions
+	for range r.length() {
+		options.Certifiers = append(options.Certifiers, r.hexValue(publicKeySize))
+	}
+	for range r.length() {
+		options.Types = append(options.Types, r.base64Value(certificateTypeSize))
+	}
+	options.Limit = r.optionalVarInt()
+	options.Offset = r.optionalVarInt()
+	r.privileged()
+	return options, r.done()
+}
+
+// encodeListCertificatesResult writes the total count followed by the length prefixed certificates.
+func encodeListCertificatesResult(result wallet.ListCertificatesResult) ([]byte, error) {
+	w := &writer{}
+	w.varInt(uint64(result.TotalCount))
+	for _, certificate := range result.Certificates {
+		raw, err := encodeCertificate(certificate)
+		if err != nil {
+			return nil, err
+		}
+		w.varBytes(raw)
+	}
+	return w.result(), nil
+}
+
+func decodeListCertificatesResult(data []byte) (wallet.ListCertificatesResult, error) {
+	r := &reader{data: data}
+	result := wallet.ListCertificatesResult{
+		TotalCount:   int(r.varInt()),
+		Certificates: []wallet.Certificate{},
+	}
+	for r.err == nil && len(r.data) > 0 {
+		certificate, err := decodeCertificate(r.varBytes())
+		if err != nil {
+			return wallet.ListCertificatesResult{}, err
+		}
+		result.Certificates = append(result.Certificates, certificate)
+	}
+	return result, r.err
+}
+
+// encodeAcquireCertificate encodes the certificate acquired with the "direct" acquisition protocol.
+func encodeAcquireCertificate(certificate wallet.Certificate) ([]byte, error) {
+	w := &encodingWriter{}
+	w.base64Value("certificate type", certificate.Type, certificateTypeSize)
+	w.hexValue("certifier", certificate.Certifier, publicKeySize)
+	w.fields(certificate.Fields)
+	w.privileged(wallet.Privilege{})
+	w.byte(acquisitionProtocolDirect)
+	w.base64Value("serial number", certificate.SerialNumber, serialNumberSize)
+	w.outpoint("revocation outpoint", certificate.RevocationOutpoint)
+	w.varHex("signature", certificate.Signature)
+	w.byte(keyringRevealerCertifier)
+	w.keyring(certificate.Keyring)
+	return w.result(), w.err
+}
+
+// decodeAcquireCertificate decodes the certificate acquired with the "direct" acquisition protocol,
+// the subject isn't sent, it's the identity key of the wallet.
+func decodeAcquireCertificate(params []byte) (wallet.Certificate, error) {
+	r := &reader{data: params}
+	certificate := wallet.Certificate{
+		Type:      r.base64Value(certificateTypeSize),
+		Certifier: r.hexValue(publicKeySize),
+		Fields:    r.fields(),
+	}
+	r.privileged()
+	if protocol := r.byte(); r.err == nil && protocol != acquisitionProtocolDirect {
+		return wallet.Certificate{}, fmt.Errorf("%w: only the direct acquisition protocol is supported", ErrMalformedFrame)
+	}
+	certificate.SerialNumber = r.base64Value(serialNumberSize)
+	certificate.RevocationOutpoint = r.outpoint()
+	certificate.Signature = hex.EncodeToString(r.varBytes())
+	if revealer := r.byte(); r.err == nil && revealer != keyringRevealerCertifier {
+		return wallet.Certificate{}, fmt.Errorf("%w: only the certifier can reveal the keyring", ErrMalformedFrame)
+	}
+	certificate.Keyring = r.keyring()
+	return certificate, r.done()
+}
+
+func encodeProveCertificate(args proveArgs) ([]byte, error) {
+	w := &encodingWriter{}
+	w.base64Value("certificate type", args.certificate.Type, certificateTypeSize)
+	w.hexValue("subject", args.certificate.Subject, publicKeySize)
+	w.base64Value("serial number", args.certificate.SerialNumber, serialNumberSize)
+	w.hexValue("certifier", args.certificate.Certifier, publicKeySize)
+	w.outpoint("revocation outpoint", args.certificate.RevocationOutpoint)
+	w.varHex("signature", args.certificate.Signature)
+	w.fields(args.certificate.Fields)
+	w.strings(args.fieldsToReveal)
+	w.hexValue("verifier", args.verifier, publicKeySize)
+	if err := validatePrivilege(args.privilege); err != nil {
+		w.fail(err)
+	}
+	w.privileged(args.privilege)
+	return w.result(), w.err
+}
+
+func decodeProveCertificate(params []byte) (proveArgs, error) {
+	r := &reader{data: params}
+	var args proveArgs
+	args.certificate.Type = r.base64Value(certificateTypeSize)
+	args.certificate.Subject = r.hexValue(publicKeySize)
+	args.certificate.SerialNumber = r.base64Value(serialNumberSize)
+	args.certificate.Certifier = r.hexValue(publicKeySize)
+	args.certificate.RevocationOutpoint = r.outpoint()
+	args.certificate.Signature = hex.EncodeToString(r.varBytes())
+	args.certificate.Fields = r.fields()
+	args.fieldsToReveal = r.strings()
+	args.verifier = r.hexValue(publicKeySize)
+	args.privilege = r.privileged()
+	return args, r.done()
+}
+
+func encodeKeyring(keyring map[string]string) ([]byte, error) {
+	w := &encodingWriter{}
+	w.keyring(keyring)
+	return w.result(), w.err
+}
+
+func decodeKeyring(data []byte) (map[string]string, error) {
+	r := &reader{data: data}
+	keyring := r.keyring()
+	return keyring, r.done()
+}
+
+func encodeRelinquishCertificate(args relinquishArgs) ([]byte, error) {
+	w := &encodingWriter{}
+	w.base64Value("certificate type", args.certType, certificateTypeSize)
+	w.base64Value("serial number", args.serialNumber, serialNumberSize)
+	w.hexValue("certifier", args.certifier, publicKeySize)
+	return w.result(), w.err
+}
+
+func decodeRelinquishCertificate(params []byte) (relinquishArgs, error) {
+	r := &reader{data: params}
+	args := relinquishArgs{
+		certType:     r.base64Value(certificateTypeSize),
+		serialNumber: r.base64Value(serialNumberSize),
+		certifier:    r.hexValue(publicKeySize),
+	}
+	return args, r.done()
+}
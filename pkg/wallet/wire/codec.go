@@ -0,0 +1,178 @@
+package wire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrMalformedFrame is returned when a frame can't be decoded.
+var ErrMalformedFrame = errors.New("malformed wire frame")
+
+// noValue is the int8 written in place of an absent optional value.
+const noValue = 0xFF
+
+// writer encodes the values the way the TypeScript SDK's Utils.Writer does.
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) byte(value byte) {
+	w.buf.WriteByte(value)
+}
+
+func (w *writer) bytes(value []byte) {
+	w.buf.Write(value)
+}
+
+func (w *writer) bool(value bool) {
+	if value {
+		w.byte(1)
+	} else {
+		w.byte(0)
+	}
+}
+
+func (w *writer) varInt(value uint64) {
+	switch {
+	case value < 0xFD:
+		w.byte(byte(value))
+	case value <= math.MaxUint16:
+		w.byte(0xFD)
+		w.buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(value)))
+	case value <= math.MaxUint32:
+		w.byte(0xFE)
+		w.buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(value)))
+	default:
+		w.byte(0xFF)
+		w.buf.Write(binary.LittleEndian.AppendUint64(nil, value))
+	}
+}
+
+func (w *writer) varBytes(value []byte) {
+	w.varInt(uint64(len(value)))
+	w.bytes(value)
+}
+
+func (w *writer) string(value string) {
+	w.varBytes([]byte(value))
+}
+
+func (w *writer) result() []byte {
+	return w.buf.Bytes()
+}
+
+// reader decodes the values written by the writer, the first failure is kept in err and makes the next reads no-ops.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s", ErrMalformedFrame, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *reader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.data) {
+		r.fail("%d bytes expected, %d left", n, len(r.data))
+		return nil
+	}
+	value := r.data[:n]
+	r.data = r.data[n:]
+	return value
+}
+
+func (r *reader) byte() byte {
+	value := r.bytes(1)
+	if value == nil {
+		return 0
+	}
+	return value[0]
+}
+
+// peek returns the next byte without consuming it.
+func (r *reader) peek() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.data) == 0 {
+		r.fail("unexpected end of data")
+		return 0
+	}
+	return r.data[0]
+}
+
+func (r *reader) bool() bool {
+	switch value := r.byte(); value {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		r.fail("invalid bool %d", value)
+		return false
+	}
+}
+
+func (r *reader) varInt() uint64 {
+	switch prefix := r.byte(); prefix {
+	case 0xFD:
+		if value := r.bytes(2); value != nil {
+			return uint64(binary.LittleEndian.Uint16(value))
+		}
+	case 0xFE:
+		if value := r.bytes(4); value != nil {
+			return uint64(binary.LittleEndian.Uint32(value))
+		}
+	case 0xFF:
+		if value := r.bytes(8); value != nil {
+			return binary.LittleEndian.Uint64(value)
+		}
+	default:
+		return uint64(prefix)
+	}
+	return 0
+}
+
+// length reads a varint length or number of entries, failing if it's larger than the number of remaining bytes,
+// so a malicious length can't make the decoder allocate more memory than the frame takes.
+func (r *reader) length() int {
+	value := r.varInt()
+	if r.err == nil && value > uint64(len(r.data)) {
+		r.fail("length %d exceeds the %d bytes left", value, len(r.data))
+		return 0
+	}
+	return int(value)
+}
+
+func (r *reader) varBytes() []byte {
+	return r.bytes(r.length())
+}
+
+func (r *reader) string() string {
+	return string(r.varBytes())
+}
+
+func (r *reader) rest() []byte {
+	if r.err != nil {
+		return nil
+	}
+	value := r.data
+	r.data = nil
+	return value
+}
+
+// done fails if there is data left after all the values were read.
+func (r *reader) done() error {
+	if r.err == nil && len(r.data) > 0 {
+		r.fail("%d unexpected trailing bytes", len(r.data))
+	}
+	return r.err
+}
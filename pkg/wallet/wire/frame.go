@@ -0,0 +1,178 @@
+package wire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the max frame size.
+var ErrFrameTooLarge = errors.New("wire frame too large")
+
+// DefaultMaxFrameSize is the default max size of a frame read from the connection.
+const DefaultMaxFrameSize = 16 << 20
+
+// call is the code of a wallet call in the BRC-100 wallet wire format, the same as in the TypeScript SDK.
+type call byte
+
+const (
+	callCreateAction                 call = 1
+	callInternalizeAction            call = 5
+	callGetPublicKey                 call = 8
+	callRevealCounterpartyKeyLinkage call = 9
+	callRevealSpecificKeyLinkage     call = 10
+	callEncrypt                      call = 11
+	callDecrypt                      call = 12
+	callCreateHMAC                   call = 13
+	callVerifyHMAC                   call = 14
+	callCreateSignature              call = 15
+	callVerifySignature              call = 16
+	callAcquireCertificate           call = 17
+	callListCertificates             call = 18
+	callProveCertificate             call = 19
+	callRelinquishCertificate        call = 20
+	callGetHeight                    call = 25
+	callGetNetwork                   call = 27
+	callGetVersion                   call = 28
+)
+
+func (c call) String() string {
+	switch c {
+	case callCreateAction:
+		return "createAction"
+	case callInternalizeAction:
+		return "internalizeAction"
+	case callGetPublicKey:
+		return "getPublicKey"
+	case callRevealCounterpartyKeyLinkage:
+		return "revealCounterpartyKeyLinkage"
+	case callRevealSpecificKeyLinkage:
+		return "revealSpecificKeyLinkage"
+	case callEncrypt:
+		return "encrypt"
+	case callDecrypt:
+		return "decrypt"
+	case callCreateHMAC:
+		return "createHmac"
+	case callVerifyHMAC:
+		return "verifyHmac"
+	case callCreateSignature:
+		return "createSignature"
+	case callVerifySignature:
+		return "verifySignature"
+	case callAcquireCertificate:
+		return "acquireCertificate"
+	case callListCertificates:
+		return "listCertificates"
+	case callProveCertificate:
+		return "proveCertificate"
+	case callRelinquishCertificate:
+		return "relinquishCertificate"
+	case callGetHeight:
+		return "getHeight"
+	case callGetNetwork:
+		return "getNetwork"
+	case callGetVersion:
+		return "getVersion"
+	}
+	return fmt.Sprintf("call(%d)", byte(c))
+}
+
+// errorCodeGeneric is the error code of the errors returned by the Processor, like the TypeScript SDK's WalletError.
+const errorCodeGeneric = 1
+
+// WalletError is the error returned by the wallet in an error frame.
+type WalletError struct {
+	// Code is the error code, never zero
+	Code byte
+	// Message is the error message
+	Message string
+	// Stack is the stack trace of the error, if the wallet sends one
+	Stack string
+}
+
+func (e *WalletError) Error() string {
+	return fmt.Sprintf("wallet error %d: %s", e.Code, e.Message)
+}
+
+// writeFrame writes the payload prefixed with its length as a 4 byte big endian integer.
+// The wire format itself has no framing, so it's needed to send the messages over a stream.
+func writeFrame(w io.Writer, payload []byte) error {
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(payload)), uint32(len(payload)))
+	frame = append(frame, payload...)
+	if _, err := w.Write(frame); err != nil {
+		return fmt.Errorf("failed to write frame: %w", err)
+	}
+	return nil
+}
+
+// readFrame reads a frame written by writeFrame, rejecting the frames larger than maxSize before reading them.
+func readFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("%w: %d bytes, max %d", ErrFrameTooLarge, size, maxSize)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, fmt.Errorf("failed to read frame: %w", err)
+	}
+	return payload, nil
+}
+
+// encodeRequest builds the request frame: the call code, the length prefixed originator and the params.
+func encodeRequest(c call, originator string, params []byte) ([]byte, error) {
+	if len(originator) > 255 {
+		return nil, fmt.Errorf("originator longer than 255 bytes: %q", originator)
+	}
+	request := make([]byte, 0, 2+len(originator)+len(params))
+	request = append(request, byte(c), byte(len(originator)))
+	request = append(request, originator...)
+	return append(request, params...), nil
+}
+
+func decodeRequest(request []byte) (c call, originator string, params []byte, err error) {
+	r := &reader{data: request}
+	c = call(r.byte())
+	originator = string(r.bytes(int(r.byte())))
+	params = r.rest()
+	return c, originator, params, r.err
+}
+
+// encodeResult builds the result frame: a zero byte followed by the result.
+func encodeResult(result []byte) []byte {
+	return append([]byte{0}, result...)
+}
+
+// encodeError builds the error frame: the error code followed by the length prefixed message and stack.
+func encodeError(code byte, message string, stack string) []byte {
+	w := &writer{}
+	w.byte(code)
+	w.string(message)
+	w.string(stack)
+	return w.result()
+}
+
+// decodeResult returns the result of a result frame, or the WalletError of an error frame.
+func decodeResult(frame []byte) ([]byte, error) {
+	r := &reader{data: frame}
+	code := r.byte()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if code == 0 {
+		return r.rest(), nil
+	}
+
+	walletErr := &WalletError{Code: code}
+	walletErr.Message = r.string()
+	walletErr.Stack = r.string()
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	return nil, walletErr
+}
@@ -0,0 +1,93 @@
+package wire
+
+import "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+
+// specificLinkageArgs are the params of revealSpecificKeyLinkage.
+type specificLinkageArgs struct {
+	keyArgs
+	verifier string
+}
+
+// encodeRevealCounterpartyKeyLinkage encodes the identity keys of the counterparty and the verifier,
+// the call is never privileged.
+func encodeRevealCounterpartyKeyLinkage(counterparty string, verifier string) ([]byte, error) {
+	w := &encodingWriter{}
+	w.privileged(wallet.Privilege{})
+	w.hexValue("counterparty", counterparty, publicKeySize)
+	w.hexValue("verifier", verifier, publicKeySize)
+	return w.result(), w.err
+}
+
+func decodeRevealCounterpartyKeyLinkage(params []byte) (counterparty string, verifier string, err error) {
+	r := &reader{data: params}
+	r.privileged()
+	counterparty = r.hexValue(publicKeySize)
+	verifier = r.hexValue(publicKeySize)
+	return counterparty, verifier, r.done()
+}
+
+func encodeRevealSpecificKeyLinkage(args specificLinkageArgs) ([]byte, error) {
+	w := &encodingWriter{}
+	if err := w.keyArgs(args.keyArgs); err != nil {
+		return nil, err
+	}
+	w.hexValue("verifier", args.verifier, publicKeySize)
+	return w.result(), w.err
+}
+
+func decodeRevealSpecificKeyLinkage(params []byte) (specificLinkageArgs, error) {
+	r := &reader{data: params}
+	args := specificLinkageArgs{keyArgs: r.keyArgs()}
+	args.verifier = r.hexValue(publicKeySize)
+	return args, r.done()
+}
+
+// encodeKeyLinkageResult encodes the result of revealCounterpartyKeyLinkage, or of revealSpecificKeyLinkage
+// with the protocol, keyID and proof type. The encrypted proof is always empty, the results carry no proof.
+func encodeKeyLinkageResult(c call, result wallet.KeyLinkageResult) ([]byte, error) {
+	w := &encodingWriter{}
+	w.hexValue("prover", result.Prover, publicKeySize)
+	w.hexValue("verifier", result.Verifier, publicKeySize)
+	w.hexValue("counterparty", result.Counterparty, publicKeySize)
+	if c == callRevealCounterpartyKeyLinkage {
+		w.string(result.RevelationTime)
+		w.varBytes(result.EncryptedLinkage)
+		// encryptedLinkageProof
+		w.varBytes(nil)
+		return w.result(), w.err
+	}
+
+	w.byte(byte(result.ProtocolID.SecurityLevel))
+	w.string(result.ProtocolID.Protocol)
+	w.string(result.KeyID)
+	w.varBytes(result.EncryptedLinkage)
+	// encryptedLinkageProof
+	w.varBytes(nil)
+	w.byte(result.ProofType)
+	return w.result(), w.err
+}
+
+// decodeKeyLinkageResult decodes the result of either revelation, dropping the encrypted proof.
+func decodeKeyLinkageResult(c call, data []byte) (wallet.KeyLinkageResult, error) {
+	r := &reader{data: data}
+	result := wallet.KeyLinkageResult{
+		Prover:       r.hexValue(publicKeySize),
+		Verifier:     r.hexValue(publicKeySize),
+		Counterparty: r.hexValue(publicKeySize),
+	}
+	if c == callRevealCounterpartyKeyLinkage {
+		result.RevelationTime = r.string()
+		result.EncryptedLinkage = r.varBytes()
+		r.varBytes()
+		result.ProofType = wallet.KeyLinkageProofNone
+		return result, r.done()
+	}
+
+	result.ProtocolID.SecurityLevel = wallet.SecurityLevel(r.byte())
+	result.ProtocolID.Protocol = r.string()
+	result.KeyID = r.string()
+	result.EncryptedLinkage = r.varBytes()
+	r.varBytes()
+	result.ProofType = r.byte()
+	return result, r.done()
+}
@@ -0,0 +1,37 @@
+package wire
+
+import (
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+)
+
+// Option configures the Wallet.
+type Option func(w *Wallet, nonceOptions *[]nonce.Option)
+
+// WithOriginator sets the originator sent with every call, the domain of the app using the wallet, up to 255 bytes.
+func WithOriginator(originator string) Option {
+	return func(w *Wallet, _ *[]nonce.Option) {
+		w.originator = originator
+	}
+}
+
+// WithMaxFrameSize overrides the max size of a response frame, DefaultMaxFrameSize by default.
+func WithMaxFrameSize(size int) Option {
+	return func(w *Wallet, _ *[]nonce.Option) {
+		w.maxFrameSize = size
+	}
+}
+
+// WithVerifyConcurrency overrides the number of concurrent calls verifying the signatures of VerifySignatures,
+// the calls over a stream are always sent one by one.
+func WithVerifyConcurrency(workers int) Option {
+	return func(w *Wallet, _ *[]nonce.Option) {
+		w.verifyWorkers = workers
+	}
+}
+
+// WithNonceOptions configures the nonces created and verified by the wallet, e.g. their max age and reuse.
+func WithNonceOptions(opts ...nonce.Option) Option {
+	return func(_ *Wallet, nonceOptions *[]nonce.Option) {
+		*nonceOptions = append(*nonceOptions, opts...)
+	}
+}
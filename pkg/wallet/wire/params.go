@@ -0,0 +1,324 @@
+package wire
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+const (
+	counterpartyUninitialized = 0
+	counterpartySelf          = 11
+	counterpartyAnyone        = 12
+
+	publicKeySize = 33
+	hmacSize      = 32
+
+	networkMainnet = 0
+	networkTestnet = 1
+
+	// maxPrivilegedReasonLength is the longest privileged reason, the TypeScript SDK prefixes it with an int8 length
+	maxPrivilegedReasonLength = math.MaxInt8
+
+	// signDataFlag precedes the data to sign, the other flag precedes a hash to sign directly, which isn't supported here
+	signDataFlag = 1
+)
+
+// keyArgs are the params shared by the calls using a derived key.
+type keyArgs struct {
+	protocol     wallet.Protocol
+	keyID        string
+	counterparty wallet.Counterparty
+	privilege    wallet.Privilege
+}
+
+// dataArgs are the params of encrypt, decrypt, createHmac and createSignature.
+type dataArgs struct {
+	keyArgs
+	data []byte
+}
+
+// verifyArgs are the params of verifyHmac and verifySignature, the proof is the HMAC or the signature.
+type verifyArgs struct {
+	keyArgs
+	data  []byte
+	proof []byte
+}
+
+func validatePrivilege(privilege wallet.Privilege) error {
+	if len(privilege.Reason) > maxPrivilegedReasonLength {
+		return fmt.Errorf("privileged reason longer than %d bytes", maxPrivilegedReasonLength)
+	}
+	return nil
+}
+
+// privileged writes the privileged flag and the reason, validated with validatePrivilege.
+func (w *writer) privileged(privilege wallet.Privilege) {
+	if privilege.Privileged {
+		w.byte(1)
+	} else {
+		w.byte(noValue)
+	}
+	if privilege.Reason == "" {
+		w.byte(noValue)
+		return
+	}
+	w.byte(byte(len(privilege.Reason)))
+	w.bytes([]byte(privilege.Reason))
+}
+
+func (r *reader) privileged() wallet.Privilege {
+	privilege := wallet.Privilege{Privileged: r.byte() == 1}
+	if length := r.byte(); length != noValue {
+		privilege.Reason = string(r.bytes(int(length)))
+	}
+	return privilege
+}
+
+func (w *writer) counterparty(counterparty wallet.Counterparty) {
+	switch counterparty.Type {
+	case wallet.CounterpartyTypeSelf:
+		w.byte(counterpartySelf)
+	case wallet.CounterpartyTypeAnyone:
+		w.byte(counterpartyAnyone)
+	case wallet.CounterpartyTypeOther:
+		w.bytes(counterparty.PublicKey.Compressed())
+	default:
+		w.byte(counterpartyUninitialized)
+	}
+}
+
+func (r *reader) counterparty() wallet.Counterparty {
+	switch r.peek() {
+	case counterpartyUninitialized:
+		r.byte()
+		return wallet.Counterparty{}
+	case counterpartySelf:
+		r.byte()
+		return wallet.CounterpartySelf()
+	case counterpartyAnyone:
+		r.byte()
+		return wallet.CounterpartyAnyone()
+	}
+	return wallet.CounterpartyOf(r.publicKey())
+}
+
+func (r *reader) publicKey() *ec.PublicKey {
+	raw := r.bytes(publicKeySize)
+	if r.err != nil {
+		return nil
+	}
+	publicKey, err := ec.ParsePubKey(raw)
+	if err != nil {
+		r.fail("invalid public key: %s", err)
+		return nil
+	}
+	return publicKey
+}
+
+func (w *writer) keyArgs(args keyArgs) error {
+	if args.counterparty.Type == wallet.CounterpartyTypeOther {
+		if err := args.counterparty.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validatePrivilege(args.privilege); err != nil {
+		return err
+	}
+	w.byte(byte(args.protocol.SecurityLevel))
+	w.string(args.protocol.Protocol)
+	w.string(args.keyID)
+	w.counterparty(args.counterparty)
+	w.privileged(args.privilege)
+	return nil
+}
+
+func (r *reader) keyArgs() keyArgs {
+	var args keyArgs
+	args.protocol.SecurityLevel = wallet.SecurityLevel(r.byte())
+	args.protocol.Protocol = r.string()
+	args.keyID = r.string()
+	args.counterparty = r.counterparty()
+	args.privilege = r.privileged()
+	return args
+}
+
+// optionalVarInt writes a varint, or the TypeScript SDK's -1 varint for the zero value.
+func (w *writer) optionalVarInt(value int) {
+	if value == 0 {
+		w.varInt(math.MaxUint64)
+		return
+	}
+	w.varInt(uint64(value))
+}
+
+func (r *reader) optionalVarInt() int {
+	value := r.varInt()
+	if value == math.MaxUint64 {
+		return 0
+	}
+	if value > math.MaxInt32 {
+		r.fail("value out of range: %d", value)
+		return 0
+	}
+	return int(value)
+}
+
+func encodeGetPublicKey(options wallet.GetPublicKeyOptions) ([]byte, error) {
+	w := &writer{}
+	w.bool(options.IdentityKey)
+	privilege := wallet.Privilege{Privileged: options.Privileged, Reason: options.PrivilegedReason}
+	if options.IdentityKey {
+		if err := validatePrivilege(privilege); err != nil {
+			return nil, err
+		}
+		w.privileged(privilege)
+		return w.result(), nil
+	}
+
+	err := w.keyArgs(keyArgs{
+		protocol:     options.ProtocolID,
+		keyID:        options.KeyID,
+		counterparty: options.Counterparty,
+		privilege:    privilege,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w.bool(options.ForSelf)
+	// seekPermission
+	w.byte(noValue)
+	return w.result(), nil
+}
+
+func decodeGetPublicKey(params []byte) (wallet.GetPublicKeyOptions, error) {
+	r := &reader{data: params}
+	var options wallet.GetPublicKeyOptions
+	options.IdentityKey = r.bool()
+	if options.IdentityKey {
+		privilege := r.privileged()
+		options.Privileged = privilege.Privileged
+		options.PrivilegedReason = privilege.Reason
+		return options, r.done()
+	}
+
+	args := r.keyArgs()
+	options.ProtocolID = args.protocol
+	options.KeyID = args.keyID
+	options.Counterparty = args.counterparty
+	options.Privileged = args.privilege.Privileged
+	options.PrivilegedReason = args.privilege.Reason
+	options.ForSelf = r.byte() == 1
+	// seekPermission
+	r.byte()
+	return options, r.done()
+}
+
+func encodeDataArgs(c call, args dataArgs) ([]byte, error) {
+	w := &writer{}
+	if err := w.keyArgs(args.keyArgs); err != nil {
+		return nil, err
+	}
+	if c == callCreateSignature {
+		w.byte(signDataFlag)
+	}
+	w.varBytes(args.data)
+	// seekPermission
+	w.byte(noValue)
+	return w.result(), nil
+}
+
+func decodeDataArgs(c call, params []byte) (dataArgs, error) {
+	r := &reader{data: params}
+	var args dataArgs
+	args.keyArgs = r.keyArgs()
+	if c == callCreateSignature {
+		if flag := r.byte(); r.err == nil && flag != signDataFlag {
+			return dataArgs{}, fmt.Errorf("%w: signing a hash directly is not supported", ErrMalformedFrame)
+		}
+	}
+	args.data = r.varBytes()
+	// seekPermission
+	r.byte()
+	return args, r.done()
+}
+
+func encodeVerifyArgs(c call, args verifyArgs) ([]byte, error) {
+	w := &writer{}
+	if err := w.keyArgs(args.keyArgs); err != nil {
+		return nil, err
+	}
+	if c == callVerifyHMAC {
+		w.bytes(args.proof)
+		w.varBytes(args.data)
+	} else {
+		// forSelf
+		w.byte(noValue)
+		w.varBytes(args.proof)
+		w.byte(signDataFlag)
+		w.varBytes(args.data)
+	}
+	// seekPermission
+	w.byte(noValue)
+	return w.result(), nil
+}
+
+func decodeVerifyArgs(c call, params []byte) (verifyArgs, error) {
+	r := &reader{data: params}
+	var args verifyArgs
+	args.keyArgs = r.keyArgs()
+	if c == callVerifyHMAC {
+		args.proof = r.bytes(hmacSize)
+		args.data = r.varBytes()
+	} else {
+		// forSelf
+		r.byte()
+		args.proof = r.varBytes()
+		if flag := r.byte(); r.err == nil && flag != signDataFlag {
+			return verifyArgs{}, fmt.Errorf("%w: verifying a hash directly is not supported", ErrMalformedFrame)
+		}
+		args.data = r.varBytes()
+	}
+	// seekPermission
+	r.byte()
+	return args, r.done()
+}
+
+func encodeNetwork(network string) ([]byte, error) {
+	switch network {
+	case wallet.NetworkMainnet:
+		return []byte{networkMainnet}, nil
+	case wallet.NetworkTestnet:
+		return []byte{networkTestnet}, nil
+	}
+	return nil, fmt.Errorf("unknown network %q", network)
+}
+
+func decodeNetwork(result []byte) (string, error) {
+	r := &reader{data: result}
+	network := r.byte()
+	if err := r.done(); err != nil {
+		return "", err
+	}
+	switch network {
+	case networkMainnet:
+		return wallet.NetworkMainnet, nil
+	case networkTestnet:
+		return wallet.NetworkTestnet, nil
+	}
+	return "", fmt.Errorf("%w: unknown network %d", ErrMalformedFrame, network)
+}
+
+func decodeHeight(result []byte) (uint32, error) {
+	r := &reader{data: result}
+	height := r.varInt()
+	if err := r.done(); err != nil {
+		return 0, err
+	}
+	if height > math.MaxUint32 {
+		return 0, fmt.Errorf("%w: height out of range: %d", ErrMalformedFrame, height)
+	}
+	return uint32(height), nil
+}
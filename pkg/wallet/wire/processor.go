@@ -0,0 +1,248 @@
+package wire
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+)
+
+// Processor is the wallet side of the wire format, it decodes the request frames,
+// calls the wallet and encodes the results or the errors as error frames.
+type Processor struct {
+	wallet       wallet.Interface
+	maxFrameSize int
+}
+
+// NewProcessor creates a processor calling the wallet.
+func NewProcessor(w wallet.Interface) *Processor {
+	return &Processor{wallet: w, maxFrameSize: DefaultMaxFrameSize}
+}
+
+// Serve processes the request frames read from the connection until it's closed or the context is done.
+func (p *Processor) Serve(ctx context.Context, conn io.ReadWriter) error {
+	for ctx.Err() == nil {
+		request, err := readFrame(conn, p.maxFrameSize)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := writeFrame(conn, p.Process(ctx, request)); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+// Process returns the response frame for the request frame.
+func (p *Processor) Process(ctx context.Context, request []byte) []byte {
+	c, _, params, err := decodeRequest(request)
+	if err != nil {
+		return encodeError(errorCodeGeneric, err.Error(), "")
+	}
+	result, err := p.process(ctx, c, params)
+	if err != nil {
+		return encodeError(errorCodeGeneric, err.Error(), "")
+	}
+	return encodeResult(result)
+}
+
+func (p *Processor) process(ctx context.Context, c call, params []byte) ([]byte, error) {
+	switch c {
+	case callGetPublicKey:
+		options, err := decodeGetPublicKey(params)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := p.wallet.GetPublicKey(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		return hex.DecodeString(publicKey)
+
+	case callEncrypt, callDecrypt, callCreateHMAC, callCreateSignature:
+		args, err := decodeDataArgs(c, params)
+		if err != nil {
+			return nil, err
+		}
+		return p.processData(ctx, c, args)
+
+	case callVerifyHMAC, callVerifySignature:
+		args, err := decodeVerifyArgs(c, params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, p.processVerify(ctx, c, args)
+
+	case callListCertificates:
+		options, err := decodeListCertificates(params)
+		if err != nil {
+			return nil, err
+		}
+		result, err := p.wallet.ListCertificates(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		return encodeListCertificatesResult(result)
+
+	case callAcquireCertificate:
+		certificate, err := decodeAcquireCertificate(params)
+		if err != nil {
+			return nil, err
+		}
+		certificate.Subject, err = p.wallet.GetPublicKey(ctx, wallet.GetPublicKeyOptions{IdentityKey: true})
+		if err != nil {
+			return nil, err
+		}
+		if err := p.wallet.AcquireCertificate(ctx, certificate); err != nil {
+			return nil, err
+		}
+		return encodeCertificate(certificate)
+
+	case callProveCertificate:
+		args, err := decodeProveCertificate(params)
+		if err != nil {
+			return nil, err
+		}
+		args.certificate.Keyring, err = p.storedKeyring(ctx, args.certificate)
+		if err != nil {
+			return nil, err
+		}
+		keyring, err := p.wallet.ProveCertificate(ctx, args.certificate, args.verifier, args.fieldsToReveal, args.privilege)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKeyring(keyring)
+
+	case callRelinquishCertificate:
+		args, err := decodeRelinquishCertificate(params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, p.wallet.RelinquishCertificate(ctx, args.certType, args.serialNumber, args.certifier)
+
+	case callRevealCounterpartyKeyLinkage:
+		counterparty, verifier, err := decodeRevealCounterpartyKeyLinkage(params)
+		if err != nil {
+			return nil, err
+		}
+		result, err := p.wallet.RevealCounterpartyKeyLinkage(ctx, counterparty, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKeyLinkageResult(c, result)
+
+	case callRevealSpecificKeyLinkage:
+		args, err := decodeRevealSpecificKeyLinkage(params)
+		if err != nil {
+			return nil, err
+		}
+		result, err := p.wallet.RevealSpecificKeyLinkage(ctx, args.counterparty.String(), args.verifier, args.protocol, args.keyID)
+		if err != nil {
+			return nil, err
+		}
+		return encodeKeyLinkageResult(c, result)
+
+	case callGetNetwork:
+		network, err := p.wallet.GetNetwork(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return encodeNetwork(network)
+
+	case callGetVersion:
+		version, err := p.wallet.GetVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(version), nil
+
+	case callGetHeight:
+		height, err := p.wallet.GetHeight(ctx)
+		if err != nil {
+			return nil, err
+		}
+		w := &writer{}
+		w.varInt(uint64(height))
+		return w.result(), nil
+
+	case callCreateAction:
+		args, err := decodeCreateAction(params)
+		if err != nil {
+			return nil, err
+		}
+		result, err := p.wallet.CreateAction(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		return encodeCreateActionResult(result)
+
+	case callInternalizeAction:
+		args, err := decodeInternalizeAction(params)
+		if err != nil {
+			return nil, err
+		}
+		result, err := p.wallet.InternalizeAction(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Accepted {
+			return nil, fmt.Errorf("%s: not accepted", c)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported call %s", c)
+}
+
+// storedKeyring returns the keyring of the acquired certificate, the wire format doesn't send it with proveCertificate.
+func (p *Processor) storedKeyring(ctx context.Context, certificate wallet.Certificate) (map[string]string, error) {
+	result, err := p.wallet.ListCertificates(ctx, wallet.ListCertificatesOptions{
+		Certifiers: []string{certificate.Certifier},
+		Types:      []string{certificate.Type},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, stored := range result.Certificates {
+		if stored.SerialNumber == certificate.SerialNumber {
+			return stored.Keyring, nil
+		}
+	}
+	return nil, wallet.ErrCertificateNotFound
+}
+
+func (p *Processor) processData(ctx context.Context, c call, args dataArgs) ([]byte, error) {
+	switch c {
+	case callEncrypt:
+		return p.wallet.Encrypt(ctx, args.data, args.protocol, args.keyID, args.counterparty)
+	case callDecrypt:
+		return p.wallet.Decrypt(ctx, args.data, args.protocol, args.keyID, args.counterparty)
+	case callCreateHMAC:
+		return p.wallet.CreateHMAC(ctx, args.data, args.protocol, args.keyID, args.counterparty)
+	default:
+		return p.wallet.CreateSignature(ctx, args.data, args.protocol, args.keyID, args.counterparty, args.privilege)
+	}
+}
+
+// processVerify returns an error for an invalid HMAC or signature, the way the wallets of the TypeScript SDK do.
+func (p *Processor) processVerify(ctx context.Context, c call, args verifyArgs) error {
+	var valid bool
+	var err error
+	if c == callVerifyHMAC {
+		valid, err = p.wallet.VerifyHMAC(ctx, args.data, args.proof, args.protocol, args.keyID, args.counterparty)
+	} else {
+		valid, err = p.wallet.VerifySignature(ctx, args.data, args.proof, args.protocol, args.keyID, args.counterparty)
+	}
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("%s: not valid", c)
+	}
+	return nil
+}
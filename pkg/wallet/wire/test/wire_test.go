@@ -0,0 +1,725 @@
+package wire_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	fixtures "github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet/test"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/keywallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/wire"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/wire/testutil"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/stretchr/testify/require"
+)
+
+var protocol = wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "wire wallet test"}
+
+func newKeyWallet(t *testing.T) *keywallet.Wallet {
+	key, err := ec.NewPrivateKey()
+	require.NoError(t, err)
+	return keywallet.NewKeyWallet(key)
+}
+
+func identityKeyOf(t *testing.T, w wallet.Interface) string {
+	identityKey, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+	require.NoError(t, err)
+	return identityKey
+}
+
+// newStreamWallet connects a stream wallet to a processor serving the wallet over net.Pipe.
+func newStreamWallet(t *testing.T, w wallet.Interface) *wire.Wallet {
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	go func() { _ = wire.NewProcessor(w).Serve(context.Background(), server) }()
+	return wire.NewStreamWallet(client)
+}
+
+func TestWireWallet_RoundTrip(t *testing.T) {
+	keyWallet := newKeyWallet(t)
+	wallets := map[string]*wire.Wallet{
+		"dialer": wire.NewWallet(testutil.NewServer(t, keyWallet).Dial, wire.WithOriginator("example.com")),
+		"stream": newStreamWallet(t, keyWallet),
+	}
+
+	for name, w := range wallets {
+		t.Run(name, func(t *testing.T) {
+			t.Run("Get the identity and derived keys", func(t *testing.T) {
+				// given
+				options := wallet.GetPublicKeyOptions{ProtocolID: protocol, KeyID: "1", Counterparty: wallet.CounterpartyAnyone()}
+
+				// when
+				identityKey, identityErr := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+				derivedKey, derivedErr := w.GetPublicKey(t.Context(), options)
+
+				// then
+				require.NoError(t, identityErr)
+				require.Equal(t, identityKeyOf(t, keyWallet), identityKey)
+				require.NoError(t, derivedErr)
+				expected, err := keyWallet.GetPublicKey(t.Context(), options)
+				require.NoError(t, err)
+				require.Equal(t, expected, derivedKey)
+			})
+
+			t.Run("Create and verify a signature", func(t *testing.T) {
+				// given
+				data := []byte("signed data")
+				peer := newKeyWallet(t)
+				peerCounterparty, err := wallet.ParseCounterparty(identityKeyOf(t, peer))
+				require.NoError(t, err)
+				walletCounterparty, err := wallet.ParseCounterparty(identityKeyOf(t, keyWallet))
+				require.NoError(t, err)
+
+				// when
+				signature, err := w.CreateSignature(t.Context(), data, protocol, "1", peerCounterparty, wallet.Privilege{})
+				require.NoError(t, err)
+				peerSignature, err := peer.CreateSignature(t.Context(), data, protocol, "1", walletCounterparty, wallet.Privilege{})
+				require.NoError(t, err)
+				validForPeer, peerErr := peer.VerifySignature(t.Context(), data, signature, protocol, "1", walletCounterparty)
+				validFromPeer, err := w.VerifySignature(t.Context(), data, peerSignature, protocol, "1", peerCounterparty)
+
+				// then
+				require.NoError(t, peerErr)
+				require.True(t, validForPeer)
+				require.NoError(t, err)
+				require.True(t, validFromPeer)
+			})
+
+			t.Run("Return the error frame of an invalid signature", func(t *testing.T) {
+				// given
+				data := []byte("signed data")
+				signature, err := w.CreateSignature(t.Context(), data, protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+				require.NoError(t, err)
+
+				// when
+				valid, err := w.VerifySignature(t.Context(), []byte("other data"), signature, protocol, "1", wallet.CounterpartySelf())
+
+				// then
+				var walletErr *wire.WalletError
+				require.ErrorAs(t, err, &walletErr)
+				require.False(t, valid)
+			})
+
+			t.Run("Create and verify an HMAC", func(t *testing.T) {
+				// given
+				data := []byte("authenticated data")
+
+				// when
+				hmac, err := w.CreateHMAC(t.Context(), data, protocol, "1", wallet.Counterparty{})
+				require.NoError(t, err)
+				valid, err := w.VerifyHMAC(t.Context(), data, hmac, protocol, "1", wallet.Counterparty{})
+
+				// then
+				require.NoError(t, err)
+				require.True(t, valid)
+			})
+
+			t.Run("Encrypt and decrypt", func(t *testing.T) {
+				// given
+				plaintext := bytes.Repeat([]byte("secret "), 100)
+
+				// when
+				ciphertext, err := w.Encrypt(t.Context(), plaintext, protocol, "1", wallet.CounterpartySelf())
+				require.NoError(t, err)
+				decrypted, err := w.Decrypt(t.Context(), ciphertext, protocol, "1", wallet.CounterpartySelf())
+
+				// then
+				require.NoError(t, err)
+				require.Equal(t, plaintext, decrypted)
+			})
+
+			t.Run("Create and verify a nonce", func(t *testing.T) {
+				// when
+				value, err := w.CreateNonce(t.Context())
+				require.NoError(t, err)
+				valid, err := w.VerifyNonce(t.Context(), value)
+
+				// then
+				require.NoError(t, err)
+				require.True(t, valid)
+			})
+		})
+	}
+}
+
+func TestWireWallet_VerifySignatures(t *testing.T) {
+	t.Run("Return the result of every item", func(t *testing.T) {
+		// given
+		keyWallet := newKeyWallet(t)
+		w := wire.NewWallet(testutil.NewServer(t, keyWallet).Dial)
+		data := []byte("signed data")
+		signature, err := keyWallet.CreateSignature(t.Context(), data, protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{})
+		require.NoError(t, err)
+		item := wallet.VerifyItem{Data: data, Signature: signature, ProtocolID: protocol, KeyID: "1", Counterparty: wallet.CounterpartySelf()}
+		tampered := item
+		tampered.Data = []byte("tampered data")
+
+		// when
+		results, err := w.VerifySignatures(t.Context(), []wallet.VerifyItem{item, tampered, item})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []bool{true, false, true}, results)
+	})
+
+	t.Run("Return the transport failure", func(t *testing.T) {
+		// given
+		w := wire.NewWallet(func(context.Context) (io.ReadWriteCloser, error) {
+			return nil, io.ErrClosedPipe
+		})
+		item := wallet.VerifyItem{Data: []byte("data"), Signature: []byte("signature"), ProtocolID: protocol, KeyID: "1"}
+
+		// when
+		results, err := w.VerifySignatures(t.Context(), []wallet.VerifyItem{item, item})
+
+		// then
+		require.ErrorIs(t, err, wire.ErrTransport)
+		require.Equal(t, []bool{false, false}, results)
+	})
+}
+
+func TestWireWallet_Certificates(t *testing.T) {
+	fieldEncryption := wallet.Protocol{SecurityLevel: wallet.SecurityLevelAppAndCounterparty, Protocol: "certificate field encryption"}
+	certifier := newKeyWallet(t)
+	subject := newKeyWallet(t)
+	verifier := newKeyWallet(t)
+	w := wire.NewWallet(testutil.NewServer(t, subject).Dial)
+
+	subjectCounterparty, err := wallet.ParseCounterparty(identityKeyOf(t, subject))
+	require.NoError(t, err)
+	encryptedFieldKey, err := certifier.Encrypt(t.Context(), []byte("email field key"), fieldEncryption, "email", subjectCounterparty)
+	require.NoError(t, err)
+
+	certificate := wallet.Certificate{
+		Type:               base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+		SerialNumber:       base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)),
+		Subject:            identityKeyOf(t, subject),
+		Certifier:          identityKeyOf(t, certifier),
+		RevocationOutpoint: "0303030303030303030303030303030303030303030303030303030303030303.1",
+		Fields:             map[string]any{"email": "encrypted email"},
+		Signature:          "3044022001",
+		Keyring:            map[string]string{"email": base64.StdEncoding.EncodeToString(encryptedFieldKey)},
+	}
+
+	t.Run("Acquire and list the certificate", func(t *testing.T) {
+		// when
+		err := w.AcquireCertificate(t.Context(), certificate)
+		require.NoError(t, err)
+		result, err := w.ListCertificates(t.Context(), wallet.ListCertificatesOptions{Certifiers: []string{certificate.Certifier}})
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, 1, result.TotalCount)
+		listed := certificate
+		listed.Keyring = nil
+		require.Equal(t, []wallet.Certificate{listed}, result.Certificates)
+	})
+
+	t.Run("Prove the certificate with the stored keyring", func(t *testing.T) {
+		// given
+		proved := certificate
+		proved.Keyring = nil
+
+		// when
+		keyring, err := w.ProveCertificate(t.Context(), proved, identityKeyOf(t, verifier), []string{"email"}, wallet.Privilege{})
+
+		// then
+		require.NoError(t, err)
+		encrypted, err := base64.StdEncoding.DecodeString(keyring["email"])
+		require.NoError(t, err)
+		fieldKey, err := verifier.Decrypt(t.Context(), encrypted, fieldEncryption, certificate.SerialNumber+" email", subjectCounterparty)
+		require.NoError(t, err)
+		require.Equal(t, []byte("email field key"), fieldKey)
+	})
+
+	t.Run("Relinquish the certificate", func(t *testing.T) {
+		// when
+		err := w.RelinquishCertificate(t.Context(), certificate.Type, certificate.SerialNumber, certificate.Certifier)
+		require.NoError(t, err)
+		secondErr := w.RelinquishCertificate(t.Context(), certificate.Type, certificate.SerialNumber, certificate.Certifier)
+
+		// then
+		var walletErr *wire.WalletError
+		require.ErrorAs(t, secondErr, &walletErr)
+		require.Contains(t, walletErr.Message, wallet.ErrCertificateNotFound.Error())
+	})
+
+	t.Run("Reject a certificate which can't be encoded", func(t *testing.T) {
+		// given
+		invalid := certificate
+		invalid.Type = "email"
+
+		// when
+		err := w.AcquireCertificate(t.Context(), invalid)
+
+		// then
+		require.ErrorContains(t, err, "certificate type")
+	})
+}
+
+func TestCertificateSigningData(t *testing.T) {
+	certificate := wallet.Certificate{
+		Type:               base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+		SerialNumber:       base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32)),
+		Subject:            identityKeyOf(t, newKeyWallet(t)),
+		Certifier:          identityKeyOf(t, newKeyWallet(t)),
+		RevocationOutpoint: "0303030303030303030303030303030303030303030303030303030303030303.1",
+		Fields:             map[string]any{"email": "encrypted email", "name": "encrypted name"},
+		Signature:          "3044022001",
+	}
+
+	t.Run("Cover everything but the signature and the keyring", func(t *testing.T) {
+		// given
+		resigned := certificate.Clone()
+		resigned.Signature = "3044022002"
+		resigned.Keyring = map[string]string{"email": "a2V5"}
+		changed := certificate.Clone()
+		changed.Fields["email"] = "another encrypted email"
+
+		// when
+		data, err := wire.CertificateSigningData(certificate)
+		require.NoError(t, err)
+		resignedData, err := wire.CertificateSigningData(resigned)
+		require.NoError(t, err)
+		changedData, err := wire.CertificateSigningData(changed)
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, data, resignedData)
+		require.NotEqual(t, data, changedData)
+	})
+
+	t.Run("Reject a certificate which can't be encoded", func(t *testing.T) {
+		// given
+		invalid := certificate.Clone()
+		invalid.RevocationOutpoint = "not an outpoint"
+
+		// when
+		_, err := wire.CertificateSigningData(invalid)
+
+		// then
+		require.ErrorContains(t, err, "revocation outpoint")
+	})
+}
+
+func TestWireWallet_NetworkVersionHeight(t *testing.T) {
+	t.Run("Get the network, version and height", func(t *testing.T) {
+		// given
+		mock := wallet.NewMockWallet(fixtures.WithKeyDeriver,
+			wallet.WithMockNetwork(wallet.NetworkTestnet),
+			wallet.WithMockVersion("wire-test-1.0.0"),
+			wallet.WithMockHeight(900000),
+		)
+		w := wire.NewWallet(testutil.NewServer(t, mock).Dial)
+
+		// when
+		network, networkErr := w.GetNetwork(t.Context())
+		version, versionErr := w.GetVersion(t.Context())
+		height, heightErr := w.GetHeight(t.Context())
+
+		// then
+		require.NoError(t, networkErr)
+		require.NoError(t, versionErr)
+		require.NoError(t, heightErr)
+		require.Equal(t, wallet.NetworkTestnet, network)
+		require.Equal(t, "wire-test-1.0.0", version)
+		require.Equal(t, uint32(900000), height)
+	})
+
+	t.Run("Return the wallet error of the height", func(t *testing.T) {
+		// given
+		w := wire.NewWallet(testutil.NewServer(t, newKeyWallet(t)).Dial)
+
+		// when
+		_, err := w.GetHeight(t.Context())
+
+		// then
+		var walletErr *wire.WalletError
+		require.ErrorAs(t, err, &walletErr)
+	})
+
+	t.Run("Reject an unknown network", func(t *testing.T) {
+		// given
+		server := testutil.NewServer(t, nil, testutil.WithResponder(func([]byte) []byte {
+			return testutil.Frame([]byte{0, 2})
+		}))
+		w := wire.NewWallet(server.Dial)
+
+		// when
+		_, err := w.GetNetwork(t.Context())
+
+		// then
+		require.ErrorIs(t, err, wire.ErrMalformedFrame)
+	})
+}
+
+func TestWireWallet_Privileged(t *testing.T) {
+	const reason = "sign the certificate request"
+	mock := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockPrivilegedReasons(reason))
+	w := wire.NewWallet(testutil.NewServer(t, mock).Dial)
+
+	t.Run("Send the privileged reason to the wallet", func(t *testing.T) {
+		// when
+		signature, err := w.CreateSignature(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{Privileged: true, Reason: reason})
+
+		// then
+		require.NoError(t, err)
+		require.NotEmpty(t, signature)
+	})
+
+	t.Run("Return the rejection of another reason as WalletError", func(t *testing.T) {
+		// when
+		_, err := w.CreateSignature(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{Privileged: true, Reason: "other reason"})
+
+		// then
+		var walletErr *wire.WalletError
+		require.ErrorAs(t, err, &walletErr)
+	})
+
+	t.Run("Reject a reason too long for its length prefix", func(t *testing.T) {
+		// when
+		_, err := w.CreateSignature(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf(), wallet.Privilege{Privileged: true, Reason: string(bytes.Repeat([]byte("a"), 128))})
+
+		// then
+		require.Error(t, err)
+		var walletErr *wire.WalletError
+		require.NotErrorAs(t, err, &walletErr)
+	})
+}
+
+func TestWireWallet_RevealKeyLinkage(t *testing.T) {
+	keyWallet := newKeyWallet(t)
+	counterparty := newKeyWallet(t)
+	verifier := newKeyWallet(t)
+	proverCounterparty, err := wallet.ParseCounterparty(identityKeyOf(t, keyWallet))
+	require.NoError(t, err)
+	w := wire.NewWallet(testutil.NewServer(t, keyWallet).Dial)
+
+	t.Run("Reveal the counterparty linkage", func(t *testing.T) {
+		// when
+		result, err := w.RevealCounterpartyKeyLinkage(t.Context(), identityKeyOf(t, counterparty), identityKeyOf(t, verifier))
+		require.NoError(t, err)
+		linkage, err := verifier.Decrypt(t.Context(), result.EncryptedLinkage, wallet.CounterpartyLinkageProtocol, result.RevelationTime, proverCounterparty)
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, identityKeyOf(t, keyWallet), result.Prover)
+		require.Equal(t, identityKeyOf(t, verifier), result.Verifier)
+		require.Equal(t, identityKeyOf(t, counterparty), result.Counterparty)
+		require.Len(t, linkage, 33)
+	})
+
+	t.Run("Reveal the specific linkage", func(t *testing.T) {
+		// when
+		result, err := w.RevealSpecificKeyLinkage(t.Context(), identityKeyOf(t, counterparty), identityKeyOf(t, verifier), protocol, "1")
+		require.NoError(t, err)
+		linkage, err := verifier.Decrypt(t.Context(), result.EncryptedLinkage, wallet.SpecificLinkageProtocol(protocol), "1", proverCounterparty)
+		require.NoError(t, err)
+
+		// then
+		require.Equal(t, identityKeyOf(t, counterparty), result.Counterparty)
+		require.Equal(t, protocol, result.ProtocolID)
+		require.Equal(t, "1", result.KeyID)
+		require.Equal(t, wallet.KeyLinkageProofNone, result.ProofType)
+		require.Len(t, linkage, 32)
+	})
+
+	t.Run("Reject a counterparty which isn't an identity key", func(t *testing.T) {
+		// when
+		_, err := w.RevealCounterpartyKeyLinkage(t.Context(), "anyone", identityKeyOf(t, verifier))
+
+		// then
+		require.Error(t, err)
+		var walletErr *wire.WalletError
+		require.NotErrorAs(t, err, &walletErr)
+	})
+}
+
+// actionRecorder records the args of the created actions.
+type actionRecorder struct {
+	wallet.Interface
+	created []wallet.CreateActionArgs
+}
+
+func (r *actionRecorder) CreateAction(ctx context.Context, args wallet.CreateActionArgs) (wallet.CreateActionResult, error) {
+	r.created = append(r.created, args)
+	return r.Interface.CreateAction(ctx, args)
+}
+
+func TestWireWallet_Actions(t *testing.T) {
+	t.Run("Create an action", func(t *testing.T) {
+		// given
+		recorder := &actionRecorder{Interface: wallet.NewMockWallet(fixtures.WithKeyDeriver)}
+		w := wire.NewWallet(testutil.NewServer(t, recorder).Dial)
+		args := wallet.CreateActionArgs{
+			Description: "refund of the payment",
+			InputBEEF:   []byte("beef"),
+			Inputs: []wallet.CreateActionInput{
+				{
+					Outpoint:              fixtures.MockTxID + ".1",
+					InputDescription:      "payment output",
+					UnlockingScriptLength: 108,
+					SequenceNumber:        0xFFFFFFFF,
+				},
+				{
+					Outpoint:         fixtures.MockTxID + ".2",
+					InputDescription: "token output",
+					UnlockingScript:  "0051",
+				},
+			},
+			Outputs: []wallet.CreateActionOutput{{
+				LockingScript:      "76a914000000000000000000000000000000000000000088ac",
+				Satoshis:           1000,
+				OutputDescription:  "refund output",
+				Basket:             "refunds",
+				CustomInstructions: "instructions",
+				Tags:               []string{"refund"},
+			}},
+			LockTime: 850000,
+			Version:  1,
+			Labels:   []string{"refund"},
+		}
+
+		// when
+		result, err := w.CreateAction(t.Context(), args)
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, wallet.CreateActionResult{Txid: fixtures.MockTxID}, result)
+		require.Equal(t, []wallet.CreateActionArgs{args}, recorder.created)
+	})
+
+	t.Run("Internalize an action", func(t *testing.T) {
+		// given
+		var internalized []wallet.InternalizeActionArgs
+		mock := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockInternalizeAction(func(args wallet.InternalizeActionArgs) error {
+			internalized = append(internalized, args)
+			return nil
+		}))
+		w := wire.NewWallet(testutil.NewServer(t, mock).Dial)
+		args := wallet.InternalizeActionArgs{
+			Tx: []byte("atomic beef"),
+			Outputs: []wallet.InternalizeOutput{
+				{
+					OutputIndex: 0,
+					Protocol:    wallet.InternalizeProtocolWalletPayment,
+					PaymentRemittance: &wallet.PaymentRemittance{
+						DerivationPrefix:  "cHJlZml4",
+						DerivationSuffix:  "c3VmZml4",
+						SenderIdentityKey: fixtures.PeerIdentityKey,
+					},
+				},
+				{
+					OutputIndex:         1,
+					Protocol:            wallet.InternalizeProtocolBasketInsertion,
+					InsertionRemittance: &wallet.BasketInsertion{Basket: "tokens", Tags: []string{"token"}},
+				},
+			},
+			Description: "payment for the request",
+			Labels:      []string{"payment"},
+		}
+
+		// when
+		result, err := w.InternalizeAction(t.Context(), args)
+
+		// then
+		require.NoError(t, err)
+		require.True(t, result.Accepted)
+		require.Equal(t, []wallet.InternalizeActionArgs{args}, internalized)
+	})
+
+	t.Run("Return the rejection as WalletError", func(t *testing.T) {
+		// given
+		mock := wallet.NewMockWallet(fixtures.WithKeyDeriver, wallet.WithMockInternalizeAction(func(wallet.InternalizeActionArgs) error {
+			return errors.New(fixtures.ErrorActionRejected)
+		}))
+		w := wire.NewWallet(testutil.NewServer(t, mock).Dial)
+		args := wallet.InternalizeActionArgs{
+			Tx: []byte("atomic beef"),
+			Outputs: []wallet.InternalizeOutput{{
+				Protocol:            wallet.InternalizeProtocolBasketInsertion,
+				InsertionRemittance: &wallet.BasketInsertion{Basket: "tokens"},
+			}},
+			Description: "payment for the request",
+		}
+
+		// when
+		result, err := w.InternalizeAction(t.Context(), args)
+
+		// then
+		var walletErr *wire.WalletError
+		require.ErrorAs(t, err, &walletErr)
+		require.Equal(t, fixtures.ErrorActionRejected, walletErr.Message)
+		require.False(t, result.Accepted)
+	})
+
+	t.Run("Reject an output without remittance", func(t *testing.T) {
+		// given
+		w := wire.NewWallet(testutil.NewServer(t, newKeyWallet(t)).Dial)
+		args := wallet.InternalizeActionArgs{
+			Tx:          []byte("atomic beef"),
+			Outputs:     []wallet.InternalizeOutput{{Protocol: wallet.InternalizeProtocolWalletPayment}},
+			Description: "payment for the request",
+		}
+
+		// when
+		_, err := w.InternalizeAction(t.Context(), args)
+
+		// then
+		require.Error(t, err)
+		require.NotErrorIs(t, err, wire.ErrTransport)
+	})
+
+	t.Run("Reject a signable transaction", func(t *testing.T) {
+		// given
+		server := testutil.NewServer(t, nil, testutil.WithResponder(func([]byte) []byte {
+			// no txid and tx, two -1 varints for no noSendChange and sendWithResults, a signable transaction
+			result := append([]byte{0, 0, 0}, bytes.Repeat([]byte{0xFF}, 18)...)
+			return testutil.Frame(append(result, 1, 1, 0, 1, 0))
+		}))
+		w := wire.NewWallet(server.Dial)
+
+		// when
+		_, err := w.CreateAction(t.Context(), wallet.CreateActionArgs{Description: "refund of the payment"})
+
+		// then
+		require.ErrorIs(t, err, wire.ErrMalformedFrame)
+	})
+}
+
+func TestWireWallet_Frames(t *testing.T) {
+	t.Run("Read a response written in small chunks", func(t *testing.T) {
+		// given
+		server := testutil.NewServer(t, newKeyWallet(t), testutil.WithChunkedWrites(1))
+		w := wire.NewWallet(server.Dial)
+		plaintext := bytes.Repeat([]byte("partial "), 50)
+
+		// when
+		ciphertext, err := w.Encrypt(t.Context(), plaintext, protocol, "1", wallet.CounterpartySelf())
+		require.NoError(t, err)
+		decrypted, err := w.Decrypt(t.Context(), ciphertext, protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, plaintext, decrypted)
+	})
+
+	t.Run("Return the error frame as WalletError", func(t *testing.T) {
+		// given
+		server := testutil.NewServer(t, nil, testutil.WithResponder(func([]byte) []byte {
+			return testutil.ErrorFrame(5, "user denied")
+		}))
+		w := wire.NewWallet(server.Dial)
+
+		// when
+		_, err := w.CreateHMAC(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		var walletErr *wire.WalletError
+		require.ErrorAs(t, err, &walletErr)
+		require.Equal(t, byte(5), walletErr.Code)
+		require.Equal(t, "user denied", walletErr.Message)
+	})
+
+	t.Run("Send the call code and originator", func(t *testing.T) {
+		// given
+		requests := make(chan []byte, 1)
+		server := testutil.NewServer(t, nil, testutil.WithResponder(func(request []byte) []byte {
+			requests <- request
+			return testutil.Frame([]byte{0, 1, 2})
+		}))
+		w := wire.NewWallet(server.Dial, wire.WithOriginator("example.com"))
+
+		// when
+		hmac, err := w.CreateHMAC(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		require.NoError(t, err)
+		require.Equal(t, []byte{1, 2}, hmac)
+		request := <-requests
+		require.Equal(t, byte(13), request[0])
+		require.Equal(t, byte(len("example.com")), request[1])
+		require.Equal(t, "example.com", string(request[2:2+len("example.com")]))
+	})
+
+	tests := map[string]struct {
+		response    []byte
+		expectedErr error
+	}{
+		"oversized frame": {
+			response:    testutil.Frame(make([]byte, 1025)),
+			expectedErr: wire.ErrFrameTooLarge,
+		},
+		"truncated frame": {
+			response:    testutil.Frame([]byte{0, 1, 2})[:5],
+			expectedErr: wire.ErrTransport,
+		},
+		"empty frame": {
+			response:    testutil.Frame(nil),
+			expectedErr: wire.ErrMalformedFrame,
+		},
+		"truncated error frame": {
+			response:    testutil.Frame(testutil.ErrorFrame(1, "failure")[4:8]),
+			expectedErr: wire.ErrMalformedFrame,
+		},
+	}
+	for name, test := range tests {
+		t.Run("Reject "+name, func(t *testing.T) {
+			// given
+			server := testutil.NewServer(t, nil, testutil.WithResponder(func([]byte) []byte {
+				return test.response
+			}))
+			w := wire.NewWallet(server.Dial, wire.WithMaxFrameSize(1024))
+
+			// when
+			_, err := w.Encrypt(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf())
+
+			// then
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+
+	t.Run("Fail the next calls over a stream broken by an oversized frame", func(t *testing.T) {
+		// given
+		client, server := net.Pipe()
+		t.Cleanup(func() {
+			_ = client.Close()
+			_ = server.Close()
+		})
+		go func() {
+			_, _ = testutil.ReadFrame(server)
+			_, _ = server.Write(testutil.Frame(make([]byte, 2048)))
+		}()
+		w := wire.NewStreamWallet(client, wire.WithMaxFrameSize(1024))
+
+		// when
+		_, firstErr := w.Encrypt(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf())
+		_, secondErr := w.Encrypt(t.Context(), []byte("data"), protocol, "1", wallet.CounterpartySelf())
+
+		// then
+		require.ErrorIs(t, firstErr, wire.ErrFrameTooLarge)
+		require.ErrorIs(t, secondErr, wire.ErrTransport)
+	})
+
+	t.Run("Fail when the wallet can't be dialed", func(t *testing.T) {
+		// given
+		w := wire.NewWallet(func(context.Context) (io.ReadWriteCloser, error) {
+			return nil, io.ErrClosedPipe
+		})
+
+		// when
+		_, err := w.GetPublicKey(t.Context(), wallet.GetPublicKeyOptions{IdentityKey: true})
+
+		// then
+		require.ErrorIs(t, err, wire.ErrTransport)
+		require.ErrorIs(t, err, io.ErrClosedPipe)
+	})
+}
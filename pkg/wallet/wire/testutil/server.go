@@ -0,0 +1,132 @@
+// Package testutil provides an in-process wallet wire server for testing the wire wallet.
+package testutil
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/wire"
+)
+
+// Responder returns the raw bytes the server writes back for the request frame, including the length prefix.
+type Responder func(request []byte) []byte
+
+// Server is an in-process wallet wire server, serving a single call over every connection opened by Dial.
+type Server struct {
+	t         testing.TB
+	processor *wire.Processor
+	respond   Responder
+	chunkSize int
+}
+
+// ServerOption configures the Server.
+type ServerOption func(*Server)
+
+// WithResponder replaces the processing of the requests with the responder, e.g. to send malformed or error frames.
+func WithResponder(respond Responder) ServerOption {
+	return func(s *Server) {
+		s.respond = respond
+	}
+}
+
+// WithChunkedWrites makes the server write the responses in chunks of the given size, so the client reads them partially.
+func WithChunkedWrites(size int) ServerOption {
+	return func(s *Server) {
+		s.chunkSize = size
+	}
+}
+
+// NewServer creates a server processing the requests with the wallet.
+func NewServer(t testing.TB, w wallet.Interface, opts ...ServerOption) *Server {
+	s := &Server{t: t, processor: wire.NewProcessor(w)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Dial opens a new connection to the server, it can be used as the wire.Dialer.
+func (s *Server) Dial(_ context.Context) (io.ReadWriteCloser, error) {
+	client, server := net.Pipe()
+	s.t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	go s.serve(server)
+	return client, nil
+}
+
+// serve responds to a single request and closes the connection, like a wallet dialed by the wire.Dialer.
+func (s *Server) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	request, err := ReadFrame(conn)
+	if err != nil {
+		return
+	}
+	var response []byte
+	if s.respond != nil {
+		response = s.respond(request)
+	} else {
+		response = Frame(s.processor.Process(context.Background(), request))
+	}
+
+	var out io.Writer = conn
+	if s.chunkSize > 0 {
+		out = &chunkedWriter{w: conn, size: s.chunkSize}
+	}
+	_, _ = out.Write(response)
+}
+
+// Frame prefixes the payload with its length, the way frames are sent over the connection.
+func Frame(payload []byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), payload...)
+}
+
+// ReadFrame reads a length prefixed frame.
+func ReadFrame(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(header[:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// ErrorFrame returns the framed error frame with the code and message, as sent by the wallet on failure.
+func ErrorFrame(code byte, message string) []byte {
+	payload := []byte{code}
+	payload = append(payload, varBytes([]byte(message))...)
+	payload = append(payload, varBytes(nil)...)
+	return Frame(payload)
+}
+
+// varBytes prefixes the value with its length as a varint, supporting only the lengths below 0xFD.
+func varBytes(value []byte) []byte {
+	return append([]byte{byte(len(value))}, value...)
+}
+
+type chunkedWriter struct {
+	w    io.Writer
+	size int
+}
+
+func (c *chunkedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		chunk := p[written:min(written+c.size, len(p))]
+		n, err := c.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
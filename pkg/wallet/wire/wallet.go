@@ -0,0 +1,363 @@
+package wire
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/4chain-ag/go-bsv-middleware/pkg/temporary/wallet"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/internal/batch"
+	"github.com/4chain-ag/go-bsv-middleware/pkg/wallet/nonce"
+)
+
+// ErrTransport is returned when the wallet can't be reached or the connection fails during a call.
+var ErrTransport = errors.New("wallet wire transport failed")
+
+// Dialer opens a new connection to the wallet, the connection is closed after a single call.
+type Dialer func(ctx context.Context) (io.ReadWriteCloser, error)
+
+var _ wallet.Interface = (*Wallet)(nil)
+
+// Wallet is a wallet.Interface implementation sending the calls to a wallet speaking the BRC-100 wallet wire format.
+// Every message is sent over the connection prefixed with its length as a 4 byte big endian integer.
+//
+// The context deadline is applied to the connections supporting SetDeadline, like net.Conn,
+// the calls over other connections can't be interrupted.
+//
+// The wire format has no nonce calls, so the nonces are created and verified locally,
+// authenticated with HMACs created by the wallet, see nonce.Manager.
+type Wallet struct {
+	dial          Dialer
+	originator    string
+	maxFrameSize  int
+	verifyWorkers int
+	nonces        *nonce.Manager
+
+	// mu serializes the calls over the shared stream
+	mu     sync.Mutex
+	stream io.ReadWriter
+	// broken is the failure which left the shared stream in an unknown state
+	broken error
+}
+
+// NewWallet creates a wallet opening a new connection for every call.
+func NewWallet(dial Dialer, opts ...Option) *Wallet {
+	return newWallet(&Wallet{dial: dial}, opts)
+}
+
+// NewStreamWallet creates a wallet sending the calls one by one over the stream.
+// A transport failure breaks the stream, the next calls fail with ErrTransport.
+func NewStreamWallet(stream io.ReadWriter, opts ...Option) *Wallet {
+	return newWallet(&Wallet{stream: stream}, opts)
+}
+
+func newWallet(w *Wallet, opts []Option) *Wallet {
+	w.maxFrameSize = DefaultMaxFrameSize
+	w.verifyWorkers = batch.DefaultWorkers
+	var nonceOptions []nonce.Option
+	for _, opt := range opts {
+		opt(w, &nonceOptions)
+	}
+	w.nonces = nonce.NewManager(w, nonceOptions...)
+	return w
+}
+
+// GetPublicKey calls getPublicKey.
+func (w *Wallet) GetPublicKey(ctx context.Context, options wallet.GetPublicKeyOptions) (string, error) {
+	params, err := encodeGetPublicKey(options)
+	if err != nil {
+		return "", err
+	}
+	result, err := w.transmit(ctx, callGetPublicKey, params)
+	if err != nil {
+		return "", err
+	}
+	if len(result) != publicKeySize {
+		return "", fmt.Errorf("%w: public key of %d bytes", ErrMalformedFrame, len(result))
+	}
+	return hex.EncodeToString(result), nil
+}
+
+// CreateSignature calls createSignature.
+func (w *Wallet) CreateSignature(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty, privilege wallet.Privilege) ([]byte, error) {
+	return w.transmitData(ctx, callCreateSignature, data, keyArgs{protocol: protocolID, keyID: keyID, counterparty: counterparty, privilege: privilege})
+}
+
+// VerifySignature calls verifySignature.
+// Wallets return an error frame for an invalid signature, so it's returned as the WalletError.
+func (w *Wallet) VerifySignature(ctx context.Context, data []byte, signature []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	return w.transmitVerify(ctx, callVerifySignature, data, signature, protocolID, keyID, counterparty)
+}
+
+// VerifySignatures calls verifySignature for every item, concurrently over the connections opened by the Dialer
+// or one by one over the stream, the wire format has no batch call.
+// The item rejected with an error frame is invalid, the transport failures and malformed frames are returned as the error.
+func (w *Wallet) VerifySignatures(ctx context.Context, items []wallet.VerifyItem) ([]bool, error) {
+	verify := func(ctx context.Context, item wallet.VerifyItem) (bool, error) {
+		return w.VerifySignature(ctx, item.Data, item.Signature, item.ProtocolID, item.KeyID, item.Counterparty)
+	}
+	workers := w.verifyWorkers
+	if w.dial == nil {
+		workers = 1
+	}
+	return batch.VerifySignatures(ctx, items, workers, verify, isWalletFailure)
+}
+
+// CreateHMAC calls createHmac.
+func (w *Wallet) CreateHMAC(ctx context.Context, data []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	return w.transmitData(ctx, callCreateHMAC, data, keyArgs{protocol: protocolID, keyID: keyID, counterparty: counterparty})
+}
+
+// VerifyHMAC calls verifyHmac.
+// Wallets return an error frame for an invalid HMAC, so it's returned as the WalletError.
+func (w *Wallet) VerifyHMAC(ctx context.Context, data []byte, hmac []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	if len(hmac) != hmacSize {
+		return false, nil
+	}
+	return w.transmitVerify(ctx, callVerifyHMAC, data, hmac, protocolID, keyID, counterparty)
+}
+
+// Encrypt calls encrypt.
+func (w *Wallet) Encrypt(ctx context.Context, plaintext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	return w.transmitData(ctx, callEncrypt, plaintext, keyArgs{protocol: protocolID, keyID: keyID, counterparty: counterparty})
+}
+
+// Decrypt calls decrypt.
+func (w *Wallet) Decrypt(ctx context.Context, ciphertext []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) ([]byte, error) {
+	return w.transmitData(ctx, callDecrypt, ciphertext, keyArgs{protocol: protocolID, keyID: keyID, counterparty: counterparty})
+}
+
+// CreateNonce creates a nonce locally, authenticated with an HMAC created by the wallet.
+func (w *Wallet) CreateNonce(ctx context.Context) (string, error) {
+	return w.nonces.Create(ctx)
+}
+
+// VerifyNonce verifies the nonce locally, with an HMAC verified by the wallet.
+func (w *Wallet) VerifyNonce(ctx context.Context, value string) (bool, error) {
+	if err := w.nonces.Verify(ctx, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListCertificates calls listCertificates.
+func (w *Wallet) ListCertificates(ctx context.Context, options wallet.ListCertificatesOptions) (wallet.ListCertificatesResult, error) {
+	params, err := encodeListCertificates(options)
+	if err != nil {
+		return wallet.ListCertificatesResult{}, err
+	}
+	result, err := w.transmit(ctx, callListCertificates, params)
+	if err != nil {
+		return wallet.ListCertificatesResult{}, err
+	}
+	return decodeListCertificatesResult(result)
+}
+
+// AcquireCertificate calls acquireCertificate with the "direct" acquisition protocol.
+func (w *Wallet) AcquireCertificate(ctx context.Context, certificate wallet.Certificate) error {
+	params, err := encodeAcquireCertificate(certificate)
+	if err != nil {
+		return err
+	}
+	_, err = w.transmit(ctx, callAcquireCertificate, params)
+	return err
+}
+
+// RelinquishCertificate calls relinquishCertificate.
+func (w *Wallet) RelinquishCertificate(ctx context.Context, certType string, serialNumber string, certifier string) error {
+	params, err := encodeRelinquishCertificate(relinquishArgs{certType: certType, serialNumber: serialNumber, certifier: certifier})
+	if err != nil {
+		return err
+	}
+	_, err = w.transmit(ctx, callRelinquishCertificate, params)
+	return err
+}
+
+// ProveCertificate calls proveCertificate.
+func (w *Wallet) ProveCertificate(ctx context.Context, certificate wallet.Certificate, verifier string, fieldsToReveal []string, privilege wallet.Privilege) (map[string]string, error) {
+	params, err := encodeProveCertificate(proveArgs{certificate: certificate, fieldsToReveal: fieldsToReveal, verifier: verifier, privilege: privilege})
+	if err != nil {
+		return nil, err
+	}
+	result, err := w.transmit(ctx, callProveCertificate, params)
+	if err != nil {
+		return nil, err
+	}
+	return decodeKeyring(result)
+}
+
+// RevealCounterpartyKeyLinkage calls revealCounterpartyKeyLinkage, the counterparty and verifier are identity keys.
+func (w *Wallet) RevealCounterpartyKeyLinkage(ctx context.Context, counterparty string, verifier string) (wallet.KeyLinkageResult, error) {
+	params, err := encodeRevealCounterpartyKeyLinkage(counterparty, verifier)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	result, err := w.transmit(ctx, callRevealCounterpartyKeyLinkage, params)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	return decodeKeyLinkageResult(callRevealCounterpartyKeyLinkage, result)
+}
+
+// RevealSpecificKeyLinkage calls revealSpecificKeyLinkage, the verifier is an identity key.
+func (w *Wallet) RevealSpecificKeyLinkage(ctx context.Context, counterparty string, verifier string, protocolID wallet.Protocol, keyID string) (wallet.KeyLinkageResult, error) {
+	parsedCounterparty, err := wallet.ParseCounterparty(counterparty)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	params, err := encodeRevealSpecificKeyLinkage(specificLinkageArgs{
+		keyArgs:  keyArgs{protocol: protocolID, keyID: keyID, counterparty: parsedCounterparty},
+		verifier: verifier,
+	})
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	result, err := w.transmit(ctx, callRevealSpecificKeyLinkage, params)
+	if err != nil {
+		return wallet.KeyLinkageResult{}, err
+	}
+	return decodeKeyLinkageResult(callRevealSpecificKeyLinkage, result)
+}
+
+// GetNetwork calls getNetwork.
+func (w *Wallet) GetNetwork(ctx context.Context) (string, error) {
+	result, err := w.transmit(ctx, callGetNetwork, nil)
+	if err != nil {
+		return "", err
+	}
+	return decodeNetwork(result)
+}
+
+// GetVersion calls getVersion.
+func (w *Wallet) GetVersion(ctx context.Context) (string, error) {
+	result, err := w.transmit(ctx, callGetVersion, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// GetHeight calls getHeight.
+func (w *Wallet) GetHeight(ctx context.Context) (uint32, error) {
+	result, err := w.transmit(ctx, callGetHeight, nil)
+	if err != nil {
+		return 0, err
+	}
+	return decodeHeight(result)
+}
+
+// CreateAction calls createAction.
+func (w *Wallet) CreateAction(ctx context.Context, args wallet.CreateActionArgs) (wallet.CreateActionResult, error) {
+	params, err := encodeCreateAction(args)
+	if err != nil {
+		return wallet.CreateActionResult{}, err
+	}
+	result, err := w.transmit(ctx, callCreateAction, params)
+	if err != nil {
+		return wallet.CreateActionResult{}, err
+	}
+	return decodeCreateActionResult(result)
+}
+
+// InternalizeAction calls internalizeAction, the action is accepted unless the wallet returns an error frame.
+func (w *Wallet) InternalizeAction(ctx context.Context, args wallet.InternalizeActionArgs) (wallet.InternalizeActionResult, error) {
+	params, err := encodeInternalizeAction(args)
+	if err != nil {
+		return wallet.InternalizeActionResult{}, err
+	}
+	if _, err := w.transmit(ctx, callInternalizeAction, params); err != nil {
+		return wallet.InternalizeActionResult{}, err
+	}
+	return wallet.InternalizeActionResult{Accepted: true}, nil
+}
+
+func (w *Wallet) transmitData(ctx context.Context, c call, data []byte, args keyArgs) ([]byte, error) {
+	params, err := encodeDataArgs(c, dataArgs{keyArgs: args, data: data})
+	if err != nil {
+		return nil, err
+	}
+	return w.transmit(ctx, c, params)
+}
+
+func (w *Wallet) transmitVerify(ctx context.Context, c call, data []byte, proof []byte, protocolID wallet.Protocol, keyID string, counterparty wallet.Counterparty) (bool, error) {
+	params, err := encodeVerifyArgs(c, verifyArgs{
+		keyArgs: keyArgs{protocol: protocolID, keyID: keyID, counterparty: counterparty},
+		data:    data,
+		proof:   proof,
+	})
+	if err != nil {
+		return false, err
+	}
+	if _, err := w.transmit(ctx, c, params); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// isWalletFailure returns true for the errors which aren't the wallet's answer to the call.
+func isWalletFailure(err error) bool {
+	return errors.Is(err, ErrTransport) || errors.Is(err, ErrMalformedFrame) || batch.IsContextError(err)
+}
+
+// transmit sends the request frame of the call and returns the result, or the WalletError of an error frame.
+func (w *Wallet) transmit(ctx context.Context, c call, params []byte) ([]byte, error) {
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("ctx err: %w", ctx.Err())
+	}
+
+	request, err := encodeRequest(c, w.originator, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.dial != nil {
+		conn, err := w.dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrTransport, c, err)
+		}
+		defer func() { _ = conn.Close() }()
+		return w.roundTrip(ctx, c, conn, request)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.broken != nil {
+		return nil, fmt.Errorf("%w: %s: stream broken by a previous call: %w", ErrTransport, c, w.broken)
+	}
+	result, err := w.roundTrip(ctx, c, w.stream, request)
+	if errors.Is(err, ErrTransport) {
+		w.broken = err
+	}
+	return result, err
+}
+
+// roundTrip writes the request and reads the response frame, all the failures leaving the connection
+// in an unknown state are returned as ErrTransport.
+func (w *Wallet) roundTrip(ctx context.Context, c call, conn io.ReadWriter, request []byte) ([]byte, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if conn, ok := conn.(interface{ SetDeadline(time.Time) error }); ok {
+			if err := conn.SetDeadline(deadline); err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrTransport, c, err)
+			}
+			defer func() { _ = conn.SetDeadline(time.Time{}) }()
+		}
+	}
+
+	if err := writeFrame(conn, request); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrTransport, c, err)
+	}
+	frame, err := readFrame(conn, w.maxFrameSize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrTransport, c, err)
+	}
+
+	result, err := decodeResult(frame)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c, err)
+	}
+	return result, nil
+}